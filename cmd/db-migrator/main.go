@@ -12,13 +12,19 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/application/handler"
+	"github.com/raoptimus/db-migrator.go/internal/application/presenter"
+	"github.com/raoptimus/db-migrator.go/internal/domain/service"
 	"github.com/raoptimus/db-migrator.go/internal/domain/validator"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/adapter/urfavecli"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/log"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/source"
 	"github.com/urfave/cli/v3"
 )
 
@@ -33,6 +39,7 @@ func main() {
 	options := handler.Options{}
 	logger := log.Std
 	var handlers *handler.Handlers
+	var migrationSource *source.Source
 
 	cmd := &cli.Command{
 		Name:      "Database Migration Tool",
@@ -45,17 +52,40 @@ func main() {
 			More details about the tool can be found at https://github.com/raoptimus/db-migrator.go`,
 		Version: fmt.Sprintf("%s.rev[%s]", Version, GitCommit),
 		Before: func(ctx context.Context, command *cli.Command) (context.Context, error) {
+			if options.Output == handler.OutputJSON {
+				// stdout is reserved for JSON documents, the log goes to stderr
+				logger = log.New(os.Stderr)
+			}
+			// a remote source is downloaded by the first command reading the migrations, not by help or create
+			src, err := source.OpenLazy(ctx, options.Directory, nil)
+			if err != nil {
+				return ctx, errors.WithMessage(err, "migrationPath")
+			}
+			migrationSource = src
+			options.FS, options.Directory = src.FS, src.Directory
 			handlers = handler.NewHandlers(&options, logger)
 
 			return ctx, nil
 		},
+		After: func(context.Context, *cli.Command) error {
+			if migrationSource == nil {
+				return nil
+			}
+
+			return migrationSource.Close()
+		},
 		Commands: []*cli.Command{
 			{
 				Name: "up",
 				Action: func(ctx context.Context, c *cli.Command) error {
 					return urfavecli.Adapt(handlers.Upgrade)(ctx, c)
 				},
-				Flags: flags(&options, true),
+				Flags: append(flags(&options, true), &cli.BoolFlag{
+					Name:        "resume",
+					Sources:     cli.EnvVars("RESUME"),
+					Usage:       "Continue migrations left pending or failed by an earlier run after their committed statements",
+					Destination: &options.Resume,
+				}),
 			},
 			{
 				Name: "down",
@@ -83,7 +113,27 @@ func main() {
 				Action: func(ctx context.Context, c *cli.Command) error {
 					return urfavecli.Adapt(handlers.Create)(ctx, c)
 				},
-				Flags: flags(&options, false),
+				Flags: append(flags(&options, false),
+					&cli.StringFlag{
+						Name:        "fromDiff",
+						Aliases:     []string{"from-diff"},
+						Usage:       "SQL file of the desired schema to generate the up and down migrations from",
+						Destination: &options.FromDiff,
+					},
+					&cli.StringFlag{
+						Name:        "diffSource",
+						Usage:       "Schema compared with the desired one: replay replays the up migrations in memory, live loads it from the database",
+						Value:       handler.DiffSourceReplay,
+						Destination: &options.DiffSource,
+						Validator: func(s string) error {
+							if s != handler.DiffSourceReplay && s != handler.DiffSourceLive {
+								return fmt.Errorf("unsupported diff source %q", s)
+							}
+
+							return nil
+						},
+					},
+				),
 			},
 			{
 				Name: "history",
@@ -115,15 +165,171 @@ func main() {
 				},
 				Flags: flags(&options, true),
 			},
+			{
+				Name:  "verify",
+				Usage: "Check that the files of applied migrations have not been changed or removed",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return urfavecli.Adapt(handlers.Verify)(ctx, c)
+				},
+				Flags: flags(&options, true),
+			},
+			{
+				Name: "status",
+				Usage: "Show applied, pending, out-of-order, missing-file and dirty migrations; " +
+					"exits with 3 (pending), 4 (out-of-order), 5 (missing file) or 6 (dirty) unless up to date",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return urfavecli.Adapt(handlers.Status)(ctx, c)
+				},
+				Flags: flags(&options, true),
+			},
+			{
+				Name:      "force",
+				Usage:     "Record a migration repaired by hand as applied or reverted without running it",
+				ArgsUsage: "<version>",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return urfavecli.Adapt(handlers.Force)(ctx, c)
+				},
+				Flags: append(flags(&options, true), &cli.StringFlag{
+					Name:        "state",
+					Usage:       "State to record the migration in: applied or reverted",
+					Required:    true,
+					Destination: &options.State,
+					Validator: func(s string) error {
+						if s != service.ForceApplied && s != service.ForceReverted {
+							return fmt.Errorf("unsupported state %q", s)
+						}
+
+						return nil
+					},
+				}),
+			},
+			{
+				Name:  "dump",
+				Usage: "Write the current schema of the database into the dump file, schema.sql in migrationPath by default",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return urfavecli.Adapt(handlers.Dump)(ctx, c)
+				},
+				Flags: flags(&options, true),
+			},
+			{
+				Name:  "lock",
+				Usage: "Inspect or release the lock that prevents concurrent migration runs",
+				Commands: []*cli.Command{
+					{
+						Name:  "status",
+						Usage: "Show who holds the migration lock and since when",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return urfavecli.Adapt(handlers.LockStatus)(ctx, c)
+						},
+						Flags: flags(&options, true),
+					},
+					{
+						Name:  "release",
+						Usage: "Release the migration lock left behind by a crashed run",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return urfavecli.Adapt(handlers.LockRelease)(ctx, c)
+						},
+						Flags: flags(&options, true),
+					},
+				},
+			},
 		},
 		DefaultCommand: "help",
+		// errors that carry an exit code are handled below, together with all other errors
+		ExitErrHandler: func(context.Context, *cli.Command, error) {},
+	}
+
+	disableSliceFlagSeparator(cmd.Commands)
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	if err := cmd.Run(ctx, os.Args); err != nil {
+		if options.Output == handler.OutputJSON {
+			presenter.NewJSONPresenter(os.Stdout).ShowError(err)
+		} else {
+			logger.Error(err)
+		}
+		if notice := interruptedState(err); notice != "" {
+			log.New(os.Stderr).Warn(notice)
+		}
+
+		stop()
+		os.Exit(exitCode(err))
 	}
+}
+
+// interruptContext returns the context of the run, which the first SIGINT or SIGTERM asks to stop after
+// the migration in progress, so a terminated job leaves no migration half-applied, and the second one cancels:
+// the transaction in progress is rolled back and a migration run outside a transaction is left incomplete.
+// The notices go to stderr, which is free of the JSON output.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	notices := log.New(os.Stderr)
+
+	go func() {
+		select {
+		case <-signals:
+		case <-ctx.Done():
+			return
+		}
+		notices.Warn("Interrupted: finishing the migration in progress, the next migrations are left pending. " +
+			"Interrupt again to cancel the migration in progress.")
+		close(interrupt)
 
-	if err := cmd.Run(context.Background(), os.Args); err != nil {
-		logger.Fatal(err)
+		select {
+		case <-signals:
+		case <-ctx.Done():
+			return
+		}
+		notices.Warn("Interrupted again: canceling the migration in progress.")
+		cancel()
+	}()
+
+	return service.WithInterrupt(ctx, interrupt), func() {
+		signal.Stop(signals)
+		cancel()
 	}
 }
 
+// interruptedState describes the state an interrupted run left the database in, it is empty for other errors.
+func interruptedState(err error) string {
+	var incompleteErr *service.IncompleteError
+	switch {
+	case errors.As(err, &incompleteErr):
+		return fmt.Sprintf("The database is left partially migrated by %s, continue it with up --resume, "+
+			"or repair it before the next run and record the result with force --state applied or reverted.",
+			incompleteErr.Version)
+	case errors.Is(err, service.ErrInterrupted):
+		return "The migrations completed before the interruption are recorded in history, the rest are pending."
+	case errors.Is(err, context.Canceled):
+		return "The migration in progress was rolled back or had no statement committed, it is left pending."
+	default:
+		return ""
+	}
+}
+
+// disableSliceFlagSeparator keeps the values of repeatable flags whole, --var values may contain commas.
+// The setting is not inherited, so it is set on every command.
+func disableSliceFlagSeparator(commands []*cli.Command) {
+	for _, command := range commands {
+		command.DisableSliceFlagSeparator = true
+		disableSliceFlagSeparator(command.Commands)
+	}
+}
+
+func exitCode(err error) int {
+	var exitErr cli.ExitCoder
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return 1
+}
+
 func flags(options *handler.Options, dsnIsRequired bool) []cli.Flag {
 	return []cli.Flag{
 		&cli.StringFlag{
@@ -135,6 +341,48 @@ func flags(options *handler.Options, dsnIsRequired bool) []cli.Flag {
 			Value:       "",
 			Validator:   validator.ValidateIdentifier,
 		},
+		&cli.StringMapFlag{
+			Name:        "var",
+			Usage:       "Variable substituted into the {name} placeholders of migrations, as name=value; repeatable",
+			Destination: &options.Vars,
+		},
+		&cli.StringFlag{
+			Name:        "varsFile",
+			Sources:     cli.EnvVars("VARS_FILE"),
+			Usage:       "File of name=value lines with variables substituted into the placeholders of migrations",
+			Destination: &options.VarsFile,
+		},
+		&cli.BoolFlag{
+			Name:        "placeholderStrict",
+			Sources:     cli.EnvVars("PLACEHOLDER_STRICT"),
+			Usage:       "Fail migrations with placeholders that have neither a value nor a default",
+			Destination: &options.StrictPlaceholders,
+		},
+		&cli.BoolFlag{
+			Name:        "allowOutOfOrder",
+			Sources:     cli.EnvVars("ALLOW_OUT_OF_ORDER"),
+			Aliases:     []string{"allow-out-of-order"},
+			Usage:       "Apply pending migrations older than the latest applied migration",
+			Destination: &options.AllowOutOfOrder,
+		},
+		&cli.BoolFlag{
+			Name:        "allowDirty",
+			Sources:     cli.EnvVars("ALLOW_DIRTY"),
+			Usage:       "Run even though a migration is left pending or failed by an earlier run",
+			Destination: &options.AllowDirty,
+		},
+		&cli.StringFlag{
+			Name:        "dumpFile",
+			Sources:     cli.EnvVars("DUMP_FILE"),
+			Usage:       "File the schema is dumped to, schema.sql in migrationPath by default",
+			Destination: &options.DumpFile,
+		},
+		&cli.BoolFlag{
+			Name:        "autoDump",
+			Sources:     cli.EnvVars("AUTO_DUMP"),
+			Usage:       "Dump the schema into the dump file after migrations are applied or reverted",
+			Destination: &options.AutoDump,
+		},
 		&cli.StringFlag{
 			Name:        "dsn",
 			Sources:     cli.EnvVars("DSN"),
@@ -159,9 +407,12 @@ func flags(options *handler.Options, dsnIsRequired bool) []cli.Flag {
 			Sources:     cli.EnvVars("MIGRATION_PATH"),
 			Aliases:     []string{"p"},
 			Value:       "./migrations",
-			Usage:       "Directory for migrated files",
+			Usage:       "Directory for migrated files or a URL of the migration source: file://, s3://, http(s):// tar.gz",
 			Destination: &options.Directory,
 			Validator: func(s string) error {
+				if source.IsURL(s) {
+					return nil
+				}
 				fi, err := os.Stat(s)
 				if err != nil || !fi.IsDir() {
 					return errors.WithMessage(err, "directory does not exist")
@@ -200,6 +451,13 @@ func flags(options *handler.Options, dsnIsRequired bool) []cli.Flag {
 			Usage:       "Using replicated experimental function to clickhouse for history table of migrates",
 			Destination: &options.Replicated,
 		},
+		&cli.BoolFlag{
+			Name:        "migrationFanOut",
+			Sources:     cli.EnvVars("MIGRATION_FAN_OUT"),
+			Value:       false,
+			Usage:       "Apply the migrations to every host of a ClickHouse DSN in turn, each with its own history table",
+			Destination: &options.FanOut,
+		},
 		&cli.BoolFlag{
 			Name:        "compact",
 			Sources:     cli.EnvVars("COMPACT"),
@@ -224,5 +482,90 @@ func flags(options *handler.Options, dsnIsRequired bool) []cli.Flag {
 			Value:       false,
 			Destination: &options.DryRun,
 		},
+		&cli.StringFlag{
+			Name:        "output",
+			Sources:     cli.EnvVars("OUTPUT"),
+			Aliases:     []string{"o"},
+			Usage:       "Output format: text or json",
+			Value:       handler.OutputText,
+			Destination: &options.Output,
+			Validator: func(s string) error {
+				if s != handler.OutputText && s != handler.OutputJSON {
+					return fmt.Errorf("unsupported output format %q", s)
+				}
+
+				return nil
+			},
+		},
+		&cli.DurationFlag{
+			Name:        "lockTimeout",
+			Sources:     cli.EnvVars("LOCK_TIMEOUT"),
+			Usage:       "How long to wait for the migration lock held by another run; 0 fails at once",
+			Value:       time.Minute,
+			Destination: &options.LockTimeout,
+			Validator:   nonNegativeDuration,
+		},
+		&cli.DurationFlag{
+			Name:        "lockStaleAfter",
+			Sources:     cli.EnvVars("LOCK_STALE_AFTER"),
+			Usage:       "Break a migration lock older than this, left behind by a crashed run; 0 never breaks it",
+			Value:       0,
+			Destination: &options.LockStaleAfter,
+			Validator:   nonNegativeDuration,
+		},
+		&cli.DurationFlag{
+			Name:        "statementTimeout",
+			Sources:     cli.EnvVars("STATEMENT_TIMEOUT"),
+			Usage:       "Cancel a migration statement running longer than this; 0 means no limit",
+			Value:       0,
+			Destination: &options.StatementTimeout,
+			Validator:   nonNegativeDuration,
+		},
+		&cli.DurationFlag{
+			Name:        "migrationTimeout",
+			Sources:     cli.EnvVars("MIGRATION_TIMEOUT"),
+			Usage:       "Cancel a migration running longer than this; 0 means no limit",
+			Value:       0,
+			Destination: &options.MigrationTimeout,
+			Validator:   nonNegativeDuration,
+		},
+		&cli.DurationFlag{
+			Name:        "statementLockTimeout",
+			Sources:     cli.EnvVars("STATEMENT_LOCK_TIMEOUT"),
+			Usage:       "Fail a migration statement waiting for a table lock longer than this; 0 keeps the database default",
+			Value:       0,
+			Destination: &options.StatementLockTimeout,
+			Validator:   nonNegativeDuration,
+		},
+		&cli.IntFlag{
+			Name:        "retryAttempts",
+			Sources:     cli.EnvVars("RETRY_ATTEMPTS"),
+			Usage:       "Run a statement that failed on a lock timeout again up to this many times",
+			Value:       0,
+			Destination: &options.RetryAttempts,
+			Validator: func(n int) error {
+				if n < 0 {
+					return fmt.Errorf("retry attempts %d must not be negative", n)
+				}
+
+				return nil
+			},
+		},
+		&cli.DurationFlag{
+			Name:        "retryBackoff",
+			Sources:     cli.EnvVars("RETRY_BACKOFF"),
+			Usage:       "Pause before the first retry, doubled before every next one",
+			Value:       time.Second,
+			Destination: &options.RetryBackoff,
+			Validator:   nonNegativeDuration,
+		},
 	}
 }
+
+func nonNegativeDuration(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("duration %s must not be negative", d)
+	}
+
+	return nil
+}
@@ -0,0 +1,2 @@
+DROP TABLE test2;
+DROP TABLE test;
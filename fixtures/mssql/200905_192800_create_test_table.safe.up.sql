@@ -0,0 +1,19 @@
+
+CREATE TABLE test (
+    id INT PRIMARY KEY,
+    rank SMALLINT,
+    title nvarchar(255),
+    synonyms nvarchar(max),
+    updated_at datetime2
+);
+GO
+
+create index test_rank_idx ON test(rank);
+GO
+
+CREATE TABLE test2 (
+    id int PRIMARY KEY REFERENCES test(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    document nvarchar(max),
+    updated_at datetime2
+);
+GO
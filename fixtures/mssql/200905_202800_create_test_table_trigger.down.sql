@@ -0,0 +1,3 @@
+
+DROP TRIGGER test_index_update_trigger;
+GO
@@ -0,0 +1,23 @@
+
+CREATE TRIGGER test_index_update_trigger ON test
+AFTER INSERT, UPDATE
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    UPDATE t2 SET
+        document = CONCAT(i.title, ' ', i.synonyms),
+        updated_at = SYSDATETIME()
+    FROM test2 t2
+    JOIN inserted i ON i.id = t2.id;
+
+    INSERT INTO test2 (id, document, updated_at)
+    SELECT i.id, CONCAT(i.title, ' ', i.synonyms), SYSDATETIME()
+    FROM inserted i
+    WHERE NOT EXISTS (SELECT 1 FROM test2 t2 WHERE t2.id = i.id);
+END
+GO
+
+insert into test (id, rank, title, synonyms, updated_at)
+values (1, 0, 'some title 1', 'some', SYSDATETIME());
+GO
@@ -0,0 +1,10 @@
+insert into test (id, rank, title, synonyms, updated_at)
+values
+    (2, 0, 'some title 2', 'some', SYSDATETIME()),
+    (3, 0, 'some title 3', 'some', SYSDATETIME())
+;
+GO
+
+insert into test (id, rank, title, synonyms, updated_at)
+values (2, 0, 'some title 4', 'some', SYSDATETIME());
+GO
@@ -0,0 +1,16 @@
+
+CREATE TABLE test (
+    id INTEGER PRIMARY KEY,
+    rank SMALLINT,
+    title text,
+    synonyms text,
+    updated_at TIMESTAMP
+);
+
+create index test_rank_idx ON test(rank);
+
+CREATE TABLE test2 (
+    id INTEGER PRIMARY KEY REFERENCES test(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    document text,
+    updated_at timestamp
+);
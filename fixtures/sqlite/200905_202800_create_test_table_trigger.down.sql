@@ -0,0 +1,4 @@
+
+DROP TRIGGER test_index_update_trigger;
+DROP TRIGGER test_index_insert_trigger;
+
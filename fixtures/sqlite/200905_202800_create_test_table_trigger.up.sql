@@ -0,0 +1,26 @@
+
+CREATE TRIGGER test_index_insert_trigger
+    AFTER INSERT ON test
+    FOR EACH ROW
+BEGIN
+    INSERT INTO test2
+    VALUES (
+        NEW.id,
+        COALESCE(NEW.title, '') || ' ' || COALESCE(NEW.synonyms, ''),
+        CURRENT_TIMESTAMP
+    );
+END;
+
+CREATE TRIGGER test_index_update_trigger
+    AFTER UPDATE ON test
+    FOR EACH ROW
+BEGIN
+    UPDATE test2 SET
+        document = CASE WHEN NEW.synonyms IS NULL THEN COALESCE(NEW.title, '')
+                   ELSE COALESCE(NEW.title, '') || ' ' || NEW.synonyms END,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = NEW.id;
+END;
+
+insert into test (id, rank, title, synonyms, updated_at)
+values (1, 0, 'some title 1', 'some', CURRENT_TIMESTAMP);
@@ -0,0 +1,8 @@
+insert into test (id, rank, title, synonyms, updated_at)
+values
+    (2, 0, 'some title 2', 'some', CURRENT_TIMESTAMP),
+    (3, 0, 'some title 3', 'some', CURRENT_TIMESTAMP)
+;
+
+insert into test (id, rank, title, synonyms, updated_at)
+values (2, 0, 'some title 4', 'some', CURRENT_TIMESTAMP);
@@ -16,6 +16,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/helper/console"
 	"github.com/raoptimus/db-migrator.go/internal/helper/timex"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 )
 
 const fileModeExecutable = 0o755
@@ -24,12 +25,16 @@ const fileModeExecutable = 0o755
 var ErrInvalidFileName = errors.New("the migration name should contain letters, digits, underscore and/or backslash characters only")
 var regexpFileName = regexp.MustCompile(`^[\w\\]+$`)
 
+// ErrReadOnlySource is returned when migration files are created in a source other than a local directory.
+var ErrReadOnlySource = errors.New("migration files can only be created in a local directory")
+
 // Create handles the creation of new migration files.
 type Create struct {
 	options         *Options
 	file            File
 	logger          Logger
 	fileNameBuilder FileNameBuilder
+	schemaLoader    SchemaLoader
 }
 
 // NewCreate creates a new Create handler instance.
@@ -38,17 +43,24 @@ func NewCreate(
 	logger Logger,
 	file File,
 	fileNameBuilder FileNameBuilder,
+	schemaLoader SchemaLoader,
 ) *Create {
 	return &Create{
 		options:         options,
 		file:            file,
 		logger:          logger,
 		fileNameBuilder: fileNameBuilder,
+		schemaLoader:    schemaLoader,
 	}
 }
 
 // Handle processes the create command to generate new migration files.
+// With FromDiff the files get the statements that change the current schema into the desired one and back,
+// otherwise they are empty.
 func (c *Create) Handle(cmd *Command) error {
+	if c.options.FS != nil {
+		return errors.WithStack(ErrReadOnlySource)
+	}
 	if !cmd.Args.Present() {
 		return errors.WithStack(ErrInvalidFileName)
 	}
@@ -58,6 +70,18 @@ func (c *Create) Handle(cmd *Command) error {
 		return ErrInvalidFileName
 	}
 
+	var upSQL, downSQL []byte
+	if c.options.FromDiff != "" {
+		var err error
+		if upSQL, downSQL, err = c.diff(cmd); err != nil {
+			return err
+		}
+		if upSQL == nil {
+			c.logger.Warn("The schema does not differ from the desired one, no migration created.")
+			return nil
+		}
+	}
+
 	prefix := timex.StdTime.Now().Format("060102_150405")
 	version := prefix + "_" + migrationName
 	fileNameUp, _ := c.fileNameBuilder.Up(version, true)
@@ -76,11 +100,11 @@ func (c *Create) Handle(cmd *Command) error {
 		return err
 	}
 
-	if err := c.file.Create(fileNameUp); err != nil {
+	if err := c.writeFile(fileNameUp, upSQL); err != nil {
 		return err
 	}
 
-	if err := c.file.Create(fileNameDown); err != nil {
+	if err := c.writeFile(fileNameDown, downSQL); err != nil {
 		return err
 	}
 
@@ -89,6 +113,50 @@ func (c *Create) Handle(cmd *Command) error {
 	return nil
 }
 
+// diff returns the statements of the up and down migrations that change the current schema
+// into the desired one of FromDiff and back, or nil when the schemas do not differ.
+func (c *Create) diff(cmd *Command) (upSQL, downSQL []byte, err error) {
+	catalog, err := diffCatalog(c.options.DSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := c.file.ReadAll(c.options.FromDiff)
+	if err != nil {
+		return nil, nil, err
+	}
+	desired := ddl.NewSchema()
+	if err := replaySchema(desired, catalog, data); err != nil {
+		return nil, nil, errors.WithMessagef(err, "reading desired schema %s", c.options.FromDiff)
+	}
+
+	current, err := c.schemaLoader.LoadSchema(cmd)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "loading current schema")
+	}
+
+	up, down := ddl.Diff(current, desired)
+	if len(up) == 0 {
+		return nil, nil, nil
+	}
+	if upSQL, err = formatOperations(up); err != nil {
+		return nil, nil, err
+	}
+	if downSQL, err = formatOperations(down); err != nil {
+		return nil, nil, err
+	}
+
+	return upSQL, downSQL, nil
+}
+
+// writeFile writes the statements into the migration file, or creates an empty one when there are none.
+func (c *Create) writeFile(fileName string, sql []byte) error {
+	if sql == nil {
+		return c.file.Create(fileName)
+	}
+
+	return c.file.WriteFile(fileName, sql)
+}
+
 func (c *Create) createDirectory(path string) error {
 	if ok, err := c.file.Exists(path); err != nil || ok {
 		return err
@@ -11,10 +11,12 @@ package handler
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/raoptimus/db-migrator.go/internal/domain/log"
 	"github.com/raoptimus/db-migrator.go/internal/domain/model"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
 )
 
@@ -29,6 +31,9 @@ type Logger = log.Logger
 type File interface {
 	Create(filename string) error
 	Exists(path string) (bool, error)
+	Glob(pattern string) ([]string, error)
+	ReadAll(filename string) ([]byte, error)
+	WriteFile(filename string, data []byte) error
 }
 
 // FileNameBuilder defines the interface for building migration file names.
@@ -41,6 +46,13 @@ type FileNameBuilder interface {
 	Down(version string, forceSafely bool) (fname string, safely bool)
 }
 
+// SchemaLoader defines the interface for loading the current schema compared by the schema diff.
+//
+//go:generate mockery
+type SchemaLoader interface {
+	LoadSchema(cmd *Command) (*ddl.Schema, error)
+}
+
 // MigrationService defines the interface for managing database migration operations.
 //
 //go:generate mockery
@@ -61,12 +73,33 @@ type MigrationService interface {
 	ApplySQL(ctx context.Context, safely bool, version, upSQL string) error
 	// RevertSQL reverts a migration by executing the provided SQL statements
 	RevertSQL(ctx context.Context, safely bool, version, downSQL string) error
+	// DirtyMigrations returns the migrations left pending or failed by an earlier run
+	DirtyMigrations(ctx context.Context) (model.Migrations, error)
+	// Force records the migration as applied, or removes it from history as reverted, without running it
+	Force(ctx context.Context, version, state string) error
+	// DumpSchema returns the current schema of the database
+	DumpSchema(ctx context.Context) (string, error)
+	// LoadSchema returns the current tables of the database as the schema diff compares them
+	LoadSchema(ctx context.Context) (*ddl.Schema, error)
 	// LatestReleaseMigrations returns migrations from the latest release batch
 	LatestReleaseMigrations(ctx context.Context) (model.Migrations, error)
 	// ExecInTransaction executes a function within a database transaction
 	ExecInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 	// FileExists checks whether a file exists at the specified path
 	FileExists(fileName string) (bool, error)
+	// HasNoTransactionDirectives reports whether the migration file has statements that must not run
+	// within a single transaction
+	HasNoTransactionDirectives(fileName string) (bool, error)
+	// Verify returns applied migrations that do not match their files
+	Verify(ctx context.Context) (model.Drifts, error)
+	// Lock takes the migration lock, waiting while another process holds it
+	Lock(ctx context.Context) error
+	// Unlock releases the migration lock taken by Lock
+	Unlock(ctx context.Context) error
+	// LockStatus returns the current holder of the migration lock, or nil when the lock is free
+	LockStatus(ctx context.Context) (*model.Lock, error)
+	// ReleaseLock releases the migration lock regardless of its holder
+	ReleaseLock(ctx context.Context) error
 }
 
 // Connection defines the interface for database connection operations.
@@ -88,6 +121,10 @@ type Connection interface {
 type Presenter interface {
 	// PrintMigrations prints a list of migrations with optional time information.
 	PrintMigrations(migrations model.Migrations, withTime bool)
+	// ShowMigrationApplied displays the result of a single applied migration.
+	ShowMigrationApplied(version string, duration time.Duration)
+	// ShowMigrationReverted displays the result of a single reverted migration.
+	ShowMigrationReverted(version string, duration time.Duration)
 	// ShowNoNewMigrations displays a message when there are no new migrations to apply.
 	ShowNoNewMigrations()
 	// ShowNoMigrationsToRevert displays a message when there are no migrations to revert.
@@ -130,4 +167,22 @@ type Presenter interface {
 	ShowRollbackError()
 	// ShowMissingDownFiles displays a message about missing down migration files.
 	ShowMissingDownFiles(versions []string)
+	// ShowDrifts displays applied migrations that do not match their files.
+	ShowDrifts(drifts model.Drifts)
+	// ShowNoDrifts displays a message when all applied migrations match their files.
+	ShowNoDrifts()
+	// ShowStatus displays the state of every migration and a summary line.
+	ShowStatus(statuses model.MigrationStatuses)
+	// ShowLockStatus displays the holder of the migration lock, or that the lock is free when lock is nil.
+	ShowLockStatus(lock *model.Lock)
+	// AskLockReleaseConfirmation returns a confirmation question for releasing the migration lock.
+	AskLockReleaseConfirmation(lock *model.Lock) string
+	// ShowLockReleased displays a success message after the migration lock has been released.
+	ShowLockReleased(lock *model.Lock)
+	// AskForceConfirmation returns a confirmation question for forcing the migration into the state.
+	AskForceConfirmation(version, state string) string
+	// ShowForced displays a success message after the migration has been forced into the state.
+	ShowForced(version, state string)
+	// ShowDumped displays a success message after the schema has been dumped into the file.
+	ShowDumped(fileName string)
 }
@@ -9,7 +9,7 @@
 package handler
 
 import (
-	"github.com/raoptimus/db-migrator.go/internal/helper/console"
+	"time"
 )
 
 // Downgrade handles the reverting of previously applied migrations.
@@ -49,8 +49,8 @@ func (d *Downgrade) Handle(cmd *Command, svc MigrationService) error {
 	d.presenter.ShowDowngradePlan(migrations)
 
 	question := d.presenter.AskDowngradeConfirmation(migrationsCount)
-	if d.options.Interactive && !console.Confirm(question) {
-		return nil
+	if ok, err := confirm(d.options, question); !ok {
+		return err
 	}
 
 	reverted := 0
@@ -59,10 +59,12 @@ func (d *Downgrade) Handle(cmd *Command, svc MigrationService) error {
 		migration := &migrations[i]
 		fileName, safely := d.fileNameBuilder.Down(migration.Version, false)
 
+		started := time.Now()
 		if err := svc.RevertFile(cmd.Context(), migration, fileName, safely); err != nil {
 			d.presenter.ShowDowngradeError(reverted, migrationsCount)
 			return err
 		}
+		d.presenter.ShowMigrationReverted(migration.Version, time.Since(started))
 
 		reverted++
 	}
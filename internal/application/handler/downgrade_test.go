@@ -168,6 +168,9 @@ func TestDowngrade_Handle_RevertSuccessfully_NonInteractive(t *testing.T) {
 		RevertFile(mock.Anything, &migrations[0], "/migrations/200101_120000_test.down.sql", true).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationReverted(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 	presenterMock.EXPECT().
 		ShowDowngradeSuccess(1).
 		Once()
@@ -264,10 +267,16 @@ func TestDowngrade_Handle_MultipleMigrations_Successfully(t *testing.T) {
 		RevertFile(mock.Anything, &migrations[0], "/migrations/200101_120000_test.down.sql", true).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationReverted(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 	svcMock.EXPECT().
 		RevertFile(mock.Anything, &migrations[1], "/migrations/200102_120000_test.down.sql", false).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationReverted(migrations[1].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 	presenterMock.EXPECT().
 		ShowDowngradeSuccess(2).
 		Once()
@@ -0,0 +1,84 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+// Dump handles writing the current schema of the database into the dump file.
+type Dump struct {
+	options   *Options
+	presenter Presenter
+	file      File
+}
+
+// NewDump creates a new Dump handler instance.
+func NewDump(
+	options *Options,
+	presenter Presenter,
+	file File,
+) *Dump {
+	return &Dump{
+		options:   options,
+		presenter: presenter,
+		file:      file,
+	}
+}
+
+// Handle processes the dump command.
+// It writes the schema introspected by the database, without the migration history and lock tables,
+// into the dump file, replacing its content.
+func (d *Dump) Handle(cmd *Command, svc MigrationService) error {
+	fileName, err := d.options.DumpFileName()
+	if err != nil {
+		return err
+	}
+
+	schema, err := svc.DumpSchema(cmd.Context())
+	if err != nil {
+		return err
+	}
+	if err := d.file.WriteFile(fileName, []byte(schema)); err != nil {
+		return err
+	}
+
+	d.presenter.ShowDumped(fileName)
+
+	return nil
+}
+
+// AutoDump runs a handler of a command that changes the database and dumps the schema once it succeeds,
+// so the dump file follows the migrations. It dumps nothing in dry-run mode or when AutoDump is off.
+type AutoDump struct {
+	options *Options
+	handler ServiceHandler
+	dump    ServiceHandler
+}
+
+// NewAutoDump creates a new AutoDump handler instance.
+func NewAutoDump(
+	options *Options,
+	handler ServiceHandler,
+	dump ServiceHandler,
+) *AutoDump {
+	return &AutoDump{
+		options: options,
+		handler: handler,
+		dump:    dump,
+	}
+}
+
+// Handle runs the wrapped handler and then dumps the schema.
+func (a *AutoDump) Handle(cmd *Command, svc MigrationService) error {
+	if err := a.handler.Handle(cmd, svc); err != nil {
+		return err
+	}
+	if !a.options.AutoDump || a.options.DryRun {
+		return nil
+	}
+
+	return a.dump.Handle(cmd, svc)
+}
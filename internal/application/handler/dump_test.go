@@ -0,0 +1,110 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errDumpFailed = errors.New("dump failed")
+
+// TestDump_Handle_Successfully tests that Handle writes the schema into schema.sql in the migrations directory.
+func TestDump_Handle_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	fileMock := NewMockFile(t)
+	fileName := filepath.Join("/migrations", "schema.sql")
+
+	migrationServiceMock.EXPECT().DumpSchema(mock.Anything).Return("CREATE TABLE a (id int);\n", nil)
+	fileMock.EXPECT().WriteFile(fileName, []byte("CREATE TABLE a (id int);\n")).Return(nil)
+	presenterMock.EXPECT().ShowDumped(fileName)
+
+	handler := NewDump(&Options{Directory: "/migrations"}, presenterMock, fileMock)
+	err := handler.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.NoError(t, err)
+}
+
+// TestDump_Handle_DumpFile_Successfully tests that Handle writes the schema into the dump file of the options.
+func TestDump_Handle_DumpFile_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	fileMock := NewMockFile(t)
+
+	migrationServiceMock.EXPECT().DumpSchema(mock.Anything).Return("", nil)
+	fileMock.EXPECT().WriteFile("/tmp/dump.sql", []byte("")).Return(nil)
+	presenterMock.EXPECT().ShowDumped("/tmp/dump.sql")
+
+	handler := NewDump(&Options{Directory: "migrations", FS: fstest.MapFS{}, DumpFile: "/tmp/dump.sql"},
+		presenterMock, fileMock)
+	err := handler.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.NoError(t, err)
+}
+
+// TestDump_Handle_FSWithoutDumpFile_Failure tests that Handle requires the dump file
+// when the migrations are read from an fs.FS.
+func TestDump_Handle_FSWithoutDumpFile_Failure(t *testing.T) {
+	handler := NewDump(&Options{Directory: "migrations", FS: fstest.MapFS{}}, NewMockPresenter(t), NewMockFile(t))
+	err := handler.Handle(&Command{Args: &argsStub{}}, NewMockMigrationService(t))
+
+	require.ErrorIs(t, err, ErrDumpFileRequired)
+}
+
+// TestDump_Handle_Failure tests that Handle returns the error of the service and writes nothing.
+func TestDump_Handle_Failure(t *testing.T) {
+	migrationServiceMock := NewMockMigrationService(t)
+
+	migrationServiceMock.EXPECT().DumpSchema(mock.Anything).Return("", errDumpFailed)
+
+	handler := NewDump(&Options{Directory: "/migrations"}, NewMockPresenter(t), NewMockFile(t))
+	err := handler.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.ErrorIs(t, err, errDumpFailed)
+}
+
+// TestAutoDump_Handle tests that AutoDump dumps the schema only after the wrapped handler succeeds
+// with AutoDump set and not in dry-run mode.
+func TestAutoDump_Handle(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    *Options
+		handlerErr error
+		dumped     bool
+	}{
+		{name: "dumps after success", options: &Options{AutoDump: true}, dumped: true},
+		{name: "auto dump off", options: &Options{}},
+		{name: "dry run", options: &Options{AutoDump: true, DryRun: true}},
+		{name: "handler failed", options: &Options{AutoDump: true}, handlerErr: errDumpFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &Command{Args: &argsStub{}}
+			migrationServiceMock := NewMockMigrationService(t)
+			handlerMock := NewMockServiceHandler(t)
+			dumpMock := NewMockServiceHandler(t)
+
+			handlerMock.EXPECT().Handle(cmd, migrationServiceMock).Return(tt.handlerErr)
+			if tt.dumped {
+				dumpMock.EXPECT().Handle(cmd, migrationServiceMock).Return(nil)
+			}
+
+			err := NewAutoDump(tt.options, handlerMock, dumpMock).Handle(cmd, migrationServiceMock)
+
+			require.ErrorIs(t, err, tt.handlerErr)
+		})
+	}
+}
@@ -10,7 +10,9 @@ package handler
 
 import (
 	"testing"
+	"testing/fstest"
 
+	iohelp "github.com/raoptimus/db-migrator.go/internal/helper/io"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
 	"github.com/stretchr/testify/require"
 )
@@ -1070,3 +1072,15 @@ func TestNewMockMigrationService_ClickhouseInvalidClusterName_Failure(t *testing
 		})
 	}
 }
+
+func TestNewMigrationFile_Successfully(t *testing.T) {
+	require.Same(t, iohelp.StdFile, NewMigrationFile(&Options{}))
+
+	file := NewMigrationFile(&Options{
+		FS: fstest.MapFS{"migrations/200101_120000_init.up.sql": {Data: []byte("SELECT 1")}},
+	})
+	require.IsType(t, &iohelp.FS{}, file)
+	exists, err := file.Exists("migrations/200101_120000_init.up.sql")
+	require.NoError(t, err)
+	require.True(t, exists)
+}
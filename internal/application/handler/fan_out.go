@@ -0,0 +1,164 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/helper/dsn"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+)
+
+// HostError is the error a fan-out command failed with on a host.
+type HostError struct {
+	Host string
+	Err  error
+}
+
+// HostsError is returned by a fan-out command that failed on some of the hosts, which are left behind the others.
+// It implements the ExitCode method, so the process exits with the code of the failures.
+type HostsError struct {
+	// Total is the number of hosts the command ran against.
+	Total int
+	// Hosts are the hosts the command failed on, in the order of the DSN,
+	// followed by the hosts that have pending migrations applied on other hosts.
+	Hosts []HostError
+}
+
+// Error returns the hosts left behind with their errors.
+func (e *HostsError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d of %d hosts are behind:", len(e.Hosts), e.Total)
+	for _, host := range e.Hosts {
+		fmt.Fprintf(&sb, "\n  %s: %v", host.Host, host.Err)
+	}
+
+	return sb.String()
+}
+
+// ExitCode returns the highest exit code of the failures when every failure has one, such as the status
+// of a host with pending migrations, and 1 otherwise.
+func (e *HostsError) ExitCode() int {
+	code := 0
+	for _, host := range e.Hosts {
+		var exitErr interface{ ExitCode() int }
+		if !errors.As(host.Err, &exitErr) {
+			return 1
+		}
+		code = max(code, exitErr.ExitCode())
+	}
+
+	return max(code, 1)
+}
+
+// handleHosts executes the command against every host of the DSN in turn, each host with its own connection,
+// history table and migration lock. A host that cannot be connected to or that the command fails on does not stop
+// the run, the command goes on with the next host and the hosts left behind are reported at the end,
+// together with the hosts whose pending migrations were applied on other hosts.
+func (w *ServiceWrapHandler) handleHosts(cmd *Command) error {
+	parsed, err := dsn.Parse(w.options.DSN)
+	if err != nil {
+		return errors.WithMessage(err, "parsing DSN")
+	}
+	if parsed.Driver != connection.DriverClickhouse.String() || w.options.ClusterName != "" {
+		return errors.WithStack(ErrFanOutUnsupported)
+	}
+
+	hostsErr := &HostsError{Total: len(parsed.Hosts)}
+	pending := make([]hostPending, 0, len(parsed.Hosts))
+	for _, host := range parsed.Hosts {
+		if err := cmd.Context().Err(); err != nil {
+			return err
+		}
+
+		w.logger.Infof("Host %s:\n", host.Address)
+		options := *w.options
+		options.DSN = parsed.ForHost(host)
+		recorder := &pendingRecorder{handler: w.handler}
+		hostHandler := *w
+		hostHandler.handler = recorder
+		if err := hostHandler.handle(cmd, &options); err != nil {
+			w.logger.Errorf("Host %s: %v\n", host.Address, err)
+			hostsErr.Hosts = append(hostsErr.Hosts, HostError{Host: host.Address, Err: err})
+			continue
+		}
+		pending = append(pending, hostPending{host: host.Address, versions: recorder.versions})
+	}
+
+	for _, behind := range hostsBehind(pending) {
+		w.logger.Errorf("Host %s: %v\n", behind.Host, behind.Err)
+		hostsErr.Hosts = append(hostsErr.Hosts, behind)
+	}
+
+	if len(hostsErr.Hosts) > 0 {
+		return hostsErr
+	}
+
+	return nil
+}
+
+// pendingRecorder is the handler that executes the command on a host of the fan-out
+// and then records the versions of the migrations left pending on the host.
+type pendingRecorder struct {
+	handler  ServiceHandler
+	versions map[string]struct{}
+}
+
+func (h *pendingRecorder) Handle(cmd *Command, svc MigrationService) error {
+	if err := h.handler.Handle(cmd, svc); err != nil {
+		return err
+	}
+
+	migrations, err := svc.NewMigrations(cmd.Context())
+	if err != nil {
+		return errors.WithMessage(err, "failed to read pending migrations")
+	}
+	h.versions = make(map[string]struct{}, len(migrations))
+	for i := range migrations {
+		h.versions[migrations[i].Version] = struct{}{}
+	}
+
+	return nil
+}
+
+// hostPending is the versions of the migrations pending on a host of the fan-out.
+type hostPending struct {
+	host     string
+	versions map[string]struct{}
+}
+
+// hostsBehind returns the hosts with pending migrations that are applied on another host,
+// in the order of the DSN, with the versions of those migrations.
+func hostsBehind(hosts []hostPending) []HostError {
+	var behind []HostError
+	for i := range hosts {
+		var versions []string
+		for version := range hosts[i].versions {
+			for j := range hosts {
+				if _, ok := hosts[j].versions[version]; !ok {
+					versions = append(versions, version)
+					break
+				}
+			}
+		}
+		if len(versions) == 0 {
+			continue
+		}
+		sort.Strings(versions)
+		behind = append(behind, HostError{
+			Host: hosts[i].host,
+			Err:  errors.Wrap(ErrHostBehind, strings.Join(versions, ", ")),
+		})
+	}
+
+	return behind
+}
@@ -0,0 +1,48 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+// Force handles recording a migration repaired by hand as applied or reverted without running it.
+type Force struct {
+	options   *Options
+	presenter Presenter
+}
+
+// NewForce creates a new Force handler instance.
+func NewForce(
+	options *Options,
+	presenter Presenter,
+) *Force {
+	return &Force{
+		options:   options,
+		presenter: presenter,
+	}
+}
+
+// Handle processes the force command.
+// It asks for confirmation in interactive mode and records the migration given as the argument
+// in the state of the options: applied with the checksum of its file, or reverted, removed from history.
+func (f *Force) Handle(cmd *Command, svc MigrationService) error {
+	if !cmd.Args.Present() || cmd.Args.First() == "" {
+		return ErrVersionRequired
+	}
+	version := cmd.Args.First()
+
+	if ok, err := confirm(f.options, f.presenter.AskForceConfirmation(version, f.options.State)); !ok {
+		return err
+	}
+
+	if err := svc.Force(cmd.Context(), version, f.options.State); err != nil {
+		return err
+	}
+
+	f.presenter.ShowForced(version, f.options.State)
+
+	return nil
+}
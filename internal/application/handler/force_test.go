@@ -0,0 +1,93 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errForceFailed = errors.New("force failed")
+
+// TestForce_Handle_Successfully tests that Handle records the migration in the given state.
+func TestForce_Handle_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	version := "200101_120000_init"
+
+	presenterMock.EXPECT().AskForceConfirmation(version, "applied").Return("Record?")
+	migrationServiceMock.EXPECT().Force(mock.Anything, version, "applied").Return(nil)
+	presenterMock.EXPECT().ShowForced(version, "applied")
+
+	handler := NewForce(&Options{State: "applied"}, presenterMock)
+	err := handler.Handle(&Command{Args: &argsStub{present: true, first: version}}, migrationServiceMock)
+
+	require.NoError(t, err)
+}
+
+// TestForce_Handle_VersionMissing_Failure tests that Handle requires the version argument.
+func TestForce_Handle_VersionMissing_Failure(t *testing.T) {
+	handler := NewForce(&Options{State: "applied"}, NewMockPresenter(t))
+	err := handler.Handle(&Command{Args: &argsStub{}}, NewMockMigrationService(t))
+
+	require.ErrorIs(t, err, ErrVersionRequired)
+}
+
+// TestForce_Handle_Failure tests that Handle returns the error of the service.
+func TestForce_Handle_Failure(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	version := "200101_120000_init"
+
+	presenterMock.EXPECT().AskForceConfirmation(version, "reverted").Return("Record?")
+	migrationServiceMock.EXPECT().Force(mock.Anything, version, "reverted").Return(errForceFailed)
+
+	handler := NewForce(&Options{State: "reverted"}, presenterMock)
+	err := handler.Handle(&Command{Args: &argsStub{present: true, first: version}}, migrationServiceMock)
+
+	require.ErrorIs(t, err, errForceFailed)
+}
+
+// TestRefuseDirty_Dirty_Failure tests that RefuseDirty lists the migrations left dirty.
+func TestRefuseDirty_Dirty_Failure(t *testing.T) {
+	migrationServiceMock := NewMockMigrationService(t)
+
+	migrationServiceMock.EXPECT().DirtyMigrations(mock.Anything).Return(model.Migrations{
+		{Version: "200101_120000_init", Status: "failed", LastStatement: 2},
+	}, nil)
+
+	err := RefuseDirty(t.Context(), &Options{}, migrationServiceMock)
+
+	require.ErrorIs(t, err, ErrDirtyDatabase)
+	require.Contains(t, err.Error(), "200101_120000_init (failed after 2 statement(s))")
+}
+
+// TestRefuseDirty_Clean_Successfully tests that RefuseDirty passes a clean database.
+func TestRefuseDirty_Clean_Successfully(t *testing.T) {
+	migrationServiceMock := NewMockMigrationService(t)
+
+	migrationServiceMock.EXPECT().DirtyMigrations(mock.Anything).Return(model.Migrations{}, nil)
+
+	require.NoError(t, RefuseDirty(t.Context(), &Options{}, migrationServiceMock))
+}
+
+// TestRefuseDirty_Resume_Successfully tests that RefuseDirty passes a run resuming the dirty migrations.
+func TestRefuseDirty_Resume_Successfully(t *testing.T) {
+	require.NoError(t, RefuseDirty(t.Context(), &Options{Resume: true}, NewMockMigrationService(t)))
+}
+
+// TestRefuseDirty_Allowed_Successfully tests that RefuseDirty does not look at the history
+// when running on a dirty database is allowed.
+func TestRefuseDirty_Allowed_Successfully(t *testing.T) {
+	require.NoError(t, RefuseDirty(t.Context(), &Options{AllowDirty: true}, NewMockMigrationService(t)))
+}
@@ -9,36 +9,57 @@
 package handler
 
 import (
+	"os"
+
 	"github.com/raoptimus/db-migrator.go/internal/application/presenter"
 	"github.com/raoptimus/db-migrator.go/internal/domain/builder"
 	iohelp "github.com/raoptimus/db-migrator.go/internal/helper/io"
 )
 
 type Handlers struct {
-	Create     Handler
-	Upgrade    Handler
-	Downgrade  Handler
-	Redo       Handler
-	To         Handler
-	History    Handler
-	HistoryNew Handler
-	Release    Handler
-	Rollback   Handler
+	Create      Handler
+	Upgrade     Handler
+	Downgrade   Handler
+	Redo        Handler
+	To          Handler
+	History     Handler
+	HistoryNew  Handler
+	Release     Handler
+	Rollback    Handler
+	Verify      Handler
+	Status      Handler
+	LockStatus  Handler
+	LockRelease Handler
+	Force       Handler
+	Dump        Handler
 }
 
 func NewHandlers(options *Options, logger Logger) *Handlers {
-	fileNameBuilder := builder.NewFileName(iohelp.StdFile, options.Directory)
-	migrationPresenter := presenter.NewMigrationPresenter(logger)
+	fileNameBuilder := builder.NewFileName(NewMigrationFile(options), options.Directory)
+	var migrationPresenter Presenter = presenter.NewMigrationPresenter(logger)
+	if options.Output == OutputJSON {
+		migrationPresenter = presenter.NewJSONPresenter(os.Stdout)
+	}
+	dump := NewDump(options, migrationPresenter, iohelp.StdFile)
+	withAutoDump := func(handler ServiceHandler) ServiceHandler {
+		return NewAutoDump(options, handler, dump)
+	}
 
 	return &Handlers{
-		Create:     NewCreate(options, logger, iohelp.StdFile, fileNameBuilder),
-		Upgrade:    NewServiceWrapHandler(options, logger, NewUpgrade(options, migrationPresenter, fileNameBuilder)),
-		Downgrade:  NewServiceWrapHandler(options, logger, NewDowngrade(options, migrationPresenter, fileNameBuilder)),
-		Redo:       NewServiceWrapHandler(options, logger, NewRedo(options, migrationPresenter, fileNameBuilder)),
-		To:         NewServiceWrapHandler(options, logger, NewTo(options, migrationPresenter, fileNameBuilder)),
-		History:    NewServiceWrapHandler(options, logger, NewHistory(options, migrationPresenter)),
-		HistoryNew: NewServiceWrapHandler(options, logger, NewHistoryNew(options, migrationPresenter)),
-		Release:    NewServiceWrapHandler(options, logger, NewRelease(options, migrationPresenter, fileNameBuilder)),
-		Rollback:   NewServiceWrapHandler(options, logger, NewRollback(options, migrationPresenter, fileNameBuilder)),
+		Create:      NewCreate(options, logger, iohelp.StdFile, fileNameBuilder, NewSchemaDiffLoader(options, logger, iohelp.StdFile)),
+		Upgrade:     NewLockingServiceWrapHandler(options, logger, withAutoDump(NewUpgrade(options, migrationPresenter, fileNameBuilder))),
+		Downgrade:   NewLockingServiceWrapHandler(options, logger, withAutoDump(NewDowngrade(options, migrationPresenter, fileNameBuilder))),
+		Redo:        NewLockingServiceWrapHandler(options, logger, withAutoDump(NewRedo(options, migrationPresenter, fileNameBuilder))),
+		To:          NewLockingServiceWrapHandler(options, logger, withAutoDump(NewTo(options, migrationPresenter, fileNameBuilder))),
+		History:     NewServiceWrapHandler(options, logger, NewHistory(options, migrationPresenter)),
+		HistoryNew:  NewServiceWrapHandler(options, logger, NewHistoryNew(options, migrationPresenter)),
+		Release:     NewLockingServiceWrapHandler(options, logger, withAutoDump(NewRelease(options, migrationPresenter, fileNameBuilder))),
+		Rollback:    NewLockingServiceWrapHandler(options, logger, withAutoDump(NewRollback(options, migrationPresenter, fileNameBuilder))),
+		Verify:      NewServiceWrapHandler(options, logger, NewVerify(options, migrationPresenter)),
+		Status:      NewServiceWrapHandler(options, logger, NewStatus(options, migrationPresenter, fileNameBuilder)),
+		LockStatus:  NewServiceWrapHandler(options, logger, NewLockStatus(options, migrationPresenter)),
+		LockRelease: NewServiceWrapHandler(options, logger, NewLockRelease(options, migrationPresenter)),
+		Force:       NewRepairServiceWrapHandler(options, logger, NewForce(options, migrationPresenter)),
+		Dump:        NewServiceWrapHandler(options, logger, dump),
 	}
 }
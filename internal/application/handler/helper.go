@@ -9,14 +9,17 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/helper/console"
 )
 
 const (
@@ -31,6 +34,42 @@ var ErrArgumentMustBeGreaterThanZero = errors.New("the step argument must be gre
 // ErrMissingDownFiles is returned when down migration files are missing for rollback.
 var ErrMissingDownFiles = errors.New("missing down migration files")
 
+// ErrInteractiveJSONOutput is returned when a command asks for confirmation while writing JSON output.
+var ErrInteractiveJSONOutput = errors.New("the json output requires --interactive=false")
+
+// ErrMigrationsDrift is returned when applied migrations do not match their files.
+var ErrMigrationsDrift = errors.New("applied migrations do not match their files")
+
+// ErrOutOfOrderMigrations is returned when pending migrations are older than the latest applied migration
+// and applying them out of order is not allowed.
+var ErrOutOfOrderMigrations = errors.New(
+	"pending migrations are older than the latest applied migration, use --allowOutOfOrder to apply them",
+)
+
+// ErrDirtyDatabase is returned when migrations were left pending or failed by an earlier run,
+// so the database is partially migrated, and running on a dirty database is not allowed.
+var ErrDirtyDatabase = errors.New(
+	"the database is dirty, resume it with up --resume or repair it and record the result with force, " +
+		"or use --allowDirty to run anyway",
+)
+
+// ErrVersionRequired is returned when the migration version argument is missing.
+var ErrVersionRequired = errors.New("migration version is required")
+
+// ErrFanOutUnsupported is returned when the fan-out is asked for a DSN other than a ClickHouse one
+// or together with a cluster name, whose ON CLUSTER statements already reach every host.
+var ErrFanOutUnsupported = errors.New("the fan-out is supported for ClickHouse DSNs without a cluster name only")
+
+// ErrHostBehind is returned for a host of the fan-out that has pending migrations applied on other hosts.
+var ErrHostBehind = errors.New("migrations applied on other hosts are pending")
+
+// ErrNoTransactionMigrations is returned when release meets migrations whose statements must not run
+// within its single transaction.
+var ErrNoTransactionMigrations = errors.New(
+	"migrations with -- migrator:no-transaction or -- migrator:split statements cannot run within " +
+		"the single transaction of a release, apply them with up",
+)
+
 func stepOrDefault(cmd *Command, defaults int) (int, error) {
 	if !cmd.Args.Present() {
 		return defaults, nil
@@ -57,6 +96,63 @@ func stepOrDefault(cmd *Command, defaults int) (int, error) {
 	}
 }
 
+// refuseOutOfOrder returns ErrOutOfOrderMigrations listing the out of order migrations
+// unless applying them is allowed.
+func refuseOutOfOrder(options *Options, migrations model.Migrations) error {
+	if options.AllowOutOfOrder {
+		return nil
+	}
+
+	var versions []string
+	for i := range migrations {
+		if migrations[i].OutOfOrder {
+			versions = append(versions, migrations[i].Version)
+		}
+	}
+	if len(versions) > 0 {
+		return errors.Wrap(ErrOutOfOrderMigrations, strings.Join(versions, ", "))
+	}
+
+	return nil
+}
+
+// RefuseDirty returns ErrDirtyDatabase listing the migrations left pending or failed by an earlier run
+// unless running on a dirty database is allowed or the run resumes them.
+func RefuseDirty(ctx context.Context, options *Options, svc MigrationService) error {
+	if options.AllowDirty || options.Resume {
+		return nil
+	}
+
+	dirty, err := svc.DirtyMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty.Len() == 0 {
+		return nil
+	}
+
+	versions := make([]string, 0, dirty.Len())
+	for i := range dirty {
+		versions = append(versions, fmt.Sprintf("%s (%s after %d statement(s))",
+			dirty[i].Version, dirty[i].Status, dirty[i].LastStatement))
+	}
+
+	return errors.Wrap(ErrDirtyDatabase, strings.Join(versions, ", "))
+}
+
+// confirm asks the user to confirm the question when the command runs interactively.
+// The question cannot be mixed with JSON output, so JSON output requires a non-interactive run.
+func confirm(options *Options, question string) (bool, error) {
+	if !options.Interactive {
+		return true, nil
+	}
+	if options.Output == OutputJSON {
+		return false, ErrInteractiveJSONOutput
+	}
+
+	return console.Confirm(question), nil
+}
+
 // ErrTargetVersionRequired is returned when target version argument is missing.
 var ErrTargetVersionRequired = errors.New("target version is required")
 
@@ -130,10 +226,12 @@ func applyMigrations(
 		migration := &migrations[i]
 		fileName, safely := fileNameBuilder.Up(migration.Version, false)
 
+		started := time.Now()
 		if err := svc.ApplyFile(cmd.Context(), migration, fileName, safely); err != nil {
 			presenter.ShowUpgradeError(applied, len(migrations))
 			return applied, err
 		}
+		presenter.ShowMigrationApplied(migration.Version, time.Since(started))
 
 		applied++
 	}
@@ -155,10 +253,12 @@ func revertMigrations(
 		migration := &migrations[i]
 		fileName, safely := fileNameBuilder.Down(migration.Version, false)
 
+		started := time.Now()
 		if err := svc.RevertFile(cmd.Context(), migration, fileName, safely); err != nil {
 			presenter.ShowDowngradeError(reverted, len(migrations))
 			return reverted, err
 		}
+		presenter.ShowMigrationReverted(migration.Version, time.Since(started))
 
 		reverted++
 	}
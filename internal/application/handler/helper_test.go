@@ -501,6 +501,9 @@ func TestApplyMigrations_Success(t *testing.T) {
 		ApplyFile(mock.Anything, &migrations[0], "/path/file1.up.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationApplied(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	fileNameBuilder.EXPECT().
 		Up("150101_185401", false).
@@ -511,6 +514,9 @@ func TestApplyMigrations_Success(t *testing.T) {
 		ApplyFile(mock.Anything, &migrations[1], "/path/file2.up.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationApplied(migrations[1].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	cmd := &Command{Args: &argsStub{}}
 	applied, err := applyMigrations(cmd, svc, presenter, fileNameBuilder, migrations)
@@ -538,6 +544,9 @@ func TestApplyMigrations_PartialFailure(t *testing.T) {
 		ApplyFile(mock.Anything, &migrations[0], "/path/file1.up.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationApplied(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	fileNameBuilder.EXPECT().
 		Up("150101_185401", false).
@@ -595,6 +604,9 @@ func TestRevertMigrations_Success(t *testing.T) {
 		RevertFile(mock.Anything, &migrations[0], "/path/file1.down.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationReverted(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	fileNameBuilder.EXPECT().
 		Down("150101_120000", false).
@@ -605,6 +617,9 @@ func TestRevertMigrations_Success(t *testing.T) {
 		RevertFile(mock.Anything, &migrations[1], "/path/file2.down.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationReverted(migrations[1].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	cmd := &Command{Args: &argsStub{}}
 	reverted, err := revertMigrations(cmd, svc, presenter, fileNameBuilder, migrations)
@@ -632,6 +647,9 @@ func TestRevertMigrations_PartialFailure(t *testing.T) {
 		RevertFile(mock.Anything, &migrations[0], "/path/file1.down.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationReverted(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	fileNameBuilder.EXPECT().
 		Down("150101_120000", false).
@@ -669,3 +687,19 @@ func TestRevertMigrations_EmptyList_Success(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 0, reverted)
 }
+
+func TestConfirm_NonInteractive_ReturnsTrue_Successfully(t *testing.T) {
+	for _, output := range []string{OutputText, OutputJSON} {
+		ok, err := confirm(&Options{Interactive: false, Output: output}, "Apply?")
+
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+}
+
+func TestConfirm_InteractiveWithJSONOutput_Failure(t *testing.T) {
+	ok, err := confirm(&Options{Interactive: true, Output: OutputJSON}, "Apply?")
+
+	require.ErrorIs(t, err, ErrInteractiveJSONOutput)
+	require.False(t, ok)
+}
@@ -57,6 +57,30 @@ func TestIntegration_UpDown_Successfully(t *testing.T) {
 				Interactive: false,
 			},
 		},
+		{
+			name:                      "sqlite",
+			selectQueryToRecordsCount: "select count(*) from test",
+			wantRecordsCount:          1,
+			options: &Options{
+				DSN:         os.Getenv("SQLITE_DSN"),
+				Directory:   migrationsPathAbs(os.Getenv("SQLITE_MIGRATIONS_PATH")),
+				TableName:   "migration",
+				Compact:     true,
+				Interactive: false,
+			},
+		},
+		{
+			name:                      "mssql",
+			selectQueryToRecordsCount: "select count(*) from test",
+			wantRecordsCount:          1,
+			options: &Options{
+				DSN:         os.Getenv("MSSQL_DSN"),
+				Directory:   migrationsPathAbs(os.Getenv("MSSQL_MIGRATIONS_PATH")),
+				TableName:   "migration",
+				Compact:     true,
+				Interactive: false,
+			},
+		},
 		{
 			name:                      "mysql",
 			selectQueryToRecordsCount: "select count(*) from test",
@@ -234,6 +258,34 @@ func TestIntegration_ToCommand_Successfully(t *testing.T) {
 				Interactive: false,
 			},
 		},
+		{
+			name:                      "sqlite",
+			selectQueryToRecordsCount: "select count(*) from test",
+			wantRecordsCount:          1,
+			firstMigrationVersion:     "200905_192800",
+			secondMigrationVersion:    "200905_202800",
+			options: &Options{
+				DSN:         os.Getenv("SQLITE_DSN"),
+				Directory:   migrationsPathAbs(os.Getenv("SQLITE_MIGRATIONS_PATH")),
+				TableName:   "migration",
+				Compact:     true,
+				Interactive: false,
+			},
+		},
+		{
+			name:                      "mssql",
+			selectQueryToRecordsCount: "select count(*) from test",
+			wantRecordsCount:          1,
+			firstMigrationVersion:     "200905_192800",
+			secondMigrationVersion:    "200905_202800",
+			options: &Options{
+				DSN:         os.Getenv("MSSQL_DSN"),
+				Directory:   migrationsPathAbs(os.Getenv("MSSQL_MIGRATIONS_PATH")),
+				TableName:   "migration",
+				Compact:     true,
+				Interactive: false,
+			},
+		},
 		{
 			name:                      "mysql",
 			selectQueryToRecordsCount: "select count(*) from test",
@@ -0,0 +1,83 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+// LockStatus handles the display of the migration lock holder.
+type LockStatus struct {
+	options   *Options
+	presenter Presenter
+}
+
+// NewLockStatus creates a new LockStatus handler instance.
+func NewLockStatus(
+	options *Options,
+	presenter Presenter,
+) *LockStatus {
+	return &LockStatus{
+		options:   options,
+		presenter: presenter,
+	}
+}
+
+// Handle processes the lock status command.
+// It displays who holds the migration lock and since when, or that the lock is free.
+func (l *LockStatus) Handle(cmd *Command, svc MigrationService) error {
+	lock, err := svc.LockStatus(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	l.presenter.ShowLockStatus(lock)
+
+	return nil
+}
+
+// LockRelease handles releasing the migration lock left behind by a crashed process.
+type LockRelease struct {
+	options   *Options
+	presenter Presenter
+}
+
+// NewLockRelease creates a new LockRelease handler instance.
+func NewLockRelease(
+	options *Options,
+	presenter Presenter,
+) *LockRelease {
+	return &LockRelease{
+		options:   options,
+		presenter: presenter,
+	}
+}
+
+// Handle processes the lock release command.
+// It displays the lock holder, asks for confirmation in interactive mode
+// and releases the lock regardless of its holder.
+func (l *LockRelease) Handle(cmd *Command, svc MigrationService) error {
+	lock, err := svc.LockStatus(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	l.presenter.ShowLockStatus(lock)
+	if lock == nil {
+		return nil
+	}
+
+	if ok, err := confirm(l.options, l.presenter.AskLockReleaseConfirmation(lock)); !ok {
+		return err
+	}
+
+	if err := svc.ReleaseLock(cmd.Context()); err != nil {
+		return err
+	}
+
+	l.presenter.ShowLockReleased(lock)
+
+	return nil
+}
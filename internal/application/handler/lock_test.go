@@ -0,0 +1,98 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errLockQueryFailed = errors.New("lock query failed")
+
+// TestLockStatus_Handle_Held_Successfully tests that Handle shows the lock holder.
+func TestLockStatus_Handle_Held_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	lock := &model.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}
+
+	migrationServiceMock.EXPECT().LockStatus(mock.Anything).Return(lock, nil)
+	presenterMock.EXPECT().ShowLockStatus(lock)
+
+	handler := NewLockStatus(&Options{}, presenterMock)
+	err := handler.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.NoError(t, err)
+}
+
+// TestLockStatus_Handle_Failure tests that Handle returns the error of the service.
+func TestLockStatus_Handle_Failure(t *testing.T) {
+	migrationServiceMock := NewMockMigrationService(t)
+
+	migrationServiceMock.EXPECT().LockStatus(mock.Anything).Return(nil, errLockQueryFailed)
+
+	handler := NewLockStatus(&Options{}, NewMockPresenter(t))
+	err := handler.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.ErrorIs(t, err, errLockQueryFailed)
+}
+
+// TestLockRelease_Handle_Free_Successfully tests that Handle does nothing but report
+// the state when the lock is free.
+func TestLockRelease_Handle_Free_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+
+	migrationServiceMock.EXPECT().LockStatus(mock.Anything).Return(nil, nil)
+	presenterMock.EXPECT().ShowLockStatus((*model.Lock)(nil))
+
+	handler := NewLockRelease(&Options{}, presenterMock)
+	err := handler.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.NoError(t, err)
+}
+
+// TestLockRelease_Handle_NonInteractive_Successfully tests that Handle releases
+// a held lock without asking for confirmation.
+func TestLockRelease_Handle_NonInteractive_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	lock := &model.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}
+
+	migrationServiceMock.EXPECT().LockStatus(mock.Anything).Return(lock, nil)
+	presenterMock.EXPECT().ShowLockStatus(lock)
+	presenterMock.EXPECT().AskLockReleaseConfirmation(lock).Return("Release?")
+	migrationServiceMock.EXPECT().ReleaseLock(mock.Anything).Return(nil)
+	presenterMock.EXPECT().ShowLockReleased(lock)
+
+	handler := NewLockRelease(&Options{Interactive: false}, presenterMock)
+	err := handler.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.NoError(t, err)
+}
+
+// TestLockRelease_Handle_ReleaseError_Failure tests that Handle returns the error of the service.
+func TestLockRelease_Handle_ReleaseError_Failure(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	lock := &model.Lock{Owner: "db-migrator@host:1"}
+
+	migrationServiceMock.EXPECT().LockStatus(mock.Anything).Return(lock, nil)
+	presenterMock.EXPECT().ShowLockStatus(lock)
+	presenterMock.EXPECT().AskLockReleaseConfirmation(lock).Return("Release?")
+	migrationServiceMock.EXPECT().ReleaseLock(mock.Anything).Return(errLockQueryFailed)
+
+	handler := NewLockRelease(&Options{Interactive: false}, presenterMock)
+	err := handler.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.ErrorIs(t, err, errLockQueryFailed)
+}
@@ -9,13 +9,41 @@
 package handler
 
 import (
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/joho/godotenv"
 	"github.com/pkg/errors"
 
+	"github.com/raoptimus/db-migrator.go/internal/domain/service"
 	"github.com/raoptimus/db-migrator.go/internal/domain/validator"
 )
 
 const maxConnAttempts = 100
 
+const defaultDumpFile = "schema.sql"
+
+// ErrInvalidVarName is returned for a variable name that cannot be used as a placeholder.
+var ErrInvalidVarName = errors.New("the variable name should start with a letter or underscore and contain letters, digits and underscores only")
+
+// ErrReservedVarName is returned for a variable named as a built-in placeholder.
+var ErrReservedVarName = errors.New("the variable name is reserved for a built-in placeholder")
+
+// ErrDumpFileRequired is returned when the schema is dumped without a dump file while the migrations
+// are read from an fs.FS, such as an embedded file system or a downloaded archive, which cannot be written.
+var ErrDumpFileRequired = errors.New("the dump file should be set when the migrations are not read from a local directory")
+
+var regexpVarName = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// Output formats of the command results.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
 // Options contains configuration parameters for database migration operations.
 type Options struct {
 	PlaceholderCustom  string
@@ -29,6 +57,64 @@ type Options struct {
 	Interactive        bool
 	MaxSQLOutputLength int
 	DryRun             bool
+	Output             string
+	LockTimeout        time.Duration
+	LockStaleAfter     time.Duration
+	GoMigrations       *service.GoMigrations
+	// FS is the file system the migration files are read from instead of the OS file system,
+	// Directory is a path within it.
+	FS fs.FS
+	// Vars are the named variables substituted into the {name} placeholders of migration SQL.
+	Vars map[string]string
+	// VarsFile is a file of KEY=VALUE lines with more variables; Vars take precedence over it.
+	VarsFile string
+	// StrictPlaceholders fails migrations with unresolved placeholders.
+	StrictPlaceholders bool
+	// AllowOutOfOrder applies pending migrations older than the latest applied migration
+	// instead of refusing to run.
+	AllowOutOfOrder bool
+	// StatementTimeout limits the execution time of every migration statement, zero means no limit.
+	StatementTimeout time.Duration
+	// MigrationTimeout limits the execution time of every migration, zero means no limit.
+	MigrationTimeout time.Duration
+	// StatementLockTimeout limits how long a statement waits for a table lock on the database side,
+	// zero means the database default.
+	StatementLockTimeout time.Duration
+	// RetryAttempts is how many times a statement that gave up waiting for a lock is run again.
+	RetryAttempts int
+	// RetryBackoff is the pause before the first retry, doubled before every next one.
+	RetryBackoff time.Duration
+	// AllowDirty runs the commands that change the database even though migrations were left pending or failed
+	// by an earlier run, instead of refusing to run.
+	AllowDirty bool
+	// Resume continues the migrations left pending or failed by an earlier run after their committed statements.
+	Resume bool
+	// State is the state the force command records the migration in, applied or reverted.
+	State string
+	// DumpFile is the file the schema is dumped to, schema.sql in Directory by default.
+	DumpFile string
+	// AutoDump dumps the schema after every successful command that changes the database.
+	AutoDump bool
+	// FromDiff is the SQL file of the desired schema the create command generates the migration from.
+	FromDiff string
+	// DiffSource is where the current schema compared with FromDiff comes from, replay or live.
+	DiffSource string
+	// FanOut runs the commands against every host of a ClickHouse DSN in turn, each host keeping its own
+	// history table, instead of the first reachable host.
+	FanOut bool
+}
+
+// DumpFileName returns the file the schema is dumped to.
+// The dump file has to be set when the migrations are read from FS.
+func (o *Options) DumpFileName() (string, error) {
+	if o.DumpFile != "" {
+		return o.DumpFile, nil
+	}
+	if o.FS != nil {
+		return "", ErrDumpFileRequired
+	}
+
+	return filepath.Join(o.Directory, defaultDumpFile), nil
 }
 
 func (o *Options) Validate() error {
@@ -44,6 +130,43 @@ func (o *Options) Validate() error {
 	if err := validator.ValidateIdentifier(o.ClusterName); err != nil {
 		return errors.WithMessage(err, "clusterName")
 	}
+	if err := validateVars(o.Vars); err != nil {
+		return errors.WithMessage(err, "var")
+	}
+
+	return nil
+}
+
+// PlaceholderVars returns the variables of the vars file overridden by Vars.
+func (o *Options) PlaceholderVars() (map[string]string, error) {
+	if err := validateVars(o.Vars); err != nil {
+		return nil, errors.WithMessage(err, "var")
+	}
+	if o.VarsFile == "" {
+		return o.Vars, nil
+	}
+
+	vars, err := godotenv.Read(o.VarsFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading vars file %s", o.VarsFile)
+	}
+	if err := validateVars(vars); err != nil {
+		return nil, errors.WithMessagef(err, "vars file %s", o.VarsFile)
+	}
+	maps.Copy(vars, o.Vars)
+
+	return vars, nil
+}
+
+func validateVars(vars map[string]string) error {
+	for name := range vars {
+		if !regexpVarName.MatchString(name) {
+			return errors.Wrapf(ErrInvalidVarName, "%q", name)
+		}
+		if service.IsBuiltinPlaceholder(name) {
+			return errors.Wrapf(ErrReservedVarName, "%q", name)
+		}
+	}
 
 	return nil
 }
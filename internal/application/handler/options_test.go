@@ -9,6 +9,8 @@
 package handler
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -226,3 +228,73 @@ func TestOptions_Validate_InvalidClusterName_Failure(t *testing.T) {
 		})
 	}
 }
+
+func TestOptions_Validate_InvalidVars_Failure(t *testing.T) {
+	tests := []struct {
+		name        string
+		vars        map[string]string
+		expectedErr error
+	}{
+		{
+			name:        "starts with a digit",
+			vars:        map[string]string{"1tenant": "acme"},
+			expectedErr: ErrInvalidVarName,
+		},
+		{
+			name:        "contains a colon",
+			vars:        map[string]string{"env:HOME": "/root"},
+			expectedErr: ErrInvalidVarName,
+		},
+		{
+			name:        "built-in placeholder",
+			vars:        map[string]string{"cluster": "main"},
+			expectedErr: ErrReservedVarName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{
+				MaxConnAttempts: 1,
+				TableName:       "migration",
+				Vars:            tt.vars,
+			}
+
+			err := opts.Validate()
+
+			require.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestOptions_PlaceholderVars_Successfully(t *testing.T) {
+	varsFile := filepath.Join(t.TempDir(), "vars.env")
+	require.NoError(t, os.WriteFile(varsFile, []byte("# tenant defaults\ntenant=acme\nregion=eu\n"), 0o600))
+
+	opts := Options{
+		Vars:     map[string]string{"region": "us"},
+		VarsFile: varsFile,
+	}
+
+	vars, err := opts.PlaceholderVars()
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"tenant": "acme", "region": "us"}, vars)
+}
+
+func TestOptions_PlaceholderVars_InvalidVarsFile_Failure(t *testing.T) {
+	varsFile := filepath.Join(t.TempDir(), "vars.env")
+	require.NoError(t, os.WriteFile(varsFile, []byte("username=admin\n"), 0o600))
+
+	_, err := (&Options{VarsFile: varsFile}).PlaceholderVars()
+	require.ErrorIs(t, err, ErrReservedVarName)
+
+	_, err = (&Options{VarsFile: filepath.Join(t.TempDir(), "missing.env")}).PlaceholderVars()
+	require.Error(t, err)
+}
+
+func TestOptions_PlaceholderVars_InvalidVar_Failure(t *testing.T) {
+	_, err := (&Options{Vars: map[string]string{"password": "secret"}}).PlaceholderVars()
+
+	require.ErrorIs(t, err, ErrReservedVarName)
+}
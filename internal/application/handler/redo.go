@@ -9,8 +9,9 @@
 package handler
 
 import (
+	"time"
+
 	"github.com/raoptimus/db-migrator.go/internal/domain/model"
-	"github.com/raoptimus/db-migrator.go/internal/helper/console"
 )
 
 // Redo handles the reverting and reapplying of previously applied migrations.
@@ -54,8 +55,8 @@ func (r *Redo) Handle(cmd *Command, svc MigrationService) error {
 	r.presenter.ShowRedoPlan(migrations)
 
 	question := r.presenter.AskRedoConfirmation(migrationsCount)
-	if r.options.Interactive && !console.Confirm(question) {
-		return nil
+	if ok, err := confirm(r.options, question); !ok {
+		return err
 	}
 
 	reversedMigrations := make(model.Migrations, 0, migrationsCount)
@@ -63,10 +64,12 @@ func (r *Redo) Handle(cmd *Command, svc MigrationService) error {
 		migration := &migrations[i]
 		fileName, safely := r.fileNameBuilder.Down(migration.Version, false)
 
+		started := time.Now()
 		if err := svc.RevertFile(cmd.Context(), migration, fileName, safely); err != nil {
 			r.presenter.ShowRedoError()
 			return err
 		}
+		r.presenter.ShowMigrationReverted(migration.Version, time.Since(started))
 
 		reversedMigrations = append(reversedMigrations, migrations[i])
 	}
@@ -75,10 +78,12 @@ func (r *Redo) Handle(cmd *Command, svc MigrationService) error {
 		migration := &reversedMigrations[i]
 		fileName, safely := r.fileNameBuilder.Up(migration.Version, false)
 
+		started := time.Now()
 		if err := svc.ApplyFile(cmd.Context(), migration, fileName, safely); err != nil {
 			r.presenter.ShowRedoError()
 			return err
 		}
+		r.presenter.ShowMigrationApplied(migration.Version, time.Since(started))
 	}
 
 	r.presenter.ShowRedoSuccess(migrationsCount)
@@ -170,6 +170,9 @@ func TestRedo_Handle_RedoSuccessfully_NonInteractive(t *testing.T) {
 		RevertFile(mock.Anything, &migrations[0], "/migrations/200101_120000_test.down.sql", true).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationReverted(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	// Apply phase
 	fileNameBuilderMock.EXPECT().
@@ -180,6 +183,9 @@ func TestRedo_Handle_RedoSuccessfully_NonInteractive(t *testing.T) {
 		ApplyFile(mock.Anything, &migrations[0], "/migrations/200101_120000_test.up.sql", true).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationApplied(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	presenterMock.EXPECT().
 		ShowRedoSuccess(1).
@@ -275,6 +281,9 @@ func TestRedo_Handle_ApplyFileError_Failure(t *testing.T) {
 		RevertFile(mock.Anything, &migrations[0], "/migrations/200101_120000_test.down.sql", true).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationReverted(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	// Apply phase fails
 	fileNameBuilderMock.EXPECT().
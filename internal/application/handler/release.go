@@ -10,9 +10,12 @@ package handler
 
 import (
 	"context"
+	"strings"
 	"time"
 
-	"github.com/raoptimus/db-migrator.go/internal/helper/console"
+	"github.com/pkg/errors"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
 )
 
 // Release handles the application of all pending migrations atomically in a single transaction.
@@ -47,12 +50,18 @@ func (r *Release) Handle(cmd *Command, svc MigrationService) error {
 		r.presenter.ShowNoNewMigrations()
 		return nil
 	}
+	if err := refuseOutOfOrder(r.options, migrations); err != nil {
+		return err
+	}
+	if err := r.refuseNoTransaction(svc, migrations); err != nil {
+		return err
+	}
 
 	r.presenter.ShowUpgradePlan(migrations, migrations.Len())
 
 	question := r.presenter.AskUpgradeConfirmation(migrations.Len())
-	if r.options.Interactive && !console.Confirm(question) {
-		return nil
+	if ok, err := confirm(r.options, question); !ok {
+		return err
 	}
 
 	applyTime := time.Now().Unix()
@@ -62,9 +71,11 @@ func (r *Release) Handle(cmd *Command, svc MigrationService) error {
 			migration := &migrations[i]
 			fileName, _ := r.fileNameBuilder.Up(migration.Version, false)
 
+			started := time.Now()
 			if err := svc.ApplyFileWithApplyTime(ctx, migration, fileName, applyTime); err != nil {
 				return err
 			}
+			r.presenter.ShowMigrationApplied(migration.Version, time.Since(started))
 		}
 
 		return nil
@@ -77,3 +88,24 @@ func (r *Release) Handle(cmd *Command, svc MigrationService) error {
 	r.presenter.ShowUpgradeSuccess(migrations.Len())
 	return nil
 }
+
+// refuseNoTransaction returns ErrNoTransactionMigrations listing the migrations with statements
+// that must run outside a transaction or in transactions of their own.
+func (r *Release) refuseNoTransaction(svc MigrationService, migrations model.Migrations) error {
+	var versions []string
+	for i := range migrations {
+		fileName, _ := r.fileNameBuilder.Up(migrations[i].Version, false)
+		ok, err := svc.HasNoTransactionDirectives(fileName)
+		if err != nil {
+			return err
+		}
+		if ok {
+			versions = append(versions, migrations[i].Version)
+		}
+	}
+	if len(versions) > 0 {
+		return errors.Wrap(ErrNoTransactionMigrations, strings.Join(versions, ", "))
+	}
+
+	return nil
+}
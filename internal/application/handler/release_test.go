@@ -116,10 +116,18 @@ func TestRelease_Handle_ApplySuccessfully_NonInteractive(t *testing.T) {
 	fileNameBuilderMock.EXPECT().
 		Up("200101_120000", false).
 		Return("/migrations/200101_120000_test.up.sql", true).
+		Times(2)
+	svcMock.EXPECT().
+		HasNoTransactionDirectives("/migrations/200101_120000_test.up.sql").
+		Return(false, nil).
 		Once()
 	fileNameBuilderMock.EXPECT().
 		Up("200102_120000", false).
 		Return("/migrations/200102_120000_test.up.sql", true).
+		Times(2)
+	svcMock.EXPECT().
+		HasNoTransactionDirectives("/migrations/200102_120000_test.up.sql").
+		Return(false, nil).
 		Once()
 
 	// Capture applyTime to verify both migrations use the same value
@@ -139,6 +147,13 @@ func TestRelease_Handle_ApplySuccessfully_NonInteractive(t *testing.T) {
 		Return(nil).
 		Once()
 
+	presenterMock.EXPECT().
+		ShowMigrationApplied(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
+	presenterMock.EXPECT().
+		ShowMigrationApplied(migrations[1].Version, mock.AnythingOfType("time.Duration")).
+		Once()
+
 	// ExecInTransaction should call the provided function
 	svcMock.EXPECT().
 		ExecInTransaction(mock.Anything, mock.AnythingOfType("func(context.Context) error")).
@@ -189,6 +204,15 @@ func TestRelease_Handle_TransactionError_Failure(t *testing.T) {
 		Return("Confirm?").
 		Once()
 
+	fileNameBuilderMock.EXPECT().
+		Up("200101_120000", false).
+		Return("/migrations/200101_120000_test.up.sql", true).
+		Once()
+	svcMock.EXPECT().
+		HasNoTransactionDirectives("/migrations/200101_120000_test.up.sql").
+		Return(false, nil).
+		Once()
+
 	txErr := errors.New("transaction commit failed")
 	svcMock.EXPECT().
 		ExecInTransaction(mock.Anything, mock.AnythingOfType("func(context.Context) error")).
@@ -239,6 +263,10 @@ func TestRelease_Handle_ApplyFileErrorInsideTx_Failure(t *testing.T) {
 	fileNameBuilderMock.EXPECT().
 		Up("200101_120000", false).
 		Return("/migrations/200101_120000_test.up.sql", true).
+		Times(2)
+	svcMock.EXPECT().
+		HasNoTransactionDirectives("/migrations/200101_120000_test.up.sql").
+		Return(false, nil).
 		Once()
 	svcMock.EXPECT().
 		ApplyFileWithApplyTime(mock.Anything, &migrations[0], "/migrations/200101_120000_test.up.sql", mock.AnythingOfType("int64")).
@@ -270,3 +298,52 @@ func TestRelease_Handle_ApplyFileErrorInsideTx_Failure(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, applyErr, err)
 }
+
+func TestRelease_Handle_OutOfOrder_Failure(t *testing.T) {
+	svcMock := NewMockMigrationService(t)
+
+	svcMock.EXPECT().
+		NewMigrations(mock.Anything).
+		Return(model.Migrations{{Version: "200101_120000_merged", OutOfOrder: true}}, nil).
+		Once()
+
+	release := NewRelease(&Options{}, NewMockPresenter(t), NewMockFileNameBuilder(t))
+
+	err := release.Handle(&Command{Args: &argsStub{present: false}}, svcMock)
+
+	require.ErrorIs(t, err, ErrOutOfOrderMigrations)
+}
+
+func TestRelease_Handle_NoTransactionDirectives_Failure(t *testing.T) {
+	fileNameBuilderMock := NewMockFileNameBuilder(t)
+	svcMock := NewMockMigrationService(t)
+
+	svcMock.EXPECT().
+		NewMigrations(mock.Anything).
+		Return(model.Migrations{{Version: "200101_120000"}, {Version: "200102_120000"}}, nil).
+		Once()
+	fileNameBuilderMock.EXPECT().
+		Up("200101_120000", false).
+		Return("/migrations/200101_120000_test.up.sql", false).
+		Once()
+	fileNameBuilderMock.EXPECT().
+		Up("200102_120000", false).
+		Return("/migrations/200102_120000_test.safe.up.sql", true).
+		Once()
+	svcMock.EXPECT().
+		HasNoTransactionDirectives("/migrations/200101_120000_test.up.sql").
+		Return(false, nil).
+		Once()
+	svcMock.EXPECT().
+		HasNoTransactionDirectives("/migrations/200102_120000_test.safe.up.sql").
+		Return(true, nil).
+		Once()
+
+	release := NewRelease(&Options{}, NewMockPresenter(t), fileNameBuilderMock)
+
+	err := release.Handle(&Command{Args: &argsStub{present: false}}, svcMock)
+
+	require.ErrorIs(t, err, ErrNoTransactionMigrations)
+	require.ErrorContains(t, err, "200102_120000")
+	require.NotContains(t, err.Error(), "200101_120000")
+}
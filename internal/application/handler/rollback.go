@@ -10,8 +10,7 @@ package handler
 
 import (
 	"context"
-
-	"github.com/raoptimus/db-migrator.go/internal/helper/console"
+	"time"
 )
 
 // Rollback handles the reverting of the latest release batch atomically in a single transaction.
@@ -47,9 +46,13 @@ func (r *Rollback) Handle(cmd *Command, svc MigrationService) error {
 		return nil
 	}
 
-	// Check all down files exist before proceeding
+	// Check all down files exist before proceeding,
+	// migrations with down SQL stored in history can be reverted without them
 	var missingVersions []string
 	for i := range migrations {
+		if migrations[i].DownSQL != "" {
+			continue
+		}
 		fileName, _ := r.fileNameBuilder.Down(migrations[i].Version, false)
 		exists, err := svc.FileExists(fileName)
 		if err != nil {
@@ -68,8 +71,8 @@ func (r *Rollback) Handle(cmd *Command, svc MigrationService) error {
 	r.presenter.ShowDowngradePlan(migrations)
 
 	question := r.presenter.AskDowngradeConfirmation(migrations.Len())
-	if r.options.Interactive && !console.Confirm(question) {
-		return nil
+	if ok, err := confirm(r.options, question); !ok {
+		return err
 	}
 
 	err = svc.ExecInTransaction(cmd.Context(), func(ctx context.Context) error {
@@ -77,9 +80,11 @@ func (r *Rollback) Handle(cmd *Command, svc MigrationService) error {
 			migration := &migrations[i]
 			fileName, _ := r.fileNameBuilder.Down(migration.Version, false)
 
+			started := time.Now()
 			if err := svc.RevertFile(ctx, migration, fileName, false); err != nil {
 				return err
 			}
+			r.presenter.ShowMigrationReverted(migration.Version, time.Since(started))
 		}
 
 		return nil
@@ -142,6 +142,66 @@ func TestRollback_Handle_MissingDownFiles_Failure(t *testing.T) {
 	require.Equal(t, ErrMissingDownFiles, err)
 }
 
+// TestRollback_Handle_MissingDownFileWithStoredDownSQL_Successfully tests that Handle
+// does not require a down file for migrations whose down SQL is stored in history.
+func TestRollback_Handle_MissingDownFileWithStoredDownSQL_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	fileNameBuilderMock := NewMockFileNameBuilder(t)
+	svcMock := NewMockMigrationService(t)
+
+	migrations := model.Migrations{
+		{Version: "200101_120000", ApplyTime: 1609502400, DownSQL: "DROP TABLE test;"},
+	}
+
+	svcMock.EXPECT().
+		LatestReleaseMigrations(mock.Anything).
+		Return(migrations, nil).
+		Once()
+
+	fileNameBuilderMock.EXPECT().
+		Down("200101_120000", false).
+		Return("/migrations/200101_120000_test.down.sql", false).
+		Once()
+
+	presenterMock.EXPECT().
+		ShowDowngradePlan(migrations).
+		Once()
+	presenterMock.EXPECT().
+		AskDowngradeConfirmation(1).
+		Return("Confirm?").
+		Once()
+
+	svcMock.EXPECT().
+		RevertFile(mock.Anything, &migrations[0], "/migrations/200101_120000_test.down.sql", false).
+		Return(nil).
+		Once()
+	presenterMock.EXPECT().
+		ShowMigrationReverted(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
+	svcMock.EXPECT().
+		ExecInTransaction(mock.Anything, mock.AnythingOfType("func(context.Context) error")).
+		Run(func(ctx context.Context, fn func(context.Context) error) {
+			_ = fn(ctx)
+		}).
+		Return(nil).
+		Once()
+
+	presenterMock.EXPECT().
+		ShowDowngradeSuccess(1).
+		Once()
+
+	rollback := NewRollback(
+		&Options{Interactive: false},
+		presenterMock,
+		fileNameBuilderMock,
+	)
+
+	cmd := &Command{Args: &argsStub{present: false}}
+	err := rollback.Handle(cmd, svcMock)
+
+	require.NoError(t, err)
+}
+
 // TestRollback_Handle_RevertSuccessfully_NonInteractive tests that Handle reverts
 // all migrations atomically within a transaction.
 func TestRollback_Handle_RevertSuccessfully_NonInteractive(t *testing.T) {
@@ -190,10 +250,16 @@ func TestRollback_Handle_RevertSuccessfully_NonInteractive(t *testing.T) {
 		RevertFile(mock.Anything, &migrations[0], "/migrations/200101_120000_test.down.sql", false).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationReverted(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 	svcMock.EXPECT().
 		RevertFile(mock.Anything, &migrations[1], "/migrations/200102_120000_test.down.sql", false).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationReverted(migrations[1].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	// ExecInTransaction should call the provided function
 	svcMock.EXPECT().
@@ -0,0 +1,165 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/helper/dsn"
+	"github.com/raoptimus/db-migrator.go/internal/helper/sqlio"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
+)
+
+// Sources of the current schema the desired one is compared against.
+const (
+	// DiffSourceReplay builds the current schema in memory by replaying the up migrations, without a database.
+	DiffSourceReplay = "replay"
+	// DiffSourceLive loads the current schema from the database.
+	DiffSourceLive = "live"
+)
+
+var (
+	// ErrInvalidDiffSource is returned for a source of the current schema other than replay or live.
+	ErrInvalidDiffSource = errors.New("the diff source should be replay or live")
+	// ErrDiffUnsupported is returned for a DSN whose DDL the schema diff cannot parse;
+	// only the Spark SQL of Iceberg is parsed.
+	ErrDiffUnsupported = errors.New("the schema diff is supported for Iceberg DSNs only")
+)
+
+// SchemaDiffLoader loads the current schema the desired one is compared against by create --from-diff.
+type SchemaDiffLoader struct {
+	options *Options
+	logger  Logger
+	file    File
+}
+
+// NewSchemaDiffLoader creates a new SchemaDiffLoader instance.
+func NewSchemaDiffLoader(
+	options *Options,
+	logger Logger,
+	file File,
+) *SchemaDiffLoader {
+	return &SchemaDiffLoader{
+		options: options,
+		logger:  logger,
+		file:    file,
+	}
+}
+
+// LoadSchema returns the current schema of the diff source.
+func (l *SchemaDiffLoader) LoadSchema(cmd *Command) (*ddl.Schema, error) {
+	switch l.options.DiffSource {
+	case "", DiffSourceReplay:
+		return l.replaySchema()
+	case DiffSourceLive:
+		live := &liveSchema{}
+		if err := NewServiceWrapHandler(l.options, l.logger, live).Handle(cmd); err != nil {
+			return nil, err
+		}
+		return live.schema, nil
+	default:
+		return nil, errors.Wrapf(ErrInvalidDiffSource, "%q", l.options.DiffSource)
+	}
+}
+
+// replaySchema replays the up migrations of the migration directory in the order of their versions.
+func (l *SchemaDiffLoader) replaySchema() (*ddl.Schema, error) {
+	catalog, err := diffCatalog(l.options.DSN)
+	if err != nil {
+		return nil, err
+	}
+	fileNames, err := l.file.Glob(filepath.Join(l.options.Directory, "*.up.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(fileNames)
+
+	schema := ddl.NewSchema()
+	for _, fileName := range fileNames {
+		data, err := l.file.ReadAll(fileName)
+		if err != nil {
+			return nil, err
+		}
+		if err := replaySchema(schema, catalog, data); err != nil {
+			return nil, errors.WithMessagef(err, "replaying %s", fileName)
+		}
+	}
+
+	return schema, nil
+}
+
+// liveSchema is the handler that loads the current schema from the database.
+type liveSchema struct {
+	schema *ddl.Schema
+}
+
+func (h *liveSchema) Handle(cmd *Command, svc MigrationService) error {
+	schema, err := svc.LoadSchema(cmd.Context())
+	if err != nil {
+		return err
+	}
+	h.schema = schema
+
+	return nil
+}
+
+// diffCatalog returns the catalog stripped from the identifiers of the replayed statements,
+// the warehouse of the Iceberg DSN the way the Iceberg driver strips it.
+// The DSN of another driver fails with ErrDiffUnsupported.
+func diffCatalog(dsnStr string) (string, error) {
+	parsed, err := dsn.Parse(dsnStr)
+	if err != nil || parsed.Driver != string(connection.DriverIceberg) {
+		return "", errors.WithStack(ErrDiffUnsupported)
+	}
+
+	return parsed.Database, nil
+}
+
+// replaySchema applies the DDL statements of the SQL to the schema.
+// The statements that are not DDL, such as inserts, are skipped; DDL the schema diff does not know fails.
+func replaySchema(schema *ddl.Schema, catalog string, sql []byte) error {
+	scanner := sqlio.NewScanner(bytes.NewReader(sql), sqlio.DialectDefault)
+	for scanner.Scan() {
+		if !ddl.IsDDL(scanner.SQL()) {
+			continue
+		}
+		op, err := ddl.Parse(catalog, scanner.SQL())
+		if err != nil {
+			return errors.WithMessagef(err, "statement #%d", scanner.Index())
+		}
+		if err := schema.Apply(op); err != nil {
+			return errors.WithMessagef(err, "statement #%d", scanner.Index())
+		}
+	}
+
+	return scanner.Err()
+}
+
+// formatOperations renders the operations as an SQL migration, one statement per paragraph.
+func formatOperations(ops []ddl.Operation) ([]byte, error) {
+	var b strings.Builder
+	for i, op := range ops {
+		stmt, err := ddl.Format(op)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(stmt)
+		b.WriteString(";\n")
+	}
+
+	return []byte(b.String()), nil
+}
@@ -9,6 +9,9 @@
 package handler
 
 import (
+	"os"
+	"strconv"
+
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/domain/service"
 	"github.com/raoptimus/db-migrator.go/internal/helper/dsn"
@@ -31,14 +34,21 @@ func NewMigrationService(
 		return nil, errors.WithMessage(err, "parsing DSN")
 	}
 
+	vars, err := options.PlaceholderVars()
+	if err != nil {
+		return nil, err
+	}
+
 	// Create repository
 	var serviceRepo service.Repository
 	repo, err := repository.New(
 		conn,
 		&repository.Options{
-			TableName:   options.TableName,
-			ClusterName: options.ClusterName,
-			Replicated:  options.Replicated,
+			TableName:        options.TableName,
+			ClusterName:      options.ClusterName,
+			Replicated:       options.Replicated,
+			StatementTimeout: options.StatementTimeout,
+			LockTimeout:      options.StatementLockTimeout,
 		},
 	)
 	if err != nil {
@@ -61,9 +71,44 @@ func NewMigrationService(
 			ClusterName:        options.ClusterName,
 			Username:           parsed.Username,
 			Password:           parsed.Password,
+			Vars:               vars,
+			StrictPlaceholders: options.StrictPlaceholders,
+			LockOwner:          lockOwner(),
+			LockTimeout:        options.LockTimeout,
+			LockStaleAfter:     options.LockStaleAfter,
+			StatementTimeout:   options.StatementTimeout,
+			MigrationTimeout:   options.MigrationTimeout,
+			RetryAttempts:      options.RetryAttempts,
+			RetryBackoff:       options.RetryBackoff,
+			GoMigrations:       options.GoMigrations,
+			Connection:         conn,
+			DryRun:             options.DryRun,
+			Resume:             options.Resume,
 		},
 		logger,
-		iohelp.StdFile,
+		NewMigrationFile(options),
 		serviceRepo,
 	), nil
 }
+
+// NewMigrationFile returns the file system the migration files are read from:
+// options.FS when it is set, the OS file system otherwise.
+//
+//nolint:ireturn // Returns interface by design for dependency inversion
+func NewMigrationFile(options *Options) service.File {
+	if options.FS != nil {
+		return iohelp.NewFS(options.FS)
+	}
+
+	return iohelp.StdFile
+}
+
+// lockOwner identifies this process as the holder of the migration lock.
+func lockOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return "db-migrator@" + hostname + ":" + strconv.Itoa(os.Getpid())
+}
@@ -9,7 +9,10 @@
 package handler
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/helper/dsn"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
 )
 
@@ -20,10 +23,14 @@ type ServiceHandler interface {
 
 // ServiceWrapHandler wraps a ServiceHandler, managing database connection
 // and MigrationService lifecycle for each command execution.
+// Handlers of commands that change the database also run under the migration lock
+// and refuse to run on a dirty database.
 type ServiceWrapHandler struct {
-	options *Options
-	logger  Logger
-	handler ServiceHandler
+	options    *Options
+	logger     Logger
+	handler    ServiceHandler
+	lock       bool
+	guardDirty bool
 }
 
 // NewServiceWrapHandler creates a new ServiceWrapHandler instance.
@@ -39,26 +46,113 @@ func NewServiceWrapHandler(
 	}
 }
 
+// NewLockingServiceWrapHandler creates a new ServiceWrapHandler instance
+// that takes the migration lock and checks that the database is not dirty before delegating to the handler.
+func NewLockingServiceWrapHandler(
+	options *Options,
+	logger Logger,
+	handler ServiceHandler,
+) *ServiceWrapHandler {
+	w := NewRepairServiceWrapHandler(options, logger, handler)
+	w.guardDirty = true
+
+	return w
+}
+
+// NewRepairServiceWrapHandler creates a new ServiceWrapHandler instance
+// that takes the migration lock before delegating to the handler, which repairs a dirty database.
+func NewRepairServiceWrapHandler(
+	options *Options,
+	logger Logger,
+	handler ServiceHandler,
+) *ServiceWrapHandler {
+	w := NewServiceWrapHandler(options, logger, handler)
+	w.lock = true
+
+	return w
+}
+
 // Handle executes the command by creating database connection and MigrationService,
-// then delegating to the wrapped handler.
+// then delegating to the wrapped handler. For a locking wrapper the handler runs
+// under the migration lock, which is not taken in dry-run mode.
+// A guarding wrapper returns ErrDirtyDatabase instead when migrations were left pending or failed.
+// With the fan-out the command is executed against every host of the DSN in turn,
+// otherwise against the first host of the DSN that can be connected to.
 func (w *ServiceWrapHandler) Handle(cmd *Command) error {
 	if w.options.DryRun {
 		w.options.Interactive = false
 		w.logger.Warn("[DRY RUN] No changes will be applied to the database.")
 	}
 
+	if w.options.FanOut {
+		return w.handleHosts(cmd)
+	}
+
+	return w.handle(cmd, w.options)
+}
+
+// handle executes the command against the database of the DSN of options.
+func (w *ServiceWrapHandler) handle(cmd *Command, options *Options) (err error) {
 	// Create database connection
-	conn, err := connection.Try(w.options.DSN, w.options.MaxConnAttempts)
+	conn, options, err := w.connect(options)
 	if err != nil {
 		return errors.WithMessage(err, "failed to connect to database")
 	}
 	defer conn.Close()
 
 	// Create MigrationService
-	svc, err := NewMigrationService(w.options, w.logger, conn)
+	svc, err := NewMigrationService(options, w.logger, conn)
 	if err != nil {
 		return errors.WithMessage(err, "failed to create migration service")
 	}
 
+	if w.lock && !options.DryRun {
+		if err := svc.Lock(cmd.Context()); err != nil {
+			return errors.WithMessage(err, "failed to take migration lock")
+		}
+		defer func() {
+			// The lock must be released even when the command was canceled.
+			unlockErr := svc.Unlock(context.WithoutCancel(cmd.Context()))
+			if unlockErr == nil {
+				return
+			}
+			if err == nil {
+				err = errors.WithMessage(unlockErr, "failed to release migration lock")
+				return
+			}
+			w.logger.Warnf("Failed to release the migration lock: %v\n", unlockErr)
+		}()
+	}
+
+	if w.guardDirty {
+		if err := RefuseDirty(cmd.Context(), options, svc); err != nil {
+			return err
+		}
+	}
+
 	return w.handler.Handle(cmd, svc)
 }
+
+// connect connects to the database of the DSN of options. The hosts of a multi-host ClickHouse DSN are tried
+// in the order of the DSN until one can be connected to; the options returned then have the DSN of that host.
+func (w *ServiceWrapHandler) connect(options *Options) (*connection.Connection, *Options, error) {
+	parsed, err := dsn.Parse(options.DSN)
+	if err != nil || parsed.Driver != connection.DriverClickhouse.String() || !parsed.IsCluster() {
+		conn, err := connection.Try(options.DSN, options.MaxConnAttempts)
+		return conn, options, err
+	}
+
+	for i, host := range parsed.Hosts {
+		hostOptions := *options
+		hostOptions.DSN = parsed.ForHost(host)
+		var conn *connection.Connection
+		if conn, err = connection.Try(hostOptions.DSN, options.MaxConnAttempts); err == nil {
+			return conn, &hostOptions, nil
+		}
+		if i < len(parsed.Hosts)-1 {
+			w.logger.Warnf("Host %s cannot be connected to, failing over to the next host: %v\n", host.Address, err)
+		}
+	}
+
+	return nil, nil, err
+}
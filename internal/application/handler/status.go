@@ -0,0 +1,141 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+import (
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/domain/service"
+)
+
+// Exit codes of the status command. When migrations are in different states,
+// the code of the most severe state is used: dirty, then missing file, then out of order, then pending.
+const (
+	StatusExitCodeUpToDate    = 0
+	StatusExitCodePending     = 3
+	StatusExitCodeOutOfOrder  = 4
+	StatusExitCodeMissingFile = 5
+	StatusExitCodeDirty       = 6
+)
+
+// StatusError is returned by the status command when the database is not up to date.
+// It implements the ExitCode method, so the process exits with the code of the state.
+type StatusError struct {
+	State model.MigrationState
+}
+
+// Error returns the description of the state.
+func (e *StatusError) Error() string {
+	switch e.State {
+	case model.StateDirty:
+		return "there are migrations left pending or failed with some of their statements committed"
+	case model.StateMissingFile:
+		return "there are applied migrations whose files are missing"
+	case model.StateOutOfOrder:
+		return "there are pending migrations older than the latest applied migration"
+	default:
+		return "there are pending migrations"
+	}
+}
+
+// ExitCode returns the process exit code of the state.
+func (e *StatusError) ExitCode() int {
+	switch e.State {
+	case model.StateDirty:
+		return StatusExitCodeDirty
+	case model.StateMissingFile:
+		return StatusExitCodeMissingFile
+	case model.StateOutOfOrder:
+		return StatusExitCodeOutOfOrder
+	default:
+		return StatusExitCodePending
+	}
+}
+
+// Status handles the display of applied and pending migrations together with the migration files.
+type Status struct {
+	options         *Options
+	presenter       Presenter
+	fileNameBuilder FileNameBuilder
+}
+
+// NewStatus creates a new Status handler instance.
+func NewStatus(
+	options *Options,
+	presenter Presenter,
+	fileNameBuilder FileNameBuilder,
+) *Status {
+	return &Status{
+		options:         options,
+		presenter:       presenter,
+		fileNameBuilder: fileNameBuilder,
+	}
+}
+
+// Handle processes the status command.
+// It lists every migration version as applied, pending, out of order, applied with a missing file
+// or dirty, left pending or failed by an earlier run,
+// and returns a StatusError unless all migrations are applied and their files exist.
+func (s *Status) Handle(cmd *Command, svc MigrationService) error {
+	// every applied migration is listed, not only the latest ones
+	applied, err := svc.Migrations(cmd.Context(), service.MaxLimit)
+	if err != nil {
+		return err
+	}
+
+	pending, err := svc.NewMigrations(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	statuses := make(model.MigrationStatuses, 0, applied.Len()+pending.Len())
+
+	for i := range applied {
+		state := model.StateApplied
+		fileName, _ := s.fileNameBuilder.Up(applied[i].Version, false)
+
+		exists, err := svc.FileExists(fileName)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			state = model.StateMissingFile
+		}
+
+		statuses = append(statuses, model.MigrationStatus{Migration: applied[i], State: state})
+	}
+
+	// a pending migration is out of order as NewMigrations flags it, the way up refuses it
+	for i := range pending {
+		state := model.StatePending
+		switch {
+		case pending[i].Dirty():
+			state = model.StateDirty
+		case pending[i].OutOfOrder:
+			state = model.StateOutOfOrder
+		}
+
+		statuses = append(statuses, model.MigrationStatus{Migration: pending[i], State: state})
+	}
+
+	statuses.SortByVersion()
+	s.presenter.ShowStatus(statuses)
+
+	for _, state := range []model.MigrationState{
+		model.StateDirty,
+		model.StateMissingFile,
+		model.StateOutOfOrder,
+		model.StatePending,
+	} {
+		if statuses.Count(state) > 0 {
+			return &StatusError{State: state}
+		}
+	}
+
+	return nil
+}
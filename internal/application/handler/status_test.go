@@ -0,0 +1,155 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/domain/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errStatusQueryFailed = errors.New("status query failed")
+
+// TestStatus_Handle_ReturnsStatusError tests that Handle lists every migration state
+// and returns the exit code of the most severe state.
+func TestStatus_Handle_ReturnsStatusError(t *testing.T) {
+	tests := []struct {
+		name             string
+		applied          model.Migrations
+		pending          model.Migrations
+		missingFiles     map[string]bool
+		expectedStatuses model.MigrationStatuses
+		expectedCode     int
+	}{
+		{
+			name:    "pending migrations",
+			applied: model.Migrations{{Version: "200101_120000_first"}},
+			pending: model.Migrations{{Version: "200102_120000_second"}},
+			expectedStatuses: model.MigrationStatuses{
+				{Migration: model.Migration{Version: "200101_120000_first"}, State: model.StateApplied},
+				{Migration: model.Migration{Version: "200102_120000_second"}, State: model.StatePending},
+			},
+			expectedCode: StatusExitCodePending,
+		},
+		{
+			name:    "out of order migrations",
+			applied: model.Migrations{{Version: "200103_120000_third"}, {Version: "200101_120000_first"}},
+			pending: model.Migrations{
+				{Version: "200102_120000_second", OutOfOrder: true},
+				{Version: "200104_120000_fourth"},
+			},
+			expectedStatuses: model.MigrationStatuses{
+				{Migration: model.Migration{Version: "200101_120000_first"}, State: model.StateApplied},
+				{
+					Migration: model.Migration{Version: "200102_120000_second", OutOfOrder: true},
+					State:     model.StateOutOfOrder,
+				},
+				{Migration: model.Migration{Version: "200103_120000_third"}, State: model.StateApplied},
+				{Migration: model.Migration{Version: "200104_120000_fourth"}, State: model.StatePending},
+			},
+			expectedCode: StatusExitCodeOutOfOrder,
+		},
+		{
+			name:         "missing migration files",
+			applied:      model.Migrations{{Version: "200102_120000_second"}, {Version: "200101_120000_first"}},
+			pending:      model.Migrations{},
+			missingFiles: map[string]bool{"200101_120000_first": true},
+			expectedStatuses: model.MigrationStatuses{
+				{Migration: model.Migration{Version: "200101_120000_first"}, State: model.StateMissingFile},
+				{Migration: model.Migration{Version: "200102_120000_second"}, State: model.StateApplied},
+			},
+			expectedCode: StatusExitCodeMissingFile,
+		},
+		{
+			name:    "dirty migrations",
+			applied: model.Migrations{{Version: "200101_120000_first"}},
+			pending: model.Migrations{
+				{Version: "200102_120000_second", Status: "failed", LastStatement: 1},
+				{Version: "200103_120000_third"},
+			},
+			expectedStatuses: model.MigrationStatuses{
+				{Migration: model.Migration{Version: "200101_120000_first"}, State: model.StateApplied},
+				{
+					Migration: model.Migration{Version: "200102_120000_second", Status: "failed", LastStatement: 1},
+					State:     model.StateDirty,
+				},
+				{Migration: model.Migration{Version: "200103_120000_third"}, State: model.StatePending},
+			},
+			expectedCode: StatusExitCodeDirty,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presenterMock := NewMockPresenter(t)
+			migrationServiceMock := NewMockMigrationService(t)
+			fileNameBuilderMock := NewMockFileNameBuilder(t)
+
+			migrationServiceMock.EXPECT().Migrations(mock.Anything, service.MaxLimit).Return(tt.applied, nil)
+			migrationServiceMock.EXPECT().NewMigrations(mock.Anything).Return(tt.pending, nil)
+			for _, m := range tt.applied {
+				fileName := "/migrations/" + m.Version + ".up.sql"
+				fileNameBuilderMock.EXPECT().Up(m.Version, false).Return(fileName, false)
+				migrationServiceMock.EXPECT().FileExists(fileName).Return(!tt.missingFiles[m.Version], nil)
+			}
+			presenterMock.EXPECT().ShowStatus(tt.expectedStatuses)
+
+			status := NewStatus(&Options{}, presenterMock, fileNameBuilderMock)
+			err := status.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+			var statusErr *StatusError
+			require.ErrorAs(t, err, &statusErr)
+			require.Equal(t, tt.expectedCode, statusErr.ExitCode())
+		})
+	}
+}
+
+// TestStatus_Handle_UpToDate_Successfully tests that Handle returns no error
+// when all migrations are applied and their files exist.
+func TestStatus_Handle_UpToDate_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	fileNameBuilderMock := NewMockFileNameBuilder(t)
+	fileName := "/migrations/200101_120000_first.up.sql"
+
+	migrationServiceMock.EXPECT().
+		Migrations(mock.Anything, service.MaxLimit).
+		Return(model.Migrations{{Version: "200101_120000_first"}}, nil)
+	migrationServiceMock.EXPECT().NewMigrations(mock.Anything).Return(model.Migrations{}, nil)
+	fileNameBuilderMock.EXPECT().Up("200101_120000_first", false).Return(fileName, false)
+	migrationServiceMock.EXPECT().FileExists(fileName).Return(true, nil)
+	presenterMock.EXPECT().ShowStatus(model.MigrationStatuses{
+		{Migration: model.Migration{Version: "200101_120000_first"}, State: model.StateApplied},
+	})
+
+	status := NewStatus(&Options{}, presenterMock, fileNameBuilderMock)
+	err := status.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.NoError(t, err)
+}
+
+// TestStatus_Handle_NewMigrationsReturnsError_Failure tests that Handle returns
+// the error from MigrationService.NewMigrations.
+func TestStatus_Handle_NewMigrationsReturnsError_Failure(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	fileNameBuilderMock := NewMockFileNameBuilder(t)
+
+	migrationServiceMock.EXPECT().Migrations(mock.Anything, service.MaxLimit).Return(model.Migrations{}, nil)
+	migrationServiceMock.EXPECT().NewMigrations(mock.Anything).Return(nil, errStatusQueryFailed)
+
+	status := NewStatus(&Options{}, presenterMock, fileNameBuilderMock)
+	err := status.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.ErrorIs(t, err, errStatusQueryFailed)
+}
@@ -10,7 +10,6 @@ package handler
 
 import (
 	"github.com/raoptimus/db-migrator.go/internal/domain/model"
-	"github.com/raoptimus/db-migrator.go/internal/helper/console"
 )
 
 // To handles the migration to a specific version.
@@ -68,43 +67,79 @@ func (t *To) Handle(cmd *Command, svc MigrationService) error {
 	}
 
 	// Direction: DOWN (revert migrations)
-	return t.handleDowngrade(cmd, svc, targetVersion)
+	if err := t.handleDowngrade(cmd, svc, targetVersion); err != nil {
+		return err
+	}
+
+	// migrations older than the target that landed after newer ones were applied are still pending
+	return t.handleOutOfOrder(cmd, svc, targetVersion)
 }
 
 // handleUpgrade applies all migrations up to and including the target version.
 func (t *To) handleUpgrade(cmd *Command, svc MigrationService, targetVersion string) error {
-	// Get all new migrations
-	allNewMigrations, err := svc.NewMigrations(cmd.Context())
+	migrationsToApply, err := t.pendingUpTo(cmd, svc, targetVersion)
+	if err != nil {
+		return err
+	}
+
+	// Check if there are migrations to apply
+	if len(migrationsToApply) == 0 {
+		t.presenter.ShowNoNewMigrations()
+		return nil
+	}
+
+	return t.apply(cmd, svc, migrationsToApply)
+}
+
+// handleOutOfOrder applies the out of order migrations up to and including the already applied target version.
+func (t *To) handleOutOfOrder(cmd *Command, svc MigrationService, targetVersion string) error {
+	migrationsToApply, err := t.pendingUpTo(cmd, svc, targetVersion)
 	if err != nil {
 		return err
 	}
+	if len(migrationsToApply) == 0 {
+		return nil
+	}
+
+	return t.apply(cmd, svc, migrationsToApply)
+}
+
+// pendingUpTo returns the pending migrations up to and including the target version in version order.
+func (t *To) pendingUpTo(cmd *Command, svc MigrationService, targetVersion string) (model.Migrations, error) {
+	allNewMigrations, err := svc.NewMigrations(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
 
 	// Filter: keep only migrations <= targetVersion
-	migrationsToApply := make(model.Migrations, 0)
+	migrations := make(model.Migrations, 0)
 	for _, m := range allNewMigrations {
 		// Extract timestamp part for comparison (e.g., "251002_184510_change_scheme" -> "251002_184510")
 		migrationTimestamp := extractTimestamp(m.Version)
 		if migrationTimestamp <= targetVersion {
-			migrationsToApply = append(migrationsToApply, m)
+			migrations = append(migrations, m)
 		}
 	}
 
-	// Check if there are migrations to apply
-	if len(migrationsToApply) == 0 {
-		t.presenter.ShowNoNewMigrations()
-		return nil
-	}
-
 	// Sort by version (ASC)
-	migrationsToApply.SortByVersion()
+	migrations.SortByVersion()
+
+	return migrations, nil
+}
+
+// apply applies the pending migrations, refusing out of order ones unless they are allowed.
+func (t *To) apply(cmd *Command, svc MigrationService, migrationsToApply model.Migrations) error {
+	if err := refuseOutOfOrder(t.options, migrationsToApply); err != nil {
+		return err
+	}
 
 	// Show migration plan
 	t.presenter.ShowUpgradePlan(migrationsToApply, len(migrationsToApply))
 
 	// User confirmation
 	question := t.presenter.AskUpgradeConfirmation(len(migrationsToApply))
-	if t.options.Interactive && !console.Confirm(question) {
-		return nil
+	if ok, err := confirm(t.options, question); !ok {
+		return err
 	}
 
 	// Apply migrations using helper function
@@ -142,15 +177,15 @@ func (t *To) handleDowngrade(cmd *Command, svc MigrationService, targetVersion s
 		return nil
 	}
 
-	// Migrations are already sorted DESC from DB (correct order for rollback)
+	// Migrations are already sorted by apply time DESC from DB, the reverse of the order they were applied in
 
 	// Show migration plan
 	t.presenter.ShowDowngradePlan(migrationsToRevert)
 
 	// User confirmation
 	question := t.presenter.AskDowngradeConfirmation(len(migrationsToRevert))
-	if t.options.Interactive && !console.Confirm(question) {
-		return nil
+	if ok, err := confirm(t.options, question); !ok {
+		return err
 	}
 
 	// Revert migrations using helper function
@@ -108,6 +108,9 @@ func TestTo_Handle_UpgradeDirection_Success(t *testing.T) {
 		ApplyFile(mock.Anything, &migrations[0], "/path/file1.up.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationApplied(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	fileNameBuilder.EXPECT().
 		Up("150101_185401", false).
@@ -118,6 +121,9 @@ func TestTo_Handle_UpgradeDirection_Success(t *testing.T) {
 		ApplyFile(mock.Anything, &migrations[1], "/path/file2.up.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationApplied(migrations[1].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	presenter.EXPECT().
 		ShowUpgradeSuccess(2).
@@ -173,6 +179,9 @@ func TestTo_Handle_UpgradeDirection_PartialMigrations_Success(t *testing.T) {
 		ApplyFile(mock.Anything, mock.Anything, mock.Anything, true).
 		Return(nil).
 		Times(2)
+	presenter.EXPECT().
+		ShowMigrationApplied(mock.Anything, mock.AnythingOfType("time.Duration")).
+		Times(2)
 
 	presenter.EXPECT().
 		ShowUpgradeSuccess(2).
@@ -257,6 +266,9 @@ func TestTo_Handle_DowngradeDirection_Success(t *testing.T) {
 		RevertFile(mock.Anything, &appliedMigrations[0], "/path/file1.down.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationReverted(appliedMigrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	fileNameBuilder.EXPECT().
 		Down("150101_140000", false).
@@ -267,11 +279,19 @@ func TestTo_Handle_DowngradeDirection_Success(t *testing.T) {
 		RevertFile(mock.Anything, &appliedMigrations[1], "/path/file2.down.sql", true).
 		Return(nil).
 		Once()
+	presenter.EXPECT().
+		ShowMigrationReverted(appliedMigrations[1].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 
 	presenter.EXPECT().
 		ShowDowngradeSuccess(2).
 		Once()
 
+	svc.EXPECT().
+		NewMigrations(mock.Anything).
+		Return(model.Migrations{{Version: "150101_185402"}}, nil).
+		Once()
+
 	// Execute
 	to := NewTo(&Options{Interactive: false}, presenter, fileNameBuilder)
 	cmd := &Command{Args: &argsStub{present: true, first: targetVersion}}
@@ -303,6 +323,11 @@ func TestTo_Handle_DowngradeDirection_AlreadyAtTarget_Success(t *testing.T) {
 		ShowNoMigrationsToRevert().
 		Once()
 
+	svc.EXPECT().
+		NewMigrations(mock.Anything).
+		Return(model.Migrations{}, nil).
+		Once()
+
 	to := NewTo(&Options{}, presenter, fileNameBuilder)
 	cmd := &Command{Args: &argsStub{present: true, first: targetVersion}}
 
@@ -496,3 +521,49 @@ func TestTo_Handle_MigrationsError_Failure(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, migrErr, err)
 }
+
+func TestTo_Handle_DowngradeDirection_AppliesOutOfOrder_Success(t *testing.T) {
+	svc := NewMockMigrationService(t)
+	presenter := NewMockPresenter(t)
+	fileNameBuilder := NewMockFileNameBuilder(t)
+
+	targetVersion := "150101_185401"
+	outOfOrder := model.Migrations{{Version: "150101_140000_merged", OutOfOrder: true}}
+
+	svc.EXPECT().Migrations(mock.Anything, 0).Return(model.Migrations{{Version: targetVersion + "_test"}}, nil).Twice()
+	presenter.EXPECT().ShowNoMigrationsToRevert().Once()
+	svc.EXPECT().NewMigrations(mock.Anything).Return(model.Migrations{
+		outOfOrder[0],
+		{Version: "150101_190000_newer"},
+	}, nil).Once()
+
+	presenter.EXPECT().ShowUpgradePlan(outOfOrder, 1).Once()
+	presenter.EXPECT().AskUpgradeConfirmation(1).Return("Apply?").Once()
+	fileNameBuilder.EXPECT().Up("150101_140000_merged", false).Return("/path/file.up.sql", true).Once()
+	svc.EXPECT().ApplyFile(mock.Anything, &outOfOrder[0], "/path/file.up.sql", true).Return(nil).Once()
+	presenter.EXPECT().ShowMigrationApplied("150101_140000_merged", mock.AnythingOfType("time.Duration")).Once()
+	presenter.EXPECT().ShowUpgradeSuccess(1).Once()
+
+	to := NewTo(&Options{AllowOutOfOrder: true}, presenter, fileNameBuilder)
+	cmd := &Command{Args: &argsStub{present: true, first: targetVersion}}
+
+	err := to.Handle(cmd, svc)
+	require.NoError(t, err)
+}
+
+func TestTo_Handle_UpgradeDirection_OutOfOrder_Failure(t *testing.T) {
+	svc := NewMockMigrationService(t)
+	presenter := NewMockPresenter(t)
+
+	svc.EXPECT().Migrations(mock.Anything, 0).Return(model.Migrations{{Version: "150101_190000_newer"}}, nil).Once()
+	svc.EXPECT().NewMigrations(mock.Anything).Return(model.Migrations{
+		{Version: "150101_140000_merged", OutOfOrder: true},
+	}, nil).Once()
+
+	to := NewTo(&Options{}, presenter, NewMockFileNameBuilder(t))
+	cmd := &Command{Args: &argsStub{present: true, first: "150101_140000"}}
+
+	err := to.Handle(cmd, svc)
+	require.ErrorIs(t, err, ErrOutOfOrderMigrations)
+	require.ErrorContains(t, err, "150101_140000_merged")
+}
@@ -9,7 +9,7 @@
 package handler
 
 import (
-	"github.com/raoptimus/db-migrator.go/internal/helper/console"
+	"time"
 )
 
 const (
@@ -58,12 +58,15 @@ func (u *Upgrade) Handle(cmd *Command, svc MigrationService) error {
 	if limit > 0 && migrations.Len() > limit {
 		migrations = migrations[:limit]
 	}
+	if err := refuseOutOfOrder(u.options, migrations); err != nil {
+		return err
+	}
 
 	u.presenter.ShowUpgradePlan(migrations, totalNewMigrations)
 
 	question := u.presenter.AskUpgradeConfirmation(migrations.Len())
-	if u.options.Interactive && !console.Confirm(question) {
-		return nil
+	if ok, err := confirm(u.options, question); !ok {
+		return err
 	}
 
 	var applied int
@@ -71,10 +74,12 @@ func (u *Upgrade) Handle(cmd *Command, svc MigrationService) error {
 		migration := &migrations[i]
 		fileName, safely := u.fileNameBuilder.Up(migration.Version, false)
 
+		started := time.Now()
 		if err := svc.ApplyFile(cmd.Context(), migration, fileName, safely); err != nil {
 			u.presenter.ShowUpgradeError(applied, migrations.Len())
 			return err
 		}
+		u.presenter.ShowMigrationApplied(migration.Version, time.Since(started))
 
 		applied++
 	}
@@ -168,6 +168,9 @@ func TestUpgrade_Handle_ApplySuccessfully_NonInteractive(t *testing.T) {
 		ApplyFile(mock.Anything, &migrations[0], "/migrations/200101_120000_test.up.sql", true).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationApplied(migrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 	presenterMock.EXPECT().
 		ShowUpgradeSuccess(1).
 		Once()
@@ -267,10 +270,16 @@ func TestUpgrade_Handle_WithLimit_Successfully(t *testing.T) {
 		ApplyFile(mock.Anything, &limitedMigrations[0], "/migrations/200101_120000_test.up.sql", true).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationApplied(limitedMigrations[0].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 	svcMock.EXPECT().
 		ApplyFile(mock.Anything, &limitedMigrations[1], "/migrations/200102_120000_test.up.sql", false).
 		Return(nil).
 		Once()
+	presenterMock.EXPECT().
+		ShowMigrationApplied(limitedMigrations[1].Version, mock.AnythingOfType("time.Duration")).
+		Once()
 	presenterMock.EXPECT().
 		ShowUpgradeSuccess(2).
 		Once()
@@ -286,3 +295,52 @@ func TestUpgrade_Handle_WithLimit_Successfully(t *testing.T) {
 
 	require.NoError(t, err)
 }
+
+func TestUpgrade_Handle_OutOfOrder_Failure(t *testing.T) {
+	svcMock := NewMockMigrationService(t)
+
+	svcMock.EXPECT().
+		NewMigrations(mock.Anything).
+		Return(model.Migrations{
+			{Version: "200101_120000_merged", OutOfOrder: true},
+			{Version: "200103_120000_newer"},
+		}, nil).
+		Once()
+
+	upgrade := NewUpgrade(&Options{}, NewMockPresenter(t), NewMockFileNameBuilder(t))
+
+	err := upgrade.Handle(&Command{Args: &argsStub{present: false}}, svcMock)
+
+	require.ErrorIs(t, err, ErrOutOfOrderMigrations)
+	require.ErrorContains(t, err, "200101_120000_merged")
+}
+
+func TestUpgrade_Handle_AllowOutOfOrder_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	fileNameBuilderMock := NewMockFileNameBuilder(t)
+	svcMock := NewMockMigrationService(t)
+
+	migrations := model.Migrations{
+		{Version: "200101_120000_merged", OutOfOrder: true},
+	}
+
+	svcMock.EXPECT().NewMigrations(mock.Anything).Return(migrations, nil).Once()
+	presenterMock.EXPECT().ShowUpgradePlan(migrations, 1).Once()
+	presenterMock.EXPECT().AskUpgradeConfirmation(1).Return("Confirm?").Once()
+	fileNameBuilderMock.EXPECT().
+		Up("200101_120000_merged", false).
+		Return("/migrations/200101_120000_merged.up.sql", true).
+		Once()
+	svcMock.EXPECT().
+		ApplyFile(mock.Anything, &migrations[0], "/migrations/200101_120000_merged.up.sql", true).
+		Return(nil).
+		Once()
+	presenterMock.EXPECT().ShowMigrationApplied(migrations[0].Version, mock.AnythingOfType("time.Duration")).Once()
+	presenterMock.EXPECT().ShowUpgradeSuccess(1).Once()
+
+	upgrade := NewUpgrade(&Options{AllowOutOfOrder: true}, presenterMock, fileNameBuilderMock)
+
+	err := upgrade.Handle(&Command{Args: &argsStub{present: false}}, svcMock)
+
+	require.NoError(t, err)
+}
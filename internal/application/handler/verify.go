@@ -0,0 +1,45 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+// Verify handles the verification of applied migrations against their files.
+type Verify struct {
+	options   *Options
+	presenter Presenter
+}
+
+// NewVerify creates a new Verify handler instance.
+func NewVerify(
+	options *Options,
+	presenter Presenter,
+) *Verify {
+	return &Verify{
+		options:   options,
+		presenter: presenter,
+	}
+}
+
+// Handle processes the verify command.
+// It returns ErrMigrationsDrift when an applied migration file was changed or removed,
+// or when a migration was applied before checksums were recorded.
+func (v *Verify) Handle(cmd *Command, svc MigrationService) error {
+	drifts, err := svc.Verify(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if drifts.Len() == 0 {
+		v.presenter.ShowNoDrifts()
+		return nil
+	}
+
+	v.presenter.ShowDrifts(drifts)
+
+	return ErrMigrationsDrift
+}
@@ -0,0 +1,73 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errVerifyQueryFailed = errors.New("verify query failed")
+
+// TestVerify_Handle_NoDrifts_Successfully tests that Handle reports success
+// when all applied migrations match their files.
+func TestVerify_Handle_NoDrifts_Successfully(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+
+	migrationServiceMock.EXPECT().
+		Verify(mock.Anything).
+		Return(model.Drifts{}, nil)
+	presenterMock.EXPECT().ShowNoDrifts()
+
+	verify := NewVerify(&Options{}, presenterMock)
+	err := verify.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.NoError(t, err)
+}
+
+// TestVerify_Handle_DriftsFound_Failure tests that Handle shows the drifts
+// and returns ErrMigrationsDrift.
+func TestVerify_Handle_DriftsFound_Failure(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+	drifts := model.Drifts{
+		{Version: "200101_120000_create_users", Kind: model.DriftMissing},
+	}
+
+	migrationServiceMock.EXPECT().
+		Verify(mock.Anything).
+		Return(drifts, nil)
+	presenterMock.EXPECT().ShowDrifts(drifts)
+
+	verify := NewVerify(&Options{}, presenterMock)
+	err := verify.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.ErrorIs(t, err, ErrMigrationsDrift)
+}
+
+// TestVerify_Handle_VerifyReturnsError_Failure tests that Handle returns
+// the error from MigrationService.Verify.
+func TestVerify_Handle_VerifyReturnsError_Failure(t *testing.T) {
+	presenterMock := NewMockPresenter(t)
+	migrationServiceMock := NewMockMigrationService(t)
+
+	migrationServiceMock.EXPECT().
+		Verify(mock.Anything).
+		Return(nil, errVerifyQueryFailed)
+
+	verify := NewVerify(&Options{}, presenterMock)
+	err := verify.Handle(&Command{Args: &argsStub{}}, migrationServiceMock)
+
+	require.ErrorIs(t, err, errVerifyQueryFailed)
+}
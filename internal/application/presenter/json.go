@@ -0,0 +1,488 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package presenter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/helper/plural"
+)
+
+// Event types of the JSON output.
+const (
+	EventPlan             = "plan"
+	EventMigration        = "migration"
+	EventMigrations       = "migrations"
+	EventHistory          = "history"
+	EventNew              = "new"
+	EventResult           = "result"
+	EventMissingDownFiles = "missing-down-files"
+	EventDrifts           = "drifts"
+	EventStatus           = "status"
+	EventLock             = "lock"
+	EventError            = "error"
+)
+
+// Actions reported in the JSON output.
+const (
+	ActionUpgrade     = "upgrade"
+	ActionDowngrade   = "downgrade"
+	ActionRedo        = "redo"
+	ActionRelease     = "release"
+	ActionRollback    = "rollback"
+	ActionVerify      = "verify"
+	ActionLockRelease = "lock-release"
+	ActionForce       = "force"
+	ActionDump        = "dump"
+)
+
+// Result statuses reported in the JSON output.
+const (
+	ResultSuccess             = "success"
+	ResultFailed              = "failed"
+	ResultNoNewMigrations     = "no-new-migrations"
+	ResultNoAppliedMigrations = "no-applied-migrations"
+)
+
+type jsonMigration struct {
+	Version   string               `json:"version"`
+	ApplyTime int64                `json:"apply_time,omitempty"`
+	State     model.MigrationState `json:"state,omitempty"`
+	// LastStatement is the number of the statements committed by a dirty migration.
+	LastStatement int `json:"last_statement,omitempty"`
+}
+
+type jsonPlan struct {
+	Event      string          `json:"event"`
+	Action     string          `json:"action"`
+	Total      int             `json:"total"`
+	Migrations []jsonMigration `json:"migrations"`
+}
+
+type jsonMigrationResult struct {
+	Event      string  `json:"event"`
+	Action     string  `json:"action"`
+	Version    string  `json:"version"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+type jsonMigrations struct {
+	Event      string          `json:"event"`
+	Migrations []jsonMigration `json:"migrations"`
+}
+
+type jsonCount struct {
+	Event string `json:"event"`
+	Count int    `json:"count"`
+	Total int    `json:"total"`
+}
+
+type jsonResult struct {
+	Event   string `json:"event"`
+	Action  string `json:"action,omitempty"`
+	Status  string `json:"status"`
+	Count   int    `json:"count"`
+	Total   int    `json:"total,omitempty"`
+	Message string `json:"message"`
+}
+
+type jsonVersions struct {
+	Event    string   `json:"event"`
+	Versions []string `json:"versions"`
+}
+
+type jsonDrift struct {
+	Version          string          `json:"version"`
+	Kind             model.DriftKind `json:"kind"`
+	ExpectedChecksum string          `json:"expected_checksum,omitempty"`
+	ActualChecksum   string          `json:"actual_checksum,omitempty"`
+}
+
+type jsonDrifts struct {
+	Event  string      `json:"event"`
+	Drifts []jsonDrift `json:"drifts"`
+}
+
+type jsonStatus struct {
+	Event       string          `json:"event"`
+	Migrations  []jsonMigration `json:"migrations"`
+	Applied     int             `json:"applied"`
+	Pending     int             `json:"pending"`
+	OutOfOrder  int             `json:"out_of_order"`
+	MissingFile int             `json:"missing_file"`
+	Dirty       int             `json:"dirty,omitempty"`
+}
+
+type jsonLock struct {
+	Event      string `json:"event"`
+	Held       bool   `json:"held"`
+	Owner      string `json:"owner,omitempty"`
+	AcquiredAt int64  `json:"acquired_at,omitempty"`
+}
+
+type jsonError struct {
+	Event string `json:"event"`
+	Error string `json:"error"`
+}
+
+// JSONPresenter presents migration information as JSON documents written one per line.
+// Every document has an "event" field that tells its type, so deployment tooling
+// can consume the output without parsing human-readable text.
+type JSONPresenter struct {
+	encoder *json.Encoder
+}
+
+// NewJSONPresenter creates a new JSONPresenter instance writing to w.
+func NewJSONPresenter(w io.Writer) *JSONPresenter {
+	return &JSONPresenter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// ShowUpgradePlan writes the plan for applying migrations.
+func (p *JSONPresenter) ShowUpgradePlan(migrations model.Migrations, total int) {
+	p.write(jsonPlan{
+		Event:      EventPlan,
+		Action:     ActionUpgrade,
+		Total:      total,
+		Migrations: toJSONMigrations(migrations),
+	})
+}
+
+// ShowDowngradePlan writes the plan for reverting migrations.
+func (p *JSONPresenter) ShowDowngradePlan(migrations model.Migrations) {
+	p.write(jsonPlan{
+		Event:      EventPlan,
+		Action:     ActionDowngrade,
+		Total:      migrations.Len(),
+		Migrations: toJSONMigrations(migrations),
+	})
+}
+
+// ShowRedoPlan writes the plan for redoing migrations.
+func (p *JSONPresenter) ShowRedoPlan(migrations model.Migrations) {
+	p.write(jsonPlan{
+		Event:      EventPlan,
+		Action:     ActionRedo,
+		Total:      migrations.Len(),
+		Migrations: toJSONMigrations(migrations),
+	})
+}
+
+// PrintMigrations writes a list of migrations. Apply times are included when withTime is true.
+func (p *JSONPresenter) PrintMigrations(migrations model.Migrations, withTime bool) {
+	items := toJSONMigrations(migrations)
+	if !withTime {
+		for i := range items {
+			items[i].ApplyTime = 0
+		}
+	}
+
+	p.write(jsonMigrations{Event: EventMigrations, Migrations: items})
+}
+
+// ShowMigrationApplied writes the result of a single applied migration.
+func (p *JSONPresenter) ShowMigrationApplied(version string, duration time.Duration) {
+	p.write(jsonMigrationResult{
+		Event:      EventMigration,
+		Action:     ActionUpgrade,
+		Version:    version,
+		DurationMs: durationMs(duration),
+	})
+}
+
+// ShowMigrationReverted writes the result of a single reverted migration.
+func (p *JSONPresenter) ShowMigrationReverted(version string, duration time.Duration) {
+	p.write(jsonMigrationResult{
+		Event:      EventMigration,
+		Action:     ActionDowngrade,
+		Version:    version,
+		DurationMs: durationMs(duration),
+	})
+}
+
+// AskUpgradeConfirmation returns a confirmation question for applying migrations.
+func (p *JSONPresenter) AskUpgradeConfirmation(count int) string {
+	return fmt.Sprintf("Apply the above %s?", plural.Migration(count))
+}
+
+// AskDowngradeConfirmation returns a confirmation question for reverting migrations.
+func (p *JSONPresenter) AskDowngradeConfirmation(count int) string {
+	return fmt.Sprintf("Revert the above %d %s?", count, plural.Migration(count))
+}
+
+// AskRedoConfirmation returns a confirmation question for redoing migrations.
+func (p *JSONPresenter) AskRedoConfirmation(count int) string {
+	return fmt.Sprintf("Redo the above %d %s?", count, plural.Migration(count))
+}
+
+// ShowUpgradeError writes the result of a failed upgrade.
+func (p *JSONPresenter) ShowUpgradeError(applied, total int) {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionUpgrade,
+		Status:  ResultFailed,
+		Count:   applied,
+		Total:   total,
+		Message: "The rest of the migrations are canceled.",
+	})
+}
+
+// ShowDowngradeError writes the result of a failed downgrade.
+func (p *JSONPresenter) ShowDowngradeError(reverted, total int) {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionDowngrade,
+		Status:  ResultFailed,
+		Count:   reverted,
+		Total:   total,
+		Message: "Migration failed. The rest of the migrations are canceled.",
+	})
+}
+
+// ShowRedoError writes the result of a failed redo.
+func (p *JSONPresenter) ShowRedoError() {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionRedo,
+		Status:  ResultFailed,
+		Message: "Migration failed. The rest of the migrations are canceled.",
+	})
+}
+
+// ShowReleaseError writes the result of a failed release.
+func (p *JSONPresenter) ShowReleaseError() {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionRelease,
+		Status:  ResultFailed,
+		Message: "Release failed. All changes have been rolled back.",
+	})
+}
+
+// ShowRollbackError writes the result of a failed rollback.
+func (p *JSONPresenter) ShowRollbackError() {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionRollback,
+		Status:  ResultFailed,
+		Message: "Rollback failed. Some changes may have been partially reverted.",
+	})
+}
+
+// ShowUpgradeSuccess writes the result of a successful upgrade.
+func (p *JSONPresenter) ShowUpgradeSuccess(count int) {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionUpgrade,
+		Status:  ResultSuccess,
+		Count:   count,
+		Message: "Migrated up successfully",
+	})
+}
+
+// ShowDowngradeSuccess writes the result of a successful downgrade.
+func (p *JSONPresenter) ShowDowngradeSuccess(count int) {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionDowngrade,
+		Status:  ResultSuccess,
+		Count:   count,
+		Message: "Migrated down successfully",
+	})
+}
+
+// ShowRedoSuccess writes the result of a successful redo.
+func (p *JSONPresenter) ShowRedoSuccess(count int) {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionRedo,
+		Status:  ResultSuccess,
+		Count:   count,
+		Message: "Migration redone successfully.",
+	})
+}
+
+// ShowNoNewMigrations writes the result when there are no new migrations to apply.
+func (p *JSONPresenter) ShowNoNewMigrations() {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Status:  ResultNoNewMigrations,
+		Message: "No new migrations found. Your system is up-to-date.",
+	})
+}
+
+// ShowNoMigrationsToRevert writes the result when no migration has been applied.
+func (p *JSONPresenter) ShowNoMigrationsToRevert() {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Status:  ResultNoAppliedMigrations,
+		Message: "No migration has been done before.",
+	})
+}
+
+// ShowHistoryHeader writes the number of applied migrations that follow.
+func (p *JSONPresenter) ShowHistoryHeader(count int) {
+	p.write(jsonCount{Event: EventHistory, Count: count, Total: count})
+}
+
+// ShowAllHistoryHeader writes the number of applied migrations that follow.
+func (p *JSONPresenter) ShowAllHistoryHeader(count int) {
+	p.write(jsonCount{Event: EventHistory, Count: count, Total: count})
+}
+
+// ShowNewMigrationsHeader writes the number of new migrations that follow.
+func (p *JSONPresenter) ShowNewMigrationsHeader(count int) {
+	p.write(jsonCount{Event: EventNew, Count: count, Total: count})
+}
+
+// ShowNewMigrationsLimitedHeader writes the number of new migrations that follow and the total number.
+func (p *JSONPresenter) ShowNewMigrationsLimitedHeader(shown, total int) {
+	p.write(jsonCount{Event: EventNew, Count: shown, Total: total})
+}
+
+// ShowMissingDownFiles writes the versions whose down migration files are missing.
+func (p *JSONPresenter) ShowMissingDownFiles(versions []string) {
+	p.write(jsonVersions{Event: EventMissingDownFiles, Versions: versions})
+}
+
+// ShowDrifts writes applied migrations that do not match their files.
+func (p *JSONPresenter) ShowDrifts(drifts model.Drifts) {
+	items := make([]jsonDrift, 0, drifts.Len())
+	for _, drift := range drifts {
+		items = append(items, jsonDrift{
+			Version:          drift.Version,
+			Kind:             drift.Kind,
+			ExpectedChecksum: drift.ExpectedChecksum,
+			ActualChecksum:   drift.ActualChecksum,
+		})
+	}
+
+	p.write(jsonDrifts{Event: EventDrifts, Drifts: items})
+}
+
+// ShowNoDrifts writes the result when all applied migrations match their files.
+func (p *JSONPresenter) ShowNoDrifts() {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionVerify,
+		Status:  ResultSuccess,
+		Message: "All applied migrations match their files.",
+	})
+}
+
+// ShowStatus writes the state of every migration together with the number of migrations in each state.
+func (p *JSONPresenter) ShowStatus(statuses model.MigrationStatuses) {
+	items := make([]jsonMigration, 0, statuses.Len())
+	for i := range statuses {
+		item := jsonMigration{
+			Version:   statuses[i].Version,
+			ApplyTime: statuses[i].ApplyTime,
+			State:     statuses[i].State,
+		}
+		if statuses[i].State == model.StateDirty {
+			item.LastStatement = statuses[i].LastStatement
+		}
+		items = append(items, item)
+	}
+
+	p.write(jsonStatus{
+		Event:       EventStatus,
+		Migrations:  items,
+		Applied:     statuses.Count(model.StateApplied),
+		Pending:     statuses.Count(model.StatePending),
+		OutOfOrder:  statuses.Count(model.StateOutOfOrder),
+		MissingFile: statuses.Count(model.StateMissingFile),
+		Dirty:       statuses.Count(model.StateDirty),
+	})
+}
+
+// ShowLockStatus writes the holder of the migration lock, or that the lock is free when lock is nil.
+func (p *JSONPresenter) ShowLockStatus(lock *model.Lock) {
+	if lock == nil {
+		p.write(jsonLock{Event: EventLock})
+		return
+	}
+
+	p.write(jsonLock{
+		Event:      EventLock,
+		Held:       true,
+		Owner:      lock.Owner,
+		AcquiredAt: lock.AcquiredAt,
+	})
+}
+
+// AskLockReleaseConfirmation returns a confirmation question for releasing the migration lock.
+func (p *JSONPresenter) AskLockReleaseConfirmation(lock *model.Lock) string {
+	return fmt.Sprintf("Release the migration lock held by %s?", lock.Owner)
+}
+
+// ShowLockReleased writes the result of releasing the migration lock.
+func (p *JSONPresenter) ShowLockReleased(lock *model.Lock) {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionLockRelease,
+		Status:  ResultSuccess,
+		Message: fmt.Sprintf("The migration lock held by %s has been released.", lock.Owner),
+	})
+}
+
+// AskForceConfirmation returns a confirmation question for forcing the migration into the state.
+func (p *JSONPresenter) AskForceConfirmation(version, state string) string {
+	return fmt.Sprintf("Record %s as %s in history without running it?", version, state)
+}
+
+// ShowForced writes the result of forcing the migration into the state.
+func (p *JSONPresenter) ShowForced(version, state string) {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionForce,
+		Status:  ResultSuccess,
+		Message: fmt.Sprintf("%s has been recorded as %s.", version, state),
+	})
+}
+
+// ShowDumped writes the result of dumping the schema into the file.
+func (p *JSONPresenter) ShowDumped(fileName string) {
+	p.write(jsonResult{
+		Event:   EventResult,
+		Action:  ActionDump,
+		Status:  ResultSuccess,
+		Message: fmt.Sprintf("The schema has been dumped to %s.", fileName),
+	})
+}
+
+// ShowError writes the error that terminated the command.
+func (p *JSONPresenter) ShowError(err error) {
+	p.write(jsonError{Event: EventError, Error: err.Error()})
+}
+
+func (p *JSONPresenter) write(event any) {
+	_ = p.encoder.Encode(event)
+}
+
+func toJSONMigrations(migrations model.Migrations) []jsonMigration {
+	items := make([]jsonMigration, 0, migrations.Len())
+	for i := range migrations {
+		items = append(items, jsonMigration{
+			Version:   migrations[i].Version,
+			ApplyTime: migrations[i].ApplyTime,
+		})
+	}
+
+	return items
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,204 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package presenter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONPresenter_WritesOneDocumentPerEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		show     func(p *JSONPresenter)
+		expected string
+	}{
+		{
+			name: "upgrade plan",
+			show: func(p *JSONPresenter) {
+				p.ShowUpgradePlan(model.Migrations{{Version: "210328_221600_first"}}, 3)
+			},
+			expected: `{"event":"plan","action":"upgrade","total":3,"migrations":[{"version":"210328_221600_first"}]}`,
+		},
+		{
+			name: "downgrade plan without migrations",
+			show: func(p *JSONPresenter) {
+				p.ShowDowngradePlan(model.Migrations{})
+			},
+			expected: `{"event":"plan","action":"downgrade","total":0,"migrations":[]}`,
+		},
+		{
+			name: "migrations with time",
+			show: func(p *JSONPresenter) {
+				p.PrintMigrations(model.Migrations{{Version: "210328_221600_first", ApplyTime: 1616961360}}, true)
+			},
+			expected: `{"event":"migrations","migrations":[{"version":"210328_221600_first","apply_time":1616961360}]}`,
+		},
+		{
+			name: "migrations without time",
+			show: func(p *JSONPresenter) {
+				p.PrintMigrations(model.Migrations{{Version: "210328_221600_first", ApplyTime: 1616961360}}, false)
+			},
+			expected: `{"event":"migrations","migrations":[{"version":"210328_221600_first"}]}`,
+		},
+		{
+			name: "applied migration",
+			show: func(p *JSONPresenter) {
+				p.ShowMigrationApplied("210328_221600_first", 1500*time.Microsecond)
+			},
+			expected: `{"event":"migration","action":"upgrade","version":"210328_221600_first","duration_ms":1.5}`,
+		},
+		{
+			name: "reverted migration",
+			show: func(p *JSONPresenter) {
+				p.ShowMigrationReverted("210328_221600_first", 2*time.Millisecond)
+			},
+			expected: `{"event":"migration","action":"downgrade","version":"210328_221600_first","duration_ms":2}`,
+		},
+		{
+			name: "upgrade error",
+			show: func(p *JSONPresenter) {
+				p.ShowUpgradeError(1, 3)
+			},
+			expected: `{"event":"result","action":"upgrade","status":"failed","count":1,"total":3,` +
+				`"message":"The rest of the migrations are canceled."}`,
+		},
+		{
+			name: "upgrade success",
+			show: func(p *JSONPresenter) {
+				p.ShowUpgradeSuccess(2)
+			},
+			expected: `{"event":"result","action":"upgrade","status":"success","count":2,` +
+				`"message":"Migrated up successfully"}`,
+		},
+		{
+			name: "no new migrations",
+			show: func(p *JSONPresenter) {
+				p.ShowNoNewMigrations()
+			},
+			expected: `{"event":"result","status":"no-new-migrations","count":0,` +
+				`"message":"No new migrations found. Your system is up-to-date."}`,
+		},
+		{
+			name: "new migrations limited header",
+			show: func(p *JSONPresenter) {
+				p.ShowNewMigrationsLimitedHeader(5, 10)
+			},
+			expected: `{"event":"new","count":5,"total":10}`,
+		},
+		{
+			name: "missing down files",
+			show: func(p *JSONPresenter) {
+				p.ShowMissingDownFiles([]string{"210328_221600_first"})
+			},
+			expected: `{"event":"missing-down-files","versions":["210328_221600_first"]}`,
+		},
+		{
+			name: "drifts",
+			show: func(p *JSONPresenter) {
+				p.ShowDrifts(model.Drifts{{Version: "210328_221600_first", Kind: model.DriftMissing}})
+			},
+			expected: `{"event":"drifts","drifts":[{"version":"210328_221600_first","kind":"missing"}]}`,
+		},
+		{
+			name: "status",
+			show: func(p *JSONPresenter) {
+				p.ShowStatus(model.MigrationStatuses{
+					{Migration: model.Migration{Version: "210328_221600_first", ApplyTime: 1616961360}, State: model.StateApplied},
+					{Migration: model.Migration{Version: "210328_221700_second"}, State: model.StatePending},
+				})
+			},
+			expected: `{"event":"status","migrations":[` +
+				`{"version":"210328_221600_first","apply_time":1616961360,"state":"applied"},` +
+				`{"version":"210328_221700_second","state":"pending"}],` +
+				`"applied":1,"pending":1,"out_of_order":0,"missing_file":0}`,
+		},
+		{
+			name: "dirty status",
+			show: func(p *JSONPresenter) {
+				p.ShowStatus(model.MigrationStatuses{
+					{
+						Migration: model.Migration{Version: "210328_221700_second", Status: "failed", LastStatement: 2},
+						State:     model.StateDirty,
+					},
+				})
+			},
+			expected: `{"event":"status","migrations":[` +
+				`{"version":"210328_221700_second","state":"dirty","last_statement":2}],` +
+				`"applied":0,"pending":0,"out_of_order":0,"missing_file":0,"dirty":1}`,
+		},
+		{
+			name: "forced",
+			show: func(p *JSONPresenter) {
+				p.ShowForced("210328_221700_second", "applied")
+			},
+			expected: `{"event":"result","action":"force","status":"success","count":0,` +
+				`"message":"210328_221700_second has been recorded as applied."}`,
+		},
+		{
+			name: "dumped",
+			show: func(p *JSONPresenter) {
+				p.ShowDumped("migrations/schema.sql")
+			},
+			expected: `{"event":"result","action":"dump","status":"success","count":0,` +
+				`"message":"The schema has been dumped to migrations/schema.sql."}`,
+		},
+		{
+			name: "lock free",
+			show: func(p *JSONPresenter) {
+				p.ShowLockStatus(nil)
+			},
+			expected: `{"event":"lock","held":false}`,
+		},
+		{
+			name: "lock held",
+			show: func(p *JSONPresenter) {
+				p.ShowLockStatus(&model.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000})
+			},
+			expected: `{"event":"lock","held":true,"owner":"db-migrator@host:1","acquired_at":1700000000}`,
+		},
+		{
+			name: "lock released",
+			show: func(p *JSONPresenter) {
+				p.ShowLockReleased(&model.Lock{Owner: "db-migrator@host:1"})
+			},
+			expected: `{"event":"result","action":"lock-release","status":"success","count":0,` +
+				`"message":"The migration lock held by db-migrator@host:1 has been released."}`,
+		},
+		{
+			name: "error",
+			show: func(p *JSONPresenter) {
+				p.ShowError(errors.New("failed to connect to database"))
+			},
+			expected: `{"event":"error","error":"failed to connect to database"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.show(NewJSONPresenter(&buf))
+
+			assert.Equal(t, tt.expected+"\n", buf.String())
+		})
+	}
+}
+
+func TestJSONPresenter_AskConfirmation_ReturnsQuestion(t *testing.T) {
+	presenter := NewJSONPresenter(&bytes.Buffer{})
+
+	assert.Equal(t, "Apply the above migrations?", presenter.AskUpgradeConfirmation(2))
+	assert.Equal(t, "Revert the above 1 migration?", presenter.AskDowngradeConfirmation(1))
+	assert.Equal(t, "Redo the above 2 migrations?", presenter.AskRedoConfirmation(2))
+}
@@ -10,6 +10,7 @@ package presenter
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/raoptimus/db-migrator.go/internal/domain/log"
 	"github.com/raoptimus/db-migrator.go/internal/domain/model"
@@ -79,6 +80,14 @@ func (p *MigrationPresenter) PrintMigrations(migrations model.Migrations, withTi
 	}
 }
 
+// ShowMigrationApplied does nothing: the migration service already logs
+// every applied migration together with its execution time.
+func (p *MigrationPresenter) ShowMigrationApplied(string, time.Duration) {}
+
+// ShowMigrationReverted does nothing: the migration service already logs
+// every reverted migration together with its execution time.
+func (p *MigrationPresenter) ShowMigrationReverted(string, time.Duration) {}
+
 // AskUpgradeConfirmation returns a confirmation question for applying migrations.
 func (p *MigrationPresenter) AskUpgradeConfirmation(count int) string {
 	return fmt.Sprintf("Apply the above %s?", plural.Migration(count))
@@ -213,3 +222,104 @@ func (p *MigrationPresenter) ShowMissingDownFiles(versions []string) {
 		p.logger.Errorf("\t%s\n", v)
 	}
 }
+
+// ShowDrifts displays applied migrations that do not match their files.
+func (p *MigrationPresenter) ShowDrifts(drifts model.Drifts) {
+	p.logger.Errorf("%d applied %s cannot be verified:\n", drifts.Len(), plural.Migration(drifts.Len()))
+	for _, drift := range drifts {
+		switch drift.Kind {
+		case model.DriftChanged:
+			p.logger.Errorf(
+				"\t%s: file was changed after it had been applied (expected %s, actual %s)\n",
+				drift.Version,
+				drift.ExpectedChecksum,
+				drift.ActualChecksum,
+			)
+		case model.DriftMissing:
+			p.logger.Errorf("\t%s: file is missing\n", drift.Version)
+		case model.DriftNoChecksum:
+			p.logger.Errorf("\t%s: no checksum was recorded\n", drift.Version)
+		}
+	}
+}
+
+// ShowNoDrifts displays a message when all applied migrations match their files.
+func (p *MigrationPresenter) ShowNoDrifts() {
+	p.logger.Success("All applied migrations match their files.")
+}
+
+// ShowStatus displays the state of every migration followed by a summary line.
+// Applied migrations are printed with their apply time.
+func (p *MigrationPresenter) ShowStatus(statuses model.MigrationStatuses) {
+	for i := range statuses {
+		status := &statuses[i]
+		switch status.State {
+		case model.StateApplied:
+			p.logger.Infof("\t%-12s (%s) %s\n", status.State, status.ApplyTimeFormat(), status.Version)
+		case model.StateMissingFile:
+			p.logger.Errorf("\t%-12s (%s) %s\n", status.State, status.ApplyTimeFormat(), status.Version)
+		case model.StateOutOfOrder:
+			p.logger.Errorf("\t%-12s %s\n", status.State, status.Version)
+		case model.StateDirty:
+			p.logger.Errorf("\t%-12s %s (%s after %d statement(s))\n",
+				status.State, status.Version, status.Status, status.LastStatement)
+		default:
+			p.logger.Warnf("\t%-12s %s\n", status.State, status.Version)
+		}
+	}
+
+	format := "Applied: %d, pending: %d, out-of-order: %d, missing file: %d\n"
+	args := []any{
+		statuses.Count(model.StateApplied),
+		statuses.Count(model.StatePending),
+		statuses.Count(model.StateOutOfOrder),
+		statuses.Count(model.StateMissingFile),
+	}
+	if dirty := statuses.Count(model.StateDirty); dirty > 0 {
+		format = "Applied: %d, pending: %d, out-of-order: %d, missing file: %d, dirty: %d\n"
+		args = append(args, dirty)
+	}
+	if statuses.Count(model.StateApplied) == statuses.Len() {
+		p.logger.Successf(format, args...)
+		return
+	}
+
+	p.logger.Warnf(format, args...)
+}
+
+// ShowLockStatus displays the holder of the migration lock, or that the lock is free when lock is nil.
+func (p *MigrationPresenter) ShowLockStatus(lock *model.Lock) {
+	switch {
+	case lock == nil:
+		p.logger.Success("The migration lock is free.")
+	case lock.AcquiredAt > 0:
+		p.logger.Warnf("The migration lock is held by %s since %s\n", lock.Owner, lock.AcquiredAtFormat())
+	default:
+		p.logger.Warnf("The migration lock is held by %s\n", lock.Owner)
+	}
+}
+
+// AskLockReleaseConfirmation returns a confirmation question for releasing the migration lock.
+func (p *MigrationPresenter) AskLockReleaseConfirmation(lock *model.Lock) string {
+	return fmt.Sprintf("Release the migration lock held by %s?", lock.Owner)
+}
+
+// ShowLockReleased displays a success message after the migration lock has been released.
+func (p *MigrationPresenter) ShowLockReleased(lock *model.Lock) {
+	p.logger.Successf("The migration lock held by %s has been released.\n", lock.Owner)
+}
+
+// AskForceConfirmation returns a confirmation question for forcing the migration into the state.
+func (p *MigrationPresenter) AskForceConfirmation(version, state string) string {
+	return fmt.Sprintf("Record %s as %s in history without running it?", version, state)
+}
+
+// ShowForced displays a success message after the migration has been forced into the state.
+func (p *MigrationPresenter) ShowForced(version, state string) {
+	p.logger.Successf("%s has been recorded as %s.\n", version, state)
+}
+
+// ShowDumped displays a success message after the schema has been dumped into the file.
+func (p *MigrationPresenter) ShowDumped(fileName string) {
+	p.logger.Successf("The schema has been dumped to %s.\n", fileName)
+}
@@ -368,3 +368,182 @@ func TestMigrationPresenter_ShowNewMigrationsLimitedHeader(t *testing.T) {
 	presenter := NewMigrationPresenter(logger)
 	presenter.ShowNewMigrationsLimitedHeader(5, 10)
 }
+
+func TestMigrationPresenter_ShowDrifts(t *testing.T) {
+	logger := NewMockLogger(t)
+	logger.EXPECT().
+		Errorf("%d applied %s cannot be verified:\n", 3, "migrations").
+		Return().
+		Once()
+	logger.EXPECT().
+		Errorf(
+			"\t%s: file was changed after it had been applied (expected %s, actual %s)\n",
+			"210328_221600_first",
+			"expected",
+			"actual",
+		).
+		Return().
+		Once()
+	logger.EXPECT().
+		Errorf("\t%s: file is missing\n", "210328_221700_second").
+		Return().
+		Once()
+	logger.EXPECT().
+		Errorf("\t%s: no checksum was recorded\n", "210328_221800_third").
+		Return().
+		Once()
+
+	presenter := NewMigrationPresenter(logger)
+	presenter.ShowDrifts(model.Drifts{
+		{
+			Version:          "210328_221600_first",
+			Kind:             model.DriftChanged,
+			ExpectedChecksum: "expected",
+			ActualChecksum:   "actual",
+		},
+		{Version: "210328_221700_second", Kind: model.DriftMissing},
+		{Version: "210328_221800_third", Kind: model.DriftNoChecksum},
+	})
+}
+
+func TestMigrationPresenter_ShowNoDrifts(t *testing.T) {
+	logger := NewMockLogger(t)
+	logger.EXPECT().
+		Success("All applied migrations match their files.").
+		Return().
+		Once()
+
+	presenter := NewMigrationPresenter(logger)
+	presenter.ShowNoDrifts()
+}
+
+func TestMigrationPresenter_ShowStatus(t *testing.T) {
+	logger := NewMockLogger(t)
+	applied := model.Migration{Version: "210328_221600_first", ApplyTime: 1616961360}
+	missing := model.Migration{Version: "210328_221700_second", ApplyTime: 1616961420}
+	logger.EXPECT().
+		Infof("\t%-12s (%s) %s\n", model.StateApplied, applied.ApplyTimeFormat(), applied.Version).
+		Return().
+		Once()
+	logger.EXPECT().
+		Errorf("\t%-12s (%s) %s\n", model.StateMissingFile, missing.ApplyTimeFormat(), missing.Version).
+		Return().
+		Once()
+	logger.EXPECT().
+		Errorf("\t%-12s %s\n", model.StateOutOfOrder, "210328_221650_late").
+		Return().
+		Once()
+	logger.EXPECT().
+		Warnf("\t%-12s %s\n", model.StatePending, "210328_221800_third").
+		Return().
+		Once()
+	logger.EXPECT().
+		Warnf("Applied: %d, pending: %d, out-of-order: %d, missing file: %d\n", 1, 1, 1, 1).
+		Return().
+		Once()
+
+	presenter := NewMigrationPresenter(logger)
+	presenter.ShowStatus(model.MigrationStatuses{
+		{Migration: applied, State: model.StateApplied},
+		{Migration: model.Migration{Version: "210328_221650_late"}, State: model.StateOutOfOrder},
+		{Migration: missing, State: model.StateMissingFile},
+		{Migration: model.Migration{Version: "210328_221800_third"}, State: model.StatePending},
+	})
+}
+
+func TestMigrationPresenter_ShowStatus_UpToDate(t *testing.T) {
+	logger := NewMockLogger(t)
+	logger.EXPECT().
+		Infof("\t%-12s (%s) %s\n", model.StateApplied, mock.AnythingOfType("string"), "210328_221600_first").
+		Return().
+		Once()
+	logger.EXPECT().
+		Successf("Applied: %d, pending: %d, out-of-order: %d, missing file: %d\n", 1, 0, 0, 0).
+		Return().
+		Once()
+
+	presenter := NewMigrationPresenter(logger)
+	presenter.ShowStatus(model.MigrationStatuses{
+		{Migration: model.Migration{Version: "210328_221600_first"}, State: model.StateApplied},
+	})
+}
+
+func TestMigrationPresenter_ShowLockStatus(t *testing.T) {
+	held := &model.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}
+	logger := NewMockLogger(t)
+	logger.EXPECT().Success("The migration lock is free.").Return().Once()
+	logger.EXPECT().
+		Warnf("The migration lock is held by %s since %s\n", held.Owner, held.AcquiredAtFormat()).
+		Return().
+		Once()
+	logger.EXPECT().
+		Warnf("The migration lock is held by %s\n", "root@10.0.0.5 (connection 17)").
+		Return().
+		Once()
+
+	presenter := NewMigrationPresenter(logger)
+	presenter.ShowLockStatus(nil)
+	presenter.ShowLockStatus(held)
+	presenter.ShowLockStatus(&model.Lock{Owner: "root@10.0.0.5 (connection 17)"})
+}
+
+func TestMigrationPresenter_ShowLockReleased(t *testing.T) {
+	logger := NewMockLogger(t)
+	logger.EXPECT().
+		Successf("The migration lock held by %s has been released.\n", "db-migrator@host:1").
+		Return().
+		Once()
+
+	presenter := NewMigrationPresenter(logger)
+	assert.Equal(t,
+		"Release the migration lock held by db-migrator@host:1?",
+		presenter.AskLockReleaseConfirmation(&model.Lock{Owner: "db-migrator@host:1"}),
+	)
+	presenter.ShowLockReleased(&model.Lock{Owner: "db-migrator@host:1"})
+}
+
+func TestMigrationPresenter_ShowStatus_Dirty(t *testing.T) {
+	logger := NewMockLogger(t)
+	logger.EXPECT().
+		Errorf("\t%-12s %s (%s after %d statement(s))\n", model.StateDirty, "210328_221700_second", "failed", 2).
+		Return().
+		Once()
+	logger.EXPECT().
+		Warnf("Applied: %d, pending: %d, out-of-order: %d, missing file: %d, dirty: %d\n", 0, 0, 0, 0, 1).
+		Return().
+		Once()
+
+	presenter := NewMigrationPresenter(logger)
+	presenter.ShowStatus(model.MigrationStatuses{
+		{
+			Migration: model.Migration{Version: "210328_221700_second", Status: "failed", LastStatement: 2},
+			State:     model.StateDirty,
+		},
+	})
+}
+
+func TestMigrationPresenter_ShowForced(t *testing.T) {
+	logger := NewMockLogger(t)
+	logger.EXPECT().
+		Successf("%s has been recorded as %s.\n", "210328_221700_second", "reverted").
+		Return().
+		Once()
+
+	presenter := NewMigrationPresenter(logger)
+	assert.Equal(t,
+		"Record 210328_221700_second as reverted in history without running it?",
+		presenter.AskForceConfirmation("210328_221700_second", "reverted"),
+	)
+	presenter.ShowForced("210328_221700_second", "reverted")
+}
+
+func TestMigrationPresenter_ShowDumped(t *testing.T) {
+	logger := NewMockLogger(t)
+	logger.EXPECT().
+		Successf("The schema has been dumped to %s.\n", "migrations/schema.sql").
+		Return().
+		Once()
+
+	presenter := NewMigrationPresenter(logger)
+	presenter.ShowDumped("migrations/schema.sql")
+}
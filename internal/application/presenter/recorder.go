@@ -0,0 +1,67 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package presenter
+
+import (
+	"time"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+)
+
+// RecordedMigration is a migration applied or reverted by a command.
+type RecordedMigration struct {
+	Version  string
+	Duration time.Duration
+}
+
+// Recorder presents migration information like MigrationPresenter and records the results of a command:
+// the applied and reverted migrations, the listed migrations and the migration statuses.
+// The library returns the recorded results to its callers as structured values.
+type Recorder struct {
+	*MigrationPresenter
+	// Applied are the migrations applied by the command in the order they were applied.
+	Applied []RecordedMigration
+	// Reverted are the migrations reverted by the command in the order they were reverted.
+	Reverted []RecordedMigration
+	// Migrations are the migrations listed by the command.
+	Migrations model.Migrations
+	// Statuses are the migration statuses shown by the command.
+	Statuses model.MigrationStatuses
+}
+
+// NewRecorder creates a new Recorder instance logging with logger.
+func NewRecorder(logger Logger) *Recorder {
+	return &Recorder{
+		MigrationPresenter: NewMigrationPresenter(logger),
+	}
+}
+
+// PrintMigrations prints and records a list of migrations.
+func (r *Recorder) PrintMigrations(migrations model.Migrations, withTime bool) {
+	r.MigrationPresenter.PrintMigrations(migrations, withTime)
+	r.Migrations = append(r.Migrations, migrations...)
+}
+
+// ShowMigrationApplied records the result of a single applied migration.
+func (r *Recorder) ShowMigrationApplied(version string, duration time.Duration) {
+	r.MigrationPresenter.ShowMigrationApplied(version, duration)
+	r.Applied = append(r.Applied, RecordedMigration{Version: version, Duration: duration})
+}
+
+// ShowMigrationReverted records the result of a single reverted migration.
+func (r *Recorder) ShowMigrationReverted(version string, duration time.Duration) {
+	r.MigrationPresenter.ShowMigrationReverted(version, duration)
+	r.Reverted = append(r.Reverted, RecordedMigration{Version: version, Duration: duration})
+}
+
+// ShowStatus displays and records the state of every migration.
+func (r *Recorder) ShowStatus(statuses model.MigrationStatuses) {
+	r.MigrationPresenter.ShowStatus(statuses)
+	r.Statuses = statuses
+}
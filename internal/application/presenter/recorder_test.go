@@ -0,0 +1,66 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package presenter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRecorder_RecordsAppliedAndReverted(t *testing.T) {
+	recorder := NewRecorder(NewMockLogger(t))
+
+	recorder.ShowMigrationApplied("210328_221600_first", time.Second)
+	recorder.ShowMigrationReverted("210328_221600_first", 2*time.Second)
+	recorder.ShowMigrationApplied("210328_221600_first", 3*time.Second)
+
+	assert.Equal(t, []RecordedMigration{
+		{Version: "210328_221600_first", Duration: time.Second},
+		{Version: "210328_221600_first", Duration: 3 * time.Second},
+	}, recorder.Applied)
+	assert.Equal(t, []RecordedMigration{
+		{Version: "210328_221600_first", Duration: 2 * time.Second},
+	}, recorder.Reverted)
+}
+
+func TestRecorder_RecordsPrintedMigrations_NotPlans(t *testing.T) {
+	logger := NewMockLogger(t)
+	logger.EXPECT().Warnf("Total %d %s to be reverted: \n", 1, "migration").Return().Once()
+	logger.EXPECT().Infof("\t%s\n", "210328_221600_first").Return().Once()
+	logger.EXPECT().Infof("\t(%s) %s\n", mock.Anything, "210328_221700_second").Return().Once()
+	recorder := NewRecorder(logger)
+	plan := model.Migrations{{Version: "210328_221600_first"}}
+	history := model.Migrations{{Version: "210328_221700_second", ApplyTime: 1700000000}}
+
+	recorder.ShowDowngradePlan(plan)
+	recorder.PrintMigrations(history, true)
+
+	assert.Equal(t, history, recorder.Migrations)
+}
+
+func TestRecorder_RecordsStatuses(t *testing.T) {
+	logger := NewMockLogger(t)
+	logger.EXPECT().Warnf("\t%-12s %s\n", model.StatePending, "210328_221600_first").Return().Once()
+	logger.EXPECT().
+		Warnf("Applied: %d, pending: %d, out-of-order: %d, missing file: %d\n", 0, 1, 0, 0).
+		Return().
+		Once()
+	recorder := NewRecorder(logger)
+	statuses := model.MigrationStatuses{
+		{Migration: model.Migration{Version: "210328_221600_first"}, State: model.StatePending},
+	}
+
+	recorder.ShowStatus(statuses)
+
+	assert.Equal(t, statuses, recorder.Statuses)
+}
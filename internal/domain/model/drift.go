@@ -0,0 +1,37 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package model
+
+// DriftKind describes how an applied migration differs from its file.
+type DriftKind string
+
+const (
+	// DriftChanged means the migration file was modified after the migration had been applied.
+	DriftChanged DriftKind = "changed"
+	// DriftMissing means the migration file no longer exists.
+	DriftMissing DriftKind = "missing"
+	// DriftNoChecksum means the migration was applied before checksums were recorded.
+	DriftNoChecksum DriftKind = "no-checksum"
+)
+
+// Drift represents an applied migration that cannot be verified against its file.
+type Drift struct {
+	Version          string
+	Kind             DriftKind
+	ExpectedChecksum string
+	ActualChecksum   string
+}
+
+// Drifts is a collection of Drift records.
+type Drifts []Drift
+
+// Len returns the number of drifts in the collection.
+func (s Drifts) Len() int {
+	return len(s)
+}
@@ -0,0 +1,33 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package model
+
+import "time"
+
+// Lock describes the process holding the migration lock.
+type Lock struct {
+	// Owner identifies the holder, usually as host:pid.
+	Owner string
+	// AcquiredAt is a Unix timestamp in seconds. Zero means the driver cannot tell when the lock was taken.
+	AcquiredAt int64
+}
+
+// AcquiredAtFormat returns the formatted acquire time as a string in "YYYY-MM-DD HH:MM:SS" format.
+func (l Lock) AcquiredAtFormat() string {
+	return time.Unix(l.AcquiredAt, 0).Format("2006-01-02 15:04:05")
+}
+
+// Age returns how long the lock has been held at the given moment, or zero when the acquire time is unknown.
+func (l Lock) Age(now time.Time) time.Duration {
+	if l.AcquiredAt <= 0 {
+		return 0
+	}
+
+	return now.Sub(time.Unix(l.AcquiredAt, 0))
+}
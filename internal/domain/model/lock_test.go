@@ -0,0 +1,23 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock_Age(t *testing.T) {
+	now := time.Unix(1700003600, 0)
+
+	assert.Equal(t, time.Hour, Lock{AcquiredAt: 1700000000}.Age(now))
+	assert.Zero(t, Lock{}.Age(now), "unknown acquire time must not look stale")
+}
@@ -13,13 +13,38 @@ import (
 	"time"
 )
 
+// Statuses of a migration in the history table.
+const (
+	StatusApplied = "applied"
+	// StatusPending marks a migration that is being applied, or whose run died midway.
+	StatusPending = "pending"
+	// StatusFailed marks a migration that failed after some of its statements were committed.
+	StatusFailed = "failed"
+)
+
 // Migration represents a domain migration record.
 type Migration struct {
 	Version     string
 	ApplyTime   int64
 	BodySQL     string
 	ExecutedSQL string
+	DownSQL     string
+	Checksum    string
 	Release     string
+	// OutOfOrder marks a pending migration older than the latest applied one,
+	// or an applied migration that was applied after a newer one.
+	OutOfOrder bool
+	// Status is the status of the migration record in the history table,
+	// it may be empty for an applied migration and is empty for a migration not recorded there.
+	Status string
+	// LastStatement is the number of the statements committed by a pending or failed migration.
+	LastStatement int
+}
+
+// Dirty reports whether the migration was started and not applied completely,
+// leaving the database partially migrated.
+func (m Migration) Dirty() bool {
+	return m.Status == StatusPending || m.Status == StatusFailed
 }
 
 // ApplyTimeFormat returns the formatted apply time as a string in "YYYY-MM-DD HH:MM:SS" format.
@@ -0,0 +1,68 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package model
+
+import "sort"
+
+// MigrationState describes a migration version as seen from the history table and the migration files.
+type MigrationState string
+
+const (
+	// StateApplied means the migration is applied and its file exists.
+	StateApplied MigrationState = "applied"
+	// StatePending means the migration file exists but the migration is not applied yet.
+	StatePending MigrationState = "pending"
+	// StateOutOfOrder means the migration is pending although a newer migration is already applied.
+	StateOutOfOrder MigrationState = "out-of-order"
+	// StateMissingFile means the migration is applied but its file no longer exists.
+	StateMissingFile MigrationState = "missing-file"
+	// StateDirty means the migration was left pending or failed by a run that committed some of its statements.
+	StateDirty MigrationState = "dirty"
+)
+
+// MigrationStatus represents the state of a single migration version.
+type MigrationStatus struct {
+	Migration
+	State MigrationState
+}
+
+// MigrationStatuses is a collection of MigrationStatus records that implements sort.Interface.
+type MigrationStatuses []MigrationStatus
+
+// Len returns the number of statuses in the collection.
+func (s MigrationStatuses) Len() int {
+	return len(s)
+}
+
+// Less reports whether the status at index i should sort before the status at index j.
+func (s MigrationStatuses) Less(i, j int) bool {
+	return s[i].Version < s[j].Version
+}
+
+// Swap swaps the statuses at indexes i and j.
+func (s MigrationStatuses) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// SortByVersion sorts the statuses by their version string in ascending order.
+func (s MigrationStatuses) SortByVersion() {
+	sort.Sort(s)
+}
+
+// Count returns the number of migrations in the given state.
+func (s MigrationStatuses) Count(state MigrationState) int {
+	count := 0
+	for i := range s {
+		if s[i].State == state {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,39 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationStatuses_SortByVersion(t *testing.T) {
+	statuses := MigrationStatuses{
+		{Migration: Migration{Version: "210328_221700_second"}, State: StatePending},
+		{Migration: Migration{Version: "210328_221600_first"}, State: StateApplied},
+	}
+
+	statuses.SortByVersion()
+
+	assert.Equal(t, "210328_221600_first", statuses[0].Version)
+	assert.Equal(t, "210328_221700_second", statuses[1].Version)
+}
+
+func TestMigrationStatuses_Count(t *testing.T) {
+	statuses := MigrationStatuses{
+		{Migration: Migration{Version: "210328_221600_first"}, State: StateApplied},
+		{Migration: Migration{Version: "210328_221700_second"}, State: StateApplied},
+		{Migration: Migration{Version: "210328_221800_third"}, State: StatePending},
+	}
+
+	assert.Equal(t, 2, statuses.Count(StateApplied))
+	assert.Equal(t, 1, statuses.Count(StatePending))
+	assert.Equal(t, 0, statuses.Count(StateMissingFile))
+}
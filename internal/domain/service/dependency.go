@@ -13,7 +13,10 @@ import (
 	"io"
 
 	"github.com/raoptimus/db-migrator.go/internal/domain/log"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
 )
 
 // Logger defines the interface for logging migration operations.
@@ -23,13 +26,28 @@ import (
 type Logger = log.Logger
 
 // File defines the interface for file system operations.
-// It provides methods to check file existence, open files, and read file contents.
+// It provides methods to check file existence, open files, read file contents and list files by a pattern.
 //
 //go:generate mockery
 type File interface {
 	Exists(fileName string) (bool, error)
 	Open(filename string) (io.ReadCloser, error)
 	ReadAll(filename string) ([]byte, error)
+	Glob(pattern string) ([]string, error)
+}
+
+// Connection defines the interface of the database connection passed to migrations written in Go.
+// Statements executed with a context carrying a transaction run inside that transaction.
+//
+//go:generate mockery
+type Connection interface {
+	DSN() string
+	Driver() connection.Driver
+	Ping() error
+	QueryContext(ctx context.Context, query string, args ...any) (sqlex.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sqlex.Result, error)
+	Transaction(ctx context.Context, txFn func(ctx context.Context) error) error
+	io.Closer
 }
 
 // Repository defines the interface for database migration operations.
@@ -44,9 +62,11 @@ type Repository interface {
 	// HasMigrationHistoryTable returns true if migration history table exists.
 	HasMigrationHistoryTable(ctx context.Context) (exists bool, err error)
 	// InsertMigration inserts the new migration record.
-	InsertMigration(ctx context.Context, version string) error
+	InsertMigration(ctx context.Context, migration *entity.Migration) error
 	// RemoveMigration removes the migration record.
 	RemoveMigration(ctx context.Context, version string) error
+	// UpdateMigration updates the record of migration.Version, setting its apply time to now.
+	UpdateMigration(ctx context.Context, migration *entity.Migration) error
 	// ExecQuery executes a query without returning any rows.
 	// The args are for any placeholder parameters in the query.
 	ExecQuery(ctx context.Context, query string, args ...any) error
@@ -59,12 +79,34 @@ type Repository interface {
 	DropMigrationHistoryTable(ctx context.Context) error
 	// CreateMigrationHistoryTable creates the migration history table in the database.
 	CreateMigrationHistoryTable(ctx context.Context) error
+	// UpgradeMigrationHistoryTable adds the columns missing from a history table created by an older version.
+	UpgradeMigrationHistoryTable(ctx context.Context) error
+	// NeedsMigrationHistoryTableUpgrade reports whether the history table lacks columns added by UpgradeMigrationHistoryTable.
+	NeedsMigrationHistoryTableUpgrade(ctx context.Context) (bool, error)
 	// MigrationsCount returns the total number of applied migrations.
 	MigrationsCount(ctx context.Context) (int, error)
 	// TableNameWithSchema returns the full table name including schema if applicable.
 	TableNameWithSchema() string
 	// InsertMigrationWithApplyTime inserts the new migration record with an explicit apply time.
-	InsertMigrationWithApplyTime(ctx context.Context, version string, applyTime int64) error
+	InsertMigrationWithApplyTime(ctx context.Context, migration *entity.Migration, applyTime int64) error
 	// MigrationsByMaxApplyTime returns migrations that share the maximum apply_time value.
 	MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations, error)
+	// TryLock tries to take the migration lock for lock.Owner without waiting.
+	// It returns false when the lock is held by another owner.
+	TryLock(ctx context.Context, lock *entity.Lock) (bool, error)
+	// Unlock releases the migration lock taken by TryLock.
+	Unlock(ctx context.Context) error
+	// LockHolder returns the current holder of the migration lock, or nil when the lock is free.
+	LockHolder(ctx context.Context) (*entity.Lock, error)
+	// ForceUnlock releases the migration lock regardless of its holder.
+	ForceUnlock(ctx context.Context) error
+	// SessionLock reports whether the migration lock is bound to the session of its holder,
+	// so the database releases it when the holder disconnects and it never goes stale.
+	SessionLock() bool
+	// DumpSchema returns the current schema of the database, without the migration history and lock tables,
+	// as it is introspected by the database.
+	DumpSchema(ctx context.Context) (string, error)
+	// LoadSchema returns the current tables of the database, without the migration history and lock tables,
+	// as the schema diff compares them. Only Iceberg supports it.
+	LoadSchema(ctx context.Context) (*ddl.Schema, error)
 }
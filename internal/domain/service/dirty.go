@@ -0,0 +1,199 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/domain/builder"
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/domain/service/mapper"
+	"github.com/raoptimus/db-migrator.go/internal/helper/sqlio"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+)
+
+// States a migration repaired by hand is forced into.
+const (
+	// ForceApplied records the migration as applied without running it.
+	ForceApplied = "applied"
+	// ForceReverted removes the migration from the history table without reverting it.
+	ForceReverted = "reverted"
+)
+
+// ErrInvalidForceState is returned for a state a migration cannot be forced into.
+var ErrInvalidForceState = errors.New("the state should be applied or reverted")
+
+// ErrMigrationNotRecorded is returned when forcing a migration that is not in the history table into reverted.
+var ErrMigrationNotRecorded = errors.New("migration is not recorded in the history table")
+
+// DirtyMigrations returns the migrations recorded as pending or failed, which left the database
+// partially migrated, in version order.
+func (m *Migration) DirtyMigrations(ctx context.Context) (model.Migrations, error) {
+	if err := m.InitializeTableHistory(ctx); err != nil {
+		return nil, err
+	}
+	entities, err := m.repo.Migrations(ctx, MaxLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	dirty := make(model.Migrations, 0)
+	for _, migration := range mapper.EntitiesToDomain(entities) {
+		if migration.Dirty() {
+			dirty = append(dirty, migration)
+		}
+	}
+	dirty.SortByVersion()
+
+	return dirty, nil
+}
+
+// Force records the migration of version in the history table as applied, together with the checksum
+// and the down SQL of its file, or removes it from there as reverted, without running any statement.
+// It fixes the history after the database was repaired by hand.
+func (m *Migration) Force(ctx context.Context, version, state string) error {
+	if version == baseMigration {
+		return ErrMigrationVersionReserved
+	}
+	if state != ForceApplied && state != ForceReverted {
+		return errors.Wrapf(ErrInvalidForceState, "%q", state)
+	}
+	if err := m.InitializeTableHistory(ctx); err != nil {
+		return err
+	}
+	exists, err := m.repo.ExistsMigration(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	if state == ForceReverted {
+		if !exists {
+			return errors.Wrap(ErrMigrationNotRecorded, version)
+		}
+
+		return m.repo.RemoveMigration(ctx, version)
+	}
+
+	record, err := m.forcedRecord(version)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return m.repo.UpdateMigration(ctx, record)
+	}
+
+	return m.repo.InsertMigration(ctx, record)
+}
+
+// forcedRecord returns the applied record of the migration of version, with the checksum of its up file
+// and the body of its down file. A migration written in Go has no file, so only its version is recorded.
+func (m *Migration) forcedRecord(version string) (*entity.Migration, error) {
+	record := &entity.Migration{Version: version}
+	if _, ok := m.options.GoMigrations.Get(version); ok {
+		return record, nil
+	}
+
+	fileName, _ := builder.NewFileName(m.file, m.options.Directory).Up(version, false)
+	exists, err := m.file.Exists(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "migration file %s does not exist", fileName)
+	}
+	if !exists {
+		return nil, fmt.Errorf("migration file %s does not exist", fileName)
+	}
+	body, err := m.file.ReadAll(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "migration file %s does not read", fileName)
+	}
+	record.Checksum = sqlio.ChecksumOf(body)
+	if record.DownSQL, err = m.readDownSQL(fileName, version); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// recorder writes the history record of the migration being applied. The progress of a migration whose
+// statements are committed one by one is recorded as pending, so a run that dies midway leaves the database
+// marked as dirty, a failure after some statements were committed is recorded as failed
+// and the completed migration as applied.
+type recorder struct {
+	version    string
+	outOfOrder bool
+	insertFn   func(ctx context.Context, record *entity.Migration) error
+	updateFn   func(ctx context.Context, record *entity.Migration) error
+	// recorded is set when the history table has a record of the version
+	recorded bool
+}
+
+// newRecorder returns the recorder of the migration, a migration left pending or failed by an earlier run
+// is already recorded.
+func newRecorder(
+	migration *model.Migration,
+	insertFn func(ctx context.Context, record *entity.Migration) error,
+	updateFn func(ctx context.Context, record *entity.Migration) error,
+) *recorder {
+	return &recorder{
+		version:    migration.Version,
+		outOfOrder: migration.OutOfOrder,
+		insertFn:   insertFn,
+		updateFn:   updateFn,
+		recorded:   migration.Status != "",
+	}
+}
+
+// progress records the migration as pending with the statements committed so far.
+func (r *recorder) progress(ctx context.Context, committed int, executedSQL string) error {
+	return r.write(ctx, &entity.Migration{
+		Version:       r.version,
+		ExecutedSQL:   executedSQL,
+		Status:        entity.StatusPending,
+		LastStatement: committed,
+	})
+}
+
+// fail records the migration that failed with err as failed when some of its statements are committed
+// or an earlier run recorded it, and returns err wrapped into IncompleteError when statements are committed.
+// The failure is recorded even if the run was canceled.
+func (r *recorder) fail(ctx context.Context, committed int, executedSQL string, err error) error {
+	err = incomplete(r.version, committed, err)
+	if committed == 0 && !r.recorded {
+		return err
+	}
+	if recordErr := r.write(uncanceled(ctx), &entity.Migration{
+		Version:       r.version,
+		ExecutedSQL:   executedSQL,
+		Status:        entity.StatusFailed,
+		LastStatement: committed,
+	}); recordErr != nil {
+		return errors.Wrapf(err, "the failure is not recorded in the history table: %v", recordErr)
+	}
+
+	return err
+}
+
+// applied records the completed migration as applied, the record has no status as the status defaults to applied.
+func (r *recorder) applied(ctx context.Context, record *entity.Migration) error {
+	return r.write(ctx, record)
+}
+
+func (r *recorder) write(ctx context.Context, record *entity.Migration) error {
+	record.OutOfOrder = r.outOfOrder
+	if r.recorded {
+		return r.updateFn(ctx, record)
+	}
+	if err := r.insertFn(ctx, record); err != nil {
+		return err
+	}
+	r.recorded = true
+
+	return nil
+}
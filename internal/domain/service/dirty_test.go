@@ -0,0 +1,134 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/helper/sqlio"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigration_DirtyMigrations_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().Migrations(ctx, MaxLimit).Return(entity.Migrations{
+		{Version: "200103_120000_third", Status: entity.StatusPending, LastStatement: 1},
+		{Version: "200102_120000_second"},
+		{Version: "200101_120000_first", Status: entity.StatusFailed, LastStatement: 2},
+	}, nil)
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+	dirty, err := serv.DirtyMigrations(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, model.Migrations{
+		{Version: "200101_120000_first", Status: entity.StatusFailed, LastStatement: 2},
+		{Version: "200103_120000_third", Status: entity.StatusPending, LastStatement: 1},
+	}, dirty)
+}
+
+func TestMigration_Force_AppliedNotRecorded_Inserted(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	version := "200101_120000_init"
+	upFileName := "/migrations/200101_120000_init.up.sql"
+	downFileName := "/migrations/200101_120000_init.down.sql"
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().ExistsMigration(ctx, version).Return(false, nil)
+	file.EXPECT().Exists(upFileName).Return(true, nil)
+	file.EXPECT().ReadAll(upFileName).Return([]byte("CREATE TABLE a (id int);"), nil)
+	file.EXPECT().Exists(downFileName).Return(true, nil)
+	file.EXPECT().ReadAll(downFileName).Return([]byte("DROP TABLE a;"), nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:  version,
+		Checksum: sqlio.ChecksumOf([]byte("CREATE TABLE a (id int);")),
+		DownSQL:  "DROP TABLE a;",
+	}).Return(nil)
+
+	serv := NewMigration(&Options{Directory: "/migrations"}, NewMockLogger(t), file, repo)
+
+	require.NoError(t, serv.Force(ctx, version, ForceApplied))
+}
+
+func TestMigration_Force_AppliedRecorded_Updated(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	version := "200101_120000_init"
+	upFileName := "/migrations/200101_120000_init.up.sql"
+	downFileName := "/migrations/200101_120000_init.down.sql"
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().ExistsMigration(ctx, version).Return(true, nil)
+	file.EXPECT().Exists(upFileName).Return(true, nil)
+	file.EXPECT().ReadAll(upFileName).Return([]byte("CREATE TABLE a (id int);"), nil)
+	file.EXPECT().Exists(downFileName).Return(false, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_init.safe.down.sql").Return(false, nil)
+	repo.EXPECT().UpdateMigration(ctx, &entity.Migration{
+		Version:  version,
+		Checksum: sqlio.ChecksumOf([]byte("CREATE TABLE a (id int);")),
+	}).Return(nil)
+
+	serv := NewMigration(&Options{Directory: "/migrations"}, NewMockLogger(t), file, repo)
+
+	require.NoError(t, serv.Force(ctx, version, ForceApplied))
+}
+
+func TestMigration_Force_Reverted_Removed(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	version := "200101_120000_init"
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().ExistsMigration(ctx, version).Return(true, nil)
+	repo.EXPECT().RemoveMigration(ctx, version).Return(nil)
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+
+	require.NoError(t, serv.Force(ctx, version, ForceReverted))
+}
+
+func TestMigration_Force_RevertedNotRecorded_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	version := "200101_120000_init"
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().ExistsMigration(ctx, version).Return(false, nil)
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+
+	require.ErrorIs(t, serv.Force(ctx, version, ForceReverted), ErrMigrationNotRecorded)
+}
+
+func TestMigration_Force_InvalidState_Failure(t *testing.T) {
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), NewMockRepository(t))
+
+	require.ErrorIs(t, serv.Force(context.Background(), "200101_120000_init", "dirty"), ErrInvalidForceState)
+}
+
+func TestMigration_Force_BaseMigration_Failure(t *testing.T) {
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), NewMockRepository(t))
+
+	require.ErrorIs(t, serv.Force(context.Background(), baseMigration, ForceApplied), ErrMigrationVersionReserved)
+}
@@ -3,9 +3,15 @@ package service
 import (
 	"context"
 
+	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 )
 
+// ErrHistoryTableNeedsUpgrade occurs in dry-run mode when the migration history table was created
+// by an older version and has to be upgraded, which a dry run does not do.
+var ErrHistoryTableNeedsUpgrade = errors.New("migration history table needs an upgrade, run the command without --dryRun")
+
 type DryRunRepository struct {
 	repo                Repository
 	virtualTableCreated bool
@@ -45,7 +51,12 @@ func (d *DryRunRepository) HasMigrationHistoryTable(ctx context.Context) (exists
 }
 
 // InsertMigration inserts the new migration record.
-func (d *DryRunRepository) InsertMigration(ctx context.Context, version string) error {
+func (d *DryRunRepository) InsertMigration(ctx context.Context, migration *entity.Migration) error {
+	return nil
+}
+
+// UpdateMigration updates the migration record.
+func (d *DryRunRepository) UpdateMigration(ctx context.Context, migration *entity.Migration) error {
 	return nil
 }
 
@@ -80,6 +91,34 @@ func (d *DryRunRepository) CreateMigrationHistoryTable(ctx context.Context) erro
 	return nil
 }
 
+// UpgradeMigrationHistoryTable does not change the database: it returns ErrHistoryTableNeedsUpgrade
+// when the history table was created by an older version, since its history cannot be read
+// before the upgrade. A virtual table created by this dry run is already up to date.
+func (d *DryRunRepository) UpgradeMigrationHistoryTable(ctx context.Context) error {
+	if d.virtualTableCreated {
+		return nil
+	}
+
+	needed, err := d.repo.NeedsMigrationHistoryTableUpgrade(ctx)
+	if err != nil {
+		return err
+	}
+	if needed {
+		return errors.Wrap(ErrHistoryTableNeedsUpgrade, d.repo.TableNameWithSchema())
+	}
+
+	return nil
+}
+
+// NeedsMigrationHistoryTableUpgrade reports whether the history table lacks columns added by UpgradeMigrationHistoryTable.
+func (d *DryRunRepository) NeedsMigrationHistoryTableUpgrade(ctx context.Context) (bool, error) {
+	if d.virtualTableCreated {
+		return false, nil
+	}
+
+	return d.repo.NeedsMigrationHistoryTableUpgrade(ctx)
+}
+
 // MigrationsCount returns the total number of applied migrations.
 func (d *DryRunRepository) MigrationsCount(ctx context.Context) (int, error) {
 	if d.virtualTableCreated {
@@ -90,7 +129,11 @@ func (d *DryRunRepository) MigrationsCount(ctx context.Context) (int, error) {
 }
 
 // InsertMigrationWithApplyTime inserts the new migration record with an explicit apply time.
-func (d *DryRunRepository) InsertMigrationWithApplyTime(ctx context.Context, version string, applyTime int64) error {
+func (d *DryRunRepository) InsertMigrationWithApplyTime(
+	ctx context.Context,
+	migration *entity.Migration,
+	applyTime int64,
+) error {
 	return nil
 }
 
@@ -111,3 +154,38 @@ func (d *DryRunRepository) TableNameWithSchema() string {
 
 	return d.repo.TableNameWithSchema()
 }
+
+// TryLock pretends to take the migration lock, because a dry run does not change the database.
+func (d *DryRunRepository) TryLock(ctx context.Context, lock *entity.Lock) (bool, error) {
+	return true, nil
+}
+
+// Unlock does nothing, because a dry run never takes the migration lock.
+func (d *DryRunRepository) Unlock(ctx context.Context) error {
+	return nil
+}
+
+// LockHolder returns the current holder of the migration lock.
+func (d *DryRunRepository) LockHolder(ctx context.Context) (*entity.Lock, error) {
+	return d.repo.LockHolder(ctx)
+}
+
+// ForceUnlock does nothing, because a dry run does not change the database.
+func (d *DryRunRepository) ForceUnlock(ctx context.Context) error {
+	return nil
+}
+
+// DumpSchema returns the schema of the underlying repository, the dump only reads the database.
+func (d *DryRunRepository) DumpSchema(ctx context.Context) (string, error) {
+	return d.repo.DumpSchema(ctx)
+}
+
+// LoadSchema returns the schema of the underlying repository, loading it only reads the database.
+func (d *DryRunRepository) LoadSchema(ctx context.Context) (*ddl.Schema, error) {
+	return d.repo.LoadSchema(ctx)
+}
+
+// SessionLock reports whether the lock of the underlying repository is bound to the session of its holder.
+func (d *DryRunRepository) SessionLock() bool {
+	return d.repo.SessionLock()
+}
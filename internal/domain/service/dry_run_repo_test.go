@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -30,6 +31,9 @@ func TestDryRunRepository_Delegates_BeforeVirtualTable_Successfully(t *testing.T
 	repo.EXPECT().MigrationsCount(ctx).Return(3, nil).Once()
 	repo.EXPECT().MigrationsByMaxApplyTime(ctx).Return(entity.Migrations{{Version: "230101_120000_a"}}, nil).Once()
 	repo.EXPECT().TableNameWithSchema().Return("public.migration").Once()
+	repo.EXPECT().NeedsMigrationHistoryTableUpgrade(ctx).Return(false, nil).Once()
+	repo.EXPECT().DumpSchema(ctx).Return("CREATE TABLE t (id INT);\n", nil).Once()
+	repo.EXPECT().LoadSchema(ctx).Return(ddl.NewSchema(), nil).Once()
 
 	exists, err := sut.ExistsMigration(ctx, "230101_120000_a")
 	require.NoError(t, err)
@@ -52,6 +56,15 @@ func TestDryRunRepository_Delegates_BeforeVirtualTable_Successfully(t *testing.T
 	assert.Len(t, batch, 1)
 
 	assert.Equal(t, "public.migration", sut.TableNameWithSchema())
+	require.NoError(t, sut.UpgradeMigrationHistoryTable(ctx))
+
+	schema, err := sut.DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE t (id INT);\n", schema)
+
+	loaded, err := sut.LoadSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ddl.NewSchema(), loaded)
 }
 
 // After CreateMigrationHistoryTable, the virtual table masks the real one: reads
@@ -63,6 +76,11 @@ func TestDryRunRepository_VirtualTable_MasksReads_Successfully(t *testing.T) {
 	sut := NewDryRunRepository(repo)
 
 	require.NoError(t, sut.CreateMigrationHistoryTable(ctx))
+	require.NoError(t, sut.UpgradeMigrationHistoryTable(ctx))
+
+	needed, err := sut.NeedsMigrationHistoryTableUpgrade(ctx)
+	require.NoError(t, err)
+	assert.False(t, needed)
 
 	exists, err := sut.ExistsMigration(ctx, "230101_120000_a")
 	require.NoError(t, err)
@@ -87,6 +105,21 @@ func TestDryRunRepository_VirtualTable_MasksReads_Successfully(t *testing.T) {
 	assert.Empty(t, sut.TableNameWithSchema())
 }
 
+// An outdated history table is not altered in dry-run mode, the run fails instead.
+func TestDryRunRepository_UpgradeMigrationHistoryTable_Outdated_Failure(t *testing.T) {
+	ctx := t.Context()
+	repo := NewMockRepository(t)
+	sut := NewDryRunRepository(repo)
+
+	repo.EXPECT().NeedsMigrationHistoryTableUpgrade(ctx).Return(true, nil).Once()
+	repo.EXPECT().TableNameWithSchema().Return("public.migration").Once()
+
+	err := sut.UpgradeMigrationHistoryTable(ctx)
+
+	require.ErrorIs(t, err, ErrHistoryTableNeedsUpgrade)
+	assert.Contains(t, err.Error(), "public.migration")
+}
+
 // Write operations are no-ops in dry-run mode and never reach the wrapped repository.
 func TestDryRunRepository_WriteOperations_AreNoOps_Successfully(t *testing.T) {
 	ctx := t.Context()
@@ -94,11 +127,17 @@ func TestDryRunRepository_WriteOperations_AreNoOps_Successfully(t *testing.T) {
 	sut := NewDryRunRepository(repo)
 
 	assert.False(t, sut.SupportsDDLTransactions())
-	require.NoError(t, sut.InsertMigration(ctx, "230101_120000_a"))
+	require.NoError(t, sut.InsertMigration(ctx, &entity.Migration{Version: "230101_120000_a"}))
 	require.NoError(t, sut.RemoveMigration(ctx, "230101_120000_a"))
 	require.NoError(t, sut.ExecQuery(ctx, "CREATE TABLE t (id INT)"))
 	require.NoError(t, sut.DropMigrationHistoryTable(ctx))
-	require.NoError(t, sut.InsertMigrationWithApplyTime(ctx, "230101_120000_a", 1_700_000_000))
+	require.NoError(t, sut.InsertMigrationWithApplyTime(ctx, &entity.Migration{Version: "230101_120000_a"}, 1_700_000_000))
+	require.NoError(t, sut.Unlock(ctx))
+	require.NoError(t, sut.ForceUnlock(ctx))
+
+	acquired, err := sut.TryLock(ctx, &entity.Lock{Owner: "host:1"})
+	require.NoError(t, err)
+	assert.True(t, acquired)
 
 	// ExecQueryTransaction must not invoke the callback in dry-run mode.
 	called := false
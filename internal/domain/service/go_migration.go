@@ -0,0 +1,105 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/domain/validator"
+)
+
+var (
+	// ErrGoMigrationAlreadyRegistered occurs when a migration written in Go is registered twice with the same version.
+	ErrGoMigrationAlreadyRegistered = errors.New("go migration already registered")
+	// ErrGoMigrationUpRequired occurs when a migration written in Go is registered without an up function.
+	ErrGoMigrationUpRequired = errors.New("go migration requires an up function")
+	// ErrGoMigrationDownMissing occurs when reverting a migration written in Go that has no down function.
+	ErrGoMigrationDownMissing = errors.New("go migration has no down function")
+)
+
+// GoMigrationFunc is the body of a migration written in Go.
+type GoMigrationFunc func(ctx context.Context, conn Connection) error
+
+// GoMigration is a migration written in Go. It is ordered and tracked in the history table
+// together with the migration files. A safe migration runs inside a transaction.
+type GoMigration struct {
+	Version string
+	Up      GoMigrationFunc
+	Down    GoMigrationFunc
+	Safe    bool
+}
+
+// GoMigrations is a registry of migrations written in Go keyed by version.
+// It is safe for concurrent use. A nil registry has no migrations.
+type GoMigrations struct {
+	mu         sync.RWMutex
+	migrations map[string]*GoMigration
+}
+
+// NewGoMigrations creates an empty registry of migrations written in Go.
+func NewGoMigrations() *GoMigrations {
+	return &GoMigrations{
+		migrations: make(map[string]*GoMigration),
+	}
+}
+
+// Add registers the migration. The version must follow the YYMMDD_hhmmss_name format of migration files.
+func (g *GoMigrations) Add(migration *GoMigration) error {
+	if err := validator.ValidateVersion(migration.Version); err != nil {
+		return errors.Wrap(err, migration.Version)
+	}
+	if migration.Up == nil {
+		return errors.Wrap(ErrGoMigrationUpRequired, migration.Version)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.migrations[migration.Version]; ok {
+		return errors.Wrap(ErrGoMigrationAlreadyRegistered, migration.Version)
+	}
+	g.migrations[migration.Version] = migration
+
+	return nil
+}
+
+// Get returns the migration registered with the version.
+func (g *GoMigrations) Get(version string) (*GoMigration, bool) {
+	if g == nil {
+		return nil, false
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	migration, ok := g.migrations[version]
+
+	return migration, ok
+}
+
+// Versions returns the registered versions in ascending order.
+func (g *GoMigrations) Versions() []string {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	versions := make([]string, 0, len(g.migrations))
+	for version := range g.migrations {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	return versions
+}
@@ -0,0 +1,78 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func noopGoMigrationFunc(context.Context, Connection) error {
+	return nil
+}
+
+func TestGoMigrations_Add_Successfully(t *testing.T) {
+	registry := NewGoMigrations()
+
+	require.NoError(t, registry.Add(&GoMigration{Version: "200102_120000_backfill", Up: noopGoMigrationFunc}))
+	require.NoError(t, registry.Add(&GoMigration{Version: "200101_120000_seed", Up: noopGoMigrationFunc, Safe: true}))
+
+	migration, ok := registry.Get("200101_120000_seed")
+	require.True(t, ok)
+	require.True(t, migration.Safe)
+	require.Equal(t, []string{"200101_120000_seed", "200102_120000_backfill"}, registry.Versions())
+}
+
+func TestGoMigrations_Add_Failure(t *testing.T) {
+	tests := []struct {
+		name      string
+		migration *GoMigration
+		wantErr   error
+	}{
+		{
+			name:      "invalid version",
+			migration: &GoMigration{Version: "backfill", Up: noopGoMigrationFunc},
+			wantErr:   validator.ErrVersionIsNotValid,
+		},
+		{
+			name:      "up is nil",
+			migration: &GoMigration{Version: "200101_120000_seed"},
+			wantErr:   ErrGoMigrationUpRequired,
+		},
+		{
+			name:      "already registered",
+			migration: &GoMigration{Version: "200101_120000_backfill", Up: noopGoMigrationFunc},
+			wantErr:   ErrGoMigrationAlreadyRegistered,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewGoMigrations()
+			require.NoError(t, registry.Add(&GoMigration{Version: "200101_120000_backfill", Up: noopGoMigrationFunc}))
+
+			err := registry.Add(tt.migration)
+
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGoMigrations_Nil_HasNoMigrations(t *testing.T) {
+	var registry *GoMigrations
+
+	migration, ok := registry.Get("200101_120000_seed")
+
+	require.False(t, ok)
+	require.Nil(t, migration)
+	require.Empty(t, registry.Versions())
+}
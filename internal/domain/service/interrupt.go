@@ -0,0 +1,82 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInterrupted is returned for a migration that was not started because the run was interrupted.
+var ErrInterrupted = errors.New("interrupted")
+
+type interruptKey struct{}
+
+// WithInterrupt returns a context that asks the migrations run with it to stop once interrupt is closed:
+// the migration in progress runs to its end, so it is not left half-applied, and no next migration is started.
+// Canceling the context itself stops the migration in progress at once.
+func WithInterrupt(ctx context.Context, interrupt <-chan struct{}) context.Context {
+	return context.WithValue(ctx, interruptKey{}, interrupt)
+}
+
+// checkInterrupted returns ErrInterrupted when the run was interrupted before the migration of version started.
+func checkInterrupted(ctx context.Context, version string) error {
+	interrupt, ok := ctx.Value(interruptKey{}).(<-chan struct{})
+	if !ok {
+		return nil
+	}
+	select {
+	case <-interrupt:
+		return errors.Wrapf(ErrInterrupted, "%s and the next migrations are left pending", version)
+	default:
+		return nil
+	}
+}
+
+// IncompleteError is returned for a migration that failed after some of its statements were committed,
+// the database is partially migrated. An applied migration is recorded as failed in the history table,
+// a reverted one is left there as applied.
+type IncompleteError struct {
+	Version string
+	// Committed is the number of the statements committed before the failure.
+	Committed int
+	Err       error
+}
+
+func (e *IncompleteError) Error() string {
+	return fmt.Sprintf(
+		"%s is left incomplete, its first %d statement(s) are committed: %v",
+		e.Version, e.Committed, e.Err,
+	)
+}
+
+func (e *IncompleteError) Unwrap() error {
+	return e.Err
+}
+
+// incomplete wraps err of the migration of version into IncompleteError when some of its statements are committed.
+func incomplete(version string, committed int, err error) error {
+	if committed == 0 {
+		return err
+	}
+
+	return &IncompleteError{Version: version, Committed: committed, Err: err}
+}
+
+// uncanceled returns ctx detached from its cancellation, so the history table is updated for the statements
+// already committed even when the run is canceled meanwhile.
+func uncanceled(ctx context.Context) context.Context {
+	if ctx.Done() == nil {
+		return ctx
+	}
+
+	return context.WithoutCancel(ctx)
+}
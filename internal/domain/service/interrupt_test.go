@@ -0,0 +1,186 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func interruptedContext() context.Context {
+	interrupt := make(chan struct{})
+	close(interrupt)
+
+	return WithInterrupt(context.Background(), interrupt)
+}
+
+func TestMigration_ApplyFile_Interrupted_NotStarted(t *testing.T) {
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), NewMockRepository(t))
+
+	err := serv.ApplyFile(interruptedContext(), &model.Migration{Version: "200101_120000_init"}, "init.up.sql", false)
+
+	require.ErrorIs(t, err, ErrInterrupted)
+	assert.Contains(t, err.Error(), "200101_120000_init and the next migrations are left pending")
+}
+
+func TestMigration_RevertFile_Interrupted_NotStarted(t *testing.T) {
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), NewMockRepository(t))
+
+	err := serv.RevertFile(interruptedContext(), &model.Migration{Version: "200101_120000_init"}, "init.down.sql", false)
+
+	require.ErrorIs(t, err, ErrInterrupted)
+}
+
+func TestMigration_ApplySQL_NotInterrupted_Successfully(t *testing.T) {
+	ctx := WithInterrupt(context.Background(), make(chan struct{}))
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_init"
+
+	repo.EXPECT().ExecQuery(ctx, "SELECT 1").Return(nil)
+	repo.EXPECT().InsertMigration(ctx, mock.Anything).Return(nil)
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{}, logger, NewMockFile(t), repo)
+
+	require.NoError(t, serv.ApplySQL(ctx, false, version, "SELECT 1;"))
+}
+
+func TestMigration_ApplySQL_CanceledAfterStatements_Recorded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_init"
+
+	repo.EXPECT().
+		ExecQuery(ctx, "SELECT 1").
+		RunAndReturn(func(context.Context, string, ...any) error {
+			cancel()
+			return nil
+		})
+	repo.EXPECT().
+		InsertMigration(mock.Anything, mock.MatchedBy(func(record *entity.Migration) bool {
+			return record.Version == version
+		})).
+		RunAndReturn(func(ctx context.Context, _ *entity.Migration) error {
+			return ctx.Err()
+		})
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{}, logger, NewMockFile(t), repo)
+
+	require.NoError(t, serv.ApplySQL(ctx, false, version, "SELECT 1;"))
+}
+
+func TestMigration_ApplySQL_Canceled_Incomplete(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_init"
+
+	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE a (id int)").Return(nil)
+	repo.EXPECT().
+		InsertMigration(ctx, &entity.Migration{
+			Version:       version,
+			ExecutedSQL:   "CREATE TABLE a (id int);\n",
+			Status:        entity.StatusPending,
+			LastStatement: 1,
+		}).
+		Return(nil)
+	repo.EXPECT().
+		ExecQuery(ctx, "CREATE TABLE b (id int)").
+		RunAndReturn(func(context.Context, string, ...any) error {
+			cancel()
+			return context.Canceled
+		})
+	// the failure is recorded even though the run is canceled
+	repo.EXPECT().
+		UpdateMigration(mock.Anything, &entity.Migration{
+			Version:       version,
+			ExecutedSQL:   "CREATE TABLE a (id int);\n",
+			Status:        entity.StatusFailed,
+			LastStatement: 1,
+		}).
+		RunAndReturn(func(ctx context.Context, _ *entity.Migration) error {
+			return ctx.Err()
+		})
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything).Times(2)
+	logger.EXPECT().Errorf("*** failed to apply %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{}, logger, NewMockFile(t), repo)
+	err := serv.ApplySQL(ctx, false, version,
+		"CREATE TABLE a (id int); CREATE TABLE b (id int); CREATE TABLE c (id int);")
+
+	require.ErrorIs(t, err, context.Canceled)
+	var incompleteErr *IncompleteError
+	require.True(t, errors.As(err, &incompleteErr))
+	assert.Equal(t, version, incompleteErr.Version)
+	assert.Equal(t, 1, incompleteErr.Committed)
+}
+
+func TestMigration_ApplySQL_Safely_RolledBack_NotIncomplete(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_init"
+
+	repo.EXPECT().
+		ExecQueryTransaction(ctx, mock.AnythingOfType("func(context.Context) error")).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		})
+	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE a (id int)").Return(nil)
+	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE b (id int)").Return(context.Canceled)
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything).Times(2)
+	logger.EXPECT().Errorf("*** failed to apply %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{}, logger, NewMockFile(t), repo)
+	err := serv.ApplySQL(ctx, true, version, "CREATE TABLE a (id int); CREATE TABLE b (id int);")
+
+	require.ErrorIs(t, err, context.Canceled)
+	var incompleteErr *IncompleteError
+	assert.False(t, errors.As(err, &incompleteErr))
+}
+
+func TestMigration_ApplySQL_WithinTransaction_NotIncomplete(t *testing.T) {
+	ctx := connection.ContextWithTx(context.Background(), sqlex.NewTx(nil))
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_init"
+
+	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE a (id int)").Return(nil)
+	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE b (id int)").Return(context.Canceled)
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything).Times(2)
+	logger.EXPECT().Errorf("*** failed to apply %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{}, logger, NewMockFile(t), repo)
+	err := serv.ApplySQL(ctx, false, version, "CREATE TABLE a (id int); CREATE TABLE b (id int);")
+
+	require.ErrorIs(t, err, context.Canceled)
+	var incompleteErr *IncompleteError
+	assert.False(t, errors.As(err, &incompleteErr))
+}
@@ -0,0 +1,111 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+)
+
+// lockRetryInterval is the pause between attempts to take the migration lock held by another process.
+const lockRetryInterval = time.Second
+
+// ErrLockTimeout occurs when the migration lock is not released by another process within the lock timeout.
+var ErrLockTimeout = errors.New("timed out waiting for the migration lock")
+
+// Lock takes the migration lock, so that only one process changes the database at a time.
+// While another process holds the lock, it retries until LockTimeout expires and then returns ErrLockTimeout.
+// A lock older than LockStaleAfter is considered abandoned by a crashed process and is broken once,
+// unless the database releases the lock itself when the holder disconnects.
+func (m *Migration) Lock(ctx context.Context) error {
+	var (
+		deadline = time.Now().Add(m.options.LockTimeout)
+		waiting  bool
+		broken   bool
+	)
+
+	for {
+		acquired, err := m.repo.TryLock(ctx, &entity.Lock{
+			Owner:      m.options.LockOwner,
+			AcquiredAt: time.Now().Unix(),
+		})
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		holder, err := m.LockStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		if holder != nil && !broken && m.isStaleLock(holder) && !m.repo.SessionLock() {
+			m.logger.Warnf("Breaking the stale migration lock held by %s since %s\n",
+				holder.Owner,
+				holder.AcquiredAtFormat(),
+			)
+			if err := m.repo.ForceUnlock(ctx); err != nil {
+				return err
+			}
+			broken = true
+
+			continue
+		}
+
+		if !time.Now().Before(deadline) {
+			if holder == nil {
+				return ErrLockTimeout
+			}
+
+			return errors.Wrapf(ErrLockTimeout, "migration lock is held by %s", holder.Owner)
+		}
+
+		if !waiting && holder != nil {
+			m.logger.Warnf("Waiting for the migration lock held by %s...\n", holder.Owner)
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Unlock releases the migration lock taken by Lock.
+func (m *Migration) Unlock(ctx context.Context) error {
+	return m.repo.Unlock(ctx)
+}
+
+// LockStatus returns the current holder of the migration lock, or nil when the lock is free.
+func (m *Migration) LockStatus(ctx context.Context) (*model.Lock, error) {
+	holder, err := m.repo.LockHolder(ctx)
+	if err != nil || holder == nil {
+		return nil, err
+	}
+
+	return &model.Lock{Owner: holder.Owner, AcquiredAt: holder.AcquiredAt}, nil
+}
+
+// ReleaseLock releases the migration lock regardless of its holder.
+func (m *Migration) ReleaseLock(ctx context.Context) error {
+	return m.repo.ForceUnlock(ctx)
+}
+
+// isStaleLock reports whether the lock is older than LockStaleAfter.
+func (m *Migration) isStaleLock(lock *model.Lock) bool {
+	return m.options.LockStaleAfter > 0 && lock.Age(time.Now()) > m.options.LockStaleAfter
+}
@@ -0,0 +1,166 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigration_Lock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+
+	repo.EXPECT().
+		TryLock(ctx, mock.MatchedBy(func(lock *entity.Lock) bool {
+			return lock.Owner == "db-migrator@host:1" && lock.AcquiredAt > 0
+		})).
+		Return(true, nil).
+		Once()
+
+	serv := NewMigration(&Options{LockOwner: "db-migrator@host:1"}, logger, NewMockFile(t), repo)
+	require.NoError(t, serv.Lock(ctx))
+}
+
+func TestMigration_Lock_HeldByAnotherProcess_TimesOut(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+
+	repo.EXPECT().TryLock(ctx, mock.Anything).Return(false, nil).Once()
+	repo.EXPECT().
+		LockHolder(ctx).
+		Return(&entity.Lock{Owner: "db-migrator@other:2", AcquiredAt: time.Now().Unix()}, nil).
+		Once()
+
+	serv := NewMigration(&Options{
+		LockOwner:      "db-migrator@host:1",
+		LockStaleAfter: time.Hour,
+	}, logger, NewMockFile(t), repo)
+	err := serv.Lock(ctx)
+	require.ErrorIs(t, err, ErrLockTimeout)
+	assert.Contains(t, err.Error(), "db-migrator@other:2")
+}
+
+func TestMigration_Lock_BreaksStaleLock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	acquiredAt := time.Now().Add(-2 * time.Hour).Unix()
+
+	repo.EXPECT().TryLock(ctx, mock.Anything).Return(false, nil).Once()
+	repo.EXPECT().
+		LockHolder(ctx).
+		Return(&entity.Lock{Owner: "db-migrator@crashed:2", AcquiredAt: acquiredAt}, nil).
+		Once()
+	repo.EXPECT().SessionLock().Return(false).Once()
+	logger.EXPECT().
+		Warnf("Breaking the stale migration lock held by %s since %s\n",
+			"db-migrator@crashed:2",
+			model.Lock{AcquiredAt: acquiredAt}.AcquiredAtFormat(),
+		).
+		Once()
+	repo.EXPECT().ForceUnlock(ctx).Return(nil).Once()
+	repo.EXPECT().TryLock(ctx, mock.Anything).Return(true, nil).Once()
+
+	serv := NewMigration(&Options{
+		LockOwner:      "db-migrator@host:1",
+		LockStaleAfter: time.Hour,
+	}, logger, NewMockFile(t), repo)
+	require.NoError(t, serv.Lock(ctx))
+}
+
+func TestMigration_Lock_SessionLock_DoesNotBreakOldLock(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+
+	repo.EXPECT().TryLock(ctx, mock.Anything).Return(false, nil).Once()
+	repo.EXPECT().
+		LockHolder(ctx).
+		Return(&entity.Lock{Owner: "db-migrator@other:2", AcquiredAt: time.Now().Add(-2 * time.Hour).Unix()}, nil).
+		Once()
+	repo.EXPECT().SessionLock().Return(true).Once()
+
+	serv := NewMigration(&Options{
+		LockOwner:      "db-migrator@host:1",
+		LockStaleAfter: time.Hour,
+	}, logger, NewMockFile(t), repo)
+	err := serv.Lock(ctx)
+	require.ErrorIs(t, err, ErrLockTimeout)
+	repo.AssertNotCalled(t, "ForceUnlock", ctx)
+}
+
+func TestMigration_Lock_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	expectedErr := errors.New("connection refused")
+
+	repo.EXPECT().TryLock(ctx, mock.Anything).Return(false, expectedErr).Once()
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+	require.ErrorIs(t, serv.Lock(ctx), expectedErr)
+}
+
+func TestMigration_Lock_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+
+	repo.EXPECT().TryLock(ctx, mock.Anything).Return(false, nil).Once()
+	repo.EXPECT().LockHolder(ctx).Return(&entity.Lock{Owner: "db-migrator@other:2"}, nil).Once()
+	logger.EXPECT().Warnf("Waiting for the migration lock held by %s...\n", "db-migrator@other:2").Once()
+
+	serv := NewMigration(&Options{LockTimeout: time.Minute}, logger, NewMockFile(t), repo)
+	require.ErrorIs(t, serv.Lock(ctx), context.Canceled)
+}
+
+func TestMigration_LockStatus_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+
+	repo.EXPECT().LockHolder(ctx).Return(&entity.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}, nil).Once()
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+	lock, err := serv.LockStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &model.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}, lock)
+}
+
+func TestMigration_LockStatus_Free(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+
+	repo.EXPECT().LockHolder(ctx).Return(nil, nil).Once()
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+	lock, err := serv.LockStatus(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, lock)
+}
+
+func TestMigration_ReleaseLock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+
+	repo.EXPECT().ForceUnlock(ctx).Return(nil).Once()
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+	require.NoError(t, serv.ReleaseLock(ctx))
+}
@@ -16,16 +16,28 @@ import (
 // EntityToDomain converts a DAL entity.Migration to a domain model.Migration.
 func EntityToDomain(e entity.Migration) model.Migration {
 	return model.Migration{
-		Version:   e.Version,
-		ApplyTime: e.ApplyTime,
+		Version:       e.Version,
+		ApplyTime:     e.ApplyTime,
+		ExecutedSQL:   e.ExecutedSQL,
+		DownSQL:       e.DownSQL,
+		Checksum:      e.Checksum,
+		OutOfOrder:    e.OutOfOrder,
+		Status:        e.Status,
+		LastStatement: e.LastStatement,
 	}
 }
 
 // DomainToEntity converts a domain model.Migration to a DAL entity.Migration.
 func DomainToEntity(m model.Migration) entity.Migration {
 	return entity.Migration{
-		Version:   m.Version,
-		ApplyTime: m.ApplyTime,
+		Version:       m.Version,
+		ApplyTime:     m.ApplyTime,
+		ExecutedSQL:   m.ExecutedSQL,
+		DownSQL:       m.DownSQL,
+		Checksum:      m.Checksum,
+		OutOfOrder:    m.OutOfOrder,
+		Status:        m.Status,
+		LastStatement: m.LastStatement,
 	}
 }
 
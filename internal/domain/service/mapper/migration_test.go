@@ -25,12 +25,20 @@ func TestEntityToDomain(t *testing.T) {
 		{
 			name: "converts entity with all fields",
 			entity: entity.Migration{
-				Version:   "210328_221600_test",
-				ApplyTime: 1616961360,
+				Version:     "210328_221600_test",
+				ApplyTime:   1616961360,
+				ExecutedSQL: "CREATE TABLE test",
+				DownSQL:     "DROP TABLE test",
+				Checksum:    "58e7702b",
+				OutOfOrder:  true,
 			},
 			want: model.Migration{
-				Version:   "210328_221600_test",
-				ApplyTime: 1616961360,
+				Version:     "210328_221600_test",
+				ApplyTime:   1616961360,
+				ExecutedSQL: "CREATE TABLE test",
+				DownSQL:     "DROP TABLE test",
+				Checksum:    "58e7702b",
+				OutOfOrder:  true,
 			},
 		},
 		{
@@ -56,6 +64,10 @@ func TestEntityToDomain(t *testing.T) {
 			got := EntityToDomain(tt.entity)
 			assert.Equal(t, tt.want.Version, got.Version)
 			assert.Equal(t, tt.want.ApplyTime, got.ApplyTime)
+			assert.Equal(t, tt.want.ExecutedSQL, got.ExecutedSQL)
+			assert.Equal(t, tt.want.DownSQL, got.DownSQL)
+			assert.Equal(t, tt.want.Checksum, got.Checksum)
+			assert.Equal(t, tt.want.OutOfOrder, got.OutOfOrder)
 		})
 	}
 }
@@ -69,16 +81,24 @@ func TestDomainToEntity(t *testing.T) {
 		{
 			name: "converts migration with all fields",
 			migration: model.Migration{
-				Version:   "210328_221600_test",
-				ApplyTime: 1616961360,
+				Version:     "210328_221600_test",
+				ApplyTime:   1616961360,
+				ExecutedSQL: "CREATE TABLE test",
+				DownSQL:     "DROP TABLE test",
+				Checksum:    "58e7702b",
+				OutOfOrder:  true,
 			},
 			want: entity.Migration{
-				Version:   "210328_221600_test",
-				ApplyTime: 1616961360,
+				Version:     "210328_221600_test",
+				ApplyTime:   1616961360,
+				ExecutedSQL: "CREATE TABLE test",
+				DownSQL:     "DROP TABLE test",
+				Checksum:    "58e7702b",
+				OutOfOrder:  true,
 			},
 		},
 		{
-			name: "converts migration with extra fields (they are ignored)",
+			name: "converts migration with extra fields (body and release are ignored)",
 			migration: model.Migration{
 				Version:     "210328_221600_test",
 				ApplyTime:   1616961360,
@@ -87,8 +107,9 @@ func TestDomainToEntity(t *testing.T) {
 				Release:     "v1.0.0",
 			},
 			want: entity.Migration{
-				Version:   "210328_221600_test",
-				ApplyTime: 1616961360,
+				Version:     "210328_221600_test",
+				ApplyTime:   1616961360,
+				ExecutedSQL: "CREATE TABLE test",
 			},
 		},
 		{
@@ -103,6 +124,10 @@ func TestDomainToEntity(t *testing.T) {
 			got := DomainToEntity(tt.migration)
 			assert.Equal(t, tt.want.Version, got.Version)
 			assert.Equal(t, tt.want.ApplyTime, got.ApplyTime)
+			assert.Equal(t, tt.want.ExecutedSQL, got.ExecutedSQL)
+			assert.Equal(t, tt.want.DownSQL, got.DownSQL)
+			assert.Equal(t, tt.want.Checksum, got.Checksum)
+			assert.Equal(t, tt.want.OutOfOrder, got.OutOfOrder)
 		})
 	}
 }
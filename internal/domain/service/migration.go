@@ -11,26 +11,41 @@ package service
 import (
 	"context"
 	"fmt"
+	"io"
+	"maps"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/domain/builder"
 	"github.com/raoptimus/db-migrator.go/internal/domain/model"
 	"github.com/raoptimus/db-migrator.go/internal/domain/service/mapper"
 	"github.com/raoptimus/db-migrator.go/internal/domain/validator"
+	"github.com/raoptimus/db-migrator.go/internal/helper/placeholder"
 	"github.com/raoptimus/db-migrator.go/internal/helper/sqlio"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 )
 
 const (
 	baseMigration            = "000000_000000_base"
 	defaultLimit             = 10000
-	maxLimit                 = 100000
 	regexpFileNameGroupCount = 5
 	credentialMask           = "****" // Mask for hiding credentials in output
 )
 
+// MaxLimit is the limit of history records read when every applied migration is needed,
+// as opposed to the default limit of Migrations.
+const MaxLimit = 100000
+
+// ErrResumeChanged occurs when resuming a migration whose statements committed by the earlier run
+// differ from the ones in its file.
+var ErrResumeChanged = errors.New("the committed statements have changed since the earlier run, " +
+	"repair the database by hand and record the result with force")
+
 // ErrMigrationVersionReserved occurs when attempting to apply or revert the reserved base migration version.
 var ErrMigrationVersionReserved = errors.New("migration version reserved")
 
@@ -41,10 +56,11 @@ var (
 // Migration is the service handling migration operations.
 // It orchestrates migration file processing, SQL execution, and history tracking.
 type Migration struct {
-	options *Options
-	logger  Logger
-	file    File
-	repo    Repository
+	options  *Options
+	logger   Logger
+	file     File
+	repo     Repository
+	template *placeholder.Template
 }
 
 // NewMigration creates a new Migration service instance.
@@ -56,13 +72,52 @@ func NewMigration(
 	repo Repository,
 ) *Migration {
 	return &Migration{
-		options: options,
-		logger:  logger,
-		file:    file,
-		repo:    repo,
+		options:  options,
+		logger:   logger,
+		file:     file,
+		repo:     repo,
+		template: placeholder.New(placeholderVars(options), options.StrictPlaceholders),
+	}
+}
+
+// Built-in placeholders, set from the options rather than from Options.Vars.
+const (
+	placeholderCluster  = "cluster"
+	placeholderCustom   = "placeholder_custom"
+	placeholderUsername = "username"
+	placeholderPassword = "password"
+)
+
+// IsBuiltinPlaceholder reports whether name is a built-in placeholder, which cannot be set as a variable.
+func IsBuiltinPlaceholder(name string) bool {
+	switch name {
+	case placeholderCluster, placeholderCustom, placeholderUsername, placeholderPassword:
+		return true
+	default:
+		return false
 	}
 }
 
+// placeholderVars returns the variables substituted into migration SQL: Options.Vars and the built-in
+// placeholders. In strict mode built-in placeholders without a value are left unresolved.
+func placeholderVars(options *Options) map[string]string {
+	builtin := map[string]string{
+		placeholderCluster:  options.ClusterName,
+		placeholderCustom:   options.PlaceholderCustom,
+		placeholderUsername: options.Username,
+		placeholderPassword: options.Password,
+	}
+	vars := make(map[string]string, len(options.Vars)+len(builtin))
+	maps.Copy(vars, options.Vars)
+	for name, value := range builtin {
+		if value != "" || !options.StrictPlaceholders {
+			vars[name] = value
+		}
+	}
+
+	return vars
+}
+
 // InitializeTableHistory creates the migration history table if it does not exist.
 // It inserts a base migration record after creating the table.
 func (m *Migration) InitializeTableHistory(ctx context.Context) error {
@@ -72,7 +127,7 @@ func (m *Migration) InitializeTableHistory(ctx context.Context) error {
 	}
 
 	if exists {
-		return nil
+		return m.repo.UpgradeMigrationHistoryTable(ctx)
 	}
 
 	m.logger.Warnf("Creating migration history table %s...\n", m.repo.TableNameWithSchema())
@@ -81,7 +136,7 @@ func (m *Migration) InitializeTableHistory(ctx context.Context) error {
 		return err
 	}
 
-	if err := m.repo.InsertMigration(ctx, baseMigration); err != nil {
+	if err := m.repo.InsertMigration(ctx, &entity.Migration{Version: baseMigration}); err != nil {
 		if err2 := m.repo.DropMigrationHistoryTable(ctx); err2 != nil {
 			return errors.Wrap(err, err2.Error())
 		}
@@ -93,7 +148,8 @@ func (m *Migration) InitializeTableHistory(ctx context.Context) error {
 }
 
 // Migrations retrieves the list of applied migrations from the database.
-// It excludes the base migration from the returned list and uses a default limit if none is provided.
+// It excludes the base migration and the migrations recorded as pending or failed from the returned list
+// and uses a default limit if none is provided.
 func (m *Migration) Migrations(ctx context.Context, limit int) (model.Migrations, error) {
 	if err := m.InitializeTableHistory(ctx); err != nil {
 		return nil, err
@@ -106,36 +162,60 @@ func (m *Migration) Migrations(ctx context.Context, limit int) (model.Migrations
 		return nil, err
 	}
 
-	migrations := mapper.EntitiesToDomain(entities)
+	return appliedOnly(mapper.EntitiesToDomain(entities)), nil
+}
 
-	for i := range migrations {
-		if migrations[i].Version == baseMigration {
-			return append(migrations[:i], migrations[i+1:]...), nil
+// appliedOnly returns the migrations without the base migration and the migrations recorded as pending or failed.
+func appliedOnly(migrations model.Migrations) model.Migrations {
+	result := make(model.Migrations, 0, len(migrations))
+	for _, migration := range migrations {
+		if migration.Version != baseMigration && !migration.Dirty() {
+			result = append(result, migration)
 		}
 	}
 
-	return migrations, nil
+	return result
 }
 
 // NewMigrations retrieves the list of pending migrations that have not been applied yet.
-// It compares migration files in the directory against the database history to identify new migrations.
+// It compares migration files in the directory and the registered migrations written in Go
+// against the database history to identify new migrations.
+// Pending migrations older than the latest applied migration are marked as out of order.
+// A migration recorded as pending or failed is new, it keeps the status and the last statement of its record.
 func (m *Migration) NewMigrations(ctx context.Context) (model.Migrations, error) {
 	if err := m.InitializeTableHistory(ctx); err != nil {
 		return nil, err
 	}
-	entities, err := m.repo.Migrations(ctx, maxLimit)
+	entities, err := m.repo.Migrations(ctx, MaxLimit)
 	if err != nil {
 		return nil, err
 	}
 
-	migrations := mapper.EntitiesToDomain(entities)
+	applied := make(map[string]struct{}, len(entities))
+	dirty := make(map[string]model.Migration)
+	latestApplied := ""
+	for _, migration := range mapper.EntitiesToDomain(entities) {
+		switch {
+		case migration.Version == baseMigration:
+		case migration.Dirty():
+			dirty[migration.Version] = migration
+		default:
+			applied[migration.Version] = struct{}{}
+			latestApplied = max(latestApplied, migration.Version)
+		}
+	}
 
-	files, err := filepath.Glob(filepath.Join(m.options.Directory, "*.up.sql"))
-	if err != nil {
-		return nil, err
+	var files []string
+	// a library user may have only migrations written in Go and no directory
+	if m.options.Directory != "" {
+		files, err = m.file.Glob(filepath.Join(m.options.Directory, "*.up.sql"))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	newMigrations := make(model.Migrations, 0)
+	fileVersions := make(map[string]struct{}, len(files))
 	var baseFilename string
 
 	for _, file := range files {
@@ -147,17 +227,8 @@ func (m *Migration) NewMigrations(ctx context.Context) (model.Migrations, error)
 		if len(groups) != regexpFileNameGroupCount {
 			return nil, fmt.Errorf("file name %s is invalid", baseFilename)
 		}
-		found := false
-		for _, migration := range migrations {
-			if migration.Version == baseMigration {
-				continue
-			}
-			if migration.Version == groups[1] {
-				found = true
-				break
-			}
-		}
-		if !found {
+		fileVersions[groups[1]] = struct{}{}
+		if _, ok := applied[groups[1]]; !ok {
 			newMigrations = append(
 				newMigrations,
 				model.Migration{
@@ -167,13 +238,31 @@ func (m *Migration) NewMigrations(ctx context.Context) (model.Migrations, error)
 		}
 	}
 
+	for _, version := range m.options.GoMigrations.Versions() {
+		if _, ok := fileVersions[version]; ok {
+			return nil, fmt.Errorf("migration %s is written in Go and also has a migration file", version)
+		}
+		if _, ok := applied[version]; !ok {
+			newMigrations = append(newMigrations, model.Migration{Version: version})
+		}
+	}
+
 	newMigrations.SortByVersion()
+	for i := range newMigrations {
+		newMigrations[i].OutOfOrder = newMigrations[i].Version < latestApplied
+		if migration, ok := dirty[newMigrations[i].Version]; ok {
+			newMigrations[i].Status = migration.Status
+			newMigrations[i].LastStatement = migration.LastStatement
+			newMigrations[i].ExecutedSQL = migration.ExecutedSQL
+		}
+	}
 
 	return newMigrations, err
 }
 
 // ApplySQL applies a migration by executing the provided SQL statements.
-// It tracks execution time, logs progress, and records the migration in the history table.
+// It tracks execution time, logs progress, and records the migration together with
+// the executed SQL and the checksum of upSQL in the history table.
 // The safely parameter determines whether to execute statements within a transaction.
 func (m *Migration) ApplySQL(
 	ctx context.Context,
@@ -184,20 +273,28 @@ func (m *Migration) ApplySQL(
 	if version == baseMigration {
 		return ErrMigrationVersionReserved
 	}
+	if err := checkInterrupted(ctx, version); err != nil {
+		return err
+	}
 	m.logger.Warnf("*** applying %s\n", version)
-	scanner := sqlio.NewScanner(strings.NewReader(upSQL))
+	scanner := m.newScanner(strings.NewReader(upSQL))
+	rec := newRecorder(&model.Migration{Version: version}, m.repo.InsertMigration, m.repo.UpdateMigration)
 
 	start := time.Now()
-	err := m.apply(ctx, scanner, safely)
+	executedSQL, committed, err := m.apply(ctx, scanner, safely, nil, rec.progress)
 	elapsedTime := time.Since(start)
 	if err != nil {
 		m.logger.Errorf("*** failed to apply %s (time: %.3fs)\n", version, elapsedTime.Seconds())
+		return rec.fail(ctx, committed, executedSQL, err)
+	}
+	// the migration is recorded even if the run was canceled after its statements were committed
+	if err := rec.applied(uncanceled(ctx), &entity.Migration{
+		Version:     version,
+		ExecutedSQL: executedSQL,
+		Checksum:    sqlio.ChecksumOf([]byte(upSQL)),
+	}); err != nil {
 		return err
 	}
-	if err := m.repo.InsertMigration(ctx, version); err != nil {
-		return err
-	}
-	// todo: save downSQL
 	m.logger.Successf("*** applied %s (time: %.3fs)\n", version, elapsedTime.Seconds())
 
 	return nil
@@ -215,17 +312,20 @@ func (m *Migration) RevertSQL(
 	if version == baseMigration {
 		return ErrMigrationVersionReserved
 	}
+	if err := checkInterrupted(ctx, version); err != nil {
+		return err
+	}
 	m.logger.Warnf("*** reverting %s\n", version)
-	scanner := sqlio.NewScanner(strings.NewReader(downSQL))
+	scanner := m.newScanner(strings.NewReader(downSQL))
 	start := time.Now()
-	err := m.apply(ctx, scanner, safely)
+	_, committed, err := m.apply(ctx, scanner, safely, nil, nil)
 	elapsedTime := time.Since(start)
 	if err != nil {
 		m.logger.Errorf("*** failed to revert %s (time: %.3fs)\n", version, elapsedTime.Seconds())
 
-		return err
+		return incomplete(version, committed, err)
 	}
-	if err := m.repo.RemoveMigration(ctx, version); err != nil {
+	if err := m.repo.RemoveMigration(uncanceled(ctx), version); err != nil {
 		return err
 	}
 	m.logger.Warnf("*** reverted %s (time: %.3fs)\n", version, elapsedTime.Seconds())
@@ -234,12 +334,13 @@ func (m *Migration) RevertSQL(
 }
 
 // ApplyFile applies a migration by reading and executing SQL from a file.
-// It tracks execution time, logs progress, and records the migration in the history table.
+// It tracks execution time, logs progress, and records the migration in the history table
+// together with the executed SQL, the body of the matching down file and the file checksum.
 // The safely parameter determines whether to execute statements within a transaction.
+// A registered migration written in Go is run instead of the file, within a transaction only when it is safe.
 func (m *Migration) ApplyFile(ctx context.Context, migration *model.Migration, fileName string, safely bool) error {
-	return m.applyFileCore(ctx, migration, fileName, safely, func(ctx context.Context, version string) error {
-		return m.repo.InsertMigration(ctx, version)
-	})
+	return m.applyFileCore(ctx, migration, fileName, safely,
+		newRecorder(migration, m.repo.InsertMigration, m.repo.UpdateMigration))
 }
 
 // ApplyFileWithApplyTime applies a migration by reading and executing SQL from a file
@@ -252,25 +353,47 @@ func (m *Migration) ApplyFileWithApplyTime(
 	fileName string,
 	applyTime int64,
 ) error {
-	return m.applyFileCore(ctx, migration, fileName, false, func(ctx context.Context, version string) error {
-		return m.repo.InsertMigrationWithApplyTime(ctx, version, applyTime)
-	})
+	insertFn := func(ctx context.Context, record *entity.Migration) error {
+		return m.repo.InsertMigrationWithApplyTime(ctx, record, applyTime)
+	}
+	// the record is written again rather than updated, so it keeps the apply time of the release
+	updateFn := func(ctx context.Context, record *entity.Migration) error {
+		if err := m.repo.RemoveMigration(ctx, record.Version); err != nil {
+			return err
+		}
+		return insertFn(ctx, record)
+	}
+
+	return m.applyFileCore(ctx, migration, fileName, false, newRecorder(migration, insertFn, updateFn))
 }
 
 // applyFileCore contains the shared logic for applying a migration file.
-// insertFn controls how the migration record is stored (with or without explicit applyTime).
+// rec controls how the migration record is stored (with or without explicit applyTime).
 func (m *Migration) applyFileCore(
 	ctx context.Context,
 	migration *model.Migration,
 	fileName string,
 	safely bool,
-	insertFn func(ctx context.Context, version string) error,
+	rec *recorder,
 ) error {
 	if migration.Version == baseMigration {
 		return ErrMigrationVersionReserved
 	}
+	if err := checkInterrupted(ctx, migration.Version); err != nil {
+		return err
+	}
+	if goMigration, ok := m.options.GoMigrations.Get(migration.Version); ok {
+		return m.applyGo(ctx, goMigration, safely, rec)
+	}
 	m.logger.Warnf("*** applying %s\n", migration.Version)
-	scanner, err := m.scannerByFile(fileName)
+	f, err := m.openFile(fileName)
+	if err != nil {
+		return err
+	}
+	// the checksum is computed while the scanner reads the file
+	checksum := sqlio.NewChecksum()
+	scanner := m.newScanner(io.TeeReader(f, checksum))
+	downSQL, err := m.readDownSQL(fileName, migration.Version)
 	if err != nil {
 		return err
 	}
@@ -281,15 +404,26 @@ func (m *Migration) applyFileCore(
 		}
 	}()
 
+	committedSQL, err := m.resume(scanner, migration)
+	if err != nil {
+		return err
+	}
+
 	start := time.Now()
-	err = m.apply(ctx, scanner, safely)
+	executedSQL, committed, err := m.apply(ctx, scanner, safely, committedSQL, rec.progress)
 	elapsedTime := time.Since(start)
 	if err != nil {
 		m.logger.Errorf("*** failed to apply %s (time: %.3fs)\n", migration.Version, elapsedTime.Seconds())
 
-		return err
+		return rec.fail(ctx, committed, executedSQL, err)
 	}
-	if err := insertFn(ctx, migration.Version); err != nil {
+	// the migration is recorded even if the run was canceled after its statements were committed
+	if err := rec.applied(uncanceled(ctx), &entity.Migration{
+		Version:     migration.Version,
+		ExecutedSQL: executedSQL,
+		DownSQL:     downSQL,
+		Checksum:    checksum.Sum(),
+	}); err != nil {
 		return err
 	}
 	m.logger.Successf("*** applied %s (time: %.3fs)\n", migration.Version, elapsedTime.Seconds())
@@ -298,14 +432,22 @@ func (m *Migration) applyFileCore(
 }
 
 // RevertFile reverts a migration by reading and executing SQL from a file.
+// When the file is missing, the down SQL stored in the history table at apply time is used instead.
 // It tracks execution time, logs progress, and removes the migration from the history table.
 // The safely parameter determines whether to execute statements within a transaction.
+// A registered migration written in Go is reverted with its down function instead.
 func (m *Migration) RevertFile(ctx context.Context, migration *model.Migration, fileName string, safely bool) error {
 	if migration.Version == baseMigration {
 		return ErrMigrationVersionReserved
 	}
+	if err := checkInterrupted(ctx, migration.Version); err != nil {
+		return err
+	}
+	if goMigration, ok := m.options.GoMigrations.Get(migration.Version); ok {
+		return m.revertGo(ctx, goMigration, safely)
+	}
 	m.logger.Warnf("*** reverting %s\n", migration.Version)
-	scanner, err := m.scannerByFile(fileName)
+	scanner, err := m.revertScanner(migration, fileName)
 	if err != nil {
 		return err
 	}
@@ -317,14 +459,14 @@ func (m *Migration) RevertFile(ctx context.Context, migration *model.Migration,
 	}()
 
 	start := time.Now()
-	err = m.apply(ctx, scanner, safely)
+	_, committed, err := m.apply(ctx, scanner, safely, nil, nil)
 	elapsedTime := time.Since(start)
 	if err != nil {
 		m.logger.Errorf("*** failed to revert %s (time: %.3fs)\n",
 			migration.Version, elapsedTime.Seconds())
-		return err
+		return incomplete(migration.Version, committed, err)
 	}
-	if err := m.repo.RemoveMigration(ctx, migration.Version); err != nil {
+	if err := m.repo.RemoveMigration(uncanceled(ctx), migration.Version); err != nil {
 		return err
 	}
 	m.logger.Warnf("*** reverted %s (time: %.3fs)\n", migration.Version, elapsedTime.Seconds())
@@ -345,7 +487,13 @@ func (m *Migration) BeginCommand(sqlQuery string) time.Time {
 
 // ExecQuery executes a SQL query and logs its execution time.
 // It wraps the repository's ExecQuery method with timing and logging.
+// The statement timeout of the options limits its execution time.
 func (m *Migration) ExecQuery(ctx context.Context, sqlQuery string) error {
+	if m.options.StatementTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.options.StatementTimeout)
+		defer cancel()
+	}
 	start := m.BeginCommand(sqlQuery)
 	if err := m.repo.ExecQuery(ctx, sqlQuery); err != nil {
 		return err
@@ -384,7 +532,7 @@ func (m *Migration) Exists(ctx context.Context, version string) (bool, error) {
 }
 
 // LatestReleaseMigrations returns migrations from the latest release batch,
-// identified by the maximum apply_time value. It filters out the base migration.
+// identified by the maximum apply_time value. It filters out the base migration and the dirty ones.
 func (m *Migration) LatestReleaseMigrations(ctx context.Context) (model.Migrations, error) {
 	if err := m.InitializeTableHistory(ctx); err != nil {
 		return nil, err
@@ -395,21 +543,12 @@ func (m *Migration) LatestReleaseMigrations(ctx context.Context) (model.Migratio
 		return nil, err
 	}
 
-	migrations := mapper.EntitiesToDomain(entities)
-
-	// Filter out base migration
-	result := make(model.Migrations, 0, len(migrations))
-	for _, migration := range migrations {
-		if migration.Version != baseMigration {
-			result = append(result, migration)
-		}
-	}
-
-	return result, nil
+	return appliedOnly(mapper.EntitiesToDomain(entities)), nil
 }
 
 // ExecInTransaction executes fn within a transaction only if the driver supports DDL transactions.
-// Drivers that do not support transactional DDL (ClickHouse, MySQL, Tarantool) execute fn directly.
+// Drivers that do not support transactional DDL (ClickHouse, MySQL, Tarantool, CockroachDB, YugabyteDB)
+// execute fn directly.
 func (m *Migration) ExecInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
 	if m.repo.SupportsDDLTransactions() {
 		return m.repo.ExecQueryTransaction(ctx, fn)
@@ -417,50 +556,400 @@ func (m *Migration) ExecInTransaction(ctx context.Context, fn func(ctx context.C
 	return fn(ctx)
 }
 
+// Verify compares the applied migrations with their up files and returns, in version order,
+// the ones whose file was changed or removed, or that were applied before checksums were recorded.
+func (m *Migration) Verify(ctx context.Context) (model.Drifts, error) {
+	migrations, err := m.Migrations(ctx, MaxLimit)
+	if err != nil {
+		return nil, err
+	}
+	migrations.SortByVersion()
+
+	fileNameBuilder := builder.NewFileName(m.file, m.options.Directory)
+	drifts := make(model.Drifts, 0)
+
+	for i := range migrations {
+		migration := &migrations[i]
+		if _, ok := m.options.GoMigrations.Get(migration.Version); ok {
+			// migrations written in Go have no file to compare with
+			continue
+		}
+		fileName, _ := fileNameBuilder.Up(migration.Version, false)
+
+		exists, err := m.file.Exists(fileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "migration file %s does not exist", fileName)
+		}
+		if !exists {
+			drifts = append(drifts, model.Drift{
+				Version:          migration.Version,
+				Kind:             model.DriftMissing,
+				ExpectedChecksum: migration.Checksum,
+			})
+			continue
+		}
+
+		if migration.Checksum == "" {
+			drifts = append(drifts, model.Drift{
+				Version: migration.Version,
+				Kind:    model.DriftNoChecksum,
+			})
+			continue
+		}
+
+		body, err := m.file.ReadAll(fileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "migration file %s does not read", fileName)
+		}
+		if actual := sqlio.ChecksumOf(body); actual != migration.Checksum {
+			drifts = append(drifts, model.Drift{
+				Version:          migration.Version,
+				Kind:             model.DriftChanged,
+				ExpectedChecksum: migration.Checksum,
+				ActualChecksum:   actual,
+			})
+		}
+	}
+
+	return drifts, nil
+}
+
+// DumpSchema returns the current schema of the database as the database introspects it,
+// without the migration history and lock tables.
+func (m *Migration) DumpSchema(ctx context.Context) (string, error) {
+	schema, err := m.repo.DumpSchema(ctx)
+	if err != nil {
+		return "", errors.WithMessage(err, "dump schema")
+	}
+
+	return schema, nil
+}
+
+// LoadSchema returns the current tables of the database, without the migration history and lock tables,
+// as the schema diff compares them.
+func (m *Migration) LoadSchema(ctx context.Context) (*ddl.Schema, error) {
+	schema, err := m.repo.LoadSchema(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "load schema")
+	}
+
+	return schema, nil
+}
+
 // FileExists checks whether a file exists at the specified path.
+// Migrations written in Go have no files, so their file names are reported as existing.
 func (m *Migration) FileExists(fileName string) (bool, error) {
+	if m.isGoMigrationFile(fileName) {
+		return true, nil
+	}
+
 	return m.file.Exists(fileName)
 }
 
-func (m *Migration) apply(ctx context.Context, scanner *sqlio.Scanner, safely bool) error {
-	processScanFunc := func(ctx context.Context) error {
-		var sql string
+// isGoMigrationFile reports whether the file name is one of a registered migration written in Go.
+func (m *Migration) isGoMigrationFile(fileName string) bool {
+	groups := regexpFileName.FindStringSubmatch(filepath.Base(fileName))
+	if len(groups) != regexpFileNameGroupCount {
+		return false
+	}
+	_, ok := m.options.GoMigrations.Get(groups[1])
+
+	return ok
+}
+
+// apply executes the statements read by scanner and returns them, joined and with
+// credentials masked, as they were sent to the database, together with the number of the statements
+// committed, which stays zero within an outer transaction.
+// The statements of a resumed migration committed by an earlier run, committedSQL, are counted as committed
+// and returned ahead of the executed ones.
+// Before a statement, or a transaction, runs after statements were committed, progress, unless nil,
+// is called with the number of the committed statements and the committed statements joined.
+// Run safely, the statements run within transactions split by the directives of the statements:
+// a no-transaction statement runs on its own between them and a split statement starts a new one.
+// A statement, or a transaction, that gave up waiting for a lock is run again as the retry options allow.
+func (m *Migration) apply(
+	ctx context.Context,
+	scanner *sqlio.Scanner,
+	safely bool,
+	committedSQL []string,
+	progress func(ctx context.Context, committed int, executedSQL string) error,
+) (string, int, error) {
+	if m.options.MigrationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.options.MigrationTimeout)
+		defer cancel()
+	}
+
+	var (
+		executed  = committedSQL
+		committed = len(committedSQL)
+		recorded  = len(committedSQL)
+	)
+	_, txErr := connection.TxFromContext(ctx)
+	commit := func() {
+		if txErr != nil {
+			committed = len(executed)
+		}
+	}
+	track := func() error {
+		if progress == nil || committed == recorded {
+			return nil
+		}
+		if err := progress(ctx, committed, strings.Join(executed[:committed], "")); err != nil {
+			return err
+		}
+		recorded = committed
+
+		return nil
+	}
+	execFunc := func(ctx context.Context, sql string) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		sql, err := m.template.Execute(sql)
+		if err != nil {
+			return err
+		}
+		if err := m.ExecQuery(ctx, sql); err != nil {
+			return err
+		}
+		executed = append(executed, m.sanitizeCredentials(sql)+";\n")
+
+		return nil
+	}
+	result := func(err error) (string, int, error) {
+		if err != nil {
+			// the statements of the failed transaction are rolled back
+			return strings.Join(executed[:committed], ""), committed, err
+		}
+		return strings.Join(executed, ""), committed, nil
+	}
+
+	if !safely {
 		for scanner.Scan() {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
+			if err := track(); err != nil {
+				return result(err)
 			}
-
-			sql = scanner.SQL()
-			if sql == "" {
-				continue
+			sql := scanner.SQL()
+			if err := m.retry(ctx, func() error { return execFunc(ctx, sql) }); err != nil {
+				return result(err)
 			}
+			commit()
+		}
 
-			sql = strings.ReplaceAll(sql, "{cluster}", m.options.ClusterName)
-			sql = strings.ReplaceAll(sql, "{placeholder_custom}", m.options.PlaceholderCustom)
-			sql = strings.ReplaceAll(sql, "{username}", m.options.Username)
-			sql = strings.ReplaceAll(sql, "{password}", m.options.Password)
+		return result(scanner.Err())
+	}
 
-			if err := m.ExecQuery(ctx, sql); err != nil {
-				return err
+	scanned := scanner.Scan()
+	for scanned {
+		if err := track(); err != nil {
+			return result(err)
+		}
+		if scanner.Directives().NoTransaction {
+			sql := scanner.SQL()
+			if err := m.retry(ctx, func() error { return execFunc(ctx, sql) }); err != nil {
+				return result(err)
 			}
+			commit()
+			scanned = scanner.Scan()
+			continue
 		}
 
-		return scanner.Err()
+		// the statements of the transaction are kept, so a retry runs them again
+		group := []string{scanner.SQL()}
+		groupEnded := false
+		mark := len(executed)
+		err := m.retry(ctx, func() error {
+			executed = executed[:mark]
+			return m.repo.ExecQueryTransaction(ctx, func(ctx context.Context) error {
+				for i := 0; ; i++ {
+					if i == len(group) {
+						if groupEnded {
+							return nil
+						}
+						scanned = scanner.Scan()
+						if groupEnded = !scanned || scanner.Directives().Any(); groupEnded {
+							return scanner.Err()
+						}
+						group = append(group, scanner.SQL())
+					}
+					if err := execFunc(ctx, group[i]); err != nil {
+						return err
+					}
+				}
+			})
+		})
+		if err != nil {
+			return result(err)
+		}
+		commit()
+	}
+
+	return result(scanner.Err())
+}
+
+// resume skips the statements of a dirty migration committed by the run that left it dirty when resuming
+// is enabled and returns them as they were executed. It fails with ErrResumeChanged when the file or
+// the placeholder values were changed since, as then the committed statements differ from the recorded ones.
+func (m *Migration) resume(scanner *sqlio.Scanner, migration *model.Migration) ([]string, error) {
+	if !m.options.Resume || !migration.Dirty() || migration.LastStatement == 0 {
+		return nil, nil
+	}
+
+	committedSQL := make([]string, 0, migration.LastStatement)
+	for scanner.Index() < migration.LastStatement && scanner.Scan() {
+		sql, err := m.template.Execute(scanner.SQL())
+		if err != nil {
+			return nil, err
+		}
+		committedSQL = append(committedSQL, m.sanitizeCredentials(sql)+";\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(committedSQL) < migration.LastStatement ||
+		sqlio.ChecksumOf([]byte(strings.Join(committedSQL, ""))) != sqlio.ChecksumOf([]byte(migration.ExecutedSQL)) {
+		return nil, errors.Wrapf(ErrResumeChanged, "%s: the first %d statement(s)",
+			migration.Version, migration.LastStatement)
 	}
+	m.logger.Infof("    > skip %d statement(s) committed by the earlier run\n", migration.LastStatement)
 
-	var err error
+	return committedSQL, nil
+}
+
+// HasNoTransactionDirectives reports whether the migration file has statements with
+// the no-transaction or split directive, which cannot run within a single transaction.
+func (m *Migration) HasNoTransactionDirectives(fileName string) (bool, error) {
+	if m.isGoMigrationFile(fileName) {
+		return false, nil
+	}
+
+	scanner, err := m.scannerByFile(fileName)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if closeErr := scanner.Close(); closeErr != nil {
+			m.logger.Warnf("failed to close SQL scanner: %v", closeErr)
+		}
+	}()
+
+	for scanner.Scan() {
+		if scanner.Directives().Any() {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// applyGo applies a migration written in Go and records it with rec.
+// The migration runs inside a transaction when it is safe and the caller asks to run it safely,
+// callers that already run inside a transaction pass false.
+func (m *Migration) applyGo(
+	ctx context.Context,
+	migration *GoMigration,
+	safely bool,
+	rec *recorder,
+) error {
+	m.logger.Warnf("*** applying %s\n", migration.Version)
+
+	start := time.Now()
+	err := m.runGo(ctx, migration.Up, safely && migration.Safe)
+	elapsedTime := time.Since(start)
+	if err != nil {
+		m.logger.Errorf("*** failed to apply %s (time: %.3fs)\n", migration.Version, elapsedTime.Seconds())
+
+		return rec.fail(ctx, 0, "", err)
+	}
+	if err := rec.applied(uncanceled(ctx), &entity.Migration{Version: migration.Version}); err != nil {
+		return err
+	}
+	m.logger.Successf("*** applied %s (time: %.3fs)\n", migration.Version, elapsedTime.Seconds())
+
+	return nil
+}
+
+// revertGo reverts a migration written in Go and removes it from the history table.
+func (m *Migration) revertGo(ctx context.Context, migration *GoMigration, safely bool) error {
+	if migration.Down == nil {
+		return errors.Wrap(ErrGoMigrationDownMissing, migration.Version)
+	}
+	m.logger.Warnf("*** reverting %s\n", migration.Version)
+
+	start := time.Now()
+	err := m.runGo(ctx, migration.Down, safely && migration.Safe)
+	elapsedTime := time.Since(start)
+	if err != nil {
+		m.logger.Errorf("*** failed to revert %s (time: %.3fs)\n", migration.Version, elapsedTime.Seconds())
+
+		return err
+	}
+	if err := m.repo.RemoveMigration(uncanceled(ctx), migration.Version); err != nil {
+		return err
+	}
+	m.logger.Warnf("*** reverted %s (time: %.3fs)\n", migration.Version, elapsedTime.Seconds())
+
+	return nil
+}
+
+// runGo calls the body of a migration written in Go, within a transaction when safely is true.
+func (m *Migration) runGo(ctx context.Context, fn GoMigrationFunc, safely bool) error {
+	if m.options.DryRun {
+		m.logger.Infof("    > skip the Go migration body in dry run\n")
+		return nil
+	}
 	if safely {
-		err = m.repo.ExecQueryTransaction(ctx, processScanFunc)
-	} else {
-		err = processScanFunc(ctx)
+		return m.repo.ExecQueryTransaction(ctx, func(ctx context.Context) error {
+			return fn(ctx, m.options.Connection)
+		})
 	}
 
-	return err
+	return fn(ctx, m.options.Connection)
 }
 
 func (m *Migration) scannerByFile(fileName string) (*sqlio.Scanner, error) {
+	f, err := m.openFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.newScanner(f), nil
+}
+
+// newScanner returns a scanner splitting r into the statements of the dialect of the connection.
+func (m *Migration) newScanner(r io.Reader) *sqlio.Scanner {
+	return sqlio.NewScanner(r, dialectOf(m.options.Connection))
+}
+
+// dialectOf returns the dialect of the migrations run over the connection,
+// the default one when there is no connection.
+func dialectOf(conn Connection) sqlio.Dialect {
+	if conn == nil {
+		return sqlio.DialectDefault
+	}
+	switch conn.Driver() {
+	case connection.DriverPostgres, connection.DriverCockroachDB, connection.DriverYugabyteDB:
+		return sqlio.DialectPostgres
+	case connection.DriverMySQL:
+		return sqlio.DialectMySQL
+	case connection.DriverClickhouse:
+		return sqlio.DialectClickhouse
+	case connection.DriverTarantool:
+		return sqlio.DialectTarantool
+	case connection.DriverSQLite:
+		return sqlio.DialectSQLite
+	case connection.DriverMSSQL:
+		return sqlio.DialectMSSQL
+	default:
+		return sqlio.DialectDefault
+	}
+}
+
+func (m *Migration) openFile(fileName string) (io.ReadCloser, error) {
 	exists, err := m.file.Exists(fileName)
 	if err != nil {
 		return nil, errors.Wrapf(err, "migration file %s does not exist", fileName)
@@ -474,7 +963,44 @@ func (m *Migration) scannerByFile(fileName string) (*sqlio.Scanner, error) {
 		return nil, errors.Wrapf(err, "migration file %s does not read", fileName)
 	}
 
-	return sqlio.NewScanner(f), nil
+	return f, nil
+}
+
+// readDownSQL returns the body of the down file matching the given up file, or an empty
+// string when the migration has no down file.
+func (m *Migration) readDownSQL(upFileName, version string) (string, error) {
+	downFileName, _ := builder.NewFileName(m.file, filepath.Dir(upFileName)).Down(version, false)
+	exists, err := m.file.Exists(downFileName)
+	if err != nil {
+		return "", errors.Wrapf(err, "migration file %s does not exist", downFileName)
+	}
+	if !exists {
+		return "", nil
+	}
+
+	body, err := m.file.ReadAll(downFileName)
+	if err != nil {
+		return "", errors.Wrapf(err, "migration file %s does not read", downFileName)
+	}
+
+	return string(body), nil
+}
+
+// revertScanner returns a scanner over the down file, falling back to the down SQL
+// stored in the history table when the file is missing.
+func (m *Migration) revertScanner(migration *model.Migration, fileName string) (*sqlio.Scanner, error) {
+	if migration.DownSQL != "" {
+		exists, err := m.file.Exists(fileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "migration file %s does not exist", fileName)
+		}
+		if !exists {
+			m.logger.Warnf("    > migration file %s does not exist, using the down SQL stored in history\n", fileName)
+			return m.newScanner(strings.NewReader(migration.DownSQL)), nil
+		}
+	}
+
+	return m.scannerByFile(fileName)
 }
 
 // sanitizeCredentials masks the username/password placeholder values in migration
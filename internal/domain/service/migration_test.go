@@ -16,7 +16,11 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/raoptimus/db-migrator.go/internal/helper/placeholder"
+	"github.com/raoptimus/db-migrator.go/internal/helper/sqlio"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -30,6 +34,7 @@ func TestMigration_InitializeTableHistory_TableExists_Successfully(t *testing.T)
 	logger := NewMockLogger(t)
 
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
 
 	serv := NewMigration(&Options{}, logger, file, repo)
 	err := serv.InitializeTableHistory(ctx)
@@ -47,7 +52,7 @@ func TestMigration_InitializeTableHistory_CreateTable_Successfully(t *testing.T)
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(false, nil)
 	repo.EXPECT().TableNameWithSchema().Return(tableName)
 	repo.EXPECT().CreateMigrationHistoryTable(ctx).Return(nil)
-	repo.EXPECT().InsertMigration(ctx, "000000_000000_base").Return(nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{Version: "000000_000000_base"}).Return(nil)
 
 	logger.EXPECT().Warnf("Creating migration history table %s...\n", tableName)
 	logger.EXPECT().Success("Done")
@@ -58,6 +63,22 @@ func TestMigration_InitializeTableHistory_CreateTable_Successfully(t *testing.T)
 	require.NoError(t, err)
 }
 
+func TestMigration_InitializeTableHistory_UpgradeTableReturnsError_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	expectedErr := errors.New("upgrade error")
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(expectedErr)
+
+	serv := NewMigration(&Options{}, logger, file, repo)
+	err := serv.InitializeTableHistory(ctx)
+
+	require.ErrorIs(t, err, expectedErr)
+}
+
 func TestMigration_InitializeTableHistory_HasMigrationHistoryTableReturnsError_Failure(t *testing.T) {
 	ctx := context.Background()
 	repo := NewMockRepository(t)
@@ -104,7 +125,7 @@ func TestMigration_InitializeTableHistory_InsertBaseMigrationReturnsError_DropsT
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(false, nil)
 	repo.EXPECT().TableNameWithSchema().Return(tableName)
 	repo.EXPECT().CreateMigrationHistoryTable(ctx).Return(nil)
-	repo.EXPECT().InsertMigration(ctx, "000000_000000_base").Return(insertErr)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{Version: "000000_000000_base"}).Return(insertErr)
 	repo.EXPECT().DropMigrationHistoryTable(ctx).Return(nil)
 
 	logger.EXPECT().Warnf("Creating migration history table %s...\n", tableName)
@@ -127,7 +148,7 @@ func TestMigration_InitializeTableHistory_InsertBaseMigrationReturnsError_DropTa
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(false, nil)
 	repo.EXPECT().TableNameWithSchema().Return(tableName)
 	repo.EXPECT().CreateMigrationHistoryTable(ctx).Return(nil)
-	repo.EXPECT().InsertMigration(ctx, "000000_000000_base").Return(insertErr)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{Version: "000000_000000_base"}).Return(insertErr)
 	repo.EXPECT().DropMigrationHistoryTable(ctx).Return(dropErr)
 
 	logger.EXPECT().Warnf("Creating migration history table %s...\n", tableName)
@@ -236,6 +257,7 @@ func TestMigration_Migrations_ReturnsAppliedMigrations_Successfully(t *testing.T
 			logger := NewMockLogger(t)
 
 			repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+			repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
 
 			expectedLimit := tt.limit
 			if expectedLimit < 1 {
@@ -276,6 +298,7 @@ func TestMigration_Migrations_RepositoryMigrationsReturnsError_Failure(t *testin
 	expectedErr := errors.New("query migrations error")
 
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
 	repo.EXPECT().Migrations(ctx, 10).Return(nil, expectedErr)
 
 	serv := NewMigration(&Options{}, logger, file, repo)
@@ -294,13 +317,14 @@ func TestMigration_NewMigrations_ReturnsNewMigrations_Successfully(t *testing.T)
 	logger := NewMockLogger(t)
 
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
 	repo.EXPECT().Migrations(ctx, 100000).Return(entity.Migrations{
 		{Version: "000000_000000_base"},
 		{Version: "200101_120000_create_users"},
 	}, nil)
+	file.EXPECT().Glob("/nonexistent/path/*.up.sql").Return(nil, nil)
 
 	serv := NewMigration(&Options{Directory: "/nonexistent/path"}, logger, file, repo)
-	// Note: filepath.Glob will return empty for non-existent path
 	migrations, err := serv.NewMigrations(ctx)
 
 	require.NoError(t, err)
@@ -331,6 +355,7 @@ func TestMigration_NewMigrations_RepositoryMigrationsReturnsError_Failure(t *tes
 	expectedErr := errors.New("query migrations error")
 
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
 	repo.EXPECT().Migrations(ctx, 100000).Return(nil, expectedErr)
 
 	serv := NewMigration(&Options{Directory: "/tmp"}, logger, file, repo)
@@ -351,7 +376,11 @@ func TestMigration_ApplySQL_SimpleSQLStatement_Successfully(t *testing.T) {
 	upSQL := "CREATE TABLE users (id INT);"
 
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
-	repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\n",
+		Checksum:    sqlio.ChecksumOf([]byte(upSQL)),
+	}).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE users (id INT)")
@@ -373,7 +402,18 @@ func TestMigration_ApplySQL_MultipleStatements_Successfully(t *testing.T) {
 
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE posts (id INT)").Return(nil)
-	repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+	// the first statement is committed before the second one runs
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:       version,
+		ExecutedSQL:   "CREATE TABLE users (id INT);\n",
+		Status:        entity.StatusPending,
+		LastStatement: 1,
+	}).Return(nil)
+	repo.EXPECT().UpdateMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\nCREATE TABLE posts (id INT);\n",
+		Checksum:    sqlio.ChecksumOf([]byte(upSQL)),
+	}).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE users (id INT)")
@@ -386,6 +426,32 @@ func TestMigration_ApplySQL_MultipleStatements_Successfully(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestMigration_ApplySQL_SplitsByConnectionDialect_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	conn := NewMockConnection(t)
+	version := "200101_120000_create_function"
+	function := "CREATE FUNCTION one() RETURNS text AS $body$ SELECT 'one;' $body$ LANGUAGE sql"
+	upSQL := "-- returns one;\n" + function + ";\nSELECT E'it\\'s; ok';"
+
+	conn.EXPECT().Driver().Return(connection.DriverPostgres)
+	repo.EXPECT().ExecQuery(ctx, "-- returns one;\n"+function).Return(nil)
+	repo.EXPECT().ExecQuery(ctx, "SELECT E'it\\'s; ok'").Return(nil)
+	repo.EXPECT().InsertMigration(ctx, mock.AnythingOfType("*entity.Migration")).Return(nil)
+	repo.EXPECT().UpdateMigration(ctx, mock.AnythingOfType("*entity.Migration")).Return(nil)
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.AnythingOfType("string")).Times(2)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{Connection: conn}, logger, file, repo)
+	err := serv.ApplySQL(ctx, false, version, upSQL)
+
+	require.NoError(t, err)
+}
+
 func TestMigration_ApplySQL_WithTransaction_Successfully(t *testing.T) {
 	ctx := context.Background()
 	repo := NewMockRepository(t)
@@ -400,7 +466,11 @@ func TestMigration_ApplySQL_WithTransaction_Successfully(t *testing.T) {
 			return fn(ctx)
 		})
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
-	repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\n",
+		Checksum:    sqlio.ChecksumOf([]byte(upSQL)),
+	}).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE users (id INT)")
@@ -412,6 +482,103 @@ func TestMigration_ApplySQL_WithTransaction_Successfully(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestMigration_ApplySQL_WithTransaction_Directives_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_create_users"
+	upSQL := "CREATE TABLE users (id INT);\n" +
+		"-- migrator:no-transaction\nCREATE INDEX CONCURRENTLY users_id ON users (id);\n" +
+		"INSERT INTO users VALUES (1);\n" +
+		"-- migrator:split\nUPDATE users SET id = 2;"
+
+	var calls []string
+	repo.EXPECT().
+		ExecQueryTransaction(ctx, mock.AnythingOfType("func(context.Context) error")).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			calls = append(calls, "BEGIN")
+			err := fn(ctx)
+			calls = append(calls, "COMMIT")
+
+			return err
+		}).
+		Times(3)
+	repo.EXPECT().ExecQuery(ctx, mock.AnythingOfType("string")).
+		RunAndReturn(func(_ context.Context, sql string, _ ...any) error {
+			calls = append(calls, sql)
+			return nil
+		}).
+		Times(4)
+	// the progress is recorded before the statement, or the transaction, run after the first one
+	repo.EXPECT().InsertMigration(ctx, mock.AnythingOfType("*entity.Migration")).Return(nil).Once()
+	repo.EXPECT().UpdateMigration(ctx, mock.AnythingOfType("*entity.Migration")).Return(nil).Times(3)
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.AnythingOfType("string")).Times(4)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{}, logger, file, repo)
+	err := serv.ApplySQL(ctx, true, version, upSQL)
+
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"BEGIN", "CREATE TABLE users (id INT)", "COMMIT",
+		"-- migrator:no-transaction\nCREATE INDEX CONCURRENTLY users_id ON users (id)",
+		"BEGIN", "INSERT INTO users VALUES (1)", "COMMIT",
+		"BEGIN", "-- migrator:split\nUPDATE users SET id = 2", "COMMIT",
+	}, calls)
+}
+
+func TestMigration_ApplySQL_WithoutTransaction_IgnoresDirectives_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_create_users"
+	upSQL := "CREATE TABLE users (id INT);\n-- migrator:split\nUPDATE users SET id = 2;"
+
+	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
+	repo.EXPECT().ExecQuery(ctx, "-- migrator:split\nUPDATE users SET id = 2").Return(nil)
+	repo.EXPECT().InsertMigration(ctx, mock.AnythingOfType("*entity.Migration")).Return(nil)
+	repo.EXPECT().UpdateMigration(ctx, mock.AnythingOfType("*entity.Migration")).Return(nil)
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.AnythingOfType("string")).Times(2)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{}, logger, file, repo)
+	err := serv.ApplySQL(ctx, false, version, upSQL)
+
+	require.NoError(t, err)
+}
+
+func TestMigration_HasNoTransactionDirectives_Successfully(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want bool
+	}{
+		{name: "no directives", sql: "CREATE TABLE users (id INT); SELECT '-- migrator:split'", want: false},
+		{name: "no-transaction", sql: "SELECT 1;\n-- migrator:no-transaction\nVACUUM users;", want: true},
+		{name: "split", sql: "-- migrator:split\n\nSELECT 1;", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := NewMockFile(t)
+			fileName := "/migrations/200101_120000_create_users.safe.up.sql"
+			file.EXPECT().Exists(fileName).Return(true, nil)
+			file.EXPECT().Open(fileName).Return(io.NopCloser(strings.NewReader(tt.sql)), nil)
+
+			serv := NewMigration(&Options{}, NewMockLogger(t), file, NewMockRepository(t))
+			got, err := serv.HasNoTransactionDirectives(fileName)
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestMigration_ApplySQL_ReplacesCredentialPlaceholders_Successfully(t *testing.T) {
 	ctx := context.Background()
 	repo := NewMockRepository(t)
@@ -421,7 +588,11 @@ func TestMigration_ApplySQL_ReplacesCredentialPlaceholders_Successfully(t *testi
 	upSQL := "CREATE USER {username} WITH PASSWORD '{password}';"
 
 	repo.EXPECT().ExecQuery(ctx, "CREATE USER admin WITH PASSWORD 'secret123'").Return(nil)
-	repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE USER **** WITH PASSWORD '****';\n",
+		Checksum:    sqlio.ChecksumOf([]byte(upSQL)),
+	}).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE USER **** WITH PASSWORD '****'")
@@ -478,7 +649,11 @@ func TestMigration_ApplySQL_InsertMigrationReturnsError_Failure(t *testing.T) {
 	expectedErr := errors.New("insert migration error")
 
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
-	repo.EXPECT().InsertMigration(ctx, version).Return(expectedErr)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\n",
+		Checksum:    sqlio.ChecksumOf([]byte(upSQL)),
+	}).Return(expectedErr)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE users (id INT)")
@@ -625,12 +800,21 @@ func TestMigration_ApplyFile_SimpleSQLStatement_Successfully(t *testing.T) {
 	fileName := "/migrations/200101_120000_create_users.up.sql"
 	sqlContent := "CREATE TABLE users (id INT);"
 	sqlReader := io.NopCloser(strings.NewReader(sqlContent))
+	downFileName := "/migrations/200101_120000_create_users.down.sql"
+	downSQL := "DROP TABLE users;"
 
 	file.EXPECT().Exists(fileName).Return(true, nil)
 	file.EXPECT().Open(fileName).Return(sqlReader, nil)
+	file.EXPECT().Exists(downFileName).Return(true, nil)
+	file.EXPECT().ReadAll(downFileName).Return([]byte(downSQL), nil)
 
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
-	repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\n",
+		DownSQL:     downSQL,
+		Checksum:    sqlio.ChecksumOf([]byte(sqlContent)),
+	}).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE users (id INT)")
@@ -654,10 +838,23 @@ func TestMigration_ApplyFile_MultipleStatements_Successfully(t *testing.T) {
 
 	file.EXPECT().Exists(fileName).Return(true, nil)
 	file.EXPECT().Open(fileName).Return(sqlReader, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_tables.down.sql").Return(false, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_tables.safe.down.sql").Return(false, nil)
 
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE posts (id INT)").Return(nil)
-	repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+	// the first statement is committed before the second one runs
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:       version,
+		ExecutedSQL:   "CREATE TABLE users (id INT);\n",
+		Status:        entity.StatusPending,
+		LastStatement: 1,
+	}).Return(nil)
+	repo.EXPECT().UpdateMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\nCREATE TABLE posts (id INT);\n",
+		Checksum:    sqlio.ChecksumOf([]byte(sqlContent)),
+	}).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE users (id INT)")
@@ -682,6 +879,8 @@ func TestMigration_ApplyFile_WithTransaction_Successfully(t *testing.T) {
 
 	file.EXPECT().Exists(fileName).Return(true, nil)
 	file.EXPECT().Open(fileName).Return(sqlReader, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_users.down.sql").Return(false, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_users.safe.down.sql").Return(false, nil)
 
 	repo.EXPECT().
 		ExecQueryTransaction(ctx, mock.AnythingOfType("func(context.Context) error")).
@@ -689,7 +888,11 @@ func TestMigration_ApplyFile_WithTransaction_Successfully(t *testing.T) {
 			return fn(ctx)
 		})
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
-	repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\n",
+		Checksum:    sqlio.ChecksumOf([]byte(sqlContent)),
+	}).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE users (id INT)")
@@ -787,6 +990,8 @@ func TestMigration_ApplyFile_ExecQueryReturnsError_Failure(t *testing.T) {
 
 	file.EXPECT().Exists(fileName).Return(true, nil)
 	file.EXPECT().Open(fileName).Return(sqlReader, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_users.down.sql").Return(false, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_users.safe.down.sql").Return(false, nil)
 
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(expectedErr)
 
@@ -813,9 +1018,15 @@ func TestMigration_ApplyFile_InsertMigrationReturnsError_Failure(t *testing.T) {
 
 	file.EXPECT().Exists(fileName).Return(true, nil)
 	file.EXPECT().Open(fileName).Return(sqlReader, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_users.down.sql").Return(false, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_users.safe.down.sql").Return(false, nil)
 
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
-	repo.EXPECT().InsertMigration(ctx, version).Return(expectedErr)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\n",
+		Checksum:    sqlio.ChecksumOf([]byte(sqlContent)),
+	}).Return(expectedErr)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE users (id INT)")
@@ -854,6 +1065,33 @@ func TestMigration_RevertFile_SimpleSQLStatement_Successfully(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestMigration_RevertFile_FileDoesNotExist_UsesStoredDownSQL_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_create_users"
+	fileName := "/migrations/200101_120000_create_users.down.sql"
+
+	file.EXPECT().Exists(fileName).Return(false, nil)
+
+	repo.EXPECT().ExecQuery(ctx, "DROP TABLE users").Return(nil)
+	repo.EXPECT().RemoveMigration(ctx, version).Return(nil)
+
+	logger.EXPECT().Warnf("*** reverting %s\n", version)
+	logger.EXPECT().Warnf(
+		"    > migration file %s does not exist, using the down SQL stored in history\n",
+		fileName,
+	)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "DROP TABLE users")
+	logger.EXPECT().Warnf("*** reverted %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{}, logger, file, repo)
+	err := serv.RevertFile(ctx, &model.Migration{Version: version, DownSQL: "DROP TABLE users;"}, fileName, false)
+
+	require.NoError(t, err)
+}
+
 func TestMigration_RevertFile_WithTransaction_Successfully(t *testing.T) {
 	ctx := context.Background()
 	repo := NewMockRepository(t)
@@ -1489,7 +1727,9 @@ func TestMigration_Apply_ReplacesCredentialPlaceholders(t *testing.T) {
 			version := "200101_120000_test"
 
 			repo.EXPECT().ExecQuery(ctx, tt.expectedSQL).Return(nil)
-			repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+			repo.EXPECT().InsertMigration(ctx, mock.MatchedBy(func(m *entity.Migration) bool {
+				return m.Version == version
+			})).Return(nil)
 
 			logger.EXPECT().Warnf("*** applying %s\n", version)
 			logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything)
@@ -1503,6 +1743,43 @@ func TestMigration_Apply_ReplacesCredentialPlaceholders(t *testing.T) {
 	}
 }
 
+func TestMigration_Apply_ReplacesVariablePlaceholders(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_test"
+
+	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE acme.events_eu ON CLUSTER main").Return(nil)
+	repo.EXPECT().InsertMigration(ctx, mock.Anything).Return(nil)
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{
+		ClusterName: "main",
+		Vars:        map[string]string{"tenant": "acme"},
+	}, logger, NewMockFile(t), repo)
+	err := serv.ApplySQL(ctx, false, version, "CREATE TABLE {tenant}.events_{region|eu} ON CLUSTER {cluster};")
+
+	require.NoError(t, err)
+}
+
+func TestMigration_Apply_StrictPlaceholders_Unresolved_Failure(t *testing.T) {
+	ctx := context.Background()
+	logger := NewMockLogger(t)
+	version := "200101_120000_test"
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Errorf("*** failed to apply %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	// the built-in {cluster} placeholder has no value
+	serv := NewMigration(&Options{StrictPlaceholders: true}, logger, NewMockFile(t), NewMockRepository(t))
+	err := serv.ApplySQL(ctx, false, version, "CREATE TABLE events ON CLUSTER {cluster};")
+
+	require.ErrorIs(t, err, placeholder.ErrUnresolved)
+}
+
 // --- NewMigration Constructor Test ---
 
 func TestNewMigration_ReturnsInitializedStruct(t *testing.T) {
@@ -1536,7 +1813,11 @@ func TestMigration_ApplySQL_EmptySQLContent_Successfully(t *testing.T) {
 	version := "200101_120000_empty"
 	upSQL := ""
 
-	repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "",
+		Checksum:    sqlio.ChecksumOf([]byte(upSQL)),
+	}).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
@@ -1555,7 +1836,11 @@ func TestMigration_ApplySQL_OnlySemicolonsAndWhitespace_Successfully(t *testing.
 	version := "200101_120000_empty"
 	upSQL := "  ;  ;  ;"
 
-	repo.EXPECT().InsertMigration(ctx, version).Return(nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "",
+		Checksum:    sqlio.ChecksumOf([]byte(upSQL)),
+	}).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
@@ -1581,9 +1866,15 @@ func TestMigration_ApplyFileWithApplyTime_Successfully(t *testing.T) {
 
 	file.EXPECT().Exists(fileName).Return(true, nil)
 	file.EXPECT().Open(fileName).Return(sqlReader, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_users.down.sql").Return(false, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_users.safe.down.sql").Return(false, nil)
 
 	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE users (id INT)").Return(nil)
-	repo.EXPECT().InsertMigrationWithApplyTime(ctx, version, applyTime).Return(nil)
+	repo.EXPECT().InsertMigrationWithApplyTime(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\n",
+		Checksum:    sqlio.ChecksumOf([]byte(sqlContent)),
+	}, applyTime).Return(nil)
 
 	logger.EXPECT().Warnf("*** applying %s\n", version)
 	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE users (id INT)")
@@ -1617,6 +1908,7 @@ func TestMigration_LatestReleaseMigrations_ReturnsFilteredMigrations_Successfull
 	logger := NewMockLogger(t)
 
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
 	repo.EXPECT().MigrationsByMaxApplyTime(ctx).Return(entity.Migrations{
 		{Version: "000000_000000_base", ApplyTime: 1700000000},
 		{Version: "200101_120000_create_users", ApplyTime: 1700000000},
@@ -1639,6 +1931,7 @@ func TestMigration_LatestReleaseMigrations_EmptyResult_Successfully(t *testing.T
 	logger := NewMockLogger(t)
 
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
 	repo.EXPECT().MigrationsByMaxApplyTime(ctx).Return(entity.Migrations{}, nil)
 
 	serv := NewMigration(&Options{}, logger, file, repo)
@@ -1656,6 +1949,7 @@ func TestMigration_LatestReleaseMigrations_RepositoryError_Failure(t *testing.T)
 	expectedErr := errors.New("query error")
 
 	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
 	repo.EXPECT().MigrationsByMaxApplyTime(ctx).Return(nil, expectedErr)
 
 	serv := NewMigration(&Options{}, logger, file, repo)
@@ -1777,3 +2071,573 @@ func TestMigration_FileExists_Error_Failure(t *testing.T) {
 	require.ErrorIs(t, err, expectedErr)
 	require.False(t, exists)
 }
+
+// --- Verify Tests ---
+
+func TestMigration_Verify_ReturnsDriftsInVersionOrder_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	body := "CREATE TABLE users (id INT);\n"
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().Migrations(ctx, 100000).Return(entity.Migrations{
+		{Version: "200104_120000_legacy"},
+		{Version: "200103_120000_removed", Checksum: "removed-checksum"},
+		{Version: "200102_120000_changed", Checksum: "stale-checksum"},
+		{Version: "200101_120000_unchanged", Checksum: sqlio.ChecksumOf([]byte(body))},
+		{Version: "000000_000000_base"},
+	}, nil)
+
+	for _, version := range []string{
+		"200101_120000_unchanged",
+		"200102_120000_changed",
+		"200103_120000_removed",
+		"200104_120000_legacy",
+	} {
+		file.EXPECT().Exists("/migrations/"+version+".up.sql").Return(version != "200103_120000_removed", nil)
+	}
+	file.EXPECT().Exists("/migrations/200103_120000_removed.safe.up.sql").Return(false, nil)
+	file.EXPECT().ReadAll("/migrations/200101_120000_unchanged.up.sql").Return([]byte(body), nil)
+	file.EXPECT().ReadAll("/migrations/200102_120000_changed.up.sql").Return([]byte(body), nil)
+
+	serv := NewMigration(&Options{Directory: "/migrations"}, logger, file, repo)
+	drifts, err := serv.Verify(ctx)
+
+	require.NoError(t, err)
+	require.Equal(t, model.Drifts{
+		{
+			Version:          "200102_120000_changed",
+			Kind:             model.DriftChanged,
+			ExpectedChecksum: "stale-checksum",
+			ActualChecksum:   sqlio.ChecksumOf([]byte(body)),
+		},
+		{
+			Version:          "200103_120000_removed",
+			Kind:             model.DriftMissing,
+			ExpectedChecksum: "removed-checksum",
+		},
+		{
+			Version: "200104_120000_legacy",
+			Kind:    model.DriftNoChecksum,
+		},
+	}, drifts)
+}
+
+func TestMigration_Verify_NoDrifts_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	body := "CREATE TABLE users (id INT);\n"
+	fileName := "/migrations/200101_120000_create_users.up.sql"
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().Migrations(ctx, 100000).Return(entity.Migrations{
+		{Version: "200101_120000_create_users", Checksum: sqlio.ChecksumOf([]byte(body))},
+	}, nil)
+	file.EXPECT().Exists(fileName).Return(true, nil)
+	file.EXPECT().ReadAll(fileName).Return([]byte(body), nil)
+
+	serv := NewMigration(&Options{Directory: "/migrations"}, logger, file, repo)
+	drifts, err := serv.Verify(ctx)
+
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+}
+
+func TestMigration_Verify_ReadAllReturnsError_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	fileName := "/migrations/200101_120000_create_users.up.sql"
+	expectedErr := errors.New("read error")
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().Migrations(ctx, 100000).Return(entity.Migrations{
+		{Version: "200101_120000_create_users", Checksum: "checksum"},
+	}, nil)
+	file.EXPECT().Exists(fileName).Return(true, nil)
+	file.EXPECT().ReadAll(fileName).Return(nil, expectedErr)
+
+	serv := NewMigration(&Options{Directory: "/migrations"}, logger, file, repo)
+	drifts, err := serv.Verify(ctx)
+
+	require.ErrorIs(t, err, expectedErr)
+	require.Nil(t, drifts)
+}
+
+func TestMigration_Verify_RepositoryMigrationsReturnsError_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	expectedErr := errors.New("database error")
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().Migrations(ctx, 100000).Return(nil, expectedErr)
+
+	serv := NewMigration(&Options{Directory: "/migrations"}, logger, file, repo)
+	drifts, err := serv.Verify(ctx)
+
+	require.ErrorIs(t, err, expectedErr)
+	require.Nil(t, drifts)
+}
+
+// --- Go migration Tests ---
+
+func newGoMigrations(t *testing.T, migrations ...*GoMigration) *GoMigrations {
+	t.Helper()
+	registry := NewGoMigrations()
+	for _, migration := range migrations {
+		require.NoError(t, registry.Add(migration))
+	}
+
+	return registry
+}
+
+func TestMigration_NewMigrations_MergesGoMigrations_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().Migrations(ctx, 100000).Return(entity.Migrations{
+		{Version: "200103_120000_applied_backfill"},
+		{Version: "000000_000000_base"},
+	}, nil)
+	file.EXPECT().Glob("/migrations/*.up.sql").Return([]string{"/migrations/200101_120000_create_users.up.sql"}, nil)
+
+	serv := NewMigration(&Options{
+		Directory: "/migrations",
+		GoMigrations: newGoMigrations(t,
+			&GoMigration{Version: "200103_120000_applied_backfill", Up: noopGoMigrationFunc},
+			&GoMigration{Version: "200102_120000_backfill", Up: noopGoMigrationFunc},
+		),
+	}, logger, file, repo)
+	migrations, err := serv.NewMigrations(ctx)
+
+	require.NoError(t, err)
+	// both are older than the applied 200103_120000_applied_backfill
+	require.Equal(t, model.Migrations{
+		{Version: "200101_120000_create_users", OutOfOrder: true},
+		{Version: "200102_120000_backfill", OutOfOrder: true},
+	}, migrations)
+}
+
+func TestMigration_NewMigrations_MarksOutOfOrder_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().Migrations(ctx, 100000).Return(entity.Migrations{
+		{Version: "200102_120000_orders"},
+		{Version: "200101_120000_users"},
+		{Version: "000000_000000_base"},
+	}, nil)
+	file.EXPECT().Glob("/migrations/*.up.sql").Return([]string{
+		"/migrations/200101_120000_users.up.sql",
+		"/migrations/200101_130000_merged_branch.up.sql",
+		"/migrations/200102_120000_orders.up.sql",
+		"/migrations/200103_120000_payments.up.sql",
+	}, nil)
+
+	serv := NewMigration(&Options{Directory: "/migrations"}, NewMockLogger(t), file, repo)
+	migrations, err := serv.NewMigrations(ctx)
+
+	require.NoError(t, err)
+	require.Equal(t, model.Migrations{
+		{Version: "200101_130000_merged_branch", OutOfOrder: true},
+		{Version: "200103_120000_payments"},
+	}, migrations)
+}
+
+func TestMigration_ApplyFile_OutOfOrder_RecordsIt(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_130000_merged_branch"
+
+	repo.EXPECT().InsertMigration(ctx, mock.MatchedBy(func(m *entity.Migration) bool {
+		return m.Version == version && m.OutOfOrder
+	})).Return(nil)
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{
+		GoMigrations: newGoMigrations(t, &GoMigration{Version: version, Up: noopGoMigrationFunc}),
+	}, logger, NewMockFile(t), repo)
+	err := serv.ApplyFile(ctx, &model.Migration{Version: version, OutOfOrder: true}, "", false)
+
+	require.NoError(t, err)
+}
+
+func TestMigration_NewMigrations_GoMigrationHasFile_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().Migrations(ctx, 100000).Return(entity.Migrations{{Version: "000000_000000_base"}}, nil)
+	file.EXPECT().Glob("/migrations/*.up.sql").Return([]string{"/migrations/200101_120000_backfill.up.sql"}, nil)
+
+	serv := NewMigration(&Options{
+		Directory:    "/migrations",
+		GoMigrations: newGoMigrations(t, &GoMigration{Version: "200101_120000_backfill", Up: noopGoMigrationFunc}),
+	}, logger, file, repo)
+	migrations, err := serv.NewMigrations(ctx)
+
+	require.ErrorContains(t, err, "migration 200101_120000_backfill is written in Go and also has a migration file")
+	require.Nil(t, migrations)
+}
+
+func TestMigration_ApplyFile_GoMigration_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	conn := NewMockConnection(t)
+	version := "200101_120000_backfill"
+
+	conn.EXPECT().ExecContext(ctx, "UPDATE users SET active = true").Return(nil, nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{Version: version}).Return(nil)
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{
+		Connection: conn,
+		GoMigrations: newGoMigrations(t, &GoMigration{
+			Version: version,
+			Up: func(ctx context.Context, conn Connection) error {
+				_, err := conn.ExecContext(ctx, "UPDATE users SET active = true")
+				return err
+			},
+		}),
+	}, logger, file, repo)
+	// the file name builder reports a safe file for a version without files, the registration decides
+	err := serv.ApplyFile(ctx, &model.Migration{Version: version}, "/migrations/"+version+".safe.up.sql", true)
+
+	require.NoError(t, err)
+}
+
+func TestMigration_ApplyFile_SafeGoMigration_RunsInTransaction(t *testing.T) {
+	ctx := context.Background()
+	txCtx := context.WithValue(ctx, struct{}{}, "tx")
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	conn := NewMockConnection(t)
+	version := "200101_120000_backfill"
+
+	repo.EXPECT().
+		ExecQueryTransaction(ctx, mock.AnythingOfType("func(context.Context) error")).
+		RunAndReturn(func(_ context.Context, fn func(context.Context) error) error {
+			return fn(txCtx)
+		})
+	conn.EXPECT().ExecContext(txCtx, "UPDATE users SET active = true").Return(nil, nil)
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{Version: version}).Return(nil)
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{
+		Connection: conn,
+		GoMigrations: newGoMigrations(t, &GoMigration{
+			Version: version,
+			Up: func(ctx context.Context, conn Connection) error {
+				_, err := conn.ExecContext(ctx, "UPDATE users SET active = true")
+				return err
+			},
+			Safe: true,
+		}),
+	}, logger, file, repo)
+	err := serv.ApplyFile(ctx, &model.Migration{Version: version}, "/migrations/"+version+".safe.up.sql", true)
+
+	require.NoError(t, err)
+}
+
+func TestMigration_ApplyFileWithApplyTime_SafeGoMigration_RunsInOuterTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_backfill"
+	called := false
+
+	repo.EXPECT().InsertMigrationWithApplyTime(ctx, &entity.Migration{Version: version}, int64(1700000000)).Return(nil)
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{
+		GoMigrations: newGoMigrations(t, &GoMigration{
+			Version: version,
+			Up: func(context.Context, Connection) error {
+				called = true
+				return nil
+			},
+			Safe: true,
+		}),
+	}, logger, file, repo)
+	err := serv.ApplyFileWithApplyTime(ctx, &model.Migration{Version: version}, "", 1700000000)
+
+	require.NoError(t, err)
+	require.True(t, called)
+}
+
+func TestMigration_ApplyFile_GoMigrationDryRun_SkipsBody(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_backfill"
+
+	repo.EXPECT().InsertMigration(ctx, &entity.Migration{Version: version}).Return(nil)
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > skip the Go migration body in dry run\n")
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{
+		DryRun: true,
+		GoMigrations: newGoMigrations(t, &GoMigration{
+			Version: version,
+			Up: func(context.Context, Connection) error {
+				t.Fatal("the body must not run in dry run")
+				return nil
+			},
+		}),
+	}, logger, file, repo)
+	err := serv.ApplyFile(ctx, &model.Migration{Version: version}, "", true)
+
+	require.NoError(t, err)
+}
+
+func TestMigration_ApplyFile_GoMigrationUpReturnsError_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_backfill"
+	expectedErr := errors.New("backfill failed")
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Errorf("*** failed to apply %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{
+		GoMigrations: newGoMigrations(t, &GoMigration{
+			Version: version,
+			Up: func(context.Context, Connection) error {
+				return expectedErr
+			},
+		}),
+	}, logger, file, repo)
+	err := serv.ApplyFile(ctx, &model.Migration{Version: version}, "", true)
+
+	require.ErrorIs(t, err, expectedErr)
+}
+
+func TestMigration_RevertFile_GoMigration_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	conn := NewMockConnection(t)
+	version := "200101_120000_backfill"
+
+	conn.EXPECT().ExecContext(ctx, "UPDATE users SET active = false").Return(nil, nil)
+	repo.EXPECT().RemoveMigration(ctx, version).Return(nil)
+	logger.EXPECT().Warnf("*** reverting %s\n", version)
+	logger.EXPECT().Warnf("*** reverted %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{
+		Connection: conn,
+		GoMigrations: newGoMigrations(t, &GoMigration{
+			Version: version,
+			Up:      noopGoMigrationFunc,
+			Down: func(ctx context.Context, conn Connection) error {
+				_, err := conn.ExecContext(ctx, "UPDATE users SET active = false")
+				return err
+			},
+		}),
+	}, logger, file, repo)
+	err := serv.RevertFile(ctx, &model.Migration{Version: version}, "/migrations/"+version+".safe.down.sql", true)
+
+	require.NoError(t, err)
+}
+
+func TestMigration_RevertFile_GoMigrationWithoutDown_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_backfill"
+
+	serv := NewMigration(&Options{
+		GoMigrations: newGoMigrations(t, &GoMigration{Version: version, Up: noopGoMigrationFunc}),
+	}, logger, file, repo)
+	err := serv.RevertFile(ctx, &model.Migration{Version: version}, "", true)
+
+	require.ErrorIs(t, err, ErrGoMigrationDownMissing)
+}
+
+func TestMigration_FileExists_GoMigration_Successfully(t *testing.T) {
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+
+	serv := NewMigration(&Options{
+		GoMigrations: newGoMigrations(t, &GoMigration{Version: "200101_120000_backfill", Up: noopGoMigrationFunc}),
+	}, logger, file, repo)
+	exists, err := serv.FileExists("/migrations/200101_120000_backfill.safe.down.sql")
+
+	require.NoError(t, err)
+	require.True(t, exists)
+}
+
+func TestMigration_Verify_SkipsGoMigrations_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+
+	repo.EXPECT().HasMigrationHistoryTable(ctx).Return(true, nil)
+	repo.EXPECT().UpgradeMigrationHistoryTable(ctx).Return(nil)
+	repo.EXPECT().Migrations(ctx, 100000).Return(entity.Migrations{
+		{Version: "200101_120000_backfill"},
+	}, nil)
+
+	serv := NewMigration(&Options{
+		Directory:    "/migrations",
+		GoMigrations: newGoMigrations(t, &GoMigration{Version: "200101_120000_backfill", Up: noopGoMigrationFunc}),
+	}, logger, file, repo)
+	drifts, err := serv.Verify(ctx)
+
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+}
+
+func TestMigration_ApplyFile_Resume_SkipsCommittedStatements(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_create_tables"
+	fileName := "/migrations/200101_120000_create_tables.up.sql"
+	sqlContent := "CREATE TABLE users (id INT); CREATE TABLE posts (id INT);"
+
+	file.EXPECT().Exists(fileName).Return(true, nil)
+	file.EXPECT().Open(fileName).Return(io.NopCloser(strings.NewReader(sqlContent)), nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_tables.down.sql").Return(false, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_tables.safe.down.sql").Return(false, nil)
+
+	repo.EXPECT().ExecQuery(ctx, "CREATE TABLE posts (id INT)").Return(nil)
+	repo.EXPECT().UpdateMigration(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE users (id INT);\nCREATE TABLE posts (id INT);\n",
+		Checksum:    sqlio.ChecksumOf([]byte(sqlContent)),
+	}).Return(nil)
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > skip %d statement(s) committed by the earlier run\n", 1)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "CREATE TABLE posts (id INT)")
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{Resume: true}, logger, file, repo)
+	err := serv.ApplyFile(ctx, &model.Migration{
+		Version:       version,
+		ExecutedSQL:   "CREATE TABLE users (id INT);\n",
+		Status:        model.StatusFailed,
+		LastStatement: 1,
+	}, fileName, false)
+
+	require.NoError(t, err)
+}
+
+func TestMigration_ApplyFile_Resume_ChangedStatements_Failure(t *testing.T) {
+	ctx := context.Background()
+	file := NewMockFile(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_create_tables"
+	fileName := "/migrations/200101_120000_create_tables.up.sql"
+	sqlContent := "CREATE TABLE users (id BIGINT); CREATE TABLE posts (id INT);"
+
+	file.EXPECT().Exists(fileName).Return(true, nil)
+	file.EXPECT().Open(fileName).Return(io.NopCloser(strings.NewReader(sqlContent)), nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_tables.down.sql").Return(false, nil)
+	file.EXPECT().Exists("/migrations/200101_120000_create_tables.safe.down.sql").Return(false, nil)
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+
+	serv := NewMigration(&Options{Resume: true}, logger, file, NewMockRepository(t))
+	err := serv.ApplyFile(ctx, &model.Migration{
+		Version:       version,
+		ExecutedSQL:   "CREATE TABLE users (id INT);\n",
+		Status:        model.StatusPending,
+		LastStatement: 1,
+	}, fileName, false)
+
+	require.ErrorIs(t, err, ErrResumeChanged)
+}
+
+func TestMigration_DumpSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+
+	repo.EXPECT().DumpSchema(ctx).Return("CREATE TABLE users (id INT);\n", nil)
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+	schema, err := serv.DumpSchema(ctx)
+
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE users (id INT);\n", schema)
+}
+
+func TestMigration_DumpSchema_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	expectedErr := errors.New("dump error")
+
+	repo.EXPECT().DumpSchema(ctx).Return("", expectedErr)
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+	_, err := serv.DumpSchema(ctx)
+
+	require.ErrorIs(t, err, expectedErr)
+}
+
+func TestMigration_LoadSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	expected := &ddl.Schema{Namespaces: [][]string{{"analytics"}}}
+
+	repo.EXPECT().LoadSchema(ctx).Return(expected, nil)
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+	schema, err := serv.LoadSchema(ctx)
+
+	require.NoError(t, err)
+	require.Equal(t, expected, schema)
+}
+
+func TestMigration_LoadSchema_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	expectedErr := errors.New("load error")
+
+	repo.EXPECT().LoadSchema(ctx).Return(nil, expectedErr)
+
+	serv := NewMigration(&Options{}, NewMockLogger(t), NewMockFile(t), repo)
+	_, err := serv.LoadSchema(ctx)
+
+	require.ErrorIs(t, err, expectedErr)
+}
@@ -8,6 +8,8 @@
 
 package service
 
+import "time"
+
 // Options configures the Migration service behavior.
 // It contains settings for SQL output formatting, migration file location, and database credentials.
 type Options struct {
@@ -26,4 +28,38 @@ type Options struct {
 	Username string
 	// Password is the database password used for placeholder replacement in migration SQL.
 	Password string
+	// Vars are the named variables used for placeholder replacement in migration SQL.
+	Vars map[string]string
+	// StrictPlaceholders fails a migration with placeholders that have neither a value nor a default
+	// instead of sending them to the database as they are.
+	StrictPlaceholders bool
+
+	// LockOwner identifies this process as the holder of the migration lock.
+	LockOwner string
+	// LockTimeout is how long Lock waits while another process holds the migration lock.
+	// Zero means a single attempt.
+	LockTimeout time.Duration
+	// LockStaleAfter is the age after which a migration lock held by another process is considered
+	// abandoned and is broken. Zero disables breaking stale locks.
+	LockStaleAfter time.Duration
+
+	// StatementTimeout limits the execution time of every migration statement. Zero means no limit.
+	StatementTimeout time.Duration
+	// MigrationTimeout limits the execution time of every migration. Zero means no limit.
+	MigrationTimeout time.Duration
+	// RetryAttempts is how many times a statement, or the transaction of a safe migration, is run again
+	// after it gave up waiting for a lock. Zero disables retries.
+	RetryAttempts int
+	// RetryBackoff is the pause before the first retry, doubled before every next one.
+	RetryBackoff time.Duration
+
+	// GoMigrations are the migrations written in Go, applied together with the migration files.
+	GoMigrations *GoMigrations
+	// Connection is passed to the migrations written in Go.
+	Connection Connection
+	// Resume continues a migration left pending or failed by an earlier run after its committed statements
+	// instead of running it from the start.
+	Resume bool
+	// DryRun skips the bodies of the migrations written in Go, as they cannot be previewed.
+	DryRun bool
 }
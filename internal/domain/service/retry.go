@@ -0,0 +1,45 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/repository"
+)
+
+// retry calls fn and calls it again while it fails with a lock timeout, up to RetryAttempts more times,
+// pausing for RetryBackoff before the first retry and twice as long before every next one.
+// Within a transaction, such as the one of a release, fn is not retried: the failed statement
+// aborted the whole transaction.
+func (m *Migration) retry(ctx context.Context, fn func() error) error {
+	if _, err := connection.TxFromContext(ctx); err == nil {
+		return fn()
+	}
+
+	backoff := m.options.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > m.options.RetryAttempts || !repository.IsLockTimeout(err) {
+			return err
+		}
+
+		m.logger.Warnf("    > lock timeout, retrying in %s (%d of %d)\n", backoff, attempt, m.options.RetryAttempts)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
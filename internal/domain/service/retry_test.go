@@ -0,0 +1,190 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/repository"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errLockTimeout = errors.WithStack(&repository.DBError{Code: "55P03", Message: "lock timeout", LockTimedOut: true})
+
+func TestMigration_ApplySQL_RetriesStatementOnLockTimeout_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_add_column"
+
+	repo.EXPECT().ExecQuery(ctx, "ALTER TABLE users ADD COLUMN c int").Return(errLockTimeout).Twice()
+	repo.EXPECT().ExecQuery(ctx, "ALTER TABLE users ADD COLUMN c int").Return(nil).Once()
+	repo.EXPECT().InsertMigration(ctx, mock.MatchedBy(func(record *entity.Migration) bool {
+		return record.ExecutedSQL == "ALTER TABLE users ADD COLUMN c int;\n"
+	})).Return(nil)
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything).Times(3)
+	logger.EXPECT().Warnf("    > lock timeout, retrying in %s (%d of %d)\n", time.Millisecond, 1, 2).Once()
+	logger.EXPECT().Warnf("    > lock timeout, retrying in %s (%d of %d)\n", 2*time.Millisecond, 2, 2).Once()
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{RetryAttempts: 2, RetryBackoff: time.Millisecond}, logger, NewMockFile(t), repo)
+	err := serv.ApplySQL(ctx, false, version, "ALTER TABLE users ADD COLUMN c int;")
+
+	require.NoError(t, err)
+}
+
+func TestMigration_ApplySQL_RetriesExhausted_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_add_column"
+
+	repo.EXPECT().ExecQuery(ctx, "ALTER TABLE users ADD COLUMN c int").Return(errLockTimeout).Twice()
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything).Times(2)
+	logger.EXPECT().Warnf("    > lock timeout, retrying in %s (%d of %d)\n", time.Millisecond, 1, 1).Once()
+	logger.EXPECT().Errorf("*** failed to apply %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{RetryAttempts: 1, RetryBackoff: time.Millisecond}, logger, NewMockFile(t), repo)
+	err := serv.ApplySQL(ctx, false, version, "ALTER TABLE users ADD COLUMN c int;")
+
+	require.ErrorIs(t, err, errLockTimeout)
+}
+
+func TestMigration_ApplySQL_OtherErrorsAreNotRetried_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_add_column"
+	syntaxErr := &repository.DBError{Code: "42601", Message: "syntax error"}
+
+	repo.EXPECT().ExecQuery(ctx, "ALTER TABLE users ADD COLUMN").Return(syntaxErr).Once()
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything).Once()
+	logger.EXPECT().Errorf("*** failed to apply %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{RetryAttempts: 3, RetryBackoff: time.Millisecond}, logger, NewMockFile(t), repo)
+	err := serv.ApplySQL(ctx, false, version, "ALTER TABLE users ADD COLUMN;")
+
+	require.ErrorIs(t, err, syntaxErr)
+}
+
+func TestMigration_ApplySQL_RetriesTransactionOnLockTimeout_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_add_columns"
+
+	var calls []string
+	repo.EXPECT().
+		ExecQueryTransaction(ctx, mock.AnythingOfType("func(context.Context) error")).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			calls = append(calls, "BEGIN")
+			return fn(ctx)
+		}).
+		Twice()
+	failed := false
+	repo.EXPECT().ExecQuery(ctx, mock.AnythingOfType("string")).
+		RunAndReturn(func(_ context.Context, sql string, _ ...any) error {
+			calls = append(calls, sql)
+			if sql == "ALTER TABLE b ADD c int" && !failed {
+				failed = true
+				return errLockTimeout
+			}
+			return nil
+		}).
+		Times(4)
+	repo.EXPECT().InsertMigration(ctx, mock.MatchedBy(func(record *entity.Migration) bool {
+		return record.ExecutedSQL == "ALTER TABLE a ADD c int;\nALTER TABLE b ADD c int;\n"
+	})).Return(nil)
+
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything).Times(4)
+	logger.EXPECT().Warnf("    > lock timeout, retrying in %s (%d of %d)\n", time.Millisecond, 1, 1).Once()
+	logger.EXPECT().Successf("*** applied %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{RetryAttempts: 1, RetryBackoff: time.Millisecond}, logger, NewMockFile(t), repo)
+	err := serv.ApplySQL(ctx, true, version, "ALTER TABLE a ADD c int; ALTER TABLE b ADD c int;")
+
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"BEGIN", "ALTER TABLE a ADD c int", "ALTER TABLE b ADD c int",
+		"BEGIN", "ALTER TABLE a ADD c int", "ALTER TABLE b ADD c int",
+	}, calls)
+}
+
+func TestMigration_Retry_WithinTransaction_NotRetried(t *testing.T) {
+	ctx := connection.ContextWithTx(context.Background(), sqlex.NewTx(nil))
+	serv := NewMigration(&Options{RetryAttempts: 3, RetryBackoff: time.Millisecond}, NewMockLogger(t), NewMockFile(t),
+		NewMockRepository(t))
+
+	calls := 0
+	err := serv.retry(ctx, func() error {
+		calls++
+		return errLockTimeout
+	})
+
+	require.ErrorIs(t, err, errLockTimeout)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMigration_ExecQuery_StatementTimeout(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+
+	repo.EXPECT().
+		ExecQuery(mock.Anything, "SELECT pg_sleep(10)").
+		RunAndReturn(func(ctx context.Context, _ string, _ ...any) error {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+			return nil
+		}).
+		Once()
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", "SELECT pg_sleep(10)")
+
+	serv := NewMigration(&Options{StatementTimeout: time.Minute}, logger, NewMockFile(t), repo)
+	require.NoError(t, serv.ExecQuery(ctx, "SELECT pg_sleep(10)"))
+}
+
+func TestMigration_ApplySQL_MigrationTimeout_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository(t)
+	logger := NewMockLogger(t)
+	version := "200101_120000_slow"
+
+	repo.EXPECT().
+		ExecQuery(mock.Anything, "SELECT pg_sleep(10)").
+		RunAndReturn(func(ctx context.Context, _ string, _ ...any) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).
+		Once()
+	logger.EXPECT().Warnf("*** applying %s\n", version)
+	logger.EXPECT().Infof("    > execute SQL: %s ...\n", mock.Anything)
+	logger.EXPECT().Errorf("*** failed to apply %s (time: %.3fs)\n", version, mock.AnythingOfType("float64"))
+
+	serv := NewMigration(&Options{MigrationTimeout: 10 * time.Millisecond}, logger, NewMockFile(t), repo)
+	err := serv.ApplySQL(ctx, false, version, "SELECT pg_sleep(10); SELECT 1;")
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
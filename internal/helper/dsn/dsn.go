@@ -8,7 +8,10 @@
 
 package dsn
 
-import "net/url"
+import (
+	"net/url"
+	"strings"
+)
 
 // Host represents a single host endpoint in a DSN.
 type Host struct {
@@ -19,9 +22,9 @@ type Host struct {
 
 // DSN represents a parsed Data Source Name with support for multiple hosts (cluster mode).
 type DSN struct {
-	Driver   string     // database driver: clickhouse, postgres, mysql, tarantool
+	Driver   string     // database driver: clickhouse, postgres, mysql, tarantool, iceberg, sqlite, sqlserver, cockroachdb, yugabyte
 	Hosts    []Host     // list of hosts (supports cluster mode with multiple hosts)
-	Database string     // database name
+	Database string     // database name, the path to the database file for sqlite
 	Username string     // authentication username
 	Password string     // authentication password
 	Options  url.Values // query parameters
@@ -44,6 +47,24 @@ func (d *DSN) PrimaryAddress() string {
 	return d.Primary().Address
 }
 
+// ForHost returns the raw DSN with its list of hosts replaced with the address of host alone,
+// keeping the credentials, the database and the options.
+func (d *DSN) ForHost(host Host) string {
+	driver, rest, _ := strings.Cut(d.Raw, "://")
+
+	credentials, rest := splitCredentials(rest)
+	if credentials != "" {
+		credentials += "@"
+	}
+
+	var tail string
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		tail = rest[i:]
+	}
+
+	return driver + "://" + credentials + host.Address + tail
+}
+
 // IsCluster returns true if DSN contains multiple hosts.
 func (d *DSN) IsCluster() bool {
 	return len(d.Hosts) > 1
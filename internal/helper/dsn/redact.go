@@ -60,10 +60,8 @@ func redactStructured(rawDSN string) (string, bool) {
 	rest := rawDSN[schemeEnd+3:]   // "user:pass@host/db?params"
 
 	// Mask password in userinfo (user:PASS@...).
-	atIdx := strings.Index(rest, "@")
-	if atIdx != -1 {
-		userinfo := rest[:atIdx]
-		afterAt := rest[atIdx:] // includes "@"
+	if userinfo, hostPart := splitCredentials(rest); userinfo != "" {
+		afterAt := "@" + hostPart
 
 		colonIdx := strings.Index(userinfo, ":")
 		if colonIdx != -1 && colonIdx < len(userinfo)-1 {
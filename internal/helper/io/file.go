@@ -11,6 +11,7 @@ package iohelp
 import (
 	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 )
@@ -57,6 +58,11 @@ func (f *File) ReadAll(filename string) ([]byte, error) {
 	return io.ReadAll(ff)
 }
 
+// Glob returns the names of all files matching pattern, as filepath.Glob does.
+func (f *File) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
 // Create creates a new file with the specified name.
 // If the file already exists, it will be truncated.
 func (f *File) Create(filename string) error {
@@ -71,3 +77,13 @@ func (f *File) Create(filename string) error {
 
 	return nil
 }
+
+// WriteFile writes data to the named file, creating it if necessary and truncating it otherwise.
+func (f *File) WriteFile(filename string, data []byte) error {
+	//nolint:gosec // the file is a schema dump to be committed along with the migrations
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return errors.Wrapf(err, "writing file %s", filename)
+	}
+
+	return nil
+}
@@ -163,3 +163,36 @@ func TestFile_Create_TruncatesExistingFile(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, data)
 }
+
+func TestFile_Glob_Success(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFile()
+	require.NoError(t, f.Create(filepath.Join(dir, "200101_120000_init.up.sql")))
+	require.NoError(t, f.Create(filepath.Join(dir, "200101_120000_init.down.sql")))
+
+	files, err := f.Glob(filepath.Join(dir, "*.up.sql"))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "200101_120000_init.up.sql")}, files)
+}
+
+func TestFile_WriteFile_ReplacesContent(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "schema.sql")
+	require.NoError(t, os.WriteFile(filename, []byte("original content"), 0o600))
+
+	f := NewFile()
+	err := f.WriteFile(filename, []byte("CREATE TABLE a (id int);\n"))
+
+	require.NoError(t, err)
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE a (id int);\n", string(content))
+}
+
+func TestFile_WriteFile_InvalidPath(t *testing.T) {
+	f := NewFile()
+	err := f.WriteFile("/nonexistent/directory/schema.sql", nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "writing file")
+}
@@ -0,0 +1,64 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package iohelp
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// FS provides the read operations of File over an fs.FS, such as an embed.FS.
+// File names are paths within the file system; OS path separators are converted to slashes.
+type FS struct {
+	fsys fs.FS
+}
+
+// NewFS creates a new FS instance reading from fsys.
+func NewFS(fsys fs.FS) *FS {
+	return &FS{fsys: fsys}
+}
+
+// Exists checks whether a file exists at the specified path.
+// It returns true if the file exists, false if it does not exist, and an error for other failures.
+func (f *FS) Exists(fileName string) (bool, error) {
+	if _, err := fs.Stat(f.fsys, fsPath(fileName)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Open opens the named file for reading.
+// It returns an io.ReadCloser for the file or an error if the operation fails.
+func (f *FS) Open(filename string) (io.ReadCloser, error) {
+	return f.fsys.Open(fsPath(filename))
+}
+
+// ReadAll reads the entire contents of the named file.
+// It returns the file contents as a byte slice or an error if the operation fails.
+func (f *FS) ReadAll(filename string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, fsPath(filename))
+}
+
+// Glob returns the names of all files matching pattern, as fs.Glob does.
+func (f *FS) Glob(pattern string) ([]string, error) {
+	return fs.Glob(f.fsys, fsPath(pattern))
+}
+
+// fsPath converts an OS path into a path within the file system.
+func fsPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
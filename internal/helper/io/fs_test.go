@@ -0,0 +1,65 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package iohelp
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFS() *FS {
+	return NewFS(fstest.MapFS{
+		"migrations/200101_120000_init.up.sql":   {Data: []byte("CREATE TABLE t (id int);")},
+		"migrations/200101_120000_init.down.sql": {Data: []byte("DROP TABLE t;")},
+	})
+}
+
+func TestFS_Exists(t *testing.T) {
+	f := newTestFS()
+
+	exists, err := f.Exists("migrations/200101_120000_init.up.sql")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = f.Exists("./migrations/200101_120000_init.up.sql")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = f.Exists("migrations/200101_120000_init.safe.up.sql")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestFS_Open_Success(t *testing.T) {
+	reader, err := newTestFS().Open("migrations/200101_120000_init.up.sql")
+	require.NoError(t, err)
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE t (id int);", string(data))
+}
+
+func TestFS_ReadAll_FileNotFound(t *testing.T) {
+	data, err := newTestFS().ReadAll("migrations/missing.up.sql")
+
+	require.Error(t, err)
+	assert.Nil(t, data)
+}
+
+func TestFS_Glob_Success(t *testing.T) {
+	files, err := newTestFS().Glob("migrations/*.up.sql")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"migrations/200101_120000_init.up.sql"}, files)
+}
@@ -0,0 +1,96 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package placeholder
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnresolved is returned in strict mode for placeholders that have neither a value nor a default.
+var ErrUnresolved = errors.New("unresolved placeholders")
+
+const envPrefix = "env:"
+
+// regexpPlaceholder matches {name}, {name|default}, {env:NAME} and {env:NAME|default},
+// or any of them in double braces, which is an escaped literal placeholder.
+var regexpPlaceholder = regexp.MustCompile(
+	`\{\{(?:env:)?[A-Za-z_]\w*(?:\|[^{}]*)?\}\}|\{((?:env:)?[A-Za-z_]\w*)(?:(\|)([^{}]*))?\}`,
+)
+
+// Template substitutes the placeholders of migration SQL with variables and environment variables.
+type Template struct {
+	vars      map[string]string
+	strict    bool
+	lookupEnv func(key string) (string, bool)
+}
+
+// New creates a new Template substituting the placeholders with vars.
+// In strict mode Execute fails on unresolved placeholders, otherwise it leaves them as they are.
+func New(vars map[string]string, strict bool) *Template {
+	return &Template{
+		vars:      vars,
+		strict:    strict,
+		lookupEnv: os.LookupEnv,
+	}
+}
+
+// Execute returns sql with its placeholders substituted.
+func (t *Template) Execute(sql string) (string, error) {
+	matches := regexpPlaceholder.FindAllStringSubmatchIndex(sql, -1)
+	if len(matches) == 0 {
+		return sql, nil
+	}
+
+	var (
+		result     strings.Builder
+		unresolved []string
+		last       int
+	)
+	for _, match := range matches {
+		result.WriteString(sql[last:match[0]])
+		last = match[1]
+
+		placeholder := sql[match[0]:match[1]]
+		if match[2] < 0 {
+			// escaped literal placeholder
+			result.WriteString(placeholder[1 : len(placeholder)-1])
+			continue
+		}
+
+		value, ok := t.lookup(sql[match[2]:match[3]])
+		if !ok && match[4] >= 0 {
+			value, ok = sql[match[6]:match[7]], true
+		}
+		if !ok {
+			unresolved = append(unresolved, placeholder)
+			value = placeholder
+		}
+		result.WriteString(value)
+	}
+	result.WriteString(sql[last:])
+
+	if t.strict && len(unresolved) > 0 {
+		return "", errors.Wrap(ErrUnresolved, strings.Join(unresolved, ", "))
+	}
+
+	return result.String(), nil
+}
+
+func (t *Template) lookup(name string) (string, bool) {
+	if key, ok := strings.CutPrefix(name, envPrefix); ok {
+		return t.lookupEnv(key)
+	}
+	value, ok := t.vars[name]
+
+	return value, ok
+}
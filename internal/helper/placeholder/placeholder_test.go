@@ -0,0 +1,74 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package placeholder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTemplate(strict bool) *Template {
+	tpl := New(map[string]string{
+		"cluster": "main",
+		"tenant":  "acme",
+		"empty":   "",
+	}, strict)
+	tpl.lookupEnv = func(key string) (string, bool) {
+		env := map[string]string{"SCHEMA": "billing", "BLANK": ""}
+		value, ok := env[key]
+
+		return value, ok
+	}
+
+	return tpl
+}
+
+func TestTemplate_Execute_Successfully(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{name: "no placeholders", sql: "SELECT 1", want: "SELECT 1"},
+		{name: "variables", sql: "CREATE TABLE {tenant}.t ON CLUSTER {cluster}", want: "CREATE TABLE acme.t ON CLUSTER main"},
+		{name: "empty variable", sql: "SELECT '{empty}'", want: "SELECT ''"},
+		{name: "default of unset variable", sql: "SELECT '{region|eu}'", want: "SELECT 'eu'"},
+		{name: "default of set variable", sql: "SELECT '{tenant|other}'", want: "SELECT 'acme'"},
+		{name: "empty default", sql: "SELECT '{region|}'", want: "SELECT ''"},
+		{name: "environment variable", sql: "CREATE SCHEMA {env:SCHEMA}", want: "CREATE SCHEMA billing"},
+		{name: "empty environment variable", sql: "SELECT '{env:BLANK|x}'", want: "SELECT ''"},
+		{name: "default of unset environment variable", sql: "SELECT '{env:REGION|eu}'", want: "SELECT 'eu'"},
+		{name: "escaped placeholder", sql: "ENGINE = ReplicatedMergeTree('/t/{{shard}}', '{{replica}}')", want: "ENGINE = ReplicatedMergeTree('/t/{shard}', '{replica}')"},
+		{name: "json is not a placeholder", sql: `SELECT '{"tenant": 1}'`, want: `SELECT '{"tenant": 1}'`},
+		{name: "query parameter is not a placeholder", sql: "SELECT {id:UInt64}", want: "SELECT {id:UInt64}"},
+		{name: "unresolved placeholders are kept", sql: "SELECT '{shard}', '{env:REGION}'", want: "SELECT '{shard}', '{env:REGION}'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestTemplate(false).Execute(tt.sql)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTemplate_Execute_Strict_Successfully(t *testing.T) {
+	got, err := newTestTemplate(true).Execute("CREATE TABLE {tenant}.t_{region|eu} ON CLUSTER '{{cluster}}'")
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE acme.t_eu ON CLUSTER '{cluster}'", got)
+}
+
+func TestTemplate_Execute_Strict_Unresolved_Failure(t *testing.T) {
+	_, err := newTestTemplate(true).Execute("SELECT '{shard}', '{env:REGION}', '{tenant}'")
+	require.ErrorIs(t, err, ErrUnresolved)
+	assert.Contains(t, err.Error(), "{shard}, {env:REGION}")
+	assert.NotContains(t, err.Error(), "{tenant}")
+}
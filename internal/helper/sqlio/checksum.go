@@ -0,0 +1,52 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package sqlio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+)
+
+var carriageReturn = []byte("\r")
+
+// Checksum computes the SHA-256 checksum of a migration file normalized to LF line endings,
+// so the same file checked out with CRLF line endings has the same checksum.
+// It implements io.Writer and can be fed with io.TeeReader while the file is scanned.
+type Checksum struct {
+	hash hash.Hash
+}
+
+// NewChecksum creates a new empty Checksum.
+func NewChecksum() *Checksum {
+	return &Checksum{hash: sha256.New()}
+}
+
+// Write adds p to the checksum, skipping carriage return characters.
+func (c *Checksum) Write(p []byte) (int, error) {
+	if _, err := c.hash.Write(bytes.ReplaceAll(p, carriageReturn, nil)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Sum returns the hex encoded checksum of the data written so far.
+func (c *Checksum) Sum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
+
+// ChecksumOf returns the hex encoded checksum of data.
+func ChecksumOf(data []byte) string {
+	c := NewChecksum()
+	_, _ = c.Write(data)
+
+	return c.Sum()
+}
@@ -0,0 +1,47 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package sqlio
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumOf_Successfully(t *testing.T) {
+	got := ChecksumOf([]byte("CREATE TABLE users (id INT);\n"))
+
+	assert.Equal(t, "58e7702b20f3e39e3a58072997bbd6307b96a7ed91068ac2eed59f748f1ad7bf", got)
+	assert.NotEqual(t, got, ChecksumOf([]byte("CREATE TABLE users (id BIGINT);\n")))
+}
+
+func TestChecksumOf_IgnoresCarriageReturns_Successfully(t *testing.T) {
+	lf := ChecksumOf([]byte("CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n"))
+	crlf := ChecksumOf([]byte("CREATE TABLE a (id INT);\r\nCREATE TABLE b (id INT);\r\n"))
+
+	assert.Equal(t, lf, crlf)
+}
+
+func TestChecksum_TeeReaderWhileScanning_MatchesChecksumOf(t *testing.T) {
+	body := "CREATE TABLE a (id INT);\r\n\r\nCREATE TABLE b (id INT);\r\n\r\n"
+	checksum := NewChecksum()
+	scanner := NewScanner(io.TeeReader(strings.NewReader(body), checksum), DialectDefault)
+
+	var statements []string
+	for scanner.Scan() {
+		statements = append(statements, scanner.SQL())
+	}
+	require.NoError(t, scanner.Err())
+
+	assert.Len(t, statements, 2)
+	assert.Equal(t, ChecksumOf([]byte(body)), checksum.Sum())
+}
@@ -0,0 +1,589 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package sqlio
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnterminated occurs when a migration ends inside a quoted string, a comment or a dollar-quoted body.
+	ErrUnterminated = errors.New("unterminated token")
+	// ErrDelimiterMissing occurs when a DELIMITER command of a MySQL migration is not followed by a delimiter.
+	ErrDelimiterMissing = errors.New("DELIMITER must be followed by a delimiter")
+)
+
+// defaultDelimiter ends the statements of every dialect, unless a MySQL migration changes it with DELIMITER.
+const defaultDelimiter = ";"
+
+// delimiterCommand is the client-side command of the mysql CLI changing the statement delimiter.
+const delimiterCommand = "DELIMITER"
+
+// batchSeparator is the client-side command of sqlcmd ending a batch of SQL Server statements.
+const batchSeparator = "GO"
+
+// Dialect is the language of the migrations of a database. It defines the quotes, comments and blocks
+// the scanner steps over while looking for the `;` ending a statement.
+type Dialect string
+
+const (
+	// DialectDefault splits standard SQL: quoted strings and identifiers, -- and /* */ comments
+	// and $$ or $tag$ quoted bodies.
+	DialectDefault Dialect = ""
+	// DialectPostgres splits PostgreSQL: E'...' strings, nested /* */ comments, $tag$ quoted bodies
+	// and BEGIN ATOMIC ... END function bodies in addition to the standard SQL.
+	DialectPostgres Dialect = "postgres"
+	// DialectMySQL splits MySQL: backslash escapes, `identifiers`, "-- " and # comments,
+	// and honours the DELIMITER command of the mysql CLI.
+	DialectMySQL Dialect = "mysql"
+	// DialectClickhouse splits ClickHouse: backslash escapes, `identifiers`, # comments and $heredoc$ strings.
+	DialectClickhouse Dialect = "clickhouse"
+	// DialectTarantool splits Tarantool Lua chunks: Lua strings and comments, long brackets,
+	// and function, if, do and repeat blocks whose statements may end with `;`.
+	DialectTarantool Dialect = "tarantool"
+	// DialectSQLite splits SQLite: `identifiers`, [identifiers] and CREATE TRIGGER ... BEGIN ... END bodies.
+	DialectSQLite Dialect = "sqlite"
+	// DialectMSSQL splits SQL Server batches: the statements up to a GO line are one batch, as sqlcmd sends them,
+	// with [identifiers] and nested /* */ comments. GO n sends the batch n times.
+	DialectMSSQL Dialect = "sqlserver"
+)
+
+// lexRules are the tokens of a dialect the scanner must step over as a whole.
+type lexRules struct {
+	// backslashEscapes means a backslash escapes the next character in quoted strings.
+	backslashEscapes bool
+	// escapeStrings means E'...' strings with backslash escapes.
+	escapeStrings bool
+	// backticks means `quoted identifiers`.
+	backticks bool
+	// dollarQuotes means $$ and $tag$ quoted bodies.
+	dollarQuotes bool
+	// hashComments means # comments up to the end of the line.
+	hashComments bool
+	// dashCommentNeedsSpace means -- starts a comment only when followed by a whitespace.
+	dashCommentNeedsSpace bool
+	// nestedComments means /* */ comments may be nested.
+	nestedComments bool
+	// atomicBlocks means BEGIN ATOMIC ... END bodies whose statements end with `;`.
+	atomicBlocks bool
+	// triggerBlocks means BEGIN ... END bodies of CREATE TRIGGER statements whose statements end with `;`.
+	triggerBlocks bool
+	// brackets means [quoted identifiers].
+	brackets bool
+	// delimiterCommand means DELIMITER lines changing the delimiter of the following statements.
+	delimiterCommand bool
+	// batchSeparator means GO lines end the statements instead of the delimiter.
+	batchSeparator bool
+	// lua means Lua strings, comments and blocks instead of SQL ones.
+	lua bool
+}
+
+//nolint:gochecknoglobals // read-only lookup table
+var dialectRules = map[Dialect]*lexRules{
+	DialectDefault: {
+		dollarQuotes: true,
+	},
+	DialectPostgres: {
+		escapeStrings:  true,
+		dollarQuotes:   true,
+		nestedComments: true,
+		atomicBlocks:   true,
+	},
+	DialectMySQL: {
+		backslashEscapes:      true,
+		backticks:             true,
+		hashComments:          true,
+		dashCommentNeedsSpace: true,
+		delimiterCommand:      true,
+	},
+	DialectClickhouse: {
+		backslashEscapes: true,
+		backticks:        true,
+		dollarQuotes:     true,
+		hashComments:     true,
+	},
+	DialectTarantool: {
+		backslashEscapes: true,
+		lua:              true,
+	},
+	DialectSQLite: {
+		backticks:     true,
+		brackets:      true,
+		triggerBlocks: true,
+	},
+	DialectMSSQL: {
+		brackets:       true,
+		nestedComments: true,
+		batchSeparator: true,
+	},
+}
+
+// rulesOf returns the rules of the dialect, the default ones for an unknown dialect.
+func rulesOf(dialect Dialect) *lexRules {
+	if rules, ok := dialectRules[dialect]; ok {
+		return rules
+	}
+
+	return dialectRules[DialectDefault]
+}
+
+// statement is the first statement found in the buffered part of a migration.
+type statement struct {
+	// end is the offset just past the delimiter ending the statement,
+	// -1 when the buffered data holds no complete statement yet.
+	end int
+	// bodyEnd is the offset of the delimiter ending the statement.
+	bodyEnd int
+	// content means the statement has more than whitespace and comments.
+	content bool
+	// delimiter is the delimiter set by a DELIMITER command, which is not a statement itself.
+	delimiter string
+	// repeat is the number of times the statement is executed, set by the count of a GO line.
+	repeat int
+}
+
+// lexer finds the end of the first statement in the buffered part of a migration.
+type lexer struct {
+	rules     *lexRules
+	delimiter []byte
+	data      []byte
+	atEOF     bool
+	pos       int
+	// depth is the nesting of the blocks whose statements end with `;`.
+	depth int
+	// afterBegin means the previous word is BEGIN, which starts a block when followed by ATOMIC.
+	afterBegin bool
+	// trigger means the statement creates a trigger, whose body starts with BEGIN.
+	trigger bool
+	// content means the statement has more than whitespace and comments.
+	content bool
+	// newDelimiter is the delimiter set by the DELIMITER command just read.
+	newDelimiter string
+}
+
+// statementEnd finds the delimiter ending the first statement of data.
+// At EOF a migration ending inside a quoted string or comment is an error.
+func statementEnd(rules *lexRules, delimiter string, data []byte, atEOF bool) (statement, error) {
+	l := &lexer{rules: rules, delimiter: []byte(delimiter), data: data, atEOF: atEOF}
+	for l.pos < len(l.data) {
+		if rules.batchSeparator {
+			end, repeat, token := l.batchSeparatorAt()
+			switch {
+			case token != "" && !atEOF:
+				return statement{end: -1}, nil
+			case end > 0:
+				return statement{end: end, bodyEnd: l.pos, content: l.content, repeat: repeat}, nil
+			}
+		} else if l.depth == 0 {
+			rest := l.data[l.pos:]
+			if bytes.HasPrefix(rest, l.delimiter) {
+				return statement{end: l.pos + len(l.delimiter), bodyEnd: l.pos, content: l.content}, nil
+			}
+			if !atEOF && len(rest) < len(l.delimiter) && bytes.HasPrefix(l.delimiter, rest) {
+				return statement{end: -1}, nil
+			}
+		}
+		token := l.skipToken()
+		switch {
+		case token == "" && l.newDelimiter != "":
+			return statement{end: l.pos, delimiter: l.newDelimiter}, nil
+		case token == "":
+			continue
+		case token == delimiterCommand && atEOF:
+			return statement{}, errors.WithStack(ErrDelimiterMissing)
+		case atEOF:
+			return statement{}, errors.Wrap(ErrUnterminated, token)
+		default:
+			return statement{end: -1}, nil
+		}
+	}
+
+	return statement{end: -1, content: l.content}, nil
+}
+
+// skipToken advances over the token at the current position.
+// It returns the name of the token when it runs past the buffered data.
+func (l *lexer) skipToken() string {
+	c := l.data[l.pos]
+	switch {
+	case isSpace(c):
+		l.pos++
+		return ""
+	case c == '-' && l.peek(1) == '-':
+		return l.skipDashComment()
+	case c == '/' && l.peek(1) == '*' && !l.rules.lua:
+		return l.skipBlockComment()
+	case c == '#' && l.rules.hashComments:
+		return l.skipLineComment(l.pos + 1)
+	case l.rules.delimiterCommand && !l.content && isWordStart(c):
+		if token, ok := l.skipDelimiterCommand(); ok {
+			return token
+		}
+	}
+
+	l.content = true
+	switch {
+	case c == '\'' || c == '"':
+		return l.skipQuoted(l.pos+1, c, l.rules.backslashEscapes)
+	case c == '`' && l.rules.backticks:
+		return l.skipQuoted(l.pos+1, c, l.rules.backslashEscapes)
+	case c == '[' && l.rules.brackets:
+		return l.skipQuoted(l.pos+1, ']', false)
+	case c == '$' && l.rules.dollarQuotes:
+		return l.skipDollarQuoted()
+	case c == '[' && l.rules.lua:
+		return l.skipLongString()
+	case (c == '{' || c == '}') && l.rules.lua:
+		// fields of Lua table constructors may be separated with `;` too
+		l.luaBlock(string(c))
+		l.pos++
+		return ""
+	case isWordStart(c):
+		return l.skipWord()
+	case l.needsMore(c):
+		return "token"
+	default:
+		l.pos++
+		return ""
+	}
+}
+
+// needsMore reports whether c may start a two-character token which is cut at the end of the buffered data.
+func (l *lexer) needsMore(c byte) bool {
+	return !l.atEOF && l.pos+1 == len(l.data) && (c == '-' || c == '/')
+}
+
+// peek returns the byte at the offset from the current position, or 0 past the buffered data.
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset < len(l.data) {
+		return l.data[l.pos+offset]
+	}
+
+	return 0
+}
+
+func (l *lexer) skipDashComment() string {
+	if l.rules.lua && l.peek(2) == '[' {
+		closing, incomplete := l.longBracketAt(l.pos + 2)
+		if incomplete {
+			return "comment"
+		}
+		if closing != "" {
+			return l.skipTo(l.pos+2+len(closing), closing, "long comment")
+		}
+	}
+	if l.rules.dashCommentNeedsSpace {
+		next := l.peek(2)
+		if next == 0 && !l.atEOF {
+			return "comment"
+		}
+		if next != 0 && !isSpace(next) {
+			l.content = true
+			l.pos += 2
+			return ""
+		}
+	}
+
+	return l.skipLineComment(l.pos + 2)
+}
+
+// skipLineComment advances past the end of the line; a comment on the last line ends at EOF.
+func (l *lexer) skipLineComment(from int) string {
+	i := bytes.IndexByte(l.data[from:], '\n')
+	if i < 0 {
+		if !l.atEOF {
+			return "comment"
+		}
+		l.pos = len(l.data)
+
+		return ""
+	}
+	l.pos = from + i + 1
+
+	return ""
+}
+
+func (l *lexer) skipBlockComment() string {
+	depth := 0
+	for i := l.pos; i+1 < len(l.data); i++ {
+		switch {
+		case l.data[i] == '/' && l.data[i+1] == '*':
+			if depth == 0 || l.rules.nestedComments {
+				depth++
+			}
+			i++
+		case l.data[i] == '*' && l.data[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				l.pos = i + 1
+				return ""
+			}
+		}
+	}
+
+	return "comment /*"
+}
+
+// skipQuoted advances past the closing quote of a string or identifier opened before from.
+func (l *lexer) skipQuoted(from int, quote byte, backslashEscapes bool) string {
+	for i := from; i < len(l.data); i++ {
+		switch l.data[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			l.pos = i + 1
+			return ""
+		}
+	}
+
+	return "quoted string " + string(quote)
+}
+
+// skipDollarQuoted advances past a $tag$ ... $tag$ body; a $ not starting a tag, such as $1, is skipped alone.
+func (l *lexer) skipDollarQuoted() string {
+	i := l.pos + 1
+	for i < len(l.data) && isWordChar(l.data[i]) && l.data[i] != '$' {
+		i++
+	}
+	switch {
+	case i == len(l.data) && !l.atEOF:
+		return "dollar quote"
+	case i == len(l.data), l.data[i] != '$', i > l.pos+1 && isDigit(l.data[l.pos+1]):
+		l.pos++
+		return ""
+	}
+
+	tag := string(l.data[l.pos : i+1])
+
+	return l.skipTo(i+1, tag, "dollar-quoted string "+tag)
+}
+
+// skipLongString advances past a Lua [[ ... ]] or [==[ ... ]==] string; a [ not starting one is skipped alone.
+func (l *lexer) skipLongString() string {
+	closing, incomplete := l.longBracketAt(l.pos)
+	if incomplete {
+		return "long string"
+	}
+	if closing == "" {
+		l.pos++
+		return ""
+	}
+
+	return l.skipTo(l.pos+len(closing), closing, "long string")
+}
+
+// longBracketAt returns the closing bracket of a Lua long bracket opening at from, "" when none opens there.
+// It reports whether the opening bracket is cut at the end of the buffered data.
+func (l *lexer) longBracketAt(from int) (closing string, incomplete bool) {
+	i := from + 1
+	for i < len(l.data) && l.data[i] == '=' {
+		i++
+	}
+	if i == len(l.data) {
+		return "", !l.atEOF
+	}
+	if l.data[i] != '[' {
+		return "", false
+	}
+
+	return "]" + strings.Repeat("=", i-from-1) + "]", false
+}
+
+// skipTo advances past the first closing found after from.
+func (l *lexer) skipTo(from int, closing, name string) string {
+	end := bytes.Index(l.data[from:], []byte(closing))
+	if end < 0 {
+		return name
+	}
+	l.pos = from + end + len(closing)
+
+	return ""
+}
+
+// skipDelimiterCommand reads a DELIMITER line starting a statement and sets the delimiter it names.
+// It reports false when the statement does not start with DELIMITER.
+func (l *lexer) skipDelimiterCommand() (string, bool) {
+	i := l.wordEnd()
+	if i == len(l.data) && !l.atEOF {
+		return "word", true
+	}
+	if !strings.EqualFold(string(l.data[l.pos:i]), delimiterCommand) {
+		return "", false
+	}
+	if i < len(l.data) && l.data[i] != ' ' && l.data[i] != '\t' {
+		return "", false
+	}
+
+	lineEnd := len(l.data)
+	if n := bytes.IndexByte(l.data[i:], '\n'); n >= 0 {
+		lineEnd = i + n + 1
+	} else if !l.atEOF {
+		return delimiterCommand, true
+	}
+	fields := strings.Fields(string(l.data[i:lineEnd]))
+	if len(fields) == 0 {
+		return delimiterCommand, true
+	}
+	l.newDelimiter = fields[0]
+	l.pos = lineEnd
+
+	return "", true
+}
+
+// batchSeparatorAt reads a GO line at the current position, optionally followed by a count and a -- comment.
+// It returns the offset past the end of the line and the count, or zero when there is no GO line at the position.
+// It returns the name of the token when the line is cut at the end of the buffered data.
+func (l *lexer) batchSeparatorAt() (end, repeat int, token string) {
+	if c := l.data[l.pos]; c != 'G' && c != 'g' || !l.atLineStart() {
+		return 0, 0, ""
+	}
+	i := l.pos + len(batchSeparator)
+	if i > len(l.data) {
+		return 0, 0, "word"
+	}
+	if !strings.EqualFold(string(l.data[l.pos:i]), batchSeparator) ||
+		i < len(l.data) && !isSpace(l.data[i]) && l.data[i] != '-' {
+		return 0, 0, ""
+	}
+
+	end = len(l.data)
+	if n := bytes.IndexByte(l.data[i:], '\n'); n >= 0 {
+		end = i + n + 1
+	} else if !l.atEOF {
+		return 0, 0, batchSeparator
+	}
+	line := string(l.data[i:end])
+	if n := strings.Index(line, "--"); n >= 0 {
+		line = line[:n]
+	}
+
+	switch fields := strings.Fields(line); len(fields) {
+	case 0:
+		return end, 1, ""
+	case 1:
+		if n, err := strconv.Atoi(fields[0]); err == nil && n > 0 {
+			return end, n, ""
+		}
+	}
+
+	return 0, 0, ""
+}
+
+// atLineStart reports whether only spaces and tabs precede the current position on its line.
+func (l *lexer) atLineStart() bool {
+	for i := l.pos - 1; i >= 0; i-- {
+		switch l.data[i] {
+		case '\n':
+			return true
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// wordEnd returns the offset just past the word at the current position.
+// A word stops at the delimiter, which may be glued to it as in END$$.
+func (l *lexer) wordEnd() int {
+	i := l.pos
+	for i < len(l.data) && isWordChar(l.data[i]) && !bytes.HasPrefix(l.data[i:], l.delimiter) {
+		i++
+	}
+
+	return i
+}
+
+// skipWord advances past a word and tracks the blocks it opens or closes.
+func (l *lexer) skipWord() string {
+	i := l.wordEnd()
+	if i == len(l.data) && !l.atEOF {
+		return "word"
+	}
+	word := string(l.data[l.pos:i])
+	l.pos = i
+
+	if l.rules.escapeStrings && strings.EqualFold(word, "E") && l.peek(0) == '\'' {
+		return l.skipQuoted(l.pos+1, '\'', true)
+	}
+	switch {
+	case l.rules.lua:
+		l.luaBlock(word)
+	case l.rules.atomicBlocks:
+		l.atomicBlock(word)
+	case l.rules.triggerBlocks:
+		l.triggerBlock(word)
+	}
+
+	return ""
+}
+
+// atomicBlock tracks BEGIN ATOMIC ... END bodies of SQL functions and the CASE ... END expressions within them.
+func (l *lexer) atomicBlock(word string) {
+	switch {
+	case l.afterBegin && strings.EqualFold(word, "ATOMIC"):
+		l.depth++
+	case l.depth > 0 && strings.EqualFold(word, "CASE"):
+		l.depth++
+	case l.depth > 0 && strings.EqualFold(word, "END"):
+		l.depth--
+	}
+	l.afterBegin = strings.EqualFold(word, "BEGIN")
+}
+
+// triggerBlock tracks the BEGIN ... END body of a trigger and the CASE ... END expressions within it.
+func (l *lexer) triggerBlock(word string) {
+	switch {
+	case strings.EqualFold(word, "TRIGGER"):
+		l.trigger = true
+	case l.trigger && l.depth == 0 && strings.EqualFold(word, "BEGIN"):
+		l.depth++
+	case l.depth > 0 && strings.EqualFold(word, "CASE"):
+		l.depth++
+	case l.depth > 0 && strings.EqualFold(word, "END"):
+		l.depth--
+	}
+}
+
+// luaBlock tracks the Lua blocks, as `;` ends statements within them too.
+func (l *lexer) luaBlock(word string) {
+	switch word {
+	case "function", "do", "if", "repeat", "{":
+		l.depth++
+	case "end", "until", "}":
+		if l.depth > 0 {
+			l.depth--
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// isWordChar reports whether c continues a word; PostgreSQL identifiers may contain $.
+func isWordChar(c byte) bool {
+	return isWordStart(c) || isDigit(c) || c == '$'
+}
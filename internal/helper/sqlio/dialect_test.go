@@ -0,0 +1,266 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package sqlio
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scanAll(t *testing.T, r io.Reader, dialect Dialect) ([]string, error) {
+	t.Helper()
+
+	var stmts []string
+	scanner := NewScanner(r, dialect)
+	for scanner.Scan() {
+		stmts = append(stmts, scanner.SQL())
+	}
+
+	return stmts, scanner.Err()
+}
+
+func TestScanner_Dialects(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		sql      string
+		expected []string
+	}{
+		{
+			name:     "default: quoted strings",
+			dialect:  DialectDefault,
+			sql:      "INSERT INTO t VALUES ('a;b', \"c;d\"); SELECT 1",
+			expected: []string{"INSERT INTO t VALUES ('a;b', \"c;d\")", "SELECT 1"},
+		},
+		{
+			name:     "default: comments",
+			dialect:  DialectDefault,
+			sql:      "-- one; two\nSELECT 1; /* three; */ SELECT 2;\n-- the end\n",
+			expected: []string{"-- one; two\nSELECT 1", "/* three; */ SELECT 2"},
+		},
+		{
+			name:     "default: comment-only statement",
+			dialect:  DialectDefault,
+			sql:      "SELECT 1; -- nothing\n; SELECT 2",
+			expected: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:    "postgres: tagged dollar quotes",
+			dialect: DialectPostgres,
+			sql: "CREATE FUNCTION f() RETURNS text AS $body$ SELECT 'a;' || $x$;$x$; $body$ LANGUAGE sql;\n" +
+				"SELECT 2",
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS text AS $body$ SELECT 'a;' || $x$;$x$; $body$ LANGUAGE sql",
+				"SELECT 2",
+			},
+		},
+		{
+			name:     "postgres: positional parameters are not dollar quotes",
+			dialect:  DialectPostgres,
+			sql:      "PREPARE p AS SELECT $1; SELECT a$b$ FROM t; SELECT 3",
+			expected: []string{"PREPARE p AS SELECT $1", "SELECT a$b$ FROM t", "SELECT 3"},
+		},
+		{
+			name:     "postgres: escape strings",
+			dialect:  DialectPostgres,
+			sql:      `INSERT INTO t VALUES (E'it\'s; ok', 'a\'); SELECT 2`,
+			expected: []string{`INSERT INTO t VALUES (E'it\'s; ok', 'a\')`, "SELECT 2"},
+		},
+		{
+			name:     "postgres: quoted identifiers",
+			dialect:  DialectPostgres,
+			sql:      `CREATE TABLE "a;b" (id int); SELECT 1`,
+			expected: []string{`CREATE TABLE "a;b" (id int)`, "SELECT 1"},
+		},
+		{
+			name:     "postgres: nested comments",
+			dialect:  DialectPostgres,
+			sql:      "/* a; /* b; */ c; */ SELECT 1; SELECT 2",
+			expected: []string{"/* a; /* b; */ c; */ SELECT 1", "SELECT 2"},
+		},
+		{
+			name:    "postgres: begin atomic",
+			dialect: DialectPostgres,
+			sql: "CREATE FUNCTION f(a int) RETURNS int LANGUAGE sql\n" +
+				"BEGIN ATOMIC\n  SELECT CASE WHEN a > 0 THEN 1 ELSE 0 END;\n  SELECT 2;\nEND;\nBEGIN; SELECT 3; COMMIT",
+			expected: []string{
+				"CREATE FUNCTION f(a int) RETURNS int LANGUAGE sql\n" +
+					"BEGIN ATOMIC\n  SELECT CASE WHEN a > 0 THEN 1 ELSE 0 END;\n  SELECT 2;\nEND",
+				"BEGIN",
+				"SELECT 3",
+				"COMMIT",
+			},
+		},
+		{
+			name:     "mysql: backslash escapes and backticks",
+			dialect:  DialectMySQL,
+			sql:      "INSERT INTO `a;b` VALUES ('it\\'s; ok', \"c\\\";d\"); SELECT 1",
+			expected: []string{"INSERT INTO `a;b` VALUES ('it\\'s; ok', \"c\\\";d\")", "SELECT 1"},
+		},
+		{
+			name:     "mysql: comments",
+			dialect:  DialectMySQL,
+			sql:      "# one; two\nSELECT 1; -- three; four\nSELECT 5--1; SELECT 6",
+			expected: []string{"# one; two\nSELECT 1", "-- three; four\nSELECT 5--1", "SELECT 6"},
+		},
+		{
+			name:    "mysql: delimiter command",
+			dialect: DialectMySQL,
+			sql: "DELIMITER $$\n" +
+				"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW\nBEGIN\n  SET NEW.x = 1;\n  SET NEW.y = 'a$$b';\nEND$$\n" +
+				"DELIMITER ;\n" +
+				"INSERT INTO a VALUES (1);\n" +
+				"delimiter //\n" +
+				"CREATE PROCEDURE p() BEGIN SELECT 1; END //\n" +
+				"SELECT 2//",
+			expected: []string{
+				"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW\nBEGIN\n  SET NEW.x = 1;\n  SET NEW.y = 'a$$b';\nEND",
+				"INSERT INTO a VALUES (1)",
+				"CREATE PROCEDURE p() BEGIN SELECT 1; END",
+				"SELECT 2",
+			},
+		},
+		{
+			name:     "postgres: no delimiter command",
+			dialect:  DialectPostgres,
+			sql:      "DELIMITER //\nSELECT 1; SELECT 2",
+			expected: []string{"DELIMITER //\nSELECT 1", "SELECT 2"},
+		},
+		{
+			name:     "clickhouse: escapes and heredocs",
+			dialect:  DialectClickhouse,
+			sql:      "INSERT INTO t VALUES ('a\\';b', $$c;d$$); # e;\nSELECT 1",
+			expected: []string{"INSERT INTO t VALUES ('a\\';b', $$c;d$$)", "# e;\nSELECT 1"},
+		},
+		{
+			name:    "sqlite: trigger body",
+			dialect: DialectSQLite,
+			sql: "CREATE TRIGGER t AFTER INSERT ON a FOR EACH ROW\nBEGIN\n" +
+				"  UPDATE b SET x = CASE WHEN NEW.x > 0 THEN 1 ELSE 0 END;\n  DELETE FROM c;\nEND;\n" +
+				"BEGIN; SELECT 1; END",
+			expected: []string{
+				"CREATE TRIGGER t AFTER INSERT ON a FOR EACH ROW\nBEGIN\n" +
+					"  UPDATE b SET x = CASE WHEN NEW.x > 0 THEN 1 ELSE 0 END;\n  DELETE FROM c;\nEND",
+				"BEGIN",
+				"SELECT 1",
+				"END",
+			},
+		},
+		{
+			name:     "sqlite: quoted identifiers",
+			dialect:  DialectSQLite,
+			sql:      "CREATE TABLE [a;b] (`c;d` int, \"e;f\" text); SELECT 1",
+			expected: []string{"CREATE TABLE [a;b] (`c;d` int, \"e;f\" text)", "SELECT 1"},
+		},
+		{
+			name:    "tarantool: table constructors",
+			dialect: DialectTarantool,
+			sql: "box.schema.space.create('users', {if_not_exists = true; format = {{'id', 'unsigned'}}});\n" +
+				"box.space.users:create_index('primary')",
+			expected: []string{
+				"box.schema.space.create('users', {if_not_exists = true; format = {{'id', 'unsigned'}}})",
+				"box.space.users:create_index('primary')",
+			},
+		},
+		{
+			name:    "tarantool: blocks",
+			dialect: DialectTarantool,
+			sql: "local function g() x(); y() end;\n" +
+				"if box.space.x == nil then box.schema.space.create('x'); end;\n" +
+				"for i = 1, 3 do z(i); end; repeat w(); until true; g()",
+			expected: []string{
+				"local function g() x(); y() end",
+				"if box.space.x == nil then box.schema.space.create('x'); end",
+				"for i = 1, 3 do z(i); end",
+				"repeat w(); until true",
+				"g()",
+			},
+		},
+		{
+			name:    "tarantool: strings and comments",
+			dialect: DialectTarantool,
+			sql: "box.schema.func.create('f', {body = [==[function(a) return a .. ';' end]==]});\n" +
+				"-- a; b\nbox.cfg{}; --[[ long; comment ]] box.space.x:drop()",
+			expected: []string{
+				"box.schema.func.create('f', {body = [==[function(a) return a .. ';' end]==]})",
+				"-- a; b\nbox.cfg{}",
+				"--[[ long; comment ]] box.space.x:drop()",
+			},
+		},
+		{
+			name:    "sqlserver: GO batches",
+			dialect: DialectMSSQL,
+			sql: "CREATE TABLE t (id int);\nINSERT INTO t VALUES (1);\ngo\n" +
+				"CREATE PROCEDURE p AS\nBEGIN\n    SELECT 1;\n    SELECT 2;\nEND\n  GO  -- the procedure\n" +
+				"INSERT INTO t VALUES (2)\nGO 2\nGO\nSELECT 3",
+			expected: []string{
+				"CREATE TABLE t (id int);\nINSERT INTO t VALUES (1)",
+				"CREATE PROCEDURE p AS\nBEGIN\n    SELECT 1;\n    SELECT 2;\nEND",
+				"INSERT INTO t VALUES (2)",
+				"INSERT INTO t VALUES (2)",
+				"SELECT 3",
+			},
+		},
+		{
+			name:    "sqlserver: GO not ending a batch",
+			dialect: DialectMSSQL,
+			sql: "SELECT 'a\nGO\n' AS [x\nGO\n]; /* /* nested\nGO\n */ */ SELECT 1 GO\n" +
+				"GOTO done\n-- GO\ndone: SELECT 2\nGO x\nGO",
+			expected: []string{
+				"SELECT 'a\nGO\n' AS [x\nGO\n]; /* /* nested\nGO\n */ */ SELECT 1 GO\n" +
+					"GOTO done\n-- GO\ndone: SELECT 2\nGO x",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts, err := scanAll(t, strings.NewReader(tt.sql), tt.dialect)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, stmts)
+
+			// tokens cut at the end of the buffered data are read up to their end
+			stmts, err = scanAll(t, iotest.OneByteReader(strings.NewReader(tt.sql)), tt.dialect)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, stmts)
+		})
+	}
+}
+
+func TestScanner_Dialects_Unterminated_Failure(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		sql     string
+	}{
+		{name: "quoted string", dialect: DialectPostgres, sql: "SELECT 1; SELECT 'a; SELECT 2"},
+		{name: "dollar quote", dialect: DialectPostgres, sql: "SELECT $body$ a; SELECT 2"},
+		{name: "block comment", dialect: DialectMySQL, sql: "SELECT 1; /* a; SELECT 2"},
+		{name: "long string", dialect: DialectTarantool, sql: "x = [[ a; b"},
+		{name: "bracket identifier", dialect: DialectMSSQL, sql: "SELECT 1 AS [a\nGO\nSELECT 2"},
+		{name: "delimiter body", dialect: DialectMySQL, sql: "DELIMITER $$\nCREATE PROCEDURE p() BEGIN SELECT 'a; END$$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := scanAll(t, strings.NewReader(tt.sql), tt.dialect)
+			assert.ErrorIs(t, err, ErrUnterminated)
+		})
+	}
+}
+
+func TestScanner_MySQLDelimiterMissing_Failure(t *testing.T) {
+	_, err := scanAll(t, strings.NewReader("SELECT 1;\nDELIMITER \nSELECT 2"), DialectMySQL)
+	assert.ErrorIs(t, err, ErrDelimiterMissing)
+}
@@ -0,0 +1,78 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package sqlio
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownDirective is returned for a `-- migrator:` comment naming no known directive.
+var ErrUnknownDirective = errors.New("unknown migrator directive")
+
+const (
+	directivePrefix        = "migrator:"
+	directiveNoTransaction = "no-transaction"
+	directiveSplit         = "split"
+)
+
+// Directives are set with `-- migrator:<directive>` comments and change how a safe migration runs its statements.
+// Written in the comments just above a statement they apply to the statement, written in the comments
+// at the top of a file and separated from its first statement with an empty line they apply to the whole file.
+type Directives struct {
+	// NoTransaction runs the statement outside the transaction, as CREATE INDEX CONCURRENTLY must be.
+	NoTransaction bool
+	// Split commits the transaction before the statement and starts a new one with it.
+	Split bool
+}
+
+// Any reports whether any directive is set.
+func (d Directives) Any() bool {
+	return d.NoTransaction || d.Split
+}
+
+func (d Directives) merge(other Directives) Directives {
+	return Directives{
+		NoTransaction: d.NoTransaction || other.NoTransaction,
+		Split:         d.Split || other.Split,
+	}
+}
+
+// parseDirectives returns the directives written in the leading `--` comments of a statement.
+// With first set, the directives followed by an empty line are returned as the directives of the file.
+func parseDirectives(sql string, first bool) (stmt, file Directives, err error) {
+	for line := range strings.Lines(sql) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if first {
+				file, stmt = file.merge(stmt), Directives{}
+			}
+			continue
+		}
+		comment, ok := strings.CutPrefix(line, "--")
+		if !ok {
+			break
+		}
+		name, ok := strings.CutPrefix(strings.TrimSpace(comment), directivePrefix)
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case directiveNoTransaction:
+			stmt.NoTransaction = true
+		case directiveSplit:
+			stmt.Split = true
+		default:
+			return Directives{}, Directives{}, errors.Wrap(ErrUnknownDirective, line)
+		}
+	}
+
+	return stmt, file, nil
+}
@@ -0,0 +1,61 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package sqlio
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scanDirectives(t *testing.T, sql string) ([]Directives, error) {
+	t.Helper()
+
+	var directives []Directives
+	scanner := NewScanner(strings.NewReader(sql), DialectPostgres)
+	for scanner.Scan() {
+		directives = append(directives, scanner.Directives())
+	}
+
+	return directives, scanner.Err()
+}
+
+func TestScanner_Directives_Statement(t *testing.T) {
+	directives, err := scanDirectives(t, "CREATE TABLE t (id int);\n"+
+		"-- builds the index without locking the table\n"+
+		"-- migrator:no-transaction\n"+
+		"CREATE INDEX CONCURRENTLY t_id ON t (id);\n"+
+		"--migrator:split\n"+
+		"UPDATE t SET id = 1;\n"+
+		"SELECT '-- migrator:no-transaction';\n")
+	require.NoError(t, err)
+	assert.Equal(t, []Directives{{}, {NoTransaction: true}, {Split: true}, {}}, directives)
+}
+
+func TestScanner_Directives_File(t *testing.T) {
+	directives, err := scanDirectives(t, "-- migrator:no-transaction\n\n"+
+		"-- migrator:split\n"+
+		"ALTER TYPE mood ADD VALUE 'happy';\n"+
+		"VACUUM t;\n")
+	require.NoError(t, err)
+	assert.Equal(t, []Directives{{NoTransaction: true, Split: true}, {NoTransaction: true}}, directives)
+}
+
+func TestScanner_Directives_NotInHeaderOfLaterStatements(t *testing.T) {
+	directives, err := scanDirectives(t, "SELECT 1;\n-- migrator:no-transaction\n\nVACUUM t;\nSELECT 2")
+	require.NoError(t, err)
+	assert.Equal(t, []Directives{{}, {NoTransaction: true}, {}}, directives)
+}
+
+func TestScanner_Directives_Unknown_Failure(t *testing.T) {
+	_, err := scanDirectives(t, "SELECT 1;\n-- migrator:no-transactions\nVACUUM t")
+	assert.ErrorIs(t, err, ErrUnknownDirective)
+}
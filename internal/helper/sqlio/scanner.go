@@ -10,8 +10,6 @@ package sqlio
 
 import (
 	"bufio"
-	"bytes"
-	"fmt"
 	"io"
 	"strings"
 )
@@ -20,41 +18,41 @@ const (
 	maxMigrationSize = 10 * 1 << 20
 )
 
-var (
-	multiStmtDelimiter  = []byte(";")
-	psqlPLFuncDelimiter = []byte("$$")
-)
-
 // StartBufSize is the default starting size of the buffer used to scan and parse multi-statement migrations.
 var StartBufSize = 4096
 
 // Scanner scans SQL migration files and splits them into individual statements.
-// It handles multi-statement SQL files with various delimiters including PostgreSQL $$ delimiters for functions.
+// It steps over the quoted strings, comments and blocks of the dialect of the migrations,
+// so the `;` within them does not end a statement.
 type Scanner struct {
 	scanner *bufio.Scanner
 	sql     string
 	err     error
 	done    bool
 	closer  io.Closer // Store the closer if reader implements io.Closer
+	// directives of the current statement and of the whole file
+	directives     Directives
+	fileDirectives Directives
+	// index is the number of the current statement
+	index int
+	// repeat is the number of times the current statement is yielded, set by GO n
+	repeat int
 }
 
-// NewScanner creates a new Scanner that reads from the provided io.Reader.
-// The scanner splits SQL statements using semicolon delimiters while respecting PostgreSQL $$ delimiters.
-func NewScanner(r io.Reader) *Scanner {
+// NewScanner creates a new Scanner that reads the statements of the dialect from the provided io.Reader.
+func NewScanner(r io.Reader, dialect Dialect) *Scanner {
+	scanner := &Scanner{}
 	s := bufio.NewScanner(r)
 	s.Buffer(make([]byte, 0, StartBufSize), maxMigrationSize)
-	s.Split(splitWithDelimiter())
+	s.Split(splitStatements(dialect, &scanner.repeat))
+	scanner.scanner = s
 
 	// Check if reader also implements io.Closer
-	var closer io.Closer
 	if rc, ok := r.(io.Closer); ok {
-		closer = rc
+		scanner.closer = rc
 	}
 
-	return &Scanner{
-		scanner: s,
-		closer:  closer,
-	}
+	return scanner
 }
 
 // SQL returns the current SQL statement that was scanned.
@@ -67,18 +65,44 @@ func (s *Scanner) Err() error {
 	return s.err
 }
 
+// Index returns the number of the current SQL statement in the file, starting from 1,
+// or zero before the first statement is scanned.
+func (s *Scanner) Index() int {
+	return s.index
+}
+
+// Directives returns the directives of the current SQL statement, including the ones of the whole file.
+func (s *Scanner) Directives() Directives {
+	return s.directives.merge(s.fileDirectives)
+}
+
 // Scan advances the scanner to the next SQL statement.
 // It returns true if a statement was found, false otherwise.
 func (s *Scanner) Scan() bool {
 	if s.done {
 		return false
 	}
+	if s.repeat > 1 {
+		s.repeat--
+		s.index++
+		return true
+	}
 	for s.scanner.Scan() {
 		s.sql = strings.TrimSpace(s.scanner.Text())
 		s.sql = strings.Trim(s.sql, ";")
 		if s.sql == "" {
 			continue
 		}
+
+		directives, fileDirectives, err := parseDirectives(s.sql, s.index == 0)
+		if err != nil {
+			s.err, s.done = err, true
+			return false
+		}
+		s.directives = directives
+		s.fileDirectives = s.fileDirectives.merge(fileDirectives)
+		s.index++
+
 		return true
 	}
 
@@ -99,54 +123,44 @@ func (s *Scanner) Close() error {
 	return nil
 }
 
-func splitWithDelimiter() func(d []byte, atEOF bool) (int, []byte, error) {
-	return func(d []byte, atEOF bool) (int, []byte, error) {
-		// SplitFunc inspired by bufio.ScanLines() implementation
-		if atEOF {
-			if len(d) == 0 {
-				return 0, nil, nil
+// splitStatements returns a bufio.SplitFunc yielding the statements of the dialect one by one,
+// without the delimiter ending them.
+// Statements having nothing but whitespace and comments are skipped.
+// The number of times the statement yielded is to be executed, set by GO n, is stored in repeat.
+func splitStatements(dialect Dialect, repeat *int) bufio.SplitFunc {
+	rules := rulesOf(dialect)
+	delimiter := defaultDelimiter
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		// the skipped statements are consumed here, since bufio.Scanner stops at EOF on a nil token
+		var advance int
+		for {
+			rest := data[advance:]
+			if atEOF && len(rest) == 0 {
+				return advance, nil, nil
 			}
-		}
 
-		openPi, pLen := bytes.Index(d, psqlPLFuncDelimiter), len(psqlPLFuncDelimiter)
-		delI, delLen := bytes.Index(d, multiStmtDelimiter), len(multiStmtDelimiter)
-
-		switch {
-		case openPi > delI:
-			if len(d[:delI+delLen]) == 0 {
-				return 0, nil, nil
-			}
-			return delI + delLen, d[:delI+delLen], nil
-
-		case openPi >= 0 && openPi < delI:
-			closePi := bytes.Index(d[openPi+pLen:], psqlPLFuncDelimiter)
-			if closePi < 0 {
-				var err error
-				if atEOF {
-					err = fmt.Errorf("closed tag %s not found", psqlPLFuncDelimiter)
-				}
+			stmt, err := statementEnd(rules, delimiter, rest, atEOF)
+			switch {
+			case err != nil:
 				return 0, nil, err
+			case stmt.delimiter != "":
+				delimiter = stmt.delimiter
+				advance += stmt.end
+				continue
+			case stmt.end < 0 && !atEOF:
+				return advance, nil, nil
+			case stmt.end < 0:
+				stmt.end, stmt.bodyEnd = len(rest), len(rest)
 			}
-			offset := closePi + openPi + pLen
-			delI = bytes.Index(d[offset:], multiStmtDelimiter)
-			offset = offset + delI + delLen
-			if delI < 0 {
-				var err error
-				if atEOF {
-					err = fmt.Errorf("closed tag %s not found", multiStmtDelimiter)
-				}
-				return 0, nil, err
+			if !stmt.content {
+				advance += stmt.end
+				continue
 			}
-			return offset, d[:offset], nil
 
-		case delI >= 0:
-			return delI + delLen, d[:delI+delLen], nil
+			*repeat = stmt.repeat
 
-		default:
-			if atEOF {
-				return len(d), d, nil
-			}
-			return 0, nil, nil
+			return advance + stmt.end, rest[:stmt.bodyEnd], nil
 		}
 	}
 }
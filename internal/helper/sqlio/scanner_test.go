@@ -53,7 +53,7 @@ func TestParse(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			stmts := make([]string, 0, len(tc.expected))
-			scanner := NewScanner(strings.NewReader(tc.multiStmt))
+			scanner := NewScanner(strings.NewReader(tc.multiStmt), DialectDefault)
 
 			for scanner.Scan() {
 				stmts = append(stmts, scanner.SQL())
@@ -69,7 +69,7 @@ func TestParseDiscontinue(t *testing.T) {
 	multiStmt := "statement one; statement two"
 	expected := []string{"statement one", "statement two"}
 	stmts := make([]string, 0, len(expected))
-	scanner := NewScanner(strings.NewReader(multiStmt))
+	scanner := NewScanner(strings.NewReader(multiStmt), DialectDefault)
 	for scanner.Scan() {
 		stmts = append(stmts, scanner.SQL())
 	}
@@ -78,6 +78,19 @@ func TestParseDiscontinue(t *testing.T) {
 	assert.Equal(t, expected, stmts)
 }
 
+func TestScanner_Index_SkipsEmptyStatements(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("statement one;; -- comment only;\nstatement two;"), DialectDefault)
+	assert.Equal(t, 0, scanner.Index())
+
+	indexes := make([]int, 0, 2)
+	for scanner.Scan() {
+		indexes = append(indexes, scanner.Index())
+	}
+
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []int{1, 2}, indexes)
+}
+
 func TestParsePostgresFunctions(t *testing.T) {
 	expected := []string{`CREATE test`,
 		`CREATE OR REPLACE FUNCTION test_index_update() RETURNS trigger AS $$
@@ -92,7 +105,7 @@ END;
 $$ LANGUAGE plpgsql`, `CREATE TRIGGER test_index_update_trigger`}
 	multiStmt := strings.Join(expected, "; ")
 	stmts := make([]string, 0, len(expected))
-	scanner := NewScanner(strings.NewReader(multiStmt))
+	scanner := NewScanner(strings.NewReader(multiStmt), DialectDefault)
 	for scanner.Scan() {
 		stmts = append(stmts, scanner.SQL())
 	}
@@ -116,7 +129,7 @@ END;
 ;
 CREATE TRIGGER test_index_update_trigger`
 
-	scanner := NewScanner(strings.NewReader(multiStmt))
+	scanner := NewScanner(strings.NewReader(multiStmt), DialectDefault)
 	for scanner.Scan() {
 		scanner.SQL()
 	}
@@ -142,7 +155,7 @@ CREATE TRIGGER test_index_update_trigger`
 
 	StartBufSize = 100
 
-	scanner := NewScanner(strings.NewReader(multiStmt))
+	scanner := NewScanner(strings.NewReader(multiStmt), DialectDefault)
 	for scanner.Scan() {
 		scanner.SQL()
 	}
@@ -55,3 +55,13 @@ type SQLDB interface {
 	DBTransactor
 	io.Closer
 }
+
+//go:generate mockery
+
+// Session is a single database session pinned for exclusive use.
+// Session-level state, such as an advisory lock, lives as long as the session stays open.
+type Session interface {
+	DBQuerier
+	DBExecutor
+	io.Closer
+}
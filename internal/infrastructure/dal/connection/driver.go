@@ -26,6 +26,18 @@ const (
 
 	// DriverIceberg represents the Apache Iceberg REST catalog driver.
 	DriverIceberg Driver = "iceberg"
+
+	// DriverSQLite represents the SQLite database driver.
+	DriverSQLite Driver = "sqlite"
+
+	// DriverMSSQL represents the Microsoft SQL Server database driver.
+	DriverMSSQL Driver = "sqlserver"
+
+	// DriverCockroachDB represents the CockroachDB database, connected with the PostgreSQL driver.
+	DriverCockroachDB Driver = "cockroachdb"
+
+	// DriverYugabyteDB represents the YugabyteDB database, connected with the PostgreSQL driver.
+	DriverYugabyteDB Driver = "yugabyte"
 )
 
 // String returns the string representation of the driver.
@@ -0,0 +1,16 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package entity
+
+// Lock describes the holder of the migration lock.
+// AcquiredAt is a Unix timestamp in seconds; zero means the driver cannot tell when the lock was taken.
+type Lock struct {
+	Owner      string
+	AcquiredAt int64
+}
@@ -13,16 +13,43 @@ import (
 	"time"
 )
 
+// Statuses of a migration record.
+const (
+	// StatusApplied marks a migration that was applied completely.
+	StatusApplied = "applied"
+	// StatusPending marks a migration that is being applied, or whose run died midway.
+	StatusPending = "pending"
+	// StatusFailed marks a migration that failed after some of its statements were committed.
+	StatusFailed = "failed"
+)
+
 // Migration represents a database migration record stored in the migration history table.
-// It contains the version identifier and the timestamp when the migration was applied.
+// It contains the version identifier, the timestamp when the migration was applied,
+// the up SQL as it was executed, the down SQL needed to revert it, the checksum
+// of the migration file, whether it was applied after a newer migration, its status
+// and the number of its statements committed while it is not applied completely.
 type Migration struct {
 	Version   string `db:"version"`
 	ApplyTime int64  `db:"apply_time"`
 	// BodySQL     string `db:"body_sql"`
-	// ExecutedSQL string `db:"executed_sql"`
+	ExecutedSQL   string `db:"executed_sql"`
+	DownSQL       string `db:"down_sql"`
+	Checksum      string `db:"checksum"`
+	OutOfOrder    bool   `db:"out_of_order"`
+	Status        string `db:"status"`
+	LastStatement int    `db:"last_statement"`
 	// Release     string `db:"release"`
 }
 
+// StatusOrApplied returns the status of the record, records without one are applied.
+func (s Migration) StatusOrApplied() string {
+	if s.Status == "" {
+		return StatusApplied
+	}
+
+	return s.Status
+}
+
 // Migrations is a collection of Migration records that implements sort.Interface.
 type Migrations []Migration
 
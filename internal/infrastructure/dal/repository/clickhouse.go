@@ -11,19 +11,43 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
 )
 
+// clickhouseHistoryColumns lists the history table columns added after its first release.
+var clickhouseHistoryColumns = []historyColumn{
+	{Name: "executed_sql", Type: "String DEFAULT ''"},
+	{Name: "down_sql", Type: "String DEFAULT ''"},
+	{Name: "checksum", Type: "String DEFAULT ''"},
+	{Name: "out_of_order", Type: "UInt8 DEFAULT 0"},
+	{Name: "status", Type: "String DEFAULT 'applied'"},
+	{Name: "last_statement", Type: "UInt32 DEFAULT 0"},
+}
+
+// clickhouseWriteTimeColumn is the version of the ReplacingMergeTree engine of the history table: the time
+// in nanoseconds the server wrote the record at. It cannot be added by ALTER TABLE, the table is rebuilt with it.
+var clickhouseWriteTimeColumn = historyColumn{Name: "write_time", Type: "UInt64"}
+
+// clickhouseConvertedPathSuffix ends the ZooKeeper path of a replicated history table rebuilt by
+// convertHistoryTable, since the path of the table it replaces is taken while the records are copied.
+const clickhouseConvertedPathSuffix = "_write_time"
+
 // Clickhouse implements Repository interface for ClickHouse database.
 // It handles migration history tracking and SQL execution for ClickHouse with support for clusters and replication.
 type Clickhouse struct {
 	conn    Connection
 	options *Options
+	// lock is the lock row inserted by TryLock until Unlock.
+	lock *entity.Lock
+	// lockTableCreated is set once TryLock has created the lock table.
+	lockTableCreated bool
 }
 
 // NewClickhouse creates a new Clickhouse repository instance.
@@ -39,9 +63,9 @@ func NewClickhouse(conn Connection, options *Options) *Clickhouse {
 func (ch *Clickhouse) Migrations(ctx context.Context, limit int) (entity.Migrations, error) {
 	var (
 		q = `
-			SELECT version, apply_time 
-			FROM ` + ch.dTableNameWithSchema() + `
-			WHERE is_deleted = 0 
+			SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
+			FROM ` + ch.dTableNameWithSchema() + ` FINAL
+			WHERE is_deleted = 0
 			ORDER BY apply_time DESC, version DESC
 			LIMIT ?
 		`
@@ -56,18 +80,32 @@ func (ch *Clickhouse) Migrations(ctx context.Context, limit int) (entity.Migrati
 
 	for rows.Next() {
 		var (
-			version   string
-			applyTime int64
+			version       string
+			applyTime     int64
+			executedSQL   string
+			downSQL       string
+			checksum      string
+			outOfOrder    uint8
+			status        string
+			lastStatement uint32
 		)
 
-		if err := rows.Scan(&version, &applyTime); err != nil {
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
 			return nil, errors.Wrap(ch.dbError(err, q), "get migrations")
 		}
 
 		migrations = append(migrations,
 			entity.Migration{
-				Version:   version,
-				ApplyTime: applyTime,
+				Version:       version,
+				ApplyTime:     applyTime,
+				ExecutedSQL:   executedSQL,
+				DownSQL:       downSQL,
+				Checksum:      checksum,
+				OutOfOrder:    outOfOrder == 1,
+				Status:        status,
+				LastStatement: int(lastStatement),
 			},
 		)
 	}
@@ -119,23 +157,46 @@ func (ch *Clickhouse) HasMigrationHistoryTable(ctx context.Context) (exists bool
 }
 
 // InsertMigration inserts the new migration record.
-func (ch *Clickhouse) InsertMigration(ctx context.Context, version string) error {
-	return ch.insertMigration(ctx, version, false)
+func (ch *Clickhouse) InsertMigration(ctx context.Context, migration *entity.Migration) error {
+	return ch.insertMigration(ctx, migration, time.Now().Unix(), false)
+}
+
+// UpdateMigration replaces the record of migration.Version with a newer one applied now.
+func (ch *Clickhouse) UpdateMigration(ctx context.Context, migration *entity.Migration) error {
+	return ch.insertMigration(ctx, migration, time.Now().Unix(), false)
 }
 
 // RemoveMigration removes the migration record.
 func (ch *Clickhouse) RemoveMigration(ctx context.Context, version string) error {
-	return ch.insertMigration(ctx, version, true)
+	return ch.insertMigration(ctx, &entity.Migration{Version: version}, time.Now().Unix(), true)
 }
 
 // ExecQuery executes a query without returning any rows.
 // The args are for any placeholder parameters in the query.
+// The statement and lock timeouts of the options are sent as the max_execution_time
+// and lock_acquire_timeout settings of the query.
 func (ch *Clickhouse) ExecQuery(ctx context.Context, query string, args ...any) error {
+	if settings := ch.timeoutSettings(); len(settings) > 0 {
+		ctx = clickhouse.Context(ctx, clickhouse.WithSettings(settings))
+	}
 	_, err := ch.conn.ExecContext(ctx, query, args...)
 
 	return ch.dbError(err, query)
 }
 
+// timeoutSettings returns the query settings enforcing the statement and lock timeouts of the options.
+func (ch *Clickhouse) timeoutSettings() clickhouse.Settings {
+	settings := clickhouse.Settings{}
+	if ch.options.LockTimeout > 0 {
+		settings["lock_acquire_timeout"] = durationSeconds(ch.options.LockTimeout)
+	}
+	if ch.options.StatementTimeout > 0 {
+		settings["max_execution_time"] = durationSeconds(ch.options.StatementTimeout)
+	}
+
+	return settings
+}
+
 // ExecQueryTransaction executes txFn directly without a transaction.
 // ClickHouse does not support transactions; each statement is auto-committed.
 func (ch *Clickhouse) ExecQueryTransaction(ctx context.Context, txFn func(ctx context.Context) error) error {
@@ -152,6 +213,17 @@ func (ch *Clickhouse) ExecInTransaction(ctx context.Context, txFn func(ctx conte
 
 // CreateMigrationHistoryTable creates a new migration history table.
 func (ch *Clickhouse) CreateMigrationHistoryTable(ctx context.Context) error {
+	return errors.Wrap(
+		ch.createHistoryTable(ctx, ch.options.TableName, ch.options.TableName),
+		"create migration history table",
+	)
+}
+
+// createHistoryTable creates the history table with the given name, together with its distributed table
+// in cluster mode. A replicated table is registered in ZooKeeper under the path name.
+// The records of a version are collapsed by the ReplacingMergeTree engine in the background,
+// keeping the record written last, so the history is read with FINAL.
+func (ch *Clickhouse) createHistoryTable(ctx context.Context, table, path string) error {
 	var (
 		q         string
 		extQ      string
@@ -163,42 +235,50 @@ func (ch *Clickhouse) CreateMigrationHistoryTable(ctx context.Context) error {
 	case ch.isUsedCluster():
 		onCluster = "ON CLUSTER " + ch.options.ClusterName
 		engine = "ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/" +
-			ch.options.ClusterName + "_" + ch.options.TableName + "', '{replica}', apply_time)"
+			ch.options.ClusterName + "_" + path + "', '{replica}', write_time)"
 		extQ = fmt.Sprintf(`
 				CREATE TABLE %[2]s.d_%[3]s ON CLUSTER %[1]s AS %[2]s.%[3]s
 				ENGINE = Distributed('%[1]s', '%[2]s', %[3]s, cityHash64(toString(version)))
 			`,
 			ch.options.ClusterName,
 			ch.options.SchemaName,
-			ch.options.TableName,
+			table,
 		)
 	case ch.options.Replicated:
 		engine = "ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/" +
-			ch.options.ClusterName + "_" + ch.options.TableName + "', '{replica}', apply_time)"
+			ch.options.ClusterName + "_" + path + "', '{replica}', write_time)"
 	default:
-		engine = "ReplacingMergeTree(apply_time)"
+		engine = "ReplacingMergeTree(write_time)"
 	}
 
 	q = fmt.Sprintf(
 		`
-			CREATE TABLE %s %s (
+			CREATE TABLE %s.%s %s (
 				version String, 
 				date Date DEFAULT toDate(apply_time),
 				apply_time UInt32,
-				is_deleted UInt8
+				is_deleted UInt8,
+				executed_sql String DEFAULT '',
+				down_sql String DEFAULT '',
+				checksum String DEFAULT '',
+				out_of_order UInt8 DEFAULT 0,
+				status String DEFAULT 'applied',
+				last_statement UInt32 DEFAULT 0,
+				write_time UInt64
 			) ENGINE = %s
 			PRIMARY KEY (version)
 			PARTITION BY (toYYYYMM(date))
 			ORDER BY (version)
 			SETTINGS index_granularity=8192
 			`,
-		ch.TableNameWithSchema(),
+		ch.options.SchemaName,
+		table,
 		onCluster,
 		engine,
 	)
 
 	if _, err := ch.conn.ExecContext(ctx, q); err != nil {
-		return errors.Wrap(ch.dbError(err, q), "create migration history table")
+		return ch.dbError(err, q)
 	}
 
 	if len(extQ) == 0 {
@@ -206,7 +286,167 @@ func (ch *Clickhouse) CreateMigrationHistoryTable(ctx context.Context) error {
 	}
 
 	if _, err := ch.conn.ExecContext(ctx, extQ); err != nil {
-		return errors.Wrap(ch.dbError(err, extQ), "create migration history table")
+		return ch.dbError(err, extQ)
+	}
+
+	return nil
+}
+
+// UpgradeMigrationHistoryTable adds the columns introduced after the history table
+// was first released. In cluster mode both the local and the distributed tables are altered.
+// A table created before the write_time column is rebuilt by convertHistoryTable.
+func (ch *Clickhouse) UpgradeMigrationHistoryTable(ctx context.Context) error {
+	missing, convert, err := ch.historyColumnsToAdd(ctx)
+	if err != nil {
+		return errors.Wrap(err, "upgrade migration history table")
+	}
+
+	tables := []string{ch.TableNameWithSchema()}
+	if ch.isUsedCluster() {
+		tables = append(tables, ch.dTableNameWithSchema())
+	}
+
+	for _, column := range missing {
+		for _, table := range tables {
+			if err := ch.addColumn(ctx, table, column); err != nil {
+				return errors.Wrap(err, "upgrade migration history table")
+			}
+		}
+	}
+
+	if !convert {
+		return nil
+	}
+
+	return errors.Wrap(ch.convertHistoryTable(ctx), "upgrade migration history table")
+}
+
+// NeedsMigrationHistoryTableUpgrade reports whether the history table lacks columns
+// added by UpgradeMigrationHistoryTable.
+func (ch *Clickhouse) NeedsMigrationHistoryTableUpgrade(ctx context.Context) (bool, error) {
+	missing, convert, err := ch.historyColumnsToAdd(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "check migration history table")
+	}
+
+	return len(missing) > 0 || convert, nil
+}
+
+// historyColumnsToAdd returns the history columns the table does not have yet
+// and whether the table lacks the write_time column and has to be converted.
+func (ch *Clickhouse) historyColumnsToAdd(ctx context.Context) ([]historyColumn, bool, error) {
+	q := `
+		SELECT name
+		FROM system.columns
+		WHERE table = ? AND database = currentDatabase()
+	`
+	existing, err := queryColumnNames(ctx, ch.conn, q, ch.dTableName())
+	if err != nil {
+		return nil, false, ch.dbError(err, q)
+	}
+
+	missing := missingHistoryColumns(existing, clickhouseHistoryColumns)
+	convert := len(missingHistoryColumns(existing, []historyColumn{clickhouseWriteTimeColumn})) > 0
+
+	return missing, convert, nil
+}
+
+// convertHistoryTable rebuilds the history table created before the write_time column, whose ReplacingMergeTree
+// engine collapsed the records of a version by apply_time and relied on OPTIMIZE FINAL after every write.
+// The engine of a table cannot be altered, so the records are copied into a new table versioned by write_time,
+// which is renamed in place of the old one. The copied records take the write_time of their apply_time.
+// A conversion stopped midway is taken up again: a table renamed already is not rebuilt,
+// and the tables left behind are dropped.
+func (ch *Clickhouse) convertHistoryTable(ctx context.Context) error {
+	q := `
+		SELECT name
+		FROM system.columns
+		WHERE table = ? AND database = currentDatabase()
+	`
+	columns, err := queryColumnNames(ctx, ch.conn, q, ch.options.TableName)
+	if err != nil {
+		return errors.Wrap(ch.dbError(err, q), "convert migration history table")
+	}
+	if _, renamed := columns[clickhouseWriteTimeColumn.Name]; !renamed {
+		if err := ch.rebuildHistoryTable(ctx); err != nil {
+			return errors.Wrap(err, "convert migration history table")
+		}
+	}
+
+	if err := ch.dropTableIfExists(ctx, ch.options.SchemaName+"."+ch.options.TableName+"_old"); err != nil {
+		return errors.Wrap(err, "convert migration history table")
+	}
+
+	if ch.isUsedCluster() {
+		if err := ch.addColumn(ctx, ch.dTableNameWithSchema(), clickhouseWriteTimeColumn); err != nil {
+			return errors.Wrap(err, "convert migration history table")
+		}
+	}
+
+	return nil
+}
+
+// rebuildHistoryTable copies the records of the history table into a new table versioned by write_time
+// and renames it in place of the history table, which is kept with the _old suffix.
+// The tables left behind by an earlier rebuild are dropped first. In cluster mode the records are copied
+// through a distributed table of the new table, sharded as the old one.
+func (ch *Clickhouse) rebuildHistoryTable(ctx context.Context) error {
+	var (
+		table    = ch.options.TableName
+		newTable = table + "_new"
+		oldTable = table + "_old"
+		target   = ch.options.SchemaName + "." + newTable
+		settings string
+	)
+
+	stale := []string{ch.options.SchemaName + "." + newTable, ch.options.SchemaName + "." + oldTable}
+	if ch.isUsedCluster() {
+		target = ch.options.SchemaName + ".d_" + newTable
+		settings = " SETTINGS insert_distributed_sync = 1"
+		stale = append([]string{target}, stale...)
+	}
+	for _, name := range stale {
+		if err := ch.dropTableIfExists(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	if err := ch.createHistoryTable(ctx, newTable, table+clickhouseConvertedPathSuffix); err != nil {
+		return err
+	}
+
+	q := "INSERT INTO " + target + ` (
+			version, apply_time, is_deleted, executed_sql, down_sql, checksum, out_of_order, status, last_statement,
+			write_time
+		)
+		SELECT
+			version, apply_time, is_deleted, executed_sql, down_sql, checksum, out_of_order, status, last_statement,
+			toUInt64(apply_time) * 1000000000
+		FROM ` + ch.dTableNameWithSchema() + " FINAL" + settings
+	if _, err := ch.conn.ExecContext(ctx, q); err != nil {
+		return ch.dbError(err, q)
+	}
+
+	if ch.isUsedCluster() {
+		if err := ch.dropTable(ctx, target); err != nil {
+			return err
+		}
+	}
+
+	q = fmt.Sprintf("RENAME TABLE %[1]s.%[2]s TO %[1]s.%[3]s, %[1]s.%[4]s TO %[1]s.%[2]s%[5]s",
+		ch.options.SchemaName, table, oldTable, newTable, ch.onCluster())
+	if _, err := ch.conn.ExecContext(ctx, q); err != nil {
+		return ch.dbError(err, q)
+	}
+
+	return nil
+}
+
+// addColumn adds the column to the table unless the table has it already.
+func (ch *Clickhouse) addColumn(ctx context.Context, table string, column historyColumn) error {
+	q := "ALTER TABLE " + table + ch.onCluster() + " ADD COLUMN IF NOT EXISTS " + column.Name + " " + column.Type
+	if _, err := ch.conn.ExecContext(ctx, q); err != nil {
+		return ch.dbError(err, q)
 	}
 
 	return nil
@@ -231,7 +471,7 @@ func (ch *Clickhouse) DropMigrationHistoryTable(ctx context.Context) error {
 
 // MigrationsCount returns the number of migrations
 func (ch *Clickhouse) MigrationsCount(ctx context.Context) (int, error) {
-	q := "SELECT count(*) FROM " + ch.dTableNameWithSchema() + " WHERE is_deleted = 0"
+	q := "SELECT count(*) FROM " + ch.dTableNameWithSchema() + " FINAL WHERE is_deleted = 0"
 	var c int
 	if err := ch.QueryScalar(ctx, q, &c); err != nil {
 		return 0, err
@@ -267,7 +507,7 @@ func (ch *Clickhouse) QueryScalar(ctx context.Context, query string, ptr any) er
 // ExistsMigration checks if a migration with the given version exists in the history table.
 // It returns true if the migration record is found and not marked as deleted, false otherwise.
 func (ch *Clickhouse) ExistsMigration(ctx context.Context, version string) (bool, error) {
-	q := "SELECT 1 FROM " + ch.dTableNameWithSchema() + " WHERE version = ? AND is_deleted = 0"
+	q := "SELECT 1 FROM " + ch.dTableNameWithSchema() + " FINAL WHERE version = ? AND is_deleted = 0"
 	rows, err := ch.conn.QueryContext(ctx, q, version)
 	if err != nil {
 		return false, err
@@ -306,6 +546,17 @@ func (ch *Clickhouse) dropTable(ctx context.Context, tableName string) error {
 	return nil
 }
 
+// dropTableIfExists drops the table unless it does not exist. The table is dropped synchronously,
+// so a replicated table can be created again under the same ZooKeeper path right away.
+func (ch *Clickhouse) dropTableIfExists(ctx context.Context, tableName string) error {
+	q := "DROP TABLE IF EXISTS " + tableName + ch.onCluster() + " NO DELAY"
+	if _, err := ch.conn.ExecContext(ctx, q); err != nil {
+		return ch.dbError(err, q)
+	}
+
+	return nil
+}
+
 // dTableName returns the distributed table name for cluster deployments.
 // It adds the "d_" prefix to the table name when a cluster is used, otherwise returns the original table name.
 func (ch *Clickhouse) dTableName() string {
@@ -322,6 +573,15 @@ func (ch *Clickhouse) dTableNameWithSchema() string {
 	return ch.options.SchemaName + "." + ch.dTableName()
 }
 
+// onCluster returns the ON CLUSTER clause, prefixed with a space, when cluster mode is enabled.
+func (ch *Clickhouse) onCluster() string {
+	if ch.isUsedCluster() {
+		return " ON CLUSTER " + ch.options.ClusterName
+	}
+
+	return ""
+}
+
 // isUsedCluster checks if ClickHouse cluster mode is enabled.
 // Returns true when a cluster name is configured and replication is not explicitly enabled.
 func (ch *Clickhouse) isUsedCluster() bool {
@@ -329,67 +589,69 @@ func (ch *Clickhouse) isUsedCluster() bool {
 }
 
 // insertMigration inserts migration record.
-func (ch *Clickhouse) insertMigration(ctx context.Context, version string, isDeleted bool) error {
+// The record replaces the earlier records of the version, a removed migration is recorded with is_deleted set.
+// The records are not collapsed by OPTIMIZE FINAL: the reads collapse them with FINAL, keeping the one
+// with the latest write_time. The write_time is taken from the clock of the server, not of the migrator,
+// since the apply time cannot order the records: a release records its migrations with the time it started at,
+// and a migration may be reverted and applied again within a second.
+func (ch *Clickhouse) insertMigration(
+	ctx context.Context,
+	migration *entity.Migration,
+	applyTime int64,
+	isDeleted bool,
+) error {
 	q := `
-		INSERT INTO ` + ch.dTableNameWithSchema() + ` (version, apply_time, is_deleted) 
-		VALUES(?, ?, ?)
+		INSERT INTO ` + ch.dTableNameWithSchema() + ` (
+			version, apply_time, is_deleted, executed_sql, down_sql, checksum, out_of_order, status, last_statement,
+			write_time
+		)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, toUInt64(toUnixTimestamp64Nano(now64(9)))
 	`
 
-	//nolint:gosec // overflow ok
-	now := uint32(time.Now().Unix())
-	var isDeletedInt int
+	var isDeletedInt, outOfOrderInt int
 	if isDeleted {
 		isDeletedInt = 1
 	}
+	if migration.OutOfOrder {
+		outOfOrderInt = 1
+	}
 
 	if err := ch.ExecQueryTransaction(ctx, func(ctx context.Context) error {
-		return ch.ExecQuery(ctx, q, version, now, isDeletedInt)
+		//nolint:gosec // overflow ok
+		return ch.ExecQuery(ctx, q,
+			migration.Version,
+			uint32(applyTime),
+			isDeletedInt,
+			migration.ExecutedSQL,
+			migration.DownSQL,
+			migration.Checksum,
+			outOfOrderInt,
+			migration.StatusOrApplied(),
+			uint32(migration.LastStatement),
+		)
 	}); err != nil {
 		return errors.Wrap(ch.dbError(err, q), "insert migration")
 	}
 
-	return ch.optimizeTable(ctx)
-}
-
-// optimizeTable optimizes tables.
-func (ch *Clickhouse) optimizeTable(ctx context.Context) error {
-	var q string
-	if ch.options.Replicated || ch.options.ClusterName == "" {
-		q = fmt.Sprintf("OPTIMIZE TABLE %s FINAL", ch.options.TableName)
-	} else {
-		q = fmt.Sprintf("OPTIMIZE TABLE %s ON CLUSTER %s FINAL", ch.options.TableName, ch.options.ClusterName)
-	}
-
-	if _, err := ch.conn.ExecContext(ctx, q); err != nil {
-		return errors.Wrap(ch.dbError(err, q), "optimize table")
-	}
-
 	return nil
 }
 
 // InsertMigrationWithApplyTime inserts the new migration record with an explicit apply time.
-func (ch *Clickhouse) InsertMigrationWithApplyTime(ctx context.Context, version string, applyTime int64) error {
-	q := `
-		INSERT INTO ` + ch.dTableNameWithSchema() + ` (version, apply_time, is_deleted)
-		VALUES(?, ?, ?)
-	`
-	//nolint:gosec // overflow ok
-	if err := ch.ExecQueryTransaction(ctx, func(ctx context.Context) error {
-		return ch.ExecQuery(ctx, q, version, uint32(applyTime), 0)
-	}); err != nil {
-		return errors.Wrap(ch.dbError(err, q), "insert migration")
-	}
-
-	return ch.optimizeTable(ctx)
+func (ch *Clickhouse) InsertMigrationWithApplyTime(
+	ctx context.Context,
+	migration *entity.Migration,
+	applyTime int64,
+) error {
+	return ch.insertMigration(ctx, migration, applyTime, false)
 }
 
 // MigrationsByMaxApplyTime returns migrations that share the maximum apply_time value.
 func (ch *Clickhouse) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations, error) {
 	q := `
-		SELECT version, apply_time
-		FROM ` + ch.dTableNameWithSchema() + `
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
+		FROM ` + ch.dTableNameWithSchema() + ` FINAL
 		WHERE is_deleted = 0 AND apply_time = (
-			SELECT MAX(apply_time) FROM ` + ch.dTableNameWithSchema() + ` WHERE is_deleted = 0
+			SELECT MAX(apply_time) FROM ` + ch.dTableNameWithSchema() + ` FINAL WHERE is_deleted = 0
 		)
 		ORDER BY version DESC
 	`
@@ -403,15 +665,29 @@ func (ch *Clickhouse) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migr
 	var migrations entity.Migrations
 	for rows.Next() {
 		var (
-			version   string
-			applyTime int64
+			version       string
+			applyTime     int64
+			executedSQL   string
+			downSQL       string
+			checksum      string
+			outOfOrder    uint8
+			status        string
+			lastStatement uint32
 		)
-		if err := rows.Scan(&version, &applyTime); err != nil {
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
 			return nil, errors.Wrap(ch.dbError(err, q), "get migrations by max apply time")
 		}
 		migrations = append(migrations, entity.Migration{
-			Version:   version,
-			ApplyTime: applyTime,
+			Version:       version,
+			ApplyTime:     applyTime,
+			ExecutedSQL:   executedSQL,
+			DownSQL:       downSQL,
+			Checksum:      checksum,
+			OutOfOrder:    outOfOrder == 1,
+			Status:        status,
+			LastStatement: int(lastStatement),
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -421,6 +697,254 @@ func (ch *Clickhouse) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migr
 	return migrations, nil
 }
 
+// DumpSchema returns the SHOW CREATE TABLE statements of the tables, views and dictionaries of the database,
+// without the history and lock tables and the inner tables of materialized views, ordered by name.
+func (ch *Clickhouse) DumpSchema(ctx context.Context) (string, error) {
+	q := `
+		SELECT name
+		FROM system.tables
+		WHERE database = ? AND name NOT IN (?, ?, ?) AND NOT is_temporary AND NOT startsWith(name, '.inner')
+		ORDER BY name`
+	tables, err := queryStrings(ctx, ch.conn, q, 1,
+		ch.options.SchemaName, ch.options.TableName, ch.dTableName(), ch.options.TableName+lockTableSuffix)
+	if err != nil {
+		return "", errors.Wrap(ch.dbError(err, q), "dump tables")
+	}
+
+	var dump schemaDump
+	for _, table := range tables {
+		q = fmt.Sprintf("SHOW CREATE TABLE %s.%s", ch.options.SchemaName, table[0])
+		creates, err := queryStrings(ctx, ch.conn, q, 1)
+		if err != nil {
+			return "", errors.Wrap(ch.dbError(err, q), "dump tables")
+		}
+		for _, create := range creates {
+			dump.add(create[0])
+		}
+	}
+
+	return dump.String(), nil
+}
+
+// LoadSchema is not supported: the Clickhouse DDL cannot be read into the DDL IR of the schema diff.
+func (ch *Clickhouse) LoadSchema(context.Context) (*ddl.Schema, error) {
+	return nil, errors.WithStack(ErrLoadSchemaUnsupported)
+}
+
+// TryLock tries to take the migration lock by inserting a row into the lock table without waiting.
+// The inserting process holds the lock only if its row is the only row of the table after the insert.
+// Of two processes inserting at the same time at most one sees no other row, because each one reads
+// after its own insert is done; a loser removes its row and gets false.
+// In cluster and replicated modes the lock table is replicated to every node, the row is inserted
+// with a majority quorum and read with sequential consistency, so the election holds across replicas
+// as long as the majority of replicas is available. Otherwise the insert fails and no one gets the lock.
+func (ch *Clickhouse) TryLock(ctx context.Context, lock *entity.Lock) (bool, error) {
+	if ch.lock != nil {
+		return true, nil
+	}
+
+	if !ch.lockTableCreated {
+		if err := ch.createLockTable(ctx); err != nil {
+			return false, errors.Wrap(err, "try lock")
+		}
+		ch.lockTableCreated = true
+	}
+
+	holder, err := ch.LockHolder(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "try lock")
+	}
+	if holder != nil {
+		return false, nil
+	}
+
+	q := "INSERT INTO " + ch.lockTableNameWithSchema() + " (owner, acquired_at)" +
+		ch.lockInsertSettings() + " VALUES (?, ?)"
+	if _, err := ch.conn.ExecContext(ctx, q, lock.Owner, lock.AcquiredAt); err != nil {
+		return false, errors.Wrap(ch.dbError(err, q), "try lock")
+	}
+
+	owners, err := ch.lockOwners(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "try lock")
+	}
+	if len(owners) != 1 || owners[0] != lock.Owner {
+		if err := ch.deleteLock(ctx, lock.Owner); err != nil {
+			return false, errors.Wrap(err, "try lock")
+		}
+
+		return false, nil
+	}
+
+	ch.lock = lock
+
+	return true, nil
+}
+
+// Unlock removes the lock row inserted by TryLock.
+func (ch *Clickhouse) Unlock(ctx context.Context) error {
+	if ch.lock == nil {
+		return nil
+	}
+	owner := ch.lock.Owner
+	ch.lock = nil
+
+	return errors.Wrap(ch.deleteLock(ctx, owner), "unlock")
+}
+
+// LockHolder returns the owner of the first row of the lock table, or nil when the lock is free.
+func (ch *Clickhouse) LockHolder(ctx context.Context) (*entity.Lock, error) {
+	exists, err := ch.hasLockTable(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	var (
+		q = `
+			SELECT owner, acquired_at
+			FROM ` + ch.lockTableNameWithSchema() + `
+			ORDER BY acquired_at, owner
+			LIMIT 1
+		` + ch.lockSelectSettings()
+		lock entity.Lock
+	)
+
+	found, err := queryRow(ctx, ch.conn, q, nil, &lock.Owner, &lock.AcquiredAt)
+	if err != nil {
+		return nil, errors.Wrap(ch.dbError(err, q), "get lock holder")
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return &lock, nil
+}
+
+// ForceUnlock removes all rows of the lock table.
+func (ch *Clickhouse) ForceUnlock(ctx context.Context) error {
+	exists, err := ch.hasLockTable(ctx)
+	if err != nil || !exists {
+		return errors.Wrap(err, "force unlock")
+	}
+
+	q := "ALTER TABLE " + ch.lockTableNameWithSchema() + " DELETE WHERE 1 SETTINGS mutations_sync = 2"
+	if _, err := ch.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(ch.dbError(err, q), "force unlock")
+	}
+
+	return nil
+}
+
+// SessionLock returns false, because the lock row outlives a crashed holder.
+func (ch *Clickhouse) SessionLock() bool {
+	return false
+}
+
+// createLockTable creates the lock table if it does not exist.
+// In cluster and replicated modes all replicas of the table share one ZooKeeper path,
+// so every node of the cluster sees the same lock rows.
+func (ch *Clickhouse) createLockTable(ctx context.Context) error {
+	engine := "MergeTree"
+	if ch.isUsedCluster() || ch.options.Replicated {
+		engine = "ReplicatedMergeTree('/clickhouse/tables/" +
+			ch.options.ClusterName + "_" + ch.options.TableName + lockTableSuffix + "', '{replica}')"
+	}
+
+	q := "CREATE TABLE IF NOT EXISTS " + ch.lockTableNameWithSchema() + ch.onCluster() + ` (
+			owner String,
+			acquired_at Int64
+		) ENGINE = ` + engine + `
+		ORDER BY (acquired_at, owner)
+	`
+	if _, err := ch.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(ch.dbError(err, q), "create lock table")
+	}
+
+	return nil
+}
+
+// lockOwners returns the owners of up to two first rows of the lock table,
+// which is enough to tell whether a single process holds the lock.
+func (ch *Clickhouse) lockOwners(ctx context.Context) ([]string, error) {
+	q := `
+		SELECT owner
+		FROM ` + ch.lockTableNameWithSchema() + `
+		ORDER BY acquired_at, owner
+		LIMIT 2
+	` + ch.lockSelectSettings()
+
+	rows, err := ch.conn.QueryContext(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(ch.dbError(err, q), "get lock owners")
+	}
+	defer rows.Close()
+
+	var owners []string
+	for rows.Next() {
+		var owner string
+		if err := rows.Scan(&owner); err != nil {
+			return nil, errors.Wrap(ch.dbError(err, q), "get lock owners")
+		}
+		owners = append(owners, owner)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(ch.dbError(err, q), "get lock owners")
+	}
+
+	return owners, nil
+}
+
+// lockInsertSettings returns the settings of the lock row insert.
+// In cluster and replicated modes the insert succeeds only when the majority of replicas has the row.
+func (ch *Clickhouse) lockInsertSettings() string {
+	if ch.isUsedCluster() || ch.options.Replicated {
+		return " SETTINGS insert_quorum = 'auto', insert_quorum_parallel = 0"
+	}
+
+	return ""
+}
+
+// lockSelectSettings returns the settings of the lock table reads.
+// In cluster and replicated modes a read sees every row inserted with a quorum.
+func (ch *Clickhouse) lockSelectSettings() string {
+	if ch.isUsedCluster() || ch.options.Replicated {
+		return " SETTINGS select_sequential_consistency = 1"
+	}
+
+	return ""
+}
+
+// hasLockTable returns true if the lock table exists.
+func (ch *Clickhouse) hasLockTable(ctx context.Context) (bool, error) {
+	var (
+		q      = "EXISTS TABLE " + ch.lockTableNameWithSchema()
+		exists uint8
+	)
+	if err := ch.QueryScalar(ctx, q, &exists); err != nil {
+		return false, errors.Wrap(err, "check lock table")
+	}
+
+	return exists == 1, nil
+}
+
+// deleteLock removes the lock rows of the owner and waits until the deletion reaches every replica.
+func (ch *Clickhouse) deleteLock(ctx context.Context, owner string) error {
+	q := "ALTER TABLE " + ch.lockTableNameWithSchema() + " DELETE WHERE owner = ? SETTINGS mutations_sync = 2"
+	if _, err := ch.conn.ExecContext(ctx, q, owner); err != nil {
+		return ch.dbError(err, q)
+	}
+
+	return nil
+}
+
+// lockTableNameWithSchema returns the fully qualified name of the lock table.
+func (ch *Clickhouse) lockTableNameWithSchema() string {
+	return ch.TableNameWithSchema() + lockTableSuffix
+}
+
+// clickhouseLockTimeoutCode is DEADLOCK_AVOIDED, returned when lock_acquire_timeout expires.
+const clickhouseLockTimeoutCode = 473
+
 // dbError returns DBError is err is db error else returns got error.
 func (ch *Clickhouse) dbError(err error, q string) error {
 	var clickEx *clickhouse.Exception
@@ -429,10 +953,11 @@ func (ch *Clickhouse) dbError(err error, q string) error {
 	}
 
 	return errors.WithStack(&DBError{
-		Code:          string(clickEx.Code),
+		Code:          strconv.Itoa(int(clickEx.Code)),
 		Message:       clickEx.Message,
 		Details:       clickEx.StackTrace,
 		InternalQuery: q,
 		Cause:         err,
+		LockTimedOut:  clickEx.Code == clickhouseLockTimeoutCode,
 	})
 }
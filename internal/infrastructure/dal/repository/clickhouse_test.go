@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
 	thelp "github.com/raoptimus/db-migrator.go/internal/testutil"
 	"github.com/stretchr/testify/assert"
@@ -21,8 +24,15 @@ func TestClickhouse_CreateMigrationHistoryTable_Successfully(t *testing.T) {
 			version String, 
 			date Date DEFAULT toDate(apply_time),
 			apply_time UInt32,
-			is_deleted UInt8
-		) ENGINE = ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/test_cluster_migrates', '{replica}', apply_time)
+			is_deleted UInt8,
+			executed_sql String DEFAULT '',
+			down_sql String DEFAULT '',
+			checksum String DEFAULT '',
+			out_of_order UInt8 DEFAULT 0,
+			status String DEFAULT 'applied',
+			last_statement UInt32 DEFAULT 0,
+			write_time UInt64
+		) ENGINE = ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/test_cluster_migrates', '{replica}', write_time)
 		PRIMARY KEY (version)
 		PARTITION BY (toYYYYMM(date))
 		ORDER BY (version)
@@ -56,9 +66,9 @@ func TestClickhouse_Migrations_Failure(t *testing.T) {
 	ctx := context.Background()
 
 	expectedSQL := `
-		SELECT version, apply_time 
-		FROM default.d_migrates 
-		WHERE is_deleted = 0 
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
+		FROM default.d_migrates FINAL
+		WHERE is_deleted = 0
 		ORDER BY apply_time DESC, version DESC 
 		LIMIT ?
 	`
@@ -83,7 +93,7 @@ func TestClickhouse_ExistsMigration_Failure(t *testing.T) {
 	ctx := context.Background()
 
 	expectedSQL := `
-		SELECT 1 FROM default.d_migrates WHERE version = ? AND is_deleted = 0
+		SELECT 1 FROM default.d_migrates FINAL WHERE version = ? AND is_deleted = 0
 	`
 
 	conn := NewMockConnection(t)
@@ -131,16 +141,17 @@ func TestClickhouse_Migrations_Successfully(t *testing.T) {
 	ctx := context.Background()
 
 	expectedSQL := `
-		SELECT version, apply_time
-		FROM default.d_migrates
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
+		FROM default.d_migrates FINAL
 		WHERE is_deleted = 0
 		ORDER BY apply_time DESC, version DESC
 		LIMIT ?
 	`
 
 	rows := sqlex.NewRowsWithSlice([]interface{}{
-		[]any{"210328_221600_create_users", int64(1616968560)},
-		[]any{"210329_121500_add_index", int64(1617020100)},
+		[]any{"210328_221600_create_users", int64(1616968560), "CREATE TABLE users;\n", "DROP TABLE users;", "0f1e2d3c", uint8(1),
+			"failed", uint32(2)},
+		[]any{"210329_121500_add_index", int64(1617020100), "", "", "", uint8(0), "applied", uint32(0)},
 	})
 
 	conn := NewMockConnection(t)
@@ -161,7 +172,14 @@ func TestClickhouse_Migrations_Successfully(t *testing.T) {
 	require.Len(t, migrations, 2)
 	assert.Equal(t, "210328_221600_create_users", migrations[0].Version)
 	assert.Equal(t, int64(1616968560), migrations[0].ApplyTime)
+	assert.Equal(t, "CREATE TABLE users;\n", migrations[0].ExecutedSQL)
+	assert.Equal(t, "DROP TABLE users;", migrations[0].DownSQL)
+	assert.Equal(t, "0f1e2d3c", migrations[0].Checksum)
+	assert.True(t, migrations[0].OutOfOrder)
+	assert.Equal(t, entity.StatusFailed, migrations[0].Status)
+	assert.Equal(t, 2, migrations[0].LastStatement)
 	assert.Equal(t, "210329_121500_add_index", migrations[1].Version)
+	assert.False(t, migrations[1].OutOfOrder)
 	assert.Equal(t, int64(1617020100), migrations[1].ApplyTime)
 }
 
@@ -247,11 +265,21 @@ func TestClickhouse_InsertMigration_Successfully(t *testing.T) {
 
 	conn := NewMockConnection(t)
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32"), 0).
-		Return(nil, nil).
-		Once()
-	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string")).
+		ExecContext(ctx,
+			mock.MatchedBy(func(q string) bool {
+				return strings.Contains(q, "INSERT INTO default.d_migrates (") &&
+					strings.Contains(q, "toUInt64(toUnixTimestamp64Nano(now64(9)))")
+			}),
+			"210328_221600_test",
+			mock.AnythingOfType("uint32"),
+			0,
+			"CREATE TABLE test;\n",
+			"DROP TABLE test;",
+			"0f1e2d3c",
+			1,
+			"failed",
+			uint32(2),
+		).
 		Return(nil, nil).
 		Once()
 
@@ -261,7 +289,15 @@ func TestClickhouse_InsertMigration_Successfully(t *testing.T) {
 		ClusterName: "test_cluster",
 		Replicated:  false,
 	})
-	err := repo.InsertMigration(ctx, "210328_221600_test")
+	err := repo.InsertMigration(ctx, &entity.Migration{
+		Version:       "210328_221600_test",
+		ExecutedSQL:   "CREATE TABLE test;\n",
+		DownSQL:       "DROP TABLE test;",
+		Checksum:      "0f1e2d3c",
+		OutOfOrder:    true,
+		Status:        entity.StatusFailed,
+		LastStatement: 2,
+	})
 
 	require.NoError(t, err)
 }
@@ -271,7 +307,8 @@ func TestClickhouse_InsertMigration_ExecError_Failure(t *testing.T) {
 
 	conn := NewMockConnection(t)
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32"), 0).
+		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32"), 0, "", "", "", 0,
+			"applied", uint32(0)).
 		Return(nil, errors.New("exec failed")).
 		Once()
 
@@ -281,22 +318,42 @@ func TestClickhouse_InsertMigration_ExecError_Failure(t *testing.T) {
 		ClusterName: "test_cluster",
 		Replicated:  false,
 	})
-	err := repo.InsertMigration(ctx, "210328_221600_test")
+	err := repo.InsertMigration(ctx, &entity.Migration{Version: "210328_221600_test"})
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "insert migration")
 }
 
-func TestClickhouse_RemoveMigration_Successfully(t *testing.T) {
+func TestClickhouse_UpdateMigration_Successfully(t *testing.T) {
 	ctx := context.Background()
 
 	conn := NewMockConnection(t)
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32"), 1).
+		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32"), 0,
+			"CREATE TABLE test;\n", "", "", 0, "applied", uint32(0)).
 		Return(nil, nil).
 		Once()
+
+	repo := NewClickhouse(conn, &Options{
+		TableName:  "migrates",
+		SchemaName: "default",
+	})
+	err := repo.UpdateMigration(ctx, &entity.Migration{
+		Version:     "210328_221600_test",
+		ExecutedSQL: "CREATE TABLE test;\n",
+		Status:      entity.StatusApplied,
+	})
+
+	require.NoError(t, err)
+}
+
+func TestClickhouse_RemoveMigration_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	conn := NewMockConnection(t)
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string")).
+		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32"), 1, "", "", "", 0,
+			"applied", uint32(0)).
 		Return(nil, nil).
 		Once()
 
@@ -357,6 +414,22 @@ func TestClickhouse_ExecQuery_Failure(t *testing.T) {
 	require.Error(t, err)
 	var dbErr *DBError
 	require.ErrorAs(t, err, &dbErr)
+	assert.Equal(t, "62", dbErr.Code)
+}
+
+func TestClickhouse_ExecQuery_Timeouts_Successfully(t *testing.T) {
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(mock.Anything, "ALTER TABLE t ADD COLUMN c UInt8").
+		Return(nil, &clickhouse.Exception{Code: 473, Message: "Locking attempt timed out"}).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{LockTimeout: 1500 * time.Millisecond, StatementTimeout: time.Minute})
+	assert.Equal(t, clickhouse.Settings{"lock_acquire_timeout": int64(2), "max_execution_time": int64(60)}, repo.timeoutSettings())
+
+	err := repo.ExecQuery(context.Background(), "ALTER TABLE t ADD COLUMN c UInt8")
+	require.Error(t, err)
+	assert.True(t, IsLockTimeout(err))
 }
 
 func TestClickhouse_ExecQueryTransaction_Successfully(t *testing.T) {
@@ -384,7 +457,7 @@ func TestClickhouse_ExecQueryTransaction_Successfully(t *testing.T) {
 func TestClickhouse_MigrationsCount_Successfully(t *testing.T) {
 	ctx := context.Background()
 
-	expectedSQL := `SELECT count(*) FROM default.d_migrates WHERE is_deleted = 0`
+	expectedSQL := `SELECT count(*) FROM default.d_migrates FINAL WHERE is_deleted = 0`
 
 	rows := sqlex.NewRowsWithSlice([]interface{}{5})
 
@@ -618,8 +691,15 @@ func TestClickhouse_CreateMigrationHistoryTable_NoCluster_Successfully(t *testin
 			version String,
 			date Date DEFAULT toDate(apply_time),
 			apply_time UInt32,
-			is_deleted UInt8
-		) ENGINE = ReplacingMergeTree(apply_time)
+			is_deleted UInt8,
+			executed_sql String DEFAULT '',
+			down_sql String DEFAULT '',
+			checksum String DEFAULT '',
+			out_of_order UInt8 DEFAULT 0,
+			status String DEFAULT 'applied',
+			last_statement UInt32 DEFAULT 0,
+			write_time UInt64
+		) ENGINE = ReplacingMergeTree(write_time)
 		PRIMARY KEY (version)
 		PARTITION BY (toYYYYMM(date))
 		ORDER BY (version)
@@ -680,6 +760,258 @@ func TestClickhouse_CreateMigrationHistoryTable_Failure(t *testing.T) {
 	assert.Contains(t, err.Error(), "create migration history table")
 }
 
+func TestClickhouse_UpgradeMigrationHistoryTable_Cluster_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	expectedSQL := `
+		SELECT name
+		FROM system.columns
+		WHERE table = ? AND database = currentDatabase()
+	`
+	rows := sqlex.NewRowsWithSlice([]interface{}{"version", "date", "apply_time", "is_deleted"})
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL(expectedSQL)), "d_migrates").
+		Return(rows, nil).
+		Once()
+	for _, q := range []string{
+		"ALTER TABLE default.migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS executed_sql String DEFAULT ''",
+		"ALTER TABLE default.d_migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS executed_sql String DEFAULT ''",
+		"ALTER TABLE default.migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS down_sql String DEFAULT ''",
+		"ALTER TABLE default.d_migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS down_sql String DEFAULT ''",
+		"ALTER TABLE default.migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS checksum String DEFAULT ''",
+		"ALTER TABLE default.d_migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS checksum String DEFAULT ''",
+		"ALTER TABLE default.migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS out_of_order UInt8 DEFAULT 0",
+		"ALTER TABLE default.d_migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS out_of_order UInt8 DEFAULT 0",
+		"ALTER TABLE default.migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS status String DEFAULT 'applied'",
+		"ALTER TABLE default.d_migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS status String DEFAULT 'applied'",
+		"ALTER TABLE default.migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS last_statement UInt32 DEFAULT 0",
+		"ALTER TABLE default.d_migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS last_statement UInt32 DEFAULT 0",
+	} {
+		conn.EXPECT().
+			ExecContext(ctx, q).
+			Return(nil, nil).
+			Once()
+	}
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL(expectedSQL)), "migrates").
+		Return(sqlex.NewRowsWithSlice([]interface{}{"version", "date", "apply_time", "is_deleted"}), nil).
+		Once()
+	for _, q := range []string{
+		"DROP TABLE IF EXISTS default.d_migrates_new ON CLUSTER test_cluster NO DELAY",
+		"DROP TABLE IF EXISTS default.migrates_new ON CLUSTER test_cluster NO DELAY",
+		"DROP TABLE IF EXISTS default.migrates_old ON CLUSTER test_cluster NO DELAY",
+		`CREATE TABLE default.migrates_new ON CLUSTER test_cluster (
+			version String,
+			date Date DEFAULT toDate(apply_time),
+			apply_time UInt32,
+			is_deleted UInt8,
+			executed_sql String DEFAULT '',
+			down_sql String DEFAULT '',
+			checksum String DEFAULT '',
+			out_of_order UInt8 DEFAULT 0,
+			status String DEFAULT 'applied',
+			last_statement UInt32 DEFAULT 0,
+			write_time UInt64
+		) ENGINE = ReplicatedReplacingMergeTree(
+			'/clickhouse/tables/{shard}/test_cluster_migrates_write_time', '{replica}', write_time
+		)
+		PRIMARY KEY (version)
+		PARTITION BY (toYYYYMM(date))
+		ORDER BY (version)
+		SETTINGS index_granularity=8192`,
+		`CREATE TABLE default.d_migrates_new ON CLUSTER test_cluster AS default.migrates_new
+		ENGINE = Distributed('test_cluster', 'default', migrates_new, cityHash64(toString(version)))`,
+		`INSERT INTO default.d_migrates_new (
+			version, apply_time, is_deleted, executed_sql, down_sql, checksum, out_of_order, status, last_statement,
+			write_time
+		)
+		SELECT
+			version, apply_time, is_deleted, executed_sql, down_sql, checksum, out_of_order, status, last_statement,
+			toUInt64(apply_time) * 1000000000
+		FROM default.d_migrates FINAL SETTINGS insert_distributed_sync = 1`,
+		"DROP TABLE default.d_migrates_new ON CLUSTER test_cluster NO DELAY",
+		"RENAME TABLE default.migrates TO default.migrates_old, default.migrates_new TO default.migrates " +
+			"ON CLUSTER test_cluster",
+		"DROP TABLE IF EXISTS default.migrates_old ON CLUSTER test_cluster NO DELAY",
+		"ALTER TABLE default.d_migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS write_time UInt64",
+	} {
+		conn.EXPECT().
+			ExecContext(ctx, mock.MatchedBy(thelp.CompareSQL(q))).
+			Return(nil, nil).
+			Once()
+	}
+
+	repo := NewClickhouse(conn, &Options{
+		TableName:   "migrates",
+		SchemaName:  "default",
+		ClusterName: "test_cluster",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+
+	require.NoError(t, err)
+}
+
+func TestClickhouse_UpgradeMigrationHistoryTable_NoCluster_UpToDate_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	rows := sqlex.NewRowsWithSlice([]interface{}{
+		"version", "date", "apply_time", "is_deleted", "executed_sql", "down_sql", "checksum", "out_of_order",
+		"status", "last_statement", "write_time",
+	})
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.AnythingOfType("string"), "migrates").
+		Return(rows, nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{
+		TableName:  "migrates",
+		SchemaName: "default",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+
+	require.NoError(t, err)
+}
+
+func TestClickhouse_UpgradeMigrationHistoryTable_NoCluster_Convert_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	rows := sqlex.NewRowsWithSlice([]interface{}{
+		"version", "date", "apply_time", "is_deleted", "executed_sql", "down_sql", "checksum", "out_of_order",
+		"status", "last_statement",
+	})
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.AnythingOfType("string"), "migrates").
+		Return(rows, nil).
+		Twice()
+	for _, q := range []string{
+		"DROP TABLE IF EXISTS default.migrates_new NO DELAY",
+		"DROP TABLE IF EXISTS default.migrates_old NO DELAY",
+	} {
+		conn.EXPECT().
+			ExecContext(ctx, q).
+			Return(nil, nil).
+			Once()
+	}
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "CREATE TABLE default.migrates_new  (") &&
+				strings.Contains(q, "ENGINE = ReplacingMergeTree(write_time)")
+		})).
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.HasPrefix(q, "INSERT INTO default.migrates_new (") &&
+				strings.HasSuffix(q, "FROM default.migrates FINAL")
+		})).
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "RENAME TABLE default.migrates TO default.migrates_old, default.migrates_new TO default.migrates").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "DROP TABLE IF EXISTS default.migrates_old NO DELAY").
+		Return(nil, nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{
+		TableName:  "migrates",
+		SchemaName: "default",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+
+	require.NoError(t, err)
+}
+
+func TestClickhouse_NeedsMigrationHistoryTableUpgrade_WithoutWriteTime(t *testing.T) {
+	ctx := context.Background()
+
+	rows := sqlex.NewRowsWithSlice([]interface{}{
+		"version", "date", "apply_time", "is_deleted", "executed_sql", "down_sql", "checksum", "out_of_order",
+		"status", "last_statement",
+	})
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.AnythingOfType("string"), "migrates").
+		Return(rows, nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{
+		TableName:  "migrates",
+		SchemaName: "default",
+	})
+	needs, err := repo.NeedsMigrationHistoryTableUpgrade(ctx)
+
+	require.NoError(t, err)
+	assert.True(t, needs)
+}
+
+// TestClickhouse_UpgradeMigrationHistoryTable_Cluster_ResumeConvert_Successfully verifies that a conversion
+// stopped after the rename does not rebuild the table again and finishes with the steps left.
+func TestClickhouse_UpgradeMigrationHistoryTable_Cluster_ResumeConvert_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	columns := []interface{}{
+		"version", "date", "apply_time", "is_deleted", "executed_sql", "down_sql", "checksum", "out_of_order",
+		"status", "last_statement",
+	}
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.AnythingOfType("string"), "d_migrates").
+		Return(sqlex.NewRowsWithSlice(columns), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.AnythingOfType("string"), "migrates").
+		Return(sqlex.NewRowsWithSlice(append(columns, "write_time")), nil).
+		Once()
+	for _, q := range []string{
+		"DROP TABLE IF EXISTS default.migrates_old ON CLUSTER test_cluster NO DELAY",
+		"ALTER TABLE default.d_migrates ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS write_time UInt64",
+	} {
+		conn.EXPECT().
+			ExecContext(ctx, q).
+			Return(nil, nil).
+			Once()
+	}
+
+	repo := NewClickhouse(conn, &Options{
+		TableName:   "migrates",
+		SchemaName:  "default",
+		ClusterName: "test_cluster",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+
+	require.NoError(t, err)
+}
+
+func TestClickhouse_UpgradeMigrationHistoryTable_Failure(t *testing.T) {
+	ctx := context.Background()
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.AnythingOfType("string"), "migrates").
+		Return(nil, errors.New("oops")).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{
+		TableName:  "migrates",
+		SchemaName: "default",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upgrade migration history table")
+}
+
 func TestClickhouse_dbError_ClickhouseException_Successfully(t *testing.T) {
 	repo := NewClickhouse(nil, &Options{
 		TableName:  "migrates",
@@ -718,3 +1050,249 @@ func TestClickhouse_dbError_NonClickhouseException_Successfully(t *testing.T) {
 	var dbErr *DBError
 	assert.False(t, errors.As(err, &dbErr))
 }
+
+func TestClickhouse_TryLock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	lock := &entity.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}
+
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(thelp.CompareSQL(`
+			CREATE TABLE IF NOT EXISTS default.migration_lock (
+				owner String,
+				acquired_at Int64
+			) ENGINE = MergeTree
+			ORDER BY (acquired_at, owner)
+		`))).
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, "EXISTS TABLE default.migration_lock").
+		Return(sqlex.NewRowsWithSlice([]any{uint8(1)}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL(`
+			SELECT owner, acquired_at
+			FROM default.migration_lock
+			ORDER BY acquired_at, owner
+			LIMIT 1
+		`))).
+		Return(sqlex.NewRowsWithSlice([]any{}), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "INSERT INTO default.migration_lock (owner, acquired_at) VALUES (?, ?)", lock.Owner, lock.AcquiredAt).
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL("SELECT owner FROM default.migration_lock ORDER BY acquired_at, owner LIMIT 2"))).
+		Return(sqlex.NewRowsWithSlice([]any{lock.Owner}), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE default.migration_lock DELETE WHERE owner = ? SETTINGS mutations_sync = 2", lock.Owner).
+		Return(nil, nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{TableName: "migration", SchemaName: "default"})
+	acquired, err := repo.TryLock(ctx, lock)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	require.NoError(t, repo.Unlock(ctx))
+}
+
+func TestClickhouse_TryLock_Replicated_UsesQuorum(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	lock := &entity.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}
+
+	conn.EXPECT().ExecContext(ctx, mock.MatchedBy(thelp.CompareSQL(`
+		CREATE TABLE IF NOT EXISTS default.migration_lock (
+			owner String,
+			acquired_at Int64
+		) ENGINE = ReplicatedMergeTree('/clickhouse/tables/test_cluster_migration_lock', '{replica}')
+		ORDER BY (acquired_at, owner)
+	`))).Return(nil, nil).Once()
+	conn.EXPECT().
+		QueryContext(ctx, "EXISTS TABLE default.migration_lock").
+		Return(sqlex.NewRowsWithSlice([]any{uint8(1)}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL(`
+			SELECT owner, acquired_at
+			FROM default.migration_lock
+			ORDER BY acquired_at, owner
+			LIMIT 1
+			SETTINGS select_sequential_consistency = 1
+		`))).
+		Return(sqlex.NewRowsWithSlice([]any{}), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx,
+			"INSERT INTO default.migration_lock (owner, acquired_at) "+
+				"SETTINGS insert_quorum = 'auto', insert_quorum_parallel = 0 VALUES (?, ?)",
+			lock.Owner, lock.AcquiredAt,
+		).
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL(`
+			SELECT owner
+			FROM default.migration_lock
+			ORDER BY acquired_at, owner
+			LIMIT 2
+			SETTINGS select_sequential_consistency = 1
+		`))).
+		Return(sqlex.NewRowsWithSlice([]any{lock.Owner}), nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{
+		TableName:   "migration",
+		SchemaName:  "default",
+		ClusterName: "test_cluster",
+		Replicated:  true,
+	})
+	acquired, err := repo.TryLock(ctx, lock)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestClickhouse_TryLock_CreatesLockTableOnce(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	lock := &entity.Lock{Owner: "db-migrator@host:2", AcquiredAt: 1700000000}
+
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(thelp.CompareSQL(`
+			CREATE TABLE IF NOT EXISTS default.migration_lock (
+				owner String,
+				acquired_at Int64
+			) ENGINE = MergeTree
+			ORDER BY (acquired_at, owner)
+		`))).
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, "EXISTS TABLE default.migration_lock").
+		RunAndReturn(func(context.Context, string, ...any) (sqlex.Rows, error) {
+			return sqlex.NewRowsWithSlice([]any{uint8(1)}), nil
+		}).
+		Twice()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL("SELECT owner, acquired_at FROM default.migration_lock ORDER BY acquired_at, owner LIMIT 1"))).
+		RunAndReturn(func(context.Context, string, ...any) (sqlex.Rows, error) {
+			return sqlex.NewRowsWithSlice([]any{[]any{"db-migrator@host:1", int64(1700000000)}}), nil
+		}).
+		Twice()
+
+	repo := NewClickhouse(conn, &Options{TableName: "migration", SchemaName: "default"})
+	for range 2 {
+		acquired, err := repo.TryLock(ctx, lock)
+		require.NoError(t, err)
+		assert.False(t, acquired)
+	}
+}
+
+func TestClickhouse_TryLock_LostRace_RemovesOwnRow(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	lock := &entity.Lock{Owner: "db-migrator@host:2", AcquiredAt: 1700000000}
+	holderSQL := mock.MatchedBy(thelp.CompareSQL("SELECT owner, acquired_at FROM default.migration_lock ORDER BY acquired_at, owner LIMIT 1"))
+
+	conn.EXPECT().ExecContext(ctx, mock.Anything).Return(nil, nil).Once()
+	conn.EXPECT().
+		QueryContext(ctx, "EXISTS TABLE default.migration_lock").
+		Return(sqlex.NewRowsWithSlice([]any{uint8(1)}), nil).
+		Once()
+	conn.EXPECT().QueryContext(ctx, holderSQL).Return(sqlex.NewRowsWithSlice([]any{}), nil).Once()
+	conn.EXPECT().ExecContext(ctx, mock.Anything, lock.Owner, lock.AcquiredAt).Return(nil, nil).Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL("SELECT owner FROM default.migration_lock ORDER BY acquired_at, owner LIMIT 2"))).
+		Return(sqlex.NewRowsWithSlice([]any{"db-migrator@host:1", lock.Owner}), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE default.migration_lock DELETE WHERE owner = ? SETTINGS mutations_sync = 2", lock.Owner).
+		Return(nil, nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{TableName: "migration", SchemaName: "default"})
+	acquired, err := repo.TryLock(ctx, lock)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+}
+
+func TestClickhouse_CreateLockTable_Cluster_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(thelp.CompareSQL(`
+			CREATE TABLE IF NOT EXISTS default.migration_lock ON CLUSTER test_cluster (
+				owner String,
+				acquired_at Int64
+			) ENGINE = ReplicatedMergeTree('/clickhouse/tables/test_cluster_migration_lock', '{replica}')
+			ORDER BY (acquired_at, owner)
+		`))).
+		Return(nil, nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{TableName: "migration", SchemaName: "default", ClusterName: "test_cluster"})
+	require.NoError(t, repo.createLockTable(ctx))
+}
+
+func TestClickhouse_LockHolder_NoLockTable(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, "EXISTS TABLE default.migration_lock").
+		Return(sqlex.NewRowsWithSlice([]any{uint8(0)}), nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{TableName: "migration", SchemaName: "default"})
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+}
+
+func TestClickhouse_ForceUnlock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, "EXISTS TABLE default.migration_lock").
+		Return(sqlex.NewRowsWithSlice([]any{uint8(1)}), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE default.migration_lock DELETE WHERE 1 SETTINGS mutations_sync = 2").
+		Return(nil, nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{TableName: "migration", SchemaName: "default"})
+	require.NoError(t, repo.ForceUnlock(ctx))
+}
+
+func TestClickhouse_DumpSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM system.tables")
+		}), "default", "migration", "migration", "migration_lock").
+		Return(sqlex.NewRowsWithSlice([]any{"events"}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, "SHOW CREATE TABLE default.events").
+		Return(sqlex.NewRowsWithSlice([]any{
+			"CREATE TABLE default.events\n(\n    `id` UInt64\n)\nENGINE = MergeTree\nORDER BY id",
+		}), nil).
+		Once()
+
+	repo := NewClickhouse(conn, &Options{TableName: "migration", SchemaName: "default"})
+	schema, err := repo.DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE default.events\n(\n    `id` UInt64\n)\nENGINE = MergeTree\nORDER BY id;\n", schema)
+}
+
+func TestClickhouse_LoadSchema_Unsupported(t *testing.T) {
+	repo := NewClickhouse(NewMockConnection(t), &Options{})
+
+	_, err := repo.LoadSchema(context.Background())
+	require.ErrorIs(t, err, ErrLoadSchemaUnsupported)
+}
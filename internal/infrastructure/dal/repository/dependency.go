@@ -30,6 +30,8 @@ type IcebergCatalog interface {
 	LoadNamespaceProperties(ctx context.Context, ns []string) (map[string]string, error)
 	// UpdateNamespaceProperties updates namespace properties by removing and setting keys.
 	UpdateNamespaceProperties(ctx context.Context, ns []string, removals []string, updates map[string]string) error
+	// ListNamespaces returns the top-level namespaces of the catalog.
+	ListNamespaces(ctx context.Context) ([][]string, error)
 
 	// CreateTable creates an Iceberg table from the given IR specification.
 	CreateTable(ctx context.Context, ident ddl.Ident, spec ddl.CreateTableSpec) error
@@ -37,6 +39,12 @@ type IcebergCatalog interface {
 	TableExists(ctx context.Context, ident ddl.Ident) (bool, error)
 	// DropTable drops an Iceberg table identified by ident.
 	DropTable(ctx context.Context, ident ddl.Ident) error
+	// ListTables returns the tables of the given namespace.
+	ListTables(ctx context.Context, ns []string) ([]ddl.Ident, error)
+	// DescribeTable returns the schema, partition spec and sort order of the table as text.
+	DescribeTable(ctx context.Context, ident ddl.Ident) (string, error)
+	// LoadTable returns the schema, partition spec, sort order and properties of the table as IR.
+	LoadTable(ctx context.Context, ident ddl.Ident) (*ddl.Table, error)
 	// RenameTable renames an Iceberg table from from to to.
 	RenameTable(ctx context.Context, from, to ddl.Ident) error
 	// ApplySchemaChange applies a schema-level DDL operation (AddColumn, DropColumn,
@@ -70,3 +78,17 @@ type Connection interface {
 	// Transaction executes a function within a database transaction.
 	Transaction(ctx context.Context, txFn func(ctx context.Context) error) error
 }
+
+// Session is a single database session pinned for exclusive use.
+//
+//go:generate mockery
+type Session = connection.Session
+
+//go:generate mockery
+
+// SessionConnection is implemented by connections that can pin a single database session.
+// PostgreSQL and MySQL hold the migration lock on such a session, because their locks
+// are released as soon as the session that took them ends.
+type SessionConnection interface {
+	Session(ctx context.Context) (connection.Session, error)
+}
@@ -17,6 +17,9 @@ import (
 var (
 	// ErrPtrValueMustBeAPointerAndScalar is returned when a value is not a pointer to a scalar type.
 	ErrPtrValueMustBeAPointerAndScalar = errors.New("ptr value must be a pointer and scalar")
+	// ErrLoadSchemaUnsupported is returned by LoadSchema of the drivers whose schema cannot be read
+	// into the DDL IR of the schema diff.
+	ErrLoadSchemaUnsupported = errors.New("the live schema can only be loaded from Iceberg")
 )
 
 // DBError represents a database error with additional metadata.
@@ -28,6 +31,9 @@ type DBError struct {
 	Details       string
 	InternalQuery string
 	Cause         error
+	// LockTimedOut is set by the driver whose error code means that the statement gave up waiting for a lock.
+	// The codes differ between the databases, the same number may mean a lock timeout in one and not in another.
+	LockTimedOut bool
 }
 
 // Error returns the formatted error message including SQL state, severity, message, query, and details.
@@ -64,3 +70,16 @@ func (d *DBError) Error() string {
 func (d *DBError) Unwrap() error {
 	return d.Cause
 }
+
+// LockTimeout reports whether the statement failed because it gave up waiting for a lock,
+// so running it again later may succeed.
+func (d *DBError) LockTimeout() bool {
+	return d.LockTimedOut
+}
+
+// IsLockTimeout reports whether err is a DBError of a statement that gave up waiting for a lock.
+func IsLockTimeout(err error) bool {
+	var dbErr *DBError
+
+	return errors.As(err, &dbErr) && dbErr.LockTimeout()
+}
@@ -11,6 +11,10 @@ package repository
 import (
 	"testing"
 
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	mssql "github.com/microsoft/go-mssqldb"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -166,3 +170,41 @@ func TestDBError_ImplementsError_Successfully(t *testing.T) {
 	// Verify it implements error interface
 	require.NotNil(t, err.Error())
 }
+
+func TestIsLockTimeout(t *testing.T) {
+	assert.True(t, IsLockTimeout(errors.WithStack(&DBError{Code: "55P03", LockTimedOut: true})))
+	assert.True(t, IsLockTimeout(errors.Wrap(&DBError{Code: "1205", LockTimedOut: true}, "apply")))
+	assert.False(t, IsLockTimeout(&DBError{Code: "55P03"}))
+	assert.False(t, IsLockTimeout(errors.New("lock timeout")))
+	assert.False(t, IsLockTimeout(nil))
+}
+
+func TestDBError_LockTimeout_ByDriver(t *testing.T) {
+	var (
+		pg = NewPostgres(nil, &Options{})
+		my = NewMySQL(nil, &Options{})
+		ch = NewClickhouse(nil, &Options{})
+		ms = NewMSSQL(nil, &Options{})
+	)
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "postgres lock_not_available", err: pg.dbError(&pq.Error{Code: "55P03"}, ""), want: true},
+		{name: "postgres query_canceled", err: pg.dbError(&pq.Error{Code: "57014"}, ""), want: false},
+		{name: "mysql lock wait timeout", err: my.dbError(&mysql.MySQLError{Number: 1205}, ""), want: true},
+		{name: "mysql wrong number of columns", err: my.dbError(&mysql.MySQLError{Number: 1222}, ""), want: false},
+		{name: "mysql error 5", err: my.dbError(&mysql.MySQLError{Number: 5}, ""), want: false},
+		{name: "clickhouse deadlock avoided", err: ch.dbError(&clickhouse.Exception{Code: 473}, ""), want: true},
+		{name: "mssql lock request timeout", err: ms.dbError(mssql.Error{Number: 1222}, ""), want: true},
+		{name: "mssql deadlock victim", err: ms.dbError(mssql.Error{Number: 1205}, ""), want: false},
+		{name: "mssql error 5", err: ms.dbError(mssql.Error{Number: 5}, ""), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsLockTimeout(tt.err))
+		})
+	}
+}
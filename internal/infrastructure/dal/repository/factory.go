@@ -44,6 +44,10 @@ func NewFactoryRegistry() *FactoryRegistry {
 			&MySQLFactory{},
 			&ClickhouseFactory{},
 			&IcebergFactory{},
+			&SQLiteFactory{},
+			&MSSQLFactory{},
+			&CockroachDBFactory{},
+			&YugabyteDBFactory{},
 		},
 	}
 }
@@ -98,10 +102,12 @@ func (f *ClickhouseFactory) Create(conn Connection, options *Options) (Repositor
 	}
 
 	return NewClickhouse(conn, &Options{
-		SchemaName:  opts.Auth.Database,
-		TableName:   tableName,
-		ClusterName: options.ClusterName,
-		Replicated:  options.Replicated,
+		SchemaName:       opts.Auth.Database,
+		TableName:        tableName,
+		ClusterName:      options.ClusterName,
+		Replicated:       options.Replicated,
+		StatementTimeout: options.StatementTimeout,
+		LockTimeout:      options.LockTimeout,
 	}), nil
 }
 
@@ -136,8 +142,10 @@ func (f *MySQLFactory) Create(conn Connection, options *Options) (Repository, er
 	}
 
 	return NewMySQL(conn, &Options{
-		TableName:  options.TableName,
-		SchemaName: cfg.DBName,
+		TableName:        options.TableName,
+		SchemaName:       cfg.DBName,
+		StatementTimeout: options.StatementTimeout,
+		LockTimeout:      options.LockTimeout,
 	}), nil
 }
 
@@ -162,8 +170,66 @@ func (f *PostgresFactory) Create(conn Connection, options *Options) (Repository,
 	}
 
 	return NewPostgres(conn, &Options{
-		TableName:  tableName,
-		SchemaName: schemaName,
+		TableName:        tableName,
+		SchemaName:       schemaName,
+		StatementTimeout: options.StatementTimeout,
+		LockTimeout:      options.LockTimeout,
+	}), nil
+}
+
+// CockroachDBFactory
+
+// CockroachDBFactory creates repository instances for CockroachDB databases.
+type CockroachDBFactory struct{}
+
+// Supports returns true if the driver is CockroachDB.
+func (f *CockroachDBFactory) Supports(driver connection.Driver) bool {
+	return driver == connection.DriverCockroachDB
+}
+
+// Create creates a new Postgres repository instance with the CockroachDB overrides and validated identifiers.
+// It parses schema and table names from the table name option and validates all identifiers.
+//
+//nolint:ireturn,nolintlint // its ok
+func (f *CockroachDBFactory) Create(conn Connection, options *Options) (Repository, error) {
+	schemaName, tableName, err := parseAndValidateSchemaTableName(options.TableName, postgresDefaultSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCockroachDB(conn, &Options{
+		TableName:        tableName,
+		SchemaName:       schemaName,
+		StatementTimeout: options.StatementTimeout,
+		LockTimeout:      options.LockTimeout,
+	}), nil
+}
+
+// YugabyteDBFactory
+
+// YugabyteDBFactory creates repository instances for YugabyteDB databases.
+type YugabyteDBFactory struct{}
+
+// Supports returns true if the driver is YugabyteDB.
+func (f *YugabyteDBFactory) Supports(driver connection.Driver) bool {
+	return driver == connection.DriverYugabyteDB
+}
+
+// Create creates a new Postgres repository instance with the YugabyteDB overrides and validated identifiers.
+// It parses schema and table names from the table name option and validates all identifiers.
+//
+//nolint:ireturn,nolintlint // its ok
+func (f *YugabyteDBFactory) Create(conn Connection, options *Options) (Repository, error) {
+	schemaName, tableName, err := parseAndValidateSchemaTableName(options.TableName, postgresDefaultSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewYugabyteDB(conn, &Options{
+		TableName:        tableName,
+		SchemaName:       schemaName,
+		StatementTimeout: options.StatementTimeout,
+		LockTimeout:      options.LockTimeout,
 	}), nil
 }
 
@@ -270,3 +336,58 @@ func (f *IcebergFactory) Create(conn Connection, options *Options) (Repository,
 
 	return NewIceberg(cat, &Options{TableName: options.TableName}), nil
 }
+
+// SQLiteFactory
+
+// SQLiteFactory creates repository instances for SQLite databases.
+type SQLiteFactory struct{}
+
+// Supports returns true if the driver is SQLite.
+func (f *SQLiteFactory) Supports(driver connection.Driver) bool {
+	return driver == connection.DriverSQLite
+}
+
+// Create creates a new SQLite repository instance with validated table name.
+// An SQLite database has no schemas, so a schema prefix of the table name is validated and dropped.
+//
+//nolint:ireturn,nolintlint // its ok
+func (f *SQLiteFactory) Create(conn Connection, options *Options) (Repository, error) {
+	tableName, err := parseAndValidateTableName(options.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSQLite(conn, &Options{
+		TableName:        tableName,
+		StatementTimeout: options.StatementTimeout,
+		LockTimeout:      options.LockTimeout,
+	}), nil
+}
+
+// MSSQLFactory
+
+// MSSQLFactory creates repository instances for Microsoft SQL Server databases.
+type MSSQLFactory struct{}
+
+// Supports returns true if the driver is SQL Server.
+func (f *MSSQLFactory) Supports(driver connection.Driver) bool {
+	return driver == connection.DriverMSSQL
+}
+
+// Create creates a new SQL Server repository instance with validated identifiers.
+// It parses schema and table names from the table name option and validates all identifiers.
+//
+//nolint:ireturn,nolintlint // its ok
+func (f *MSSQLFactory) Create(conn Connection, options *Options) (Repository, error) {
+	schemaName, tableName, err := parseAndValidateSchemaTableName(options.TableName, mssqlDefaultSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMSSQL(conn, &Options{
+		TableName:        tableName,
+		SchemaName:       schemaName,
+		StatementTimeout: options.StatementTimeout,
+		LockTimeout:      options.LockTimeout,
+	}), nil
+}
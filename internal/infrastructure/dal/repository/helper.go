@@ -1,7 +1,14 @@
 package repository
 
 import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
 	"time"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
 )
 
 // checkArgIsPtrAndScalar validates that the provided argument is a pointer to a scalar type.
@@ -25,3 +32,191 @@ func checkArgIsPtrAndScalar(ptr any) error {
 		return ErrPtrValueMustBeAPointerAndScalar
 	}
 }
+
+// historyColumn describes a migration history table column and its driver-specific DDL type.
+type historyColumn struct {
+	Name string
+	Type string
+}
+
+// sessionSetting is a database setting applied to a migration statement.
+type sessionSetting struct {
+	Name  string
+	Value string
+}
+
+// queryColumnNames runs a query returning one column name per row and collects the names into a set.
+func queryColumnNames(ctx context.Context, conn Connection, query string, args ...any) (map[string]struct{}, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[string]struct{})
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names[name] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// missingHistoryColumns returns the columns that are not present in the existing set, preserving their order.
+func missingHistoryColumns(existing map[string]struct{}, columns []historyColumn) []historyColumn {
+	var missing []historyColumn
+	for _, column := range columns {
+		if _, ok := existing[column.Name]; !ok {
+			missing = append(missing, column)
+		}
+	}
+
+	return missing
+}
+
+// lockTableSuffix is appended to the history table name to name the table, space or namespace
+// that holds the migration lock on drivers without session-level locks.
+const lockTableSuffix = "_lock"
+
+// lockNamePrefix prefixes the name of session-level locks, so they do not clash with locks of other applications.
+const lockNamePrefix = "db-migrator:"
+
+// lockKey hashes a lock name into a non-negative 32-bit key, as used by PostgreSQL advisory locks.
+func lockKey(name string) int64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+
+	return int64(h.Sum32() & math.MaxInt32)
+}
+
+// queryRow runs a query and scans its first row into dest.
+// It returns false when the query returns no rows.
+func queryRow(ctx context.Context, db connection.DBQuerier, query string, args []any, dest ...any) (bool, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return false, err
+	}
+
+	return true, rows.Err()
+}
+
+// openSession pins a single database session of the connection.
+// It returns ErrSessionNotSupported when the connection cannot pin sessions.
+//
+//nolint:ireturn,nolintlint // its ok
+func openSession(ctx context.Context, conn Connection) (Session, error) {
+	sc, ok := conn.(SessionConnection)
+	if !ok {
+		return nil, errors.Wrapf(connection.ErrSessionNotSupported, "driver %v", conn.Driver())
+	}
+
+	return sc.Session(ctx)
+}
+
+// execWithSettings executes query with session settings that apply to it only: the set statements run before
+// the query and the reset statements after it. Within a transaction they run in the transaction, otherwise
+// the query runs on a session pinned for it, so the settings never reach other queries of the pool.
+func execWithSettings(ctx context.Context, conn Connection, set, reset []string, query string, args ...any) error {
+	var db connection.DBExecutor = conn
+	if _, err := connection.TxFromContext(ctx); err != nil {
+		session, err := openSession(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer session.Close()
+		db = session
+	}
+
+	for _, q := range set {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return errors.Wrap(err, "apply statement settings")
+		}
+	}
+	_, err := db.ExecContext(ctx, query, args...)
+	// the settings are reset even when the query has timed out
+	resetCtx := context.WithoutCancel(ctx)
+	for _, q := range reset {
+		if _, resetErr := db.ExecContext(resetCtx, q); resetErr != nil && err == nil {
+			err = errors.Wrap(resetErr, "reset statement settings")
+		}
+	}
+
+	return err
+}
+
+// durationMillis returns d in whole milliseconds, rounded up, so a short timeout does not become no timeout.
+func durationMillis(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// durationSeconds returns d in whole seconds, rounded up, so a short timeout does not become no timeout.
+func durationSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// queryStrings runs a query returning rows of the given number of string columns and collects them.
+func queryStrings(ctx context.Context, db connection.DBQuerier, query string, columns int, args ...any) ([][]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result [][]string
+	for rows.Next() {
+		row := make([]string, columns)
+		dest := make([]any, columns)
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// schemaDump collects the statements of a schema dump, each ended with a semicolon
+// and separated from the previous one by an empty line.
+type schemaDump struct {
+	sb strings.Builder
+	// batchSeparator, when set, is written on its own line after every statement,
+	// such as the GO of SQL Server, whose procedures and views must be the only statement of a batch.
+	batchSeparator string
+}
+
+// add appends statement to the dump.
+func (d *schemaDump) add(statement string) {
+	if d.sb.Len() > 0 {
+		d.sb.WriteString("\n")
+	}
+	d.sb.WriteString(strings.TrimRight(strings.TrimSpace(statement), ";"))
+	d.sb.WriteString(";\n")
+	if d.batchSeparator != "" {
+		d.sb.WriteString(d.batchSeparator + "\n")
+	}
+}
+
+// String returns the collected statements.
+func (d *schemaDump) String() string {
+	return d.sb.String()
+}
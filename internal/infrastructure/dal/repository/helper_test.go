@@ -158,3 +158,24 @@ func TestCheckArgIsPtrAndScalar_InvalidTypes_Failure(t *testing.T) {
 		})
 	}
 }
+
+// sessionConnection combines connection and session opener mocks,
+// as a connection that can pin database sessions does.
+type sessionConnection struct {
+	*MockConnection
+	*MockSessionConnection
+}
+
+func newSessionConnection(t *testing.T) (*sessionConnection, *MockConnection, *MockSessionConnection) {
+	t.Helper()
+	conn := NewMockConnection(t)
+	opener := NewMockSessionConnection(t)
+
+	return &sessionConnection{MockConnection: conn, MockSessionConnection: opener}, conn, opener
+}
+
+func TestLockKey_IsStableAndNonNegative(t *testing.T) {
+	assert.Equal(t, lockKey("public.migration"), lockKey("public.migration"))
+	assert.NotEqual(t, lockKey("public.migration"), lockKey("public.other"))
+	assert.GreaterOrEqual(t, lockKey("db-migrator:"), int64(0))
+}
@@ -11,6 +11,7 @@ package repository
 import (
 	"context"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -27,11 +28,36 @@ var ErrNotImplemented = errors.New("iceberg repository: not implemented")
 // as namespace properties. Full key format: "migrate.<version>".
 const icebergHistoryKeyPrefix = "migrate."
 
+// icebergExecutedSQLKeyPrefix, icebergDownSQLKeyPrefix, icebergChecksumKeyPrefix and
+// icebergOutOfOrderKeyPrefix are the key prefixes for the up SQL as executed, the down SQL,
+// the file checksum of a migration and whether it was applied out of order.
+// They deliberately do not start with icebergHistoryKeyPrefix so they are never mistaken
+// for history entries.
+const (
+	icebergExecutedSQLKeyPrefix = "migrate_sql."
+	icebergDownSQLKeyPrefix     = "migrate_down."
+	icebergChecksumKeyPrefix    = "migrate_checksum."
+	icebergOutOfOrderKeyPrefix  = "migrate_out_of_order."
+	// icebergStatusKeyPrefix and icebergLastStatementKeyPrefix are set only for a migration
+	// that is not applied completely.
+	icebergStatusKeyPrefix        = "migrate_status."
+	icebergLastStatementKeyPrefix = "migrate_last_statement."
+)
+
+// icebergLockOwnerKey and icebergLockAcquiredAtKey are the properties of the lock namespace
+// that tell who holds the migration lock and since when.
+const (
+	icebergLockOwnerKey      = "lock.owner"
+	icebergLockAcquiredAtKey = "lock.acquired_at"
+)
+
 // Iceberg implements Repository for the Apache Iceberg REST catalog backend.
 // Migration history is stored as namespace properties in the history namespace.
 type Iceberg struct {
 	cat     IcebergCatalog
 	options *Options
+	// lock is the lease taken by TryLock until Unlock.
+	lock *entity.Lock
 }
 
 // NewIceberg creates a new Iceberg repository instance.
@@ -70,6 +96,18 @@ func (i *Iceberg) CreateMigrationHistoryTable(ctx context.Context) error {
 	return nil
 }
 
+// UpgradeMigrationHistoryTable is a no-op: namespace properties are schemaless,
+// so new history attributes are simply stored under new keys.
+func (i *Iceberg) UpgradeMigrationHistoryTable(_ context.Context) error {
+	return nil
+}
+
+// NeedsMigrationHistoryTableUpgrade always returns false: the schemaless namespace properties
+// never need an upgrade.
+func (i *Iceberg) NeedsMigrationHistoryTableUpgrade(_ context.Context) (bool, error) {
+	return false, nil
+}
+
 // DropMigrationHistoryTable drops the history namespace from the catalog.
 func (i *Iceberg) DropMigrationHistoryTable(ctx context.Context) error {
 	if err := i.cat.DropNamespace(ctx, i.historyNS()); err != nil {
@@ -88,15 +126,33 @@ func (i *Iceberg) HasMigrationHistoryTable(ctx context.Context) (bool, error) {
 }
 
 // InsertMigration inserts a migration record using the current wall-clock time as apply_time.
-func (i *Iceberg) InsertMigration(ctx context.Context, version string) error {
-	return i.InsertMigrationWithApplyTime(ctx, version, time.Now().Unix())
+func (i *Iceberg) InsertMigration(ctx context.Context, migration *entity.Migration) error {
+	return i.InsertMigrationWithApplyTime(ctx, migration, time.Now().Unix())
 }
 
 // InsertMigrationWithApplyTime inserts a migration record with an explicit apply_time.
-// The record is stored as namespace property "migrate.<version>" = "<apply_time>".
-func (i *Iceberg) InsertMigrationWithApplyTime(ctx context.Context, version string, applyTime int64) error {
+// The record is stored as namespace property "migrate.<version>" = "<apply_time>";
+// non-empty up and down SQL bodies, the checksum and the out of order flag are stored
+// under their own key prefixes.
+func (i *Iceberg) InsertMigrationWithApplyTime(ctx context.Context, migration *entity.Migration, applyTime int64) error {
 	updates := map[string]string{
-		icebergHistoryKeyPrefix + version: strconv.FormatInt(applyTime, 10),
+		icebergHistoryKeyPrefix + migration.Version: strconv.FormatInt(applyTime, 10),
+	}
+	if migration.ExecutedSQL != "" {
+		updates[icebergExecutedSQLKeyPrefix+migration.Version] = migration.ExecutedSQL
+	}
+	if migration.DownSQL != "" {
+		updates[icebergDownSQLKeyPrefix+migration.Version] = migration.DownSQL
+	}
+	if migration.Checksum != "" {
+		updates[icebergChecksumKeyPrefix+migration.Version] = migration.Checksum
+	}
+	if migration.OutOfOrder {
+		updates[icebergOutOfOrderKeyPrefix+migration.Version] = strconv.FormatBool(true)
+	}
+	if status := migration.StatusOrApplied(); status != entity.StatusApplied {
+		updates[icebergStatusKeyPrefix+migration.Version] = status
+		updates[icebergLastStatementKeyPrefix+migration.Version] = strconv.Itoa(migration.LastStatement)
 	}
 	if err := i.cat.UpdateNamespaceProperties(ctx, i.historyNS(), nil, updates); err != nil {
 		return errors.Wrap(i.dbError(err), "insert migration")
@@ -104,16 +160,51 @@ func (i *Iceberg) InsertMigrationWithApplyTime(ctx context.Context, version stri
 	return nil
 }
 
+// UpdateMigration replaces the record of migration.Version with one applied now,
+// removing the properties the new record leaves empty.
+func (i *Iceberg) UpdateMigration(ctx context.Context, migration *entity.Migration) error {
+	props, err := i.cat.LoadNamespaceProperties(ctx, i.historyNS())
+	if err != nil {
+		return errors.Wrap(i.dbError(err), "update migration")
+	}
+	var removals []string
+	for _, key := range i.recordKeys(migration.Version) {
+		if _, ok := props[key]; ok {
+			removals = append(removals, key)
+		}
+	}
+	if len(removals) > 0 {
+		if err := i.cat.UpdateNamespaceProperties(ctx, i.historyNS(), removals, nil); err != nil {
+			return errors.Wrap(i.dbError(err), "update migration")
+		}
+	}
+
+	return i.InsertMigrationWithApplyTime(ctx, migration, time.Now().Unix())
+}
+
+// recordKeys returns the property keys of the record of version other than the history entry.
+func (i *Iceberg) recordKeys(version string) []string {
+	return []string{
+		icebergExecutedSQLKeyPrefix + version,
+		icebergDownSQLKeyPrefix + version,
+		icebergChecksumKeyPrefix + version,
+		icebergOutOfOrderKeyPrefix + version,
+		icebergStatusKeyPrefix + version,
+		icebergLastStatementKeyPrefix + version,
+	}
+}
+
 // RemoveMigration removes a migration record from the history namespace properties.
 func (i *Iceberg) RemoveMigration(ctx context.Context, version string) error {
-	removals := []string{icebergHistoryKeyPrefix + version}
+	removals := append([]string{icebergHistoryKeyPrefix + version}, i.recordKeys(version)...)
 	if err := i.cat.UpdateNamespaceProperties(ctx, i.historyNS(), removals, nil); err != nil {
 		return errors.Wrap(i.dbError(err), "remove migration")
 	}
 	return nil
 }
 
-// Migrations returns applied migrations sorted by version descending.
+// Migrations returns applied migrations, the most recently applied first,
+// sorted by apply_time and then by version descending.
 // If limit <= 0 all migrations are returned.
 func (i *Iceberg) Migrations(ctx context.Context, limit int) (entity.Migrations, error) {
 	migrations, err := i.loadMigrations(ctx)
@@ -121,8 +212,11 @@ func (i *Iceberg) Migrations(ctx context.Context, limit int) (entity.Migrations,
 		return nil, errors.Wrap(err, "get migrations")
 	}
 
-	// Sort ascending by version first, then reverse for DESC order.
+	// Sort ascending by apply_time and version first, then reverse for DESC order.
 	migrations.SortByVersion()
+	sort.SliceStable(migrations, func(a, b int) bool {
+		return migrations[a].ApplyTime < migrations[b].ApplyTime
+	})
 	reverseSlice(migrations)
 
 	if limit > 0 && len(migrations) > limit {
@@ -287,15 +381,227 @@ func (i *Iceberg) loadMigrations(ctx context.Context) (entity.Migrations, error)
 		if err != nil {
 			return nil, errors.WithMessagef(err, "invalid apply_time value for migration %q: %q", version, v)
 		}
+		var lastStatement int
+		if value, ok := props[icebergLastStatementKeyPrefix+version]; ok {
+			if lastStatement, err = strconv.Atoi(value); err != nil {
+				return nil, errors.WithMessagef(err, "invalid last_statement value for migration %q: %q", version, value)
+			}
+		}
 		migrations = append(migrations, entity.Migration{
-			Version:   version,
-			ApplyTime: applyTime,
+			Version:       version,
+			ApplyTime:     applyTime,
+			ExecutedSQL:   props[icebergExecutedSQLKeyPrefix+version],
+			DownSQL:       props[icebergDownSQLKeyPrefix+version],
+			Checksum:      props[icebergChecksumKeyPrefix+version],
+			OutOfOrder:    props[icebergOutOfOrderKeyPrefix+version] == strconv.FormatBool(true),
+			Status:        props[icebergStatusKeyPrefix+version],
+			LastStatement: lastStatement,
 		})
 	}
 
 	return migrations, nil
 }
 
+// DumpSchema returns the schema, partition spec and sort order of every table of the catalog,
+// without the history and lock namespaces, ordered by namespace and table name. The catalog has no DDL
+// to show, so each table is described in SQL comments.
+func (i *Iceberg) DumpSchema(ctx context.Context) (string, error) {
+	namespaces, err := i.cat.ListNamespaces(ctx)
+	if err != nil {
+		return "", i.dbError(err)
+	}
+	skip := map[string]bool{
+		strings.Join(i.historyNS(), "."): true,
+		strings.Join(i.lockNS(), "."):    true,
+	}
+
+	var idents []ddl.Ident
+	for _, ns := range namespaces {
+		if skip[strings.Join(ns, ".")] {
+			continue
+		}
+		tables, err := i.cat.ListTables(ctx, ns)
+		if err != nil {
+			return "", i.dbError(err)
+		}
+		idents = append(idents, tables...)
+	}
+	sort.Slice(idents, func(a, b int) bool {
+		return identName(idents[a]) < identName(idents[b])
+	})
+
+	var sb strings.Builder
+	for _, ident := range idents {
+		desc, err := i.cat.DescribeTable(ctx, ident)
+		if err != nil {
+			return "", i.dbError(err)
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("-- " + identName(ident) + "\n")
+		for _, line := range strings.Split(strings.TrimSpace(desc), "\n") {
+			sb.WriteString(strings.TrimRight("-- "+line, " ") + "\n")
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// LoadSchema returns the namespaces and tables of the catalog, without the history and lock namespaces,
+// ordered by namespace and table name.
+func (i *Iceberg) LoadSchema(ctx context.Context) (*ddl.Schema, error) {
+	namespaces, err := i.cat.ListNamespaces(ctx)
+	if err != nil {
+		return nil, i.dbError(err)
+	}
+	skip := map[string]bool{
+		strings.Join(i.historyNS(), "."): true,
+		strings.Join(i.lockNS(), "."):    true,
+	}
+
+	schema := ddl.NewSchema()
+	var idents []ddl.Ident
+	for _, ns := range namespaces {
+		if skip[strings.Join(ns, ".")] {
+			continue
+		}
+		schema.Namespaces = append(schema.Namespaces, ns)
+		tables, err := i.cat.ListTables(ctx, ns)
+		if err != nil {
+			return nil, i.dbError(err)
+		}
+		idents = append(idents, tables...)
+	}
+	sort.Slice(schema.Namespaces, func(a, b int) bool {
+		return strings.Join(schema.Namespaces[a], ".") < strings.Join(schema.Namespaces[b], ".")
+	})
+	sort.Slice(idents, func(a, b int) bool {
+		return identName(idents[a]) < identName(idents[b])
+	})
+
+	for _, ident := range idents {
+		table, err := i.cat.LoadTable(ctx, ident)
+		if err != nil {
+			return nil, i.dbError(err)
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+
+	return schema, nil
+}
+
+// identName returns the dotted name of the table.
+func identName(ident ddl.Ident) string {
+	return strings.Join(ident.Namespace, ".") + "." + ident.Table
+}
+
+// TryLock tries to take the migration lock lease without waiting.
+// The lease is the lock namespace itself: the catalog creates a namespace only once,
+// so of two processes creating it at the same time only one succeeds.
+// The owner and the acquire time are stored as properties of the namespace.
+func (i *Iceberg) TryLock(ctx context.Context, lock *entity.Lock) (bool, error) {
+	if i.lock != nil {
+		return true, nil
+	}
+
+	exists, err := i.cat.NamespaceExists(ctx, i.lockNS())
+	if err != nil {
+		return false, errors.Wrap(i.dbError(err), "try lock")
+	}
+	if exists {
+		return false, nil
+	}
+
+	props := map[string]string{
+		icebergLockOwnerKey:      lock.Owner,
+		icebergLockAcquiredAtKey: strconv.FormatInt(lock.AcquiredAt, 10),
+	}
+	if err := i.cat.CreateNamespace(ctx, i.lockNS(), props); err != nil {
+		// Another process may have created the namespace in the meantime.
+		if exists, existsErr := i.cat.NamespaceExists(ctx, i.lockNS()); existsErr == nil && exists {
+			return false, nil
+		}
+
+		return false, errors.Wrap(i.dbError(err), "try lock")
+	}
+
+	i.lock = lock
+
+	return true, nil
+}
+
+// Unlock drops the lock namespace created by TryLock unless the lease was taken over by another owner.
+func (i *Iceberg) Unlock(ctx context.Context) error {
+	if i.lock == nil {
+		return nil
+	}
+	owner := i.lock.Owner
+	i.lock = nil
+
+	holder, err := i.LockHolder(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unlock")
+	}
+	if holder == nil || holder.Owner != owner {
+		return nil
+	}
+
+	if err := i.cat.DropNamespace(ctx, i.lockNS()); err != nil {
+		return errors.Wrap(i.dbError(err), "unlock")
+	}
+
+	return nil
+}
+
+// LockHolder returns the owner of the lock lease, or nil when the lock is free.
+func (i *Iceberg) LockHolder(ctx context.Context) (*entity.Lock, error) {
+	exists, err := i.cat.NamespaceExists(ctx, i.lockNS())
+	if err != nil {
+		return nil, errors.Wrap(i.dbError(err), "get lock holder")
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	props, err := i.cat.LoadNamespaceProperties(ctx, i.lockNS())
+	if err != nil {
+		return nil, errors.Wrap(i.dbError(err), "get lock holder")
+	}
+
+	// A malformed acquire time is treated as unknown.
+	acquiredAt, _ := strconv.ParseInt(props[icebergLockAcquiredAtKey], 10, 64)
+
+	return &entity.Lock{Owner: props[icebergLockOwnerKey], AcquiredAt: acquiredAt}, nil
+}
+
+// ForceUnlock drops the lock namespace regardless of the lease owner.
+func (i *Iceberg) ForceUnlock(ctx context.Context) error {
+	exists, err := i.cat.NamespaceExists(ctx, i.lockNS())
+	if err != nil {
+		return errors.Wrap(i.dbError(err), "force unlock")
+	}
+	if !exists {
+		return nil
+	}
+
+	if err := i.cat.DropNamespace(ctx, i.lockNS()); err != nil {
+		return errors.Wrap(i.dbError(err), "force unlock")
+	}
+
+	return nil
+}
+
+// SessionLock returns false, because the lock namespace outlives a crashed holder.
+func (i *Iceberg) SessionLock() bool {
+	return false
+}
+
+// lockNS returns the namespace identifier used as the migration lock lease.
+func (i *Iceberg) lockNS() []string {
+	return []string{i.options.TableName + lockTableSuffix}
+}
+
 // dbError wraps a catalog error for consistent error reporting.
 // Since the Iceberg catalog client already wraps errors with context messages,
 // we use errors.WithMessage to add the "iceberg catalog" prefix for traceability.
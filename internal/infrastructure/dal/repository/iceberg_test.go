@@ -14,6 +14,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -151,7 +152,35 @@ func TestIceberg_InsertMigrationWithApplyTime_Successfully(t *testing.T) {
 		Return(nil).
 		Once()
 
-	err := repo.InsertMigrationWithApplyTime(ctx, version, applyTime)
+	err := repo.InsertMigrationWithApplyTime(ctx, &entity.Migration{Version: version}, applyTime)
+	require.NoError(t, err)
+}
+
+func TestIceberg_InsertMigrationWithApplyTime_StoresSQL_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+
+	version := "210328_221600_create_users"
+	expectedUpdates := map[string]string{
+		"migrate." + version:              "1616968560",
+		"migrate_sql." + version:          "CREATE TABLE analytics.users (id long);\n",
+		"migrate_down." + version:         "DROP TABLE analytics.users;",
+		"migrate_checksum." + version:     "0f1e2d3c",
+		"migrate_out_of_order." + version: "true",
+	}
+
+	cat.EXPECT().
+		UpdateNamespaceProperties(ctx, historyNS, ([]string)(nil), expectedUpdates).
+		Return(nil).
+		Once()
+
+	err := repo.InsertMigrationWithApplyTime(ctx, &entity.Migration{
+		Version:     version,
+		ExecutedSQL: "CREATE TABLE analytics.users (id long);\n",
+		DownSQL:     "DROP TABLE analytics.users;",
+		Checksum:    "0f1e2d3c",
+		OutOfOrder:  true,
+	}, 1616968560)
 	require.NoError(t, err)
 }
 
@@ -166,7 +195,7 @@ func TestIceberg_InsertMigrationWithApplyTime_Failure(t *testing.T) {
 		Return(errors.New("write error")).
 		Once()
 
-	err := repo.InsertMigrationWithApplyTime(ctx, "210328_221600_create_users", 1616968560)
+	err := repo.InsertMigrationWithApplyTime(ctx, &entity.Migration{Version: "210328_221600_create_users"}, 1616968560)
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "insert migration")
 }
@@ -191,7 +220,7 @@ func TestIceberg_InsertMigration_CallsCatalog(t *testing.T) {
 		Return(nil).
 		Once()
 
-	err := repo.InsertMigration(ctx, version)
+	err := repo.InsertMigration(ctx, &entity.Migration{Version: version})
 	require.NoError(t, err)
 }
 
@@ -202,7 +231,15 @@ func TestIceberg_RemoveMigration_Successfully(t *testing.T) {
 	repo, cat := newIcebergRepo(t)
 
 	version := "210328_221600_create_users"
-	expectedRemovals := []string{"migrate." + version}
+	expectedRemovals := []string{
+		"migrate." + version,
+		"migrate_sql." + version,
+		"migrate_down." + version,
+		"migrate_checksum." + version,
+		"migrate_out_of_order." + version,
+		"migrate_status." + version,
+		"migrate_last_statement." + version,
+	}
 
 	cat.EXPECT().
 		UpdateNamespaceProperties(ctx, historyNS, expectedRemovals, (map[string]string)(nil)).
@@ -219,7 +256,15 @@ func TestIceberg_RemoveMigration_Failure(t *testing.T) {
 
 	cat.EXPECT().
 		UpdateNamespaceProperties(ctx, historyNS,
-			[]string{"migrate.210328_221600_create_users"},
+			[]string{
+				"migrate.210328_221600_create_users",
+				"migrate_sql.210328_221600_create_users",
+				"migrate_down.210328_221600_create_users",
+				"migrate_checksum.210328_221600_create_users",
+				"migrate_out_of_order.210328_221600_create_users",
+				"migrate_status.210328_221600_create_users",
+				"migrate_last_statement.210328_221600_create_users",
+			},
 			(map[string]string)(nil),
 		).
 		Return(errors.New("remove error")).
@@ -230,6 +275,58 @@ func TestIceberg_RemoveMigration_Failure(t *testing.T) {
 	assert.ErrorContains(t, err, "remove migration")
 }
 
+func TestIceberg_UpdateMigration_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+
+	version := "210328_221600_create_users"
+	cat.EXPECT().
+		LoadNamespaceProperties(ctx, historyNS).
+		Return(map[string]string{
+			"migrate." + version:                "1616968560",
+			"migrate_status." + version:         "failed",
+			"migrate_last_statement." + version: "1",
+		}, nil).
+		Once()
+	cat.EXPECT().
+		UpdateNamespaceProperties(ctx, historyNS,
+			[]string{"migrate_status." + version, "migrate_last_statement." + version},
+			(map[string]string)(nil),
+		).
+		Return(nil).
+		Once()
+	cat.EXPECT().
+		UpdateNamespaceProperties(ctx, historyNS, ([]string)(nil), mock.MatchedBy(func(updates map[string]string) bool {
+			_, ok := updates["migrate."+version]
+			return ok && len(updates) == 1
+		})).
+		Return(nil).
+		Once()
+
+	err := repo.UpdateMigration(ctx, &entity.Migration{Version: version, Status: entity.StatusApplied})
+	require.NoError(t, err)
+}
+
+func TestIceberg_Migrations_Status_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+
+	cat.EXPECT().
+		LoadNamespaceProperties(ctx, historyNS).
+		Return(map[string]string{
+			"migrate.210328_221600_create_users":                "1616968560",
+			"migrate_status.210328_221600_create_users":         "failed",
+			"migrate_last_statement.210328_221600_create_users": "2",
+		}, nil).
+		Once()
+
+	migrations, err := repo.Migrations(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, entity.StatusFailed, migrations[0].Status)
+	assert.Equal(t, 2, migrations[0].LastStatement)
+}
+
 // --- Migrations ---
 
 func TestIceberg_Migrations_Successfully(t *testing.T) {
@@ -237,9 +334,10 @@ func TestIceberg_Migrations_Successfully(t *testing.T) {
 	repo, cat := newIcebergRepo(t)
 
 	props := map[string]string{
-		"migrate.210328_221600_create_users": "1616968560",
-		"migrate.210329_121500_add_index":    "1617020100",
-		"other.key":                          "ignored",
+		"migrate.210328_221600_create_users":      "1616968560",
+		"migrate.210329_121500_add_index":         "1617020100",
+		"migrate_down.210328_221600_create_users": "DROP TABLE analytics.users;",
+		"other.key": "ignored",
 	}
 
 	cat.EXPECT().
@@ -254,6 +352,8 @@ func TestIceberg_Migrations_Successfully(t *testing.T) {
 	// Returned in DESC order by version (lexicographic).
 	assert.Equal(t, "210329_121500_add_index", migrations[0].Version)
 	assert.Equal(t, "210328_221600_create_users", migrations[1].Version)
+	assert.Empty(t, migrations[0].DownSQL)
+	assert.Equal(t, "DROP TABLE analytics.users;", migrations[1].DownSQL)
 }
 
 func TestIceberg_Migrations_WithLimit(t *testing.T) {
@@ -279,6 +379,33 @@ func TestIceberg_Migrations_WithLimit(t *testing.T) {
 	assert.Equal(t, "210329_120000_v2", migrations[1].Version)
 }
 
+func TestIceberg_Migrations_OutOfOrder_SortedByApplyTime(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+
+	props := map[string]string{
+		"migrate.210328_221600_v1":              "1616968560",
+		"migrate.210329_120000_v2":              "1617098400",
+		"migrate.210330_090000_v3":              "1617020100",
+		"migrate_out_of_order.210329_120000_v2": "true",
+	}
+
+	cat.EXPECT().
+		LoadNamespaceProperties(ctx, historyNS).
+		Return(props, nil).
+		Once()
+
+	migrations, err := repo.Migrations(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	// The out of order migration was applied last, so it is reverted first.
+	assert.Equal(t, "210329_120000_v2", migrations[0].Version)
+	assert.True(t, migrations[0].OutOfOrder)
+	assert.Equal(t, "210330_090000_v3", migrations[1].Version)
+	assert.False(t, migrations[1].OutOfOrder)
+	assert.Equal(t, "210328_221600_v1", migrations[2].Version)
+}
+
 func TestIceberg_Migrations_EmptyNamespace(t *testing.T) {
 	ctx := context.Background()
 	repo, cat := newIcebergRepo(t)
@@ -943,6 +1070,23 @@ func TestIceberg_ExecQuery_ParseError(t *testing.T) {
 	require.Error(t, err)
 }
 
+// TestIceberg_ExecQuery_Index verifies that the index statements, which Iceberg tables do not have,
+// are rejected without calling the catalog.
+func TestIceberg_ExecQuery_Index(t *testing.T) {
+	for _, stmt := range []string{
+		"CREATE INDEX idx ON raw.t (id)",
+		"DROP INDEX idx ON raw.t",
+	} {
+		t.Run(stmt, func(t *testing.T) {
+			repo, cat := newIcebergRepo(t)
+			cat.EXPECT().Warehouse().Return("").Once()
+
+			err := repo.ExecQuery(context.Background(), stmt)
+			require.ErrorIs(t, err, ddl.ErrUnsupportedDDL)
+		})
+	}
+}
+
 // TestIceberg_ExecQuery_CatalogError verifies that a catalog error is propagated.
 func TestIceberg_ExecQuery_CatalogError(t *testing.T) {
 	ctx := context.Background()
@@ -957,3 +1101,175 @@ func TestIceberg_ExecQuery_CatalogError(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "catalog unreachable")
 }
+
+// --- Lock ---
+
+// lockNS is the lock namespace of the default history namespace.
+var lockNS = []string{"migration_lock"}
+
+func TestIceberg_TryLock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+	lock := &entity.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}
+
+	cat.EXPECT().NamespaceExists(ctx, lockNS).Return(false, nil).Once()
+	cat.EXPECT().
+		CreateNamespace(ctx, lockNS, map[string]string{
+			"lock.owner":       "db-migrator@host:1",
+			"lock.acquired_at": "1700000000",
+		}).
+		Return(nil).
+		Once()
+	cat.EXPECT().NamespaceExists(ctx, lockNS).Return(true, nil).Once()
+	cat.EXPECT().
+		LoadNamespaceProperties(ctx, lockNS).
+		Return(map[string]string{"lock.owner": "db-migrator@host:1", "lock.acquired_at": "1700000000"}, nil).
+		Once()
+	cat.EXPECT().DropNamespace(ctx, lockNS).Return(nil).Once()
+
+	acquired, err := repo.TryLock(ctx, lock)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	require.NoError(t, repo.Unlock(ctx))
+}
+
+func TestIceberg_TryLock_Held(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+
+	cat.EXPECT().NamespaceExists(ctx, lockNS).Return(true, nil).Once()
+
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1"})
+	require.NoError(t, err)
+	assert.False(t, acquired)
+}
+
+func TestIceberg_TryLock_LostRace(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+
+	cat.EXPECT().NamespaceExists(ctx, lockNS).Return(false, nil).Once()
+	cat.EXPECT().CreateNamespace(ctx, lockNS, mock.Anything).Return(errors.New("namespace already exists")).Once()
+	cat.EXPECT().NamespaceExists(ctx, lockNS).Return(true, nil).Once()
+
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1"})
+	require.NoError(t, err)
+	assert.False(t, acquired)
+}
+
+func TestIceberg_Unlock_TakenOver_KeepsLease(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+	repo.lock = &entity.Lock{Owner: "db-migrator@host:1"}
+
+	cat.EXPECT().NamespaceExists(ctx, lockNS).Return(true, nil).Once()
+	cat.EXPECT().
+		LoadNamespaceProperties(ctx, lockNS).
+		Return(map[string]string{"lock.owner": "db-migrator@host:2"}, nil).
+		Once()
+
+	require.NoError(t, repo.Unlock(ctx))
+}
+
+func TestIceberg_LockHolder_Free(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+
+	cat.EXPECT().NamespaceExists(ctx, lockNS).Return(false, nil).Once()
+
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+}
+
+func TestIceberg_ForceUnlock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+
+	cat.EXPECT().NamespaceExists(ctx, lockNS).Return(true, nil).Once()
+	cat.EXPECT().DropNamespace(ctx, lockNS).Return(nil).Once()
+
+	require.NoError(t, repo.ForceUnlock(ctx))
+}
+
+func TestIceberg_DumpSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+	events := ddl.Ident{Namespace: []string{"analytics"}, Table: "events"}
+	users := ddl.Ident{Namespace: []string{"analytics"}, Table: "users"}
+
+	cat.EXPECT().
+		ListNamespaces(ctx).
+		Return([][]string{historyNS, {"analytics"}, lockNS}, nil).
+		Once()
+	cat.EXPECT().
+		ListTables(ctx, []string{"analytics"}).
+		Return([]ddl.Ident{users, events}, nil).
+		Once()
+	cat.EXPECT().
+		DescribeTable(ctx, events).
+		Return("table {\n  1: id: required long\n}\npartitioned by []\nsorted by []", nil).
+		Once()
+	cat.EXPECT().
+		DescribeTable(ctx, users).
+		Return("table {\n  1: name: optional string\n}", nil).
+		Once()
+
+	schema, err := repo.DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `-- analytics.events
+-- table {
+--   1: id: required long
+-- }
+-- partitioned by []
+-- sorted by []
+
+-- analytics.users
+-- table {
+--   1: name: optional string
+-- }
+`, schema)
+}
+
+func TestIceberg_LoadSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+	events := ddl.Ident{Namespace: []string{"analytics"}, Table: "events"}
+	users := ddl.Ident{Namespace: []string{"analytics"}, Table: "users"}
+	eventsTable := &ddl.Table{Ident: events, Columns: []ddl.Field{{Name: "id", Type: ddl.IcebergType{Kind: ddl.Long}}}}
+	usersTable := &ddl.Table{Ident: users, Columns: []ddl.Field{{Name: "name", Type: ddl.IcebergType{Kind: ddl.String}}}}
+
+	cat.EXPECT().
+		ListNamespaces(ctx).
+		Return([][]string{{"raw"}, historyNS, {"analytics"}, lockNS}, nil).
+		Once()
+	cat.EXPECT().ListTables(ctx, []string{"raw"}).Return(nil, nil).Once()
+	cat.EXPECT().
+		ListTables(ctx, []string{"analytics"}).
+		Return([]ddl.Ident{users, events}, nil).
+		Once()
+	cat.EXPECT().LoadTable(ctx, events).Return(eventsTable, nil).Once()
+	cat.EXPECT().LoadTable(ctx, users).Return(usersTable, nil).Once()
+
+	schema, err := repo.LoadSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &ddl.Schema{
+		Namespaces: [][]string{{"analytics"}, {"raw"}},
+		Tables:     []*ddl.Table{eventsTable, usersTable},
+	}, schema)
+}
+
+func TestIceberg_LoadSchema_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo, cat := newIcebergRepo(t)
+	events := ddl.Ident{Namespace: []string{"analytics"}, Table: "events"}
+
+	cat.EXPECT().ListNamespaces(ctx).Return([][]string{{"analytics"}}, nil).Once()
+	cat.EXPECT().ListTables(ctx, []string{"analytics"}).Return([]ddl.Ident{events}, nil).Once()
+	catalogErr := errors.New("catalog unavailable")
+	cat.EXPECT().LoadTable(ctx, events).Return(nil, catalogErr).Once()
+
+	_, err := repo.LoadSchema(ctx)
+	require.ErrorIs(t, err, catalogErr)
+}
@@ -0,0 +1,670 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
+)
+
+const mssqlDefaultSchema = "dbo"
+
+// mssqlBatchSeparator ends the batches of a SQL Server schema dump, as sqlcmd reads them.
+const mssqlBatchSeparator = "GO"
+
+// mssqlHistoryColumns lists the history table columns added after its first release.
+var mssqlHistoryColumns = []historyColumn{
+	{Name: "executed_sql", Type: "nvarchar(max)"},
+	{Name: "down_sql", Type: "nvarchar(max)"},
+	{Name: "checksum", Type: "varchar(64)"},
+	{Name: "out_of_order", Type: "bit NOT NULL DEFAULT 0"},
+	{Name: "status", Type: "varchar(16) NOT NULL DEFAULT 'applied'"},
+	{Name: "last_statement", Type: "int NOT NULL DEFAULT 0"},
+}
+
+// MSSQL implements Repository interface for Microsoft SQL Server database.
+// It handles migration history tracking and SQL execution for SQL Server.
+type MSSQL struct {
+	conn    Connection
+	options *Options
+	// session holds the application lock taken by TryLock until Unlock.
+	session Session
+}
+
+// NewMSSQL creates a new MSSQL repository instance.
+// It returns a repository configured with the provided connection and options.
+func NewMSSQL(conn Connection, options *Options) *MSSQL {
+	return &MSSQL{
+		conn:    conn,
+		options: options,
+	}
+}
+
+// Migrations returns applied migrations history.
+func (m *MSSQL) Migrations(ctx context.Context, limit int) (entity.Migrations, error) {
+	q := fmt.Sprintf(
+		`
+		SELECT TOP (@p1) version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
+		FROM %s
+		ORDER BY apply_time DESC, version DESC`,
+		m.TableNameWithSchema(),
+	)
+
+	migrations, err := m.queryMigrations(ctx, q, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "get migrations")
+	}
+
+	return migrations, nil
+}
+
+// queryMigrations runs a query returning the columns of the history table and collects the migrations.
+func (m *MSSQL) queryMigrations(ctx context.Context, q string, args ...any) (entity.Migrations, error) {
+	rows, err := m.conn.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, m.dbError(err, q)
+	}
+	defer rows.Close()
+
+	var migrations entity.Migrations
+	for rows.Next() {
+		var (
+			version       string
+			applyTime     int64
+			executedSQL   sql.NullString
+			downSQL       sql.NullString
+			checksum      sql.NullString
+			outOfOrder    sql.NullBool
+			status        sql.NullString
+			lastStatement sql.NullInt64
+		)
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
+			return nil, m.dbError(err, q)
+		}
+
+		migrations = append(migrations, entity.Migration{
+			Version:       version,
+			ApplyTime:     applyTime,
+			ExecutedSQL:   executedSQL.String,
+			DownSQL:       downSQL.String,
+			Checksum:      checksum.String,
+			OutOfOrder:    outOfOrder.Bool,
+			Status:        status.String,
+			LastStatement: int(lastStatement.Int64),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, m.dbError(err, q)
+	}
+
+	return migrations, nil
+}
+
+// HasMigrationHistoryTable returns true if migration history table exists.
+func (m *MSSQL) HasMigrationHistoryTable(ctx context.Context) (exists bool, err error) {
+	q := `SELECT CAST(CASE WHEN OBJECT_ID(@p1, 'U') IS NULL THEN 0 ELSE 1 END AS bit)`
+	if _, err := queryRow(ctx, m.conn, q, []any{m.TableNameWithSchema()}, &exists); err != nil {
+		return false, errors.Wrap(m.dbError(err, q), "get schema table")
+	}
+
+	return exists, nil
+}
+
+// InsertMigration inserts the new migration record.
+func (m *MSSQL) InsertMigration(ctx context.Context, migration *entity.Migration) error {
+	return m.InsertMigrationWithApplyTime(ctx, migration, time.Now().Unix())
+}
+
+// RemoveMigration removes the migration record.
+func (m *MSSQL) RemoveMigration(ctx context.Context, version string) error {
+	q := fmt.Sprintf(`DELETE FROM %s WHERE version = @p1`, m.TableNameWithSchema())
+	if _, err := m.conn.ExecContext(ctx, q, version); err != nil {
+		return errors.Wrap(m.dbError(err, q), "remove migration")
+	}
+
+	return nil
+}
+
+// ExecQuery executes a query without returning any rows.
+// The args are for any placeholder parameters in the query.
+// The lock timeout of the options is set with SET LOCK_TIMEOUT for the query only. It is a setting of the session
+// that a transaction does not undo, so it is reset after the query within a transaction too.
+// SQL Server has no statement timeout of its own, the statement timeout is enforced on the client only.
+func (m *MSSQL) ExecQuery(ctx context.Context, query string, args ...any) error {
+	if m.options.LockTimeout <= 0 {
+		if _, err := m.conn.ExecContext(ctx, query, args...); err != nil {
+			return m.dbError(err, query)
+		}
+		return nil
+	}
+
+	set := []string{"SET LOCK_TIMEOUT " + strconv.FormatInt(durationMillis(m.options.LockTimeout), 10)}
+	reset := []string{"SET LOCK_TIMEOUT -1"}
+	if err := execWithSettings(ctx, m.conn, set, reset, query, args...); err != nil {
+		return m.dbError(err, query)
+	}
+
+	return nil
+}
+
+// ExecQueryTransaction executes txFn within a transaction.
+func (m *MSSQL) ExecQueryTransaction(ctx context.Context, txFn func(ctx context.Context) error) error {
+	return m.conn.Transaction(ctx, txFn)
+}
+
+// SupportsDDLTransactions returns true because SQL Server supports transactional DDL.
+func (m *MSSQL) SupportsDDLTransactions() bool { return true }
+
+// CreateMigrationHistoryTable creates a new migration history table.
+func (m *MSSQL) CreateMigrationHistoryTable(ctx context.Context) error {
+	q := fmt.Sprintf(
+		`
+		CREATE TABLE %s (
+		  version varchar(180) NOT NULL PRIMARY KEY,
+		  apply_time bigint,
+		  executed_sql nvarchar(max),
+		  down_sql nvarchar(max),
+		  checksum varchar(64),
+		  out_of_order bit NOT NULL DEFAULT 0,
+		  status varchar(16) NOT NULL DEFAULT 'applied',
+		  last_statement int NOT NULL DEFAULT 0
+		)
+		`,
+		m.TableNameWithSchema(),
+	)
+
+	if _, err := m.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(m.dbError(err, q), "create migration history table")
+	}
+
+	return nil
+}
+
+// UpgradeMigrationHistoryTable adds the columns introduced after the history table
+// was first released. It is a no-op when the table already has all of them.
+func (m *MSSQL) UpgradeMigrationHistoryTable(ctx context.Context) error {
+	missing, err := m.historyColumnsToAdd(ctx)
+	if err != nil {
+		return errors.Wrap(err, "upgrade migration history table")
+	}
+
+	for _, column := range missing {
+		q := fmt.Sprintf(`ALTER TABLE %s ADD %s %s`, m.TableNameWithSchema(), column.Name, column.Type)
+		if _, err := m.conn.ExecContext(ctx, q); err != nil {
+			return errors.Wrap(m.dbError(err, q), "upgrade migration history table")
+		}
+	}
+
+	return nil
+}
+
+// NeedsMigrationHistoryTableUpgrade reports whether the history table lacks columns
+// added by UpgradeMigrationHistoryTable.
+func (m *MSSQL) NeedsMigrationHistoryTableUpgrade(ctx context.Context) (bool, error) {
+	missing, err := m.historyColumnsToAdd(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "check migration history table")
+	}
+
+	return len(missing) > 0, nil
+}
+
+// historyColumnsToAdd returns the history columns the table does not have yet.
+func (m *MSSQL) historyColumnsToAdd(ctx context.Context) ([]historyColumn, error) {
+	q := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = @p1 AND table_name = @p2
+	`
+	existing, err := queryColumnNames(ctx, m.conn, q, m.options.SchemaName, m.options.TableName)
+	if err != nil {
+		return nil, m.dbError(err, q)
+	}
+
+	return missingHistoryColumns(existing, mssqlHistoryColumns), nil
+}
+
+// DropMigrationHistoryTable drops the migration history table.
+func (m *MSSQL) DropMigrationHistoryTable(ctx context.Context) error {
+	q := fmt.Sprintf(`DROP TABLE %s`, m.TableNameWithSchema())
+	if _, err := m.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(m.dbError(err, q), "drop migration history table")
+	}
+
+	return nil
+}
+
+// MigrationsCount returns the number of migrations
+func (m *MSSQL) MigrationsCount(ctx context.Context) (int, error) {
+	q := fmt.Sprintf(`SELECT count(*) FROM %s`, m.TableNameWithSchema())
+	var c int
+	if err := m.QueryScalar(ctx, q, &c); err != nil {
+		return 0, err
+	}
+
+	return c, nil
+}
+
+// QueryScalar executes a query and scans a single scalar value into ptr.
+// The ptr parameter must be a pointer to a scalar type (int, string, bool, etc).
+func (m *MSSQL) QueryScalar(ctx context.Context, query string, ptr any) error {
+	if err := checkArgIsPtrAndScalar(ptr); err != nil {
+		return err
+	}
+	if _, err := queryRow(ctx, m.conn, query, nil, ptr); err != nil {
+		return m.dbError(err, query)
+	}
+
+	return nil
+}
+
+// ExistsMigration checks if a migration with the given version exists in the history table.
+// It returns true if the migration record is found, false otherwise.
+func (m *MSSQL) ExistsMigration(ctx context.Context, version string) (bool, error) {
+	var (
+		q = fmt.Sprintf(
+			`SELECT CAST(CASE WHEN EXISTS(SELECT 1 FROM %s WHERE version = @p1) THEN 1 ELSE 0 END AS bit)`,
+			m.TableNameWithSchema(),
+		)
+		exists bool
+	)
+	if _, err := queryRow(ctx, m.conn, q, []any{version}, &exists); err != nil {
+		return false, m.dbError(err, q)
+	}
+
+	return exists, nil
+}
+
+// TableNameWithSchema returns the fully qualified table name with schema prefix.
+// For SQL Server, it returns schema_name.table_name format.
+func (m *MSSQL) TableNameWithSchema() string {
+	return m.options.SchemaName + "." + m.options.TableName
+}
+
+// InsertMigrationWithApplyTime inserts the new migration record with an explicit apply time.
+func (m *MSSQL) InsertMigrationWithApplyTime(ctx context.Context, migration *entity.Migration, applyTime int64) error {
+	q := fmt.Sprintf(`
+		INSERT INTO %s (version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8)`,
+		m.TableNameWithSchema(),
+	)
+	if _, err := m.conn.ExecContext(ctx, q,
+		migration.Version,
+		applyTime,
+		migration.ExecutedSQL,
+		migration.DownSQL,
+		migration.Checksum,
+		migration.OutOfOrder,
+		migration.StatusOrApplied(),
+		migration.LastStatement,
+	); err != nil {
+		return errors.Wrap(m.dbError(err, q), "insert migration")
+	}
+
+	return nil
+}
+
+// UpdateMigration updates the record of migration.Version, setting its apply time to now.
+func (m *MSSQL) UpdateMigration(ctx context.Context, migration *entity.Migration) error {
+	q := fmt.Sprintf(`
+		UPDATE %s
+		SET apply_time = @p1, executed_sql = @p2, down_sql = @p3, checksum = @p4, out_of_order = @p5,
+			status = @p6, last_statement = @p7
+		WHERE version = @p8`,
+		m.TableNameWithSchema(),
+	)
+	if _, err := m.conn.ExecContext(ctx, q,
+		time.Now().Unix(),
+		migration.ExecutedSQL,
+		migration.DownSQL,
+		migration.Checksum,
+		migration.OutOfOrder,
+		migration.StatusOrApplied(),
+		migration.LastStatement,
+		migration.Version,
+	); err != nil {
+		return errors.Wrap(m.dbError(err, q), "update migration")
+	}
+
+	return nil
+}
+
+// MigrationsByMaxApplyTime returns migrations that share the maximum apply_time value.
+func (m *MSSQL) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations, error) {
+	q := fmt.Sprintf(`
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
+		FROM %s
+		WHERE apply_time = (SELECT MAX(apply_time) FROM %s)
+		ORDER BY version DESC`,
+		m.TableNameWithSchema(),
+		m.TableNameWithSchema(),
+	)
+
+	migrations, err := m.queryMigrations(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "get migrations by max apply time")
+	}
+
+	return migrations, nil
+}
+
+// DumpSchema returns the DDL of the tables, indexes, views, functions, procedures and triggers of the schema,
+// built from the catalog views, without the history table. Every statement is followed by GO, so the dump
+// can be run with sqlcmd. The objects are ordered by name, so dumps of the same schema are equal.
+func (m *MSSQL) DumpSchema(ctx context.Context) (string, error) {
+	dump := schemaDump{batchSeparator: mssqlBatchSeparator}
+	if err := m.dumpTables(ctx, &dump); err != nil {
+		return "", errors.Wrap(err, "dump tables")
+	}
+
+	// the key and included columns are ordered alike, as ordered aggregates of a query must share the order
+	q := `
+		SELECT 'CREATE ' + CASE WHEN i.is_unique = 1 THEN 'UNIQUE ' ELSE '' END
+			+ CASE WHEN i.type = 1 THEN 'CLUSTERED ' ELSE '' END
+			+ 'INDEX ' + i.name + ' ON ' + @p1 + '.' + t.name + ' ('
+			+ STRING_AGG(CASE WHEN ic.is_included_column = 0
+				THEN c.name + CASE WHEN ic.is_descending_key = 1 THEN ' DESC' ELSE '' END END, ', ')
+				WITHIN GROUP (ORDER BY ic.key_ordinal, ic.index_column_id) + ')'
+			+ COALESCE(' INCLUDE (' + STRING_AGG(CASE WHEN ic.is_included_column = 1 THEN c.name END, ', ')
+				WITHIN GROUP (ORDER BY ic.key_ordinal, ic.index_column_id) + ')', '')
+			+ COALESCE(' WHERE ' + i.filter_definition, '')
+		FROM sys.indexes i
+		JOIN sys.tables t ON t.object_id = i.object_id
+		JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE SCHEMA_NAME(t.schema_id) = @p1 AND t.name <> @p2 AND i.type IN (1, 2)
+			AND i.is_primary_key = 0 AND i.is_unique_constraint = 0
+		GROUP BY t.name, i.name, i.is_unique, i.type, i.filter_definition
+		ORDER BY t.name, i.name`
+	indexes, err := queryStrings(ctx, m.conn, q, 1, m.options.SchemaName, m.options.TableName)
+	if err != nil {
+		return "", errors.Wrap(m.dbError(err, q), "dump indexes")
+	}
+	for _, index := range indexes {
+		dump.add(index[0])
+	}
+
+	q = `
+		SELECT m.definition
+		FROM sys.sql_modules m
+		JOIN sys.objects o ON o.object_id = m.object_id
+		WHERE SCHEMA_NAME(o.schema_id) = @p1 AND o.is_ms_shipped = 0 AND m.definition IS NOT NULL
+		ORDER BY CASE o.type WHEN 'V' THEN 0 WHEN 'TR' THEN 2 ELSE 1 END, o.name`
+	modules, err := queryStrings(ctx, m.conn, q, 1, m.options.SchemaName)
+	if err != nil {
+		return "", errors.Wrap(m.dbError(err, q), "dump modules")
+	}
+	for _, module := range modules {
+		dump.add(module[0])
+	}
+
+	return dump.String(), nil
+}
+
+// dumpTables adds the CREATE TABLE statements of the tables of the schema, with their columns
+// and constraints, to dump.
+func (m *MSSQL) dumpTables(ctx context.Context, dump *schemaDump) error {
+	q := `
+		SELECT t.name, c.name,
+			CASE
+				WHEN ty.name IN ('varchar', 'char', 'varbinary', 'binary') THEN ty.name + '('
+					+ CASE WHEN c.max_length = -1 THEN 'max' ELSE CAST(c.max_length AS varchar(10)) END + ')'
+				WHEN ty.name IN ('nvarchar', 'nchar') THEN ty.name + '('
+					+ CASE WHEN c.max_length = -1 THEN 'max' ELSE CAST(c.max_length / 2 AS varchar(10)) END + ')'
+				WHEN ty.name IN ('decimal', 'numeric') THEN ty.name + '('
+					+ CAST(c.precision AS varchar(10)) + ', ' + CAST(c.scale AS varchar(10)) + ')'
+				WHEN ty.name IN ('datetime2', 'datetimeoffset', 'time') THEN ty.name + '('
+					+ CAST(c.scale AS varchar(10)) + ')'
+				ELSE ty.name
+			END
+			+ COALESCE(' IDENTITY(' + CAST(idc.seed_value AS varchar(40)) + ', '
+				+ CAST(idc.increment_value AS varchar(40)) + ')', '')
+			+ CASE WHEN c.is_nullable = 1 THEN ' NULL' ELSE ' NOT NULL' END
+			+ COALESCE(' DEFAULT ' + dc.definition, '')
+		FROM sys.tables t
+		JOIN sys.columns c ON c.object_id = t.object_id
+		JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+		LEFT JOIN sys.identity_columns idc ON idc.object_id = c.object_id AND idc.column_id = c.column_id
+		LEFT JOIN sys.default_constraints dc ON dc.object_id = c.default_object_id
+		WHERE SCHEMA_NAME(t.schema_id) = @p1 AND t.name <> @p2
+		ORDER BY t.name, c.column_id`
+	columns, err := queryStrings(ctx, m.conn, q, 3, m.options.SchemaName, m.options.TableName)
+	if err != nil {
+		return m.dbError(err, q)
+	}
+
+	q = `
+		SELECT t.name, kc.name, CASE kc.type WHEN 'PK' THEN 'PRIMARY KEY' ELSE 'UNIQUE' END
+			+ ' (' + STRING_AGG(c.name, ', ') WITHIN GROUP (ORDER BY ic.key_ordinal) + ')'
+		FROM sys.key_constraints kc
+		JOIN sys.tables t ON t.object_id = kc.parent_object_id
+		JOIN sys.index_columns ic ON ic.object_id = kc.parent_object_id AND ic.index_id = kc.unique_index_id
+		JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE SCHEMA_NAME(t.schema_id) = @p1 AND t.name <> @p2
+		GROUP BY t.name, kc.name, kc.type
+		UNION ALL
+		SELECT t.name, fk.name, 'FOREIGN KEY ('
+			+ STRING_AGG(pc.name, ', ') WITHIN GROUP (ORDER BY fkc.constraint_column_id) + ') REFERENCES '
+			+ SCHEMA_NAME(rt.schema_id) + '.' + rt.name + ' ('
+			+ STRING_AGG(rc.name, ', ') WITHIN GROUP (ORDER BY fkc.constraint_column_id) + ')'
+			+ CASE WHEN fk.delete_referential_action = 0 THEN ''
+				ELSE ' ON DELETE ' + REPLACE(fk.delete_referential_action_desc, '_', ' ') END
+			+ CASE WHEN fk.update_referential_action = 0 THEN ''
+				ELSE ' ON UPDATE ' + REPLACE(fk.update_referential_action_desc, '_', ' ') END
+		FROM sys.foreign_keys fk
+		JOIN sys.tables t ON t.object_id = fk.parent_object_id
+		JOIN sys.tables rt ON rt.object_id = fk.referenced_object_id
+		JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+		JOIN sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+		JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+		WHERE SCHEMA_NAME(t.schema_id) = @p1 AND t.name <> @p2
+		GROUP BY t.name, fk.name, rt.schema_id, rt.name, fk.delete_referential_action,
+			fk.delete_referential_action_desc, fk.update_referential_action, fk.update_referential_action_desc
+		UNION ALL
+		SELECT t.name, cc.name, 'CHECK ' + cc.definition
+		FROM sys.check_constraints cc
+		JOIN sys.tables t ON t.object_id = cc.parent_object_id
+		WHERE SCHEMA_NAME(t.schema_id) = @p1 AND t.name <> @p2
+		ORDER BY 1, 2`
+	constraints, err := queryStrings(ctx, m.conn, q, 3, m.options.SchemaName, m.options.TableName)
+	if err != nil {
+		return m.dbError(err, q)
+	}
+
+	var (
+		tables      []string
+		definitions = make(map[string][]string)
+	)
+	for _, column := range columns {
+		if _, ok := definitions[column[0]]; !ok {
+			tables = append(tables, column[0])
+		}
+		definitions[column[0]] = append(definitions[column[0]], column[1]+" "+column[2])
+	}
+	for _, constraint := range constraints {
+		definitions[constraint[0]] = append(definitions[constraint[0]],
+			"CONSTRAINT "+constraint[1]+" "+constraint[2])
+	}
+	for _, table := range tables {
+		dump.add(fmt.Sprintf("CREATE TABLE %s.%s (\n    %s\n)",
+			m.options.SchemaName, table, strings.Join(definitions[table], ",\n    ")))
+	}
+
+	return nil
+}
+
+// LoadSchema is not supported: the SQL Server DDL cannot be read into the DDL IR of the schema diff.
+func (m *MSSQL) LoadSchema(context.Context) (*ddl.Schema, error) {
+	return nil, errors.WithStack(ErrLoadSchemaUnsupported)
+}
+
+// TryLock tries to take the application lock of the history table with sp_getapplock without waiting.
+// The lock is owned by a dedicated session; SQL Server releases it when the session ends.
+func (m *MSSQL) TryLock(ctx context.Context, _ *entity.Lock) (bool, error) {
+	if m.session != nil {
+		return true, nil
+	}
+
+	session, err := openSession(ctx, m.conn)
+	if err != nil {
+		return false, errors.Wrap(err, "try lock")
+	}
+
+	var (
+		q = `
+			DECLARE @result int;
+			EXEC @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session',
+				@LockTimeout = 0;
+			SELECT @result`
+		result int
+	)
+	if _, err := queryRow(ctx, session, q, []any{m.lockName()}, &result); err != nil {
+		_ = session.Close()
+		return false, errors.Wrap(m.dbError(err, q), "try lock")
+	}
+
+	// sp_getapplock returns 0 or 1 when the lock is granted and a negative code otherwise
+	if result < 0 {
+		if err := session.Close(); err != nil {
+			return false, errors.Wrap(err, "try lock")
+		}
+
+		return false, nil
+	}
+
+	m.session = session
+
+	return true, nil
+}
+
+// Unlock releases the application lock taken by TryLock and returns its session to the pool.
+func (m *MSSQL) Unlock(ctx context.Context) error {
+	if m.session == nil {
+		return nil
+	}
+	session := m.session
+	m.session = nil
+	defer session.Close()
+
+	q := "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'"
+	if _, err := session.ExecContext(ctx, q, m.lockName()); err != nil {
+		return errors.Wrap(m.dbError(err, q), "unlock")
+	}
+
+	return nil
+}
+
+// LockHolder returns the session holding the application lock of the history table, or nil when the lock is free.
+// The lock is considered taken when the session finished its last request, which for a lock session is
+// the sp_getapplock call. Reading the locks of other sessions requires the VIEW SERVER STATE permission.
+func (m *MSSQL) LockHolder(ctx context.Context) (*entity.Lock, error) {
+	var (
+		q = `
+			SELECT TOP 1 s.session_id, s.login_name + '@' + COALESCE(s.host_name, ''),
+				DATEDIFF(second, '19700101', SYSUTCDATETIME()) - DATEDIFF(second, s.last_request_end_time, SYSDATETIME())
+			FROM sys.dm_tran_locks l
+			JOIN sys.dm_exec_sessions s ON s.session_id = l.request_session_id
+			WHERE l.resource_type = 'APPLICATION' AND l.request_status = 'GRANTED'
+				AND l.resource_database_id = DB_ID() AND CHARINDEX(':[' + @p1 + ']:', l.resource_description) > 0`
+		id         int64
+		loginHost  string
+		acquiredAt int64
+	)
+
+	found, err := queryRow(ctx, m.conn, q, []any{m.lockName()}, &id, &loginHost, &acquiredAt)
+	if err != nil {
+		return nil, errors.Wrap(m.dbError(err, q), "get lock holder")
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return &entity.Lock{
+		Owner:      fmt.Sprintf("%s (session %d)", loginHost, id),
+		AcquiredAt: acquiredAt,
+	}, nil
+}
+
+// ForceUnlock kills the session holding the application lock of the history table,
+// which makes SQL Server release the lock.
+func (m *MSSQL) ForceUnlock(ctx context.Context) error {
+	var (
+		q = `
+			SELECT TOP 1 l.request_session_id
+			FROM sys.dm_tran_locks l
+			WHERE l.resource_type = 'APPLICATION' AND l.request_status = 'GRANTED'
+				AND l.resource_database_id = DB_ID() AND CHARINDEX(':[' + @p1 + ']:', l.resource_description) > 0`
+		id int64
+	)
+	found, err := queryRow(ctx, m.conn, q, []any{m.lockName()}, &id)
+	if err != nil {
+		return errors.Wrap(m.dbError(err, q), "force unlock")
+	}
+	if !found {
+		return nil
+	}
+
+	// KILL does not accept placeholders; the id is an integer read from the server.
+	q = "KILL " + strconv.FormatInt(id, 10)
+	if _, err := m.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(m.dbError(err, q), "force unlock")
+	}
+
+	return nil
+}
+
+// SessionLock returns true, because SQL Server releases the application lock when the session ends.
+func (m *MSSQL) SessionLock() bool {
+	return true
+}
+
+// lockName returns the name of the application lock guarding the history table.
+// sys.dm_tran_locks shows only the first 32 characters of the name, so it is limited to them.
+func (m *MSSQL) lockName() string {
+	const maxLockNameLen = 32
+
+	name := lockNamePrefix + m.TableNameWithSchema()
+	if len(name) > maxLockNameLen {
+		name = name[:maxLockNameLen]
+	}
+
+	return name
+}
+
+// mssqlLockTimeoutCode is the error 1222, returned when LOCK_TIMEOUT expires.
+const mssqlLockTimeoutCode = 1222
+
+// dbError returns DBError is err is db error else returns got error.
+// The code of the DBError is the number of the SQL Server error.
+func (m *MSSQL) dbError(err error, q string) error {
+	var msErr mssql.Error
+	if !errors.As(err, &msErr) {
+		return err
+	}
+
+	return errors.WithStack(&DBError{
+		Code:          strconv.Itoa(int(msErr.Number)),
+		Message:       msErr.Message,
+		Details:       fmt.Sprintf("Msg %d, Level %d, State %d, Line %d", msErr.Number, msErr.Class, msErr.State, msErr.LineNo),
+		InternalQuery: q,
+		Cause:         err,
+		LockTimedOut:  msErr.Number == mssqlLockTimeoutCode,
+	})
+}
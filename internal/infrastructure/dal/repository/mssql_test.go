@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
+	thelp "github.com/raoptimus/db-migrator.go/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMSSQL_ExecQuery_Failure(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, "SELECT 1").
+		Return(nil, mssql.Error{Number: 208, Class: 16, State: 1, LineNo: 1, Message: "Invalid object name 't'."})
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	err := repo.ExecQuery(ctx, "SELECT 1")
+	require.Error(t, err)
+	var dbErr *DBError
+	require.ErrorAs(t, err, &dbErr)
+	assert.Equal(t, "208", dbErr.Code)
+	assert.Equal(t, "Invalid object name 't'.", dbErr.Message)
+	assert.Equal(t, "Msg 208, Level 16, State 1, Line 1", dbErr.Details)
+	assert.Equal(t, "SELECT 1", dbErr.InternalQuery)
+}
+
+func TestMSSQL_ExecQuery_LockTimeout_Session_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn, _, opener := newSessionConnection(t)
+	session := NewMockSession(t)
+
+	opener.EXPECT().Session(ctx).Return(session, nil).Once()
+	session.EXPECT().ExecContext(ctx, "SET LOCK_TIMEOUT 5000").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(ctx, "ALTER TABLE t ADD c int").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(mock.Anything, "SET LOCK_TIMEOUT -1").Return(nil, nil).Once()
+	session.EXPECT().Close().Return(nil).Once()
+
+	repo := NewMSSQL(conn, &Options{LockTimeout: 5 * time.Second, StatementTimeout: time.Second})
+	err := repo.ExecQuery(ctx, "ALTER TABLE t ADD c int")
+	require.NoError(t, err)
+}
+
+func TestMSSQL_ExecQuery_LockTimeout_Failure(t *testing.T) {
+	ctx := connection.ContextWithTx(context.Background(), sqlex.NewTx(nil))
+	conn := NewMockConnection(t)
+
+	conn.EXPECT().ExecContext(ctx, "SET LOCK_TIMEOUT 2000").Return(nil, nil).Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE t ADD c int").
+		Return(nil, mssql.Error{Number: 1222, Message: "Lock request time out period exceeded."}).
+		Once()
+	conn.EXPECT().ExecContext(mock.Anything, "SET LOCK_TIMEOUT -1").Return(nil, nil).Once()
+
+	repo := NewMSSQL(conn, &Options{LockTimeout: 2 * time.Second})
+	err := repo.ExecQuery(ctx, "ALTER TABLE t ADD c int")
+	require.Error(t, err)
+	assert.True(t, IsLockTimeout(err))
+}
+
+func TestMSSQL_Migrations_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	expectedSQL := `
+		SELECT TOP (@p1) version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
+		FROM dbo.migration
+		ORDER BY apply_time DESC, version DESC
+	`
+
+	rows := sqlex.NewRowsWithSlice([]interface{}{
+		[]any{
+			"210328_221600_create_users",
+			int64(1616968560),
+			sql.NullString{String: "CREATE TABLE users (id int);\n", Valid: true},
+			sql.NullString{String: "DROP TABLE users;", Valid: true},
+			sql.NullString{String: "58e7702b", Valid: true},
+			sql.NullBool{Bool: true, Valid: true},
+			sql.NullString{String: "failed", Valid: true},
+			sql.NullInt64{Int64: 2, Valid: true},
+		},
+		[]any{"210329_121500_add_index", int64(1617020100), nil, nil, nil, nil, nil, nil},
+	})
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL(expectedSQL)), 10).
+		Return(rows, nil).
+		Once()
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	migrations, err := repo.Migrations(ctx, 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, entity.Migrations{
+		{
+			Version:       "210328_221600_create_users",
+			ApplyTime:     1616968560,
+			ExecutedSQL:   "CREATE TABLE users (id int);\n",
+			DownSQL:       "DROP TABLE users;",
+			Checksum:      "58e7702b",
+			OutOfOrder:    true,
+			Status:        entity.StatusFailed,
+			LastStatement: 2,
+		},
+		{Version: "210329_121500_add_index", ApplyTime: 1617020100},
+	}, migrations)
+}
+
+func TestMSSQL_HasMigrationHistoryTable_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, "SELECT CAST(CASE WHEN OBJECT_ID(@p1, 'U') IS NULL THEN 0 ELSE 1 END AS bit)", "dbo.migration").
+		Return(sqlex.NewRowsWithSlice([]any{true}), nil).
+		Once()
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	exists, err := repo.HasMigrationHistoryTable(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestMSSQL_InsertMigration_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(thelp.CompareSQL(`
+			INSERT INTO dbo.migration (version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement)
+			VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8)`)),
+			"210328_221600_create_users", int64(100), "CREATE TABLE users (id int)", "DROP TABLE users", "abc",
+			false, entity.StatusApplied, 0,
+		).
+		Return(nil, nil).
+		Once()
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	err := repo.InsertMigrationWithApplyTime(ctx, &entity.Migration{
+		Version:     "210328_221600_create_users",
+		ExecutedSQL: "CREATE TABLE users (id int)",
+		DownSQL:     "DROP TABLE users",
+		Checksum:    "abc",
+	}, 100)
+	require.NoError(t, err)
+}
+
+func TestMSSQL_UpgradeMigrationHistoryTable_AddsMissingColumns_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM information_schema.columns")
+		}), "dbo", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			"version", "apply_time", "executed_sql", "down_sql", "checksum", "out_of_order",
+		}), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE dbo.migration ADD status varchar(16) NOT NULL DEFAULT 'applied'").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE dbo.migration ADD last_statement int NOT NULL DEFAULT 0").
+		Return(nil, nil).
+		Once()
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	require.NoError(t, repo.UpgradeMigrationHistoryTable(ctx))
+}
+
+func TestMSSQL_TryLock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn, _, opener := newSessionConnection(t)
+	session := NewMockSession(t)
+
+	opener.EXPECT().Session(ctx).Return(session, nil).Once()
+	session.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "sp_getapplock @Resource = @p1")
+		}), "db-migrator:dbo.migration").
+		Return(sqlex.NewRowsWithSlice([]any{int64(0)}), nil).
+		Once()
+	session.EXPECT().
+		ExecContext(ctx, "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'", "db-migrator:dbo.migration").
+		Return(nil, nil).
+		Once()
+	session.EXPECT().Close().Return(nil).Once()
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1"})
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	require.NoError(t, repo.Unlock(ctx))
+	require.NoError(t, repo.Unlock(ctx), "unlocking twice must be a no-op")
+	assert.True(t, repo.SessionLock())
+}
+
+func TestMSSQL_TryLock_HeldByAnotherSession(t *testing.T) {
+	ctx := context.Background()
+	conn, _, opener := newSessionConnection(t)
+	session := NewMockSession(t)
+
+	opener.EXPECT().Session(ctx).Return(session, nil).Once()
+	session.EXPECT().
+		QueryContext(ctx, mock.Anything, mock.Anything).
+		Return(sqlex.NewRowsWithSlice([]any{int64(-1)}), nil).
+		Once()
+	session.EXPECT().Close().Return(nil).Once()
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1"})
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	require.NoError(t, repo.Unlock(ctx))
+}
+
+func TestMSSQL_LockHolder_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM sys.dm_tran_locks l")
+		}), "db-migrator:dbo.migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{int64(53), "sa@host", int64(1700000000)},
+		}), nil).
+		Once()
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Lock{Owner: "sa@host (session 53)", AcquiredAt: 1700000000}, holder)
+}
+
+func TestMSSQL_ForceUnlock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "SELECT TOP 1 l.request_session_id")
+		}), "db-migrator:dbo.migration").
+		Return(sqlex.NewRowsWithSlice([]any{int64(53)}), nil).
+		Once()
+	conn.EXPECT().ExecContext(ctx, "KILL 53").Return(nil, nil).Once()
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	require.NoError(t, repo.ForceUnlock(ctx))
+}
+
+func TestMSSQL_lockName_Truncated(t *testing.T) {
+	repo := NewMSSQL(nil, &Options{TableName: "migration_history_of_the_service", SchemaName: "dbo"})
+
+	assert.Equal(t, "db-migrator:dbo.migration_histor", repo.lockName())
+}
+
+func TestMSSQL_DumpSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "JOIN sys.types ty")
+		}), "dbo", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"users", "id", "int IDENTITY(1, 1) NOT NULL"},
+			[]any{"users", "name", "nvarchar(255) NULL DEFAULT (N'')"},
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM sys.key_constraints kc")
+		}), "dbo", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"users", "PK_users", "PRIMARY KEY (id)"},
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM sys.indexes i")
+		}), "dbo", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			"CREATE INDEX users_name ON dbo.users (name)",
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM sys.sql_modules m")
+		}), "dbo").
+		Return(sqlex.NewRowsWithSlice([]any{
+			"CREATE VIEW user_names AS SELECT name FROM users",
+		}), nil).
+		Once()
+
+	repo := NewMSSQL(conn, &Options{TableName: "migration", SchemaName: "dbo"})
+	schema, err := repo.DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `CREATE TABLE dbo.users (
+    id int IDENTITY(1, 1) NOT NULL,
+    name nvarchar(255) NULL DEFAULT (N''),
+    CONSTRAINT PK_users PRIMARY KEY (id)
+);
+GO
+
+CREATE INDEX users_name ON dbo.users (name);
+GO
+
+CREATE VIEW user_names AS SELECT name FROM users;
+GO
+`, schema)
+}
+
+func TestMSSQL_LoadSchema_Unsupported(t *testing.T) {
+	repo := NewMSSQL(NewMockConnection(t), &Options{})
+
+	_, err := repo.LoadSchema(context.Background())
+	require.ErrorIs(t, err, ErrLoadSchemaUnsupported)
+}
+
+func TestMSSQL_dbError_NonMSSQLError_Successfully(t *testing.T) {
+	repo := NewMSSQL(nil, &Options{TableName: "migration", SchemaName: "dbo"})
+	err := errors.New("some error")
+
+	assert.Equal(t, err, repo.dbError(err, "SELECT 1"))
+}
@@ -10,21 +10,39 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"regexp"
 	"strconv"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
 )
 
+// mysqlHistoryColumns lists the history table columns added after its first release.
+var mysqlHistoryColumns = []historyColumn{
+	{Name: "executed_sql", Type: "MEDIUMTEXT"},
+	{Name: "down_sql", Type: "MEDIUMTEXT"},
+	{Name: "checksum", Type: "VARCHAR(64)"},
+	{Name: "out_of_order", Type: "TINYINT(1) NOT NULL DEFAULT 0"},
+	{Name: "status", Type: "VARCHAR(16) NOT NULL DEFAULT 'applied'"},
+	{Name: "last_statement", Type: "INT NOT NULL DEFAULT 0"},
+}
+
+// mysqlAutoIncrement matches the AUTO_INCREMENT counter SHOW CREATE TABLE reports, which changes with the data.
+var mysqlAutoIncrement = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
 // MySQL implements Repository interface for MySQL database.
 // It handles migration history tracking and SQL execution for MySQL.
 type MySQL struct {
 	conn    Connection
 	options *Options
+	// session holds the named lock taken by TryLock until Unlock.
+	session Session
 }
 
 // NewMySQL creates a new MySQL repository instance.
@@ -41,7 +59,7 @@ func (m *MySQL) Migrations(ctx context.Context, limit int) (entity.Migrations, e
 	var (
 		q = fmt.Sprintf(
 			`
-			SELECT version, apply_time 
+			SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
 			FROM %s
 			ORDER BY apply_time DESC, version DESC
 			LIMIT ?`,
@@ -58,18 +76,32 @@ func (m *MySQL) Migrations(ctx context.Context, limit int) (entity.Migrations, e
 
 	for rows.Next() {
 		var (
-			version   string
-			applyTime int64
+			version       string
+			applyTime     int64
+			executedSQL   sql.NullString
+			downSQL       sql.NullString
+			checksum      sql.NullString
+			outOfOrder    sql.NullBool
+			status        sql.NullString
+			lastStatement sql.NullInt64
 		)
 
-		if err := rows.Scan(&version, &applyTime); err != nil {
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
 			return nil, errors.Wrap(m.dbError(err, q), "get migrations")
 		}
 
 		migrations = append(migrations,
 			entity.Migration{
-				Version:   version,
-				ApplyTime: applyTime,
+				Version:       version,
+				ApplyTime:     applyTime,
+				ExecutedSQL:   executedSQL.String,
+				DownSQL:       downSQL.String,
+				Checksum:      checksum.String,
+				OutOfOrder:    outOfOrder.Bool,
+				Status:        status.String,
+				LastStatement: int(lastStatement.Int64),
 			},
 		)
 	}
@@ -114,18 +146,8 @@ func (m *MySQL) HasMigrationHistoryTable(ctx context.Context) (exists bool, err
 }
 
 // InsertMigration inserts the new migration record.
-func (m *MySQL) InsertMigration(ctx context.Context, version string) error {
-	q := fmt.Sprintf(`
-		INSERT INTO %s (version, apply_time)
-		VALUES (?, ?)`,
-		m.options.TableName,
-	)
-	//nolint:gosec // overflow ok
-	now := uint32(time.Now().Unix())
-	if _, err := m.conn.ExecContext(ctx, q, version, now); err != nil {
-		return errors.Wrap(m.dbError(err, q), "insert migration")
-	}
-	return nil
+func (m *MySQL) InsertMigration(ctx context.Context, migration *entity.Migration) error {
+	return m.InsertMigrationWithApplyTime(ctx, migration, time.Now().Unix())
 }
 
 // RemoveMigration removes the migration record.
@@ -139,9 +161,40 @@ func (m *MySQL) RemoveMigration(ctx context.Context, version string) error {
 
 // ExecQuery executes a query without returning any rows.
 // The args are for any placeholder parameters in the query.
+// The lock timeout of the options sets lock_wait_timeout and innodb_lock_wait_timeout,
+// the statement timeout sets max_execution_time, which MySQL applies to SELECT statements only.
 func (m *MySQL) ExecQuery(ctx context.Context, query string, args ...any) error {
-	_, err := m.conn.ExecContext(ctx, query, args...)
-	return err
+	var set, reset []string
+	for _, setting := range m.timeoutSettings() {
+		set = append(set, fmt.Sprintf("SET SESSION %s = %s", setting.Name, setting.Value))
+		reset = append(reset, fmt.Sprintf("SET SESSION %s = DEFAULT", setting.Name))
+	}
+	if len(set) == 0 {
+		_, err := m.conn.ExecContext(ctx, query, args...)
+		return m.dbError(err, query)
+	}
+
+	return m.dbError(execWithSettings(ctx, m.conn, set, reset, query, args...), query)
+}
+
+// timeoutSettings returns the settings enforcing the statement and lock timeouts of the options.
+func (m *MySQL) timeoutSettings() []sessionSetting {
+	var settings []sessionSetting
+	if m.options.LockTimeout > 0 {
+		seconds := strconv.FormatInt(durationSeconds(m.options.LockTimeout), 10)
+		settings = append(settings,
+			sessionSetting{Name: "lock_wait_timeout", Value: seconds},
+			sessionSetting{Name: "innodb_lock_wait_timeout", Value: seconds},
+		)
+	}
+	if m.options.StatementTimeout > 0 {
+		settings = append(settings, sessionSetting{
+			Name:  "max_execution_time",
+			Value: strconv.FormatInt(durationMillis(m.options.StatementTimeout), 10),
+		})
+	}
+
+	return settings
 }
 
 // ExecQueryTransaction executes txFn within a transaction.
@@ -158,7 +211,13 @@ func (m *MySQL) CreateMigrationHistoryTable(ctx context.Context) error {
 		`
 				CREATE TABLE %s (
 				  version VARCHAR(180) PRIMARY KEY,
-				  apply_time INT
+				  apply_time INT,
+				  executed_sql MEDIUMTEXT,
+				  down_sql MEDIUMTEXT,
+				  checksum VARCHAR(64),
+				  out_of_order TINYINT(1) NOT NULL DEFAULT 0,
+				  status VARCHAR(16) NOT NULL DEFAULT 'applied',
+				  last_statement INT NOT NULL DEFAULT 0
 				)
 				ENGINE=InnoDB
 			`,
@@ -171,6 +230,50 @@ func (m *MySQL) CreateMigrationHistoryTable(ctx context.Context) error {
 	return nil
 }
 
+// UpgradeMigrationHistoryTable adds the columns introduced after the history table
+// was first released. It is a no-op when the table already has all of them.
+func (m *MySQL) UpgradeMigrationHistoryTable(ctx context.Context) error {
+	missing, err := m.historyColumnsToAdd(ctx)
+	if err != nil {
+		return errors.Wrap(err, "upgrade migration history table")
+	}
+
+	for _, column := range missing {
+		q := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, m.options.TableName, column.Name, column.Type)
+		if _, err := m.conn.ExecContext(ctx, q); err != nil {
+			return errors.Wrap(m.dbError(err, q), "upgrade migration history table")
+		}
+	}
+
+	return nil
+}
+
+// NeedsMigrationHistoryTableUpgrade reports whether the history table lacks columns
+// added by UpgradeMigrationHistoryTable.
+func (m *MySQL) NeedsMigrationHistoryTableUpgrade(ctx context.Context) (bool, error) {
+	missing, err := m.historyColumnsToAdd(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "check migration history table")
+	}
+
+	return len(missing) > 0, nil
+}
+
+// historyColumnsToAdd returns the history columns the table does not have yet.
+func (m *MySQL) historyColumnsToAdd(ctx context.Context) ([]historyColumn, error) {
+	q := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ?
+	`
+	existing, err := queryColumnNames(ctx, m.conn, q, m.options.SchemaName, m.options.TableName)
+	if err != nil {
+		return nil, m.dbError(err, q)
+	}
+
+	return missingHistoryColumns(existing, mysqlHistoryColumns), nil
+}
+
 // DropMigrationHistoryTable drops the migration history table.
 func (m *MySQL) DropMigrationHistoryTable(ctx context.Context) error {
 	q := fmt.Sprintf(`DROP TABLE %s`, m.options.TableName)
@@ -245,23 +348,58 @@ func (m *MySQL) TableNameWithSchema() string {
 }
 
 // InsertMigrationWithApplyTime inserts the new migration record with an explicit apply time.
-func (m *MySQL) InsertMigrationWithApplyTime(ctx context.Context, version string, applyTime int64) error {
+func (m *MySQL) InsertMigrationWithApplyTime(ctx context.Context, migration *entity.Migration, applyTime int64) error {
 	q := fmt.Sprintf(`
-		INSERT INTO %s (version, apply_time)
-		VALUES (?, ?)`,
+		INSERT INTO %s (version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
 		m.options.TableName,
 	)
 	//nolint:gosec // overflow ok
-	if _, err := m.conn.ExecContext(ctx, q, version, uint32(applyTime)); err != nil {
+	if _, err := m.conn.ExecContext(ctx, q,
+		migration.Version,
+		uint32(applyTime),
+		migration.ExecutedSQL,
+		migration.DownSQL,
+		migration.Checksum,
+		migration.OutOfOrder,
+		migration.StatusOrApplied(),
+		migration.LastStatement,
+	); err != nil {
 		return errors.Wrap(m.dbError(err, q), "insert migration")
 	}
 	return nil
 }
 
+// UpdateMigration updates the record of migration.Version, setting its apply time to now.
+func (m *MySQL) UpdateMigration(ctx context.Context, migration *entity.Migration) error {
+	q := fmt.Sprintf(`
+		UPDATE %s
+		SET apply_time = ?, executed_sql = ?, down_sql = ?, checksum = ?, out_of_order = ?,
+			status = ?, last_statement = ?
+		WHERE version = ?`,
+		m.options.TableName,
+	)
+	//nolint:gosec // overflow ok
+	if _, err := m.conn.ExecContext(ctx, q,
+		uint32(time.Now().Unix()),
+		migration.ExecutedSQL,
+		migration.DownSQL,
+		migration.Checksum,
+		migration.OutOfOrder,
+		migration.StatusOrApplied(),
+		migration.LastStatement,
+		migration.Version,
+	); err != nil {
+		return errors.Wrap(m.dbError(err, q), "update migration")
+	}
+
+	return nil
+}
+
 // MigrationsByMaxApplyTime returns migrations that share the maximum apply_time value.
 func (m *MySQL) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations, error) {
 	q := fmt.Sprintf(`
-		SELECT version, apply_time
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
 		FROM %s
 		WHERE apply_time = (SELECT MAX(apply_time) FROM %s)
 		ORDER BY version DESC`,
@@ -278,15 +416,29 @@ func (m *MySQL) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations
 	var migrations entity.Migrations
 	for rows.Next() {
 		var (
-			version   string
-			applyTime int64
+			version       string
+			applyTime     int64
+			executedSQL   sql.NullString
+			downSQL       sql.NullString
+			checksum      sql.NullString
+			outOfOrder    sql.NullBool
+			status        sql.NullString
+			lastStatement sql.NullInt64
 		)
-		if err := rows.Scan(&version, &applyTime); err != nil {
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
 			return nil, errors.Wrap(m.dbError(err, q), "get migrations by max apply time")
 		}
 		migrations = append(migrations, entity.Migration{
-			Version:   version,
-			ApplyTime: applyTime,
+			Version:       version,
+			ApplyTime:     applyTime,
+			ExecutedSQL:   executedSQL.String,
+			DownSQL:       downSQL.String,
+			Checksum:      checksum.String,
+			OutOfOrder:    outOfOrder.Bool,
+			Status:        status.String,
+			LastStatement: int(lastStatement.Int64),
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -296,6 +448,177 @@ func (m *MySQL) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations
 	return migrations, nil
 }
 
+// DumpSchema returns the SHOW CREATE TABLE and SHOW CREATE VIEW statements of the tables and views
+// of the schema, without the history table, ordered by name. The AUTO_INCREMENT counters are left out.
+func (m *MySQL) DumpSchema(ctx context.Context) (string, error) {
+	q := `
+		SELECT table_name, table_type
+		FROM information_schema.tables
+		WHERE table_schema = ? AND table_name <> ?
+		ORDER BY table_name`
+	tables, err := queryStrings(ctx, m.conn, q, 2, m.options.SchemaName, m.options.TableName)
+	if err != nil {
+		return "", errors.Wrap(m.dbError(err, q), "dump tables")
+	}
+
+	var dump schemaDump
+	for _, table := range tables {
+		name := fmt.Sprintf("`%s`.`%s`", m.options.SchemaName, table[0])
+		if table[1] == "VIEW" {
+			// SHOW CREATE VIEW returns the view, its statement and the character set and collation of the client
+			q = "SHOW CREATE VIEW " + name
+			views, err := queryStrings(ctx, m.conn, q, 4)
+			if err != nil {
+				return "", errors.Wrap(m.dbError(err, q), "dump views")
+			}
+			for _, view := range views {
+				dump.add(view[1])
+			}
+			continue
+		}
+
+		q = "SHOW CREATE TABLE " + name
+		creates, err := queryStrings(ctx, m.conn, q, 2)
+		if err != nil {
+			return "", errors.Wrap(m.dbError(err, q), "dump tables")
+		}
+		for _, create := range creates {
+			dump.add(mysqlAutoIncrement.ReplaceAllString(create[1], ""))
+		}
+	}
+
+	return dump.String(), nil
+}
+
+// LoadSchema is not supported: the MySQL DDL cannot be read into the DDL IR of the schema diff.
+func (m *MySQL) LoadSchema(context.Context) (*ddl.Schema, error) {
+	return nil, errors.WithStack(ErrLoadSchemaUnsupported)
+}
+
+// TryLock tries to take the named lock of the history table with GET_LOCK without waiting.
+// The lock is held by a dedicated session; MySQL releases it when the session ends.
+func (m *MySQL) TryLock(ctx context.Context, _ *entity.Lock) (bool, error) {
+	if m.session != nil {
+		return true, nil
+	}
+
+	session, err := openSession(ctx, m.conn)
+	if err != nil {
+		return false, errors.Wrap(err, "try lock")
+	}
+
+	var (
+		q        = "SELECT GET_LOCK(?, 0)"
+		acquired sql.NullInt64
+	)
+	if _, err := queryRow(ctx, session, q, []any{m.lockName()}, &acquired); err != nil {
+		_ = session.Close()
+		return false, errors.Wrap(m.dbError(err, q), "try lock")
+	}
+
+	if acquired.Int64 != 1 {
+		if err := session.Close(); err != nil {
+			return false, errors.Wrap(err, "try lock")
+		}
+
+		return false, nil
+	}
+
+	m.session = session
+
+	return true, nil
+}
+
+// Unlock releases the named lock taken by TryLock and returns its session to the pool.
+func (m *MySQL) Unlock(ctx context.Context) error {
+	if m.session == nil {
+		return nil
+	}
+	session := m.session
+	m.session = nil
+	defer session.Close()
+
+	q := "SELECT RELEASE_LOCK(?)"
+	if _, err := session.ExecContext(ctx, q, m.lockName()); err != nil {
+		return errors.Wrap(m.dbError(err, q), "unlock")
+	}
+
+	return nil
+}
+
+// LockHolder returns the connection holding the named lock of the history table, or nil when the lock is free.
+// The lock is considered taken when the connection finished its last statement,
+// which for a lock session is the GET_LOCK call.
+func (m *MySQL) LockHolder(ctx context.Context) (*entity.Lock, error) {
+	var (
+		q = `
+			SELECT p.ID, CONCAT(p.USER, '@', p.HOST), UNIX_TIMESTAMP() - p.TIME
+			FROM information_schema.PROCESSLIST p
+			WHERE p.ID = IS_USED_LOCK(?)`
+		id         int64
+		userHost   string
+		acquiredAt int64
+	)
+
+	found, err := queryRow(ctx, m.conn, q, []any{m.lockName()}, &id, &userHost, &acquiredAt)
+	if err != nil {
+		return nil, errors.Wrap(m.dbError(err, q), "get lock holder")
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return &entity.Lock{
+		Owner:      fmt.Sprintf("%s (connection %d)", userHost, id),
+		AcquiredAt: acquiredAt,
+	}, nil
+}
+
+// ForceUnlock kills the connection holding the named lock of the history table,
+// which makes MySQL release the lock.
+func (m *MySQL) ForceUnlock(ctx context.Context) error {
+	var (
+		q  = "SELECT IS_USED_LOCK(?)"
+		id sql.NullInt64
+	)
+	if _, err := queryRow(ctx, m.conn, q, []any{m.lockName()}, &id); err != nil {
+		return errors.Wrap(m.dbError(err, q), "force unlock")
+	}
+	if !id.Valid {
+		return nil
+	}
+
+	// KILL does not accept placeholders; the id is an integer read from the server.
+	q = "KILL " + strconv.FormatInt(id.Int64, 10)
+	if _, err := m.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(m.dbError(err, q), "force unlock")
+	}
+
+	return nil
+}
+
+// SessionLock returns true, because MySQL releases the named lock when the connection ends.
+func (m *MySQL) SessionLock() bool {
+	return true
+}
+
+// lockName returns the name of the GET_LOCK lock guarding the history table.
+// MySQL limits lock names to 64 characters.
+func (m *MySQL) lockName() string {
+	const maxLockNameLen = 64
+
+	name := lockNamePrefix + m.TableNameWithSchema()
+	if len(name) > maxLockNameLen {
+		name = name[:maxLockNameLen]
+	}
+
+	return name
+}
+
+// mysqlLockTimeoutCode is ER_LOCK_WAIT_TIMEOUT, returned when innodb_lock_wait_timeout
+// or lock_wait_timeout expires.
+const mysqlLockTimeoutCode = 1205
+
 // dbError returns DBError is err is db error else returns got error.
 func (m *MySQL) dbError(err error, q string) error {
 	var mysqlErr *mysql.MySQLError
@@ -308,5 +631,6 @@ func (m *MySQL) dbError(err error, q string) error {
 		Message:       mysqlErr.Message,
 		InternalQuery: q,
 		Cause:         err,
+		LockTimedOut:  mysqlErr.Number == mysqlLockTimeoutCode,
 	})
 }
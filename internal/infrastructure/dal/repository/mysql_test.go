@@ -10,10 +10,14 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
 	thelp "github.com/raoptimus/db-migrator.go/internal/testutil"
 	"github.com/stretchr/testify/assert"
@@ -51,6 +55,30 @@ func TestMySQL_ExecQuery_Failure(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestMySQL_ExecQuery_Timeouts_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn, _, opener := newSessionConnection(t)
+	session := NewMockSession(t)
+
+	opener.EXPECT().Session(ctx).Return(session, nil).Once()
+	session.EXPECT().ExecContext(ctx, "SET SESSION lock_wait_timeout = 2").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(ctx, "SET SESSION innodb_lock_wait_timeout = 2").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(ctx, "SET SESSION max_execution_time = 30000").Return(nil, nil).Once()
+	session.EXPECT().
+		ExecContext(ctx, "ALTER TABLE t ADD COLUMN c int").
+		Return(nil, &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}).
+		Once()
+	session.EXPECT().ExecContext(mock.Anything, "SET SESSION lock_wait_timeout = DEFAULT").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(mock.Anything, "SET SESSION innodb_lock_wait_timeout = DEFAULT").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(mock.Anything, "SET SESSION max_execution_time = DEFAULT").Return(nil, nil).Once()
+	session.EXPECT().Close().Return(nil).Once()
+
+	repo := NewMySQL(conn, &Options{LockTimeout: 1500 * time.Millisecond, StatementTimeout: 30 * time.Second})
+	err := repo.ExecQuery(ctx, "ALTER TABLE t ADD COLUMN c int")
+	require.Error(t, err)
+	assert.True(t, IsLockTimeout(err))
+}
+
 func TestMySQL_ExecQueryTransaction_Successfully(t *testing.T) {
 	ctx := context.Background()
 	conn := NewMockConnection(t)
@@ -94,7 +122,13 @@ func TestMySQL_CreateMigrationHistoryTable_Successfully(t *testing.T) {
 	expectedSQL := `
 		CREATE TABLE migration (
 		  version VARCHAR(180) PRIMARY KEY,
-		  apply_time INT
+		  apply_time INT,
+		  executed_sql MEDIUMTEXT,
+		  down_sql MEDIUMTEXT,
+		  checksum VARCHAR(64),
+		  out_of_order TINYINT(1) NOT NULL DEFAULT 0,
+		  status VARCHAR(16) NOT NULL DEFAULT 'applied',
+		  last_statement INT NOT NULL DEFAULT 0
 		)
 		ENGINE=InnoDB
 	`
@@ -133,6 +167,70 @@ func TestMySQL_CreateMigrationHistoryTable_Failure(t *testing.T) {
 	assert.Equal(t, "1050", dbErr.Code)
 }
 
+func TestMySQL_UpgradeMigrationHistoryTable_AddsMissingColumns_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	expectedSQL := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ?
+	`
+	rows := sqlex.NewRowsWithSlice([]interface{}{"version", "apply_time", "executed_sql"})
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL(expectedSQL)), "test_db", "migration").
+		Return(rows, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE migration ADD COLUMN down_sql MEDIUMTEXT").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE migration ADD COLUMN checksum VARCHAR(64)").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE migration ADD COLUMN out_of_order TINYINT(1) NOT NULL DEFAULT 0").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE migration ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'applied'").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE migration ADD COLUMN last_statement INT NOT NULL DEFAULT 0").
+		Return(nil, nil).
+		Once()
+
+	repo := NewMySQL(conn, &Options{
+		TableName:  "migration",
+		SchemaName: "test_db",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+	require.NoError(t, err)
+}
+
+func TestMySQL_UpgradeMigrationHistoryTable_Failure(t *testing.T) {
+	ctx := context.Background()
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.AnythingOfType("string"), "test_db", "migration").
+		Return(nil, &mysql.MySQLError{Number: 1142, Message: "SELECT command denied"}).
+		Once()
+
+	repo := NewMySQL(conn, &Options{
+		TableName:  "migration",
+		SchemaName: "test_db",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+	require.Error(t, err)
+	var dbErr *DBError
+	require.ErrorAs(t, err, &dbErr)
+	assert.Equal(t, "1142", dbErr.Code)
+}
+
 func TestMySQL_DropMigrationHistoryTable_Successfully(t *testing.T) {
 	ctx := context.Background()
 
@@ -175,9 +273,24 @@ func TestMySQL_DropMigrationHistoryTable_Failure(t *testing.T) {
 func TestMySQL_InsertMigration_Successfully(t *testing.T) {
 	ctx := context.Background()
 
+	expectedSQL := `
+		INSERT INTO migration (version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
 	conn := NewMockConnection(t)
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32")).
+		ExecContext(ctx,
+			mock.MatchedBy(thelp.CompareSQL(expectedSQL)),
+			"210328_221600_test",
+			mock.AnythingOfType("uint32"),
+			"CREATE TABLE test (id INT);\n",
+			"DROP TABLE test;",
+			"0f1e2d3c",
+			true,
+			"failed",
+			2,
+		).
 		Return(nil, nil).
 		Once()
 
@@ -185,7 +298,15 @@ func TestMySQL_InsertMigration_Successfully(t *testing.T) {
 		TableName:  "migration",
 		SchemaName: "test_db",
 	})
-	err := repo.InsertMigration(ctx, "210328_221600_test")
+	err := repo.InsertMigration(ctx, &entity.Migration{
+		Version:       "210328_221600_test",
+		ExecutedSQL:   "CREATE TABLE test (id INT);\n",
+		DownSQL:       "DROP TABLE test;",
+		Checksum:      "0f1e2d3c",
+		OutOfOrder:    true,
+		Status:        entity.StatusFailed,
+		LastStatement: 2,
+	})
 	require.NoError(t, err)
 }
 
@@ -194,7 +315,8 @@ func TestMySQL_InsertMigration_Failure(t *testing.T) {
 
 	conn := NewMockConnection(t)
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32")).
+		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32"), "", "", "", false,
+			"applied", 0).
 		Return(nil, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}).
 		Once()
 
@@ -202,13 +324,78 @@ func TestMySQL_InsertMigration_Failure(t *testing.T) {
 		TableName:  "migration",
 		SchemaName: "test_db",
 	})
-	err := repo.InsertMigration(ctx, "210328_221600_test")
+	err := repo.InsertMigration(ctx, &entity.Migration{Version: "210328_221600_test"})
 	require.Error(t, err)
 	var dbErr *DBError
 	require.ErrorAs(t, err, &dbErr)
 	assert.Equal(t, "1062", dbErr.Code)
 }
 
+func TestMySQL_UpdateMigration_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	expectedSQL := `
+		UPDATE migration
+		SET apply_time = ?, executed_sql = ?, down_sql = ?, checksum = ?, out_of_order = ?,
+			status = ?, last_statement = ?
+		WHERE version = ?
+	`
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx,
+			mock.MatchedBy(thelp.CompareSQL(expectedSQL)),
+			mock.AnythingOfType("uint32"),
+			"CREATE TABLE test (id int);\n",
+			"DROP TABLE test;",
+			"0f1e2d3c",
+			false,
+			"applied",
+			0,
+			"210328_221600_test",
+		).
+		Return(nil, nil).
+		Once()
+
+	repo := NewMySQL(conn, &Options{
+		TableName:  "migration",
+		SchemaName: "test_db",
+	})
+	err := repo.UpdateMigration(ctx, &entity.Migration{
+		Version:     "210328_221600_test",
+		ExecutedSQL: "CREATE TABLE test (id int);\n",
+		DownSQL:     "DROP TABLE test;",
+		Checksum:    "0f1e2d3c",
+		Status:      entity.StatusApplied,
+	})
+
+	require.NoError(t, err)
+}
+
+func TestMySQL_UpdateMigration_Failure(t *testing.T) {
+	ctx := context.Background()
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, mock.AnythingOfType("string"), mock.AnythingOfType("uint32"), "", "", "", false,
+			"failed", 1, "210328_221600_test").
+		Return(nil, &mysql.MySQLError{Number: 2006, Message: "MySQL server has gone away"}).
+		Once()
+
+	repo := NewMySQL(conn, &Options{
+		TableName:  "migration",
+		SchemaName: "test_db",
+	})
+	err := repo.UpdateMigration(ctx, &entity.Migration{
+		Version:       "210328_221600_test",
+		Status:        entity.StatusFailed,
+		LastStatement: 1,
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "update migration")
+}
+
 func TestMySQL_RemoveMigration_Successfully(t *testing.T) {
 	ctx := context.Background()
 
@@ -252,7 +439,7 @@ func TestMySQL_Migrations_Failure(t *testing.T) {
 	ctx := context.Background()
 
 	expectedSQL := `
-		SELECT version, apply_time
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
 		FROM migration
 		ORDER BY apply_time DESC, version DESC
 		LIMIT ?
@@ -413,15 +600,24 @@ func TestMySQL_Migrations_Successfully(t *testing.T) {
 	ctx := context.Background()
 
 	expectedSQL := `
-		SELECT version, apply_time
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
 		FROM migration
 		ORDER BY apply_time DESC, version DESC
 		LIMIT ?
 	`
 
 	rows := sqlex.NewRowsWithSlice([]interface{}{
-		[]any{"210328_221600_create_users", int64(1616968560)},
-		[]any{"210329_121500_add_index", int64(1617020100)},
+		[]any{
+			"210328_221600_create_users",
+			int64(1616968560),
+			sql.NullString{String: "CREATE TABLE users (id INT);\n", Valid: true},
+			sql.NullString{String: "DROP TABLE users;", Valid: true},
+			sql.NullString{String: "0f1e2d3c", Valid: true},
+			sql.NullBool{Bool: true, Valid: true},
+			sql.NullString{String: "failed", Valid: true},
+			sql.NullInt64{Int64: 2, Valid: true},
+		},
+		[]any{"210329_121500_add_index", int64(1617020100), nil, nil, nil, nil, nil, nil},
 	})
 
 	conn := NewMockConnection(t)
@@ -440,6 +636,15 @@ func TestMySQL_Migrations_Successfully(t *testing.T) {
 	require.Len(t, migrations, 2)
 	assert.Equal(t, "210328_221600_create_users", migrations[0].Version)
 	assert.Equal(t, int64(1616968560), migrations[0].ApplyTime)
+	assert.Equal(t, "CREATE TABLE users (id INT);\n", migrations[0].ExecutedSQL)
+	assert.Equal(t, "DROP TABLE users;", migrations[0].DownSQL)
+	assert.Equal(t, "0f1e2d3c", migrations[0].Checksum)
+	assert.True(t, migrations[0].OutOfOrder)
+	assert.Equal(t, entity.StatusFailed, migrations[0].Status)
+	assert.Equal(t, 2, migrations[0].LastStatement)
+	assert.Empty(t, migrations[1].DownSQL)
+	assert.False(t, migrations[1].OutOfOrder)
+	assert.Equal(t, entity.StatusApplied, migrations[1].StatusOrApplied())
 }
 
 func TestMySQL_Migrations_EmptyResult_Successfully(t *testing.T) {
@@ -605,3 +810,125 @@ func TestMySQL_ExistsMigration_NotExists_Successfully(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, exists)
 }
+
+func TestMySQL_TryLock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn, _, opener := newSessionConnection(t)
+	session := NewMockSession(t)
+
+	opener.EXPECT().Session(ctx).Return(session, nil).Once()
+	session.EXPECT().
+		QueryContext(ctx, "SELECT GET_LOCK(?, 0)", "db-migrator:docker.migration").
+		Return(sqlex.NewRowsWithSlice([]any{sql.NullInt64{Int64: 1, Valid: true}}), nil).
+		Once()
+	session.EXPECT().
+		ExecContext(ctx, "SELECT RELEASE_LOCK(?)", "db-migrator:docker.migration").
+		Return(nil, nil).
+		Once()
+	session.EXPECT().Close().Return(nil).Once()
+
+	repo := NewMySQL(conn, &Options{TableName: "migration", SchemaName: "docker"})
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1"})
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	require.NoError(t, repo.Unlock(ctx))
+}
+
+func TestMySQL_TryLock_HeldByAnotherSession(t *testing.T) {
+	ctx := context.Background()
+	conn, _, opener := newSessionConnection(t)
+	session := NewMockSession(t)
+
+	opener.EXPECT().Session(ctx).Return(session, nil).Once()
+	session.EXPECT().
+		QueryContext(ctx, "SELECT GET_LOCK(?, 0)", "db-migrator:docker.migration").
+		Return(sqlex.NewRowsWithSlice([]any{sql.NullInt64{Int64: 0, Valid: true}}), nil).
+		Once()
+	session.EXPECT().Close().Return(nil).Once()
+
+	repo := NewMySQL(conn, &Options{TableName: "migration", SchemaName: "docker"})
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1"})
+	require.NoError(t, err)
+	assert.False(t, acquired)
+}
+
+func TestMySQL_LockHolder_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.Anything, "db-migrator:docker.migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{int64(17), "root@10.0.0.5:51234", int64(1700000000)},
+		}), nil).
+		Once()
+
+	repo := NewMySQL(conn, &Options{TableName: "migration", SchemaName: "docker"})
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Lock{Owner: "root@10.0.0.5:51234 (connection 17)", AcquiredAt: 1700000000}, holder)
+}
+
+func TestMySQL_ForceUnlock_KillsHolder_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, "SELECT IS_USED_LOCK(?)", "db-migrator:docker.migration").
+		Return(sqlex.NewRowsWithSlice([]any{sql.NullInt64{Int64: 17, Valid: true}}), nil).
+		Once()
+	conn.EXPECT().ExecContext(ctx, "KILL 17").Return(nil, nil).Once()
+
+	repo := NewMySQL(conn, &Options{TableName: "migration", SchemaName: "docker"})
+	require.NoError(t, repo.ForceUnlock(ctx))
+}
+
+func TestMySQL_ForceUnlock_Free_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, "SELECT IS_USED_LOCK(?)", "db-migrator:docker.migration").
+		Return(sqlex.NewRowsWithSlice([]any{sql.NullInt64{}}), nil).
+		Once()
+
+	repo := NewMySQL(conn, &Options{TableName: "migration", SchemaName: "docker"})
+	require.NoError(t, repo.ForceUnlock(ctx))
+}
+
+func TestMySQL_DumpSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM information_schema.tables")
+		}), "docker", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"user_names", "VIEW"},
+			[]any{"users", "BASE TABLE"},
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, "SHOW CREATE VIEW `docker`.`user_names`").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"user_names", "CREATE VIEW `user_names` AS select `name` from `users`", "utf8mb4", "utf8mb4_0900_ai_ci"},
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, "SHOW CREATE TABLE `docker`.`users`").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"users", "CREATE TABLE `users` (\n  `id` int NOT NULL AUTO_INCREMENT\n) ENGINE=InnoDB AUTO_INCREMENT=42"},
+		}), nil).
+		Once()
+
+	repo := NewMySQL(conn, &Options{TableName: "migration", SchemaName: "docker"})
+	schema, err := repo.DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE VIEW `user_names` AS select `name` from `users`;\n\n"+
+		"CREATE TABLE `users` (\n  `id` int NOT NULL AUTO_INCREMENT\n) ENGINE=InnoDB;\n", schema)
+}
+
+func TestMySQL_LoadSchema_Unsupported(t *testing.T) {
+	repo := NewMySQL(NewMockConnection(t), &Options{})
+
+	_, err := repo.LoadSchema(context.Background())
+	require.ErrorIs(t, err, ErrLoadSchemaUnsupported)
+}
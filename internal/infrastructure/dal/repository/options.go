@@ -8,6 +8,8 @@
 
 package repository
 
+import "time"
+
 // Options represents configuration options for creating a repository instance.
 type Options struct {
 	// TableName is the name of the migration history table.
@@ -18,4 +20,8 @@ type Options struct {
 	ClusterName string
 	// Replicated indicates whether to use replicated tables in ClickHouse.
 	Replicated bool
+	// StatementTimeout limits the execution time of every migration statement on the server. Zero means no limit.
+	StatementTimeout time.Duration
+	// LockTimeout limits how long a migration statement waits for a lock on the server. Zero means no limit.
+	LockTimeout time.Duration
 }
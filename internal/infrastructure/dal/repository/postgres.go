@@ -10,22 +10,43 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
 )
 
 const postgresDefaultSchema = "public"
 
+// postgresHistoryColumns lists the history table columns added after its first release.
+var postgresHistoryColumns = []historyColumn{
+	{Name: "executed_sql", Type: "text"},
+	{Name: "down_sql", Type: "text"},
+	{Name: "checksum", Type: "varchar(64)"},
+	{Name: "out_of_order", Type: "boolean NOT NULL DEFAULT false"},
+	{Name: "status", Type: "varchar(16) NOT NULL DEFAULT 'applied'"},
+	{Name: "last_statement", Type: "integer NOT NULL DEFAULT 0"},
+}
+
 // Postgres implements Repository interface for PostgreSQL database.
-// It handles migration history tracking and SQL execution for PostgreSQL.
+// It handles migration history tracking and SQL execution for PostgreSQL
+// and, with the overrides of their flavor, for CockroachDB and YugabyteDB.
 type Postgres struct {
 	conn    Connection
 	options *Options
+	flavor  postgresFlavor
+	// session holds the advisory lock taken by TryLock until Unlock.
+	session Session
+	// lock is the row of the lock table inserted by TryLock until Unlock, when the flavor has no advisory locks.
+	lock *entity.Lock
 }
 
 // NewPostgres creates a new Postgres repository instance.
@@ -34,6 +55,7 @@ func NewPostgres(conn Connection, options *Options) *Postgres {
 	return &Postgres{
 		conn:    conn,
 		options: options,
+		flavor:  postgresFlavorPostgreSQL,
 	}
 }
 
@@ -42,7 +64,7 @@ func (p *Postgres) Migrations(ctx context.Context, limit int) (entity.Migrations
 	var (
 		q = fmt.Sprintf(
 			`
-			SELECT version, apply_time 
+			SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
 			FROM %s
 			ORDER BY apply_time DESC, version DESC
 			LIMIT $1`,
@@ -59,18 +81,32 @@ func (p *Postgres) Migrations(ctx context.Context, limit int) (entity.Migrations
 
 	for rows.Next() {
 		var (
-			version   string
-			applyTime int64
+			version       string
+			applyTime     int64
+			executedSQL   sql.NullString
+			downSQL       sql.NullString
+			checksum      sql.NullString
+			outOfOrder    sql.NullBool
+			status        sql.NullString
+			lastStatement sql.NullInt64
 		)
 
-		if err := rows.Scan(&version, &applyTime); err != nil {
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
 			return nil, errors.Wrap(p.dbError(err, q), "get migrations")
 		}
 
 		migrations = append(migrations,
 			entity.Migration{
-				Version:   version,
-				ApplyTime: applyTime,
+				Version:       version,
+				ApplyTime:     applyTime,
+				ExecutedSQL:   executedSQL.String,
+				DownSQL:       downSQL.String,
+				Checksum:      checksum.String,
+				OutOfOrder:    outOfOrder.Bool,
+				Status:        status.String,
+				LastStatement: int(lastStatement.Int64),
 			},
 		)
 	}
@@ -84,6 +120,11 @@ func (p *Postgres) Migrations(ctx context.Context, limit int) (entity.Migrations
 
 // HasMigrationHistoryTable returns true if migration history table exists.
 func (p *Postgres) HasMigrationHistoryTable(ctx context.Context) (exists bool, err error) {
+	if !p.flavor.pgCatalog {
+		exists, err = p.hasTable(ctx, p.options.TableName)
+		return exists, errors.Wrap(err, "get schema table")
+	}
+
 	var (
 		q = `
 			SELECT
@@ -125,24 +166,14 @@ func (p *Postgres) HasMigrationHistoryTable(ctx context.Context) (exists bool, e
 }
 
 // InsertMigration inserts the new migration record.
-func (p *Postgres) InsertMigration(ctx context.Context, version string) error {
-	q := fmt.Sprintf(`
-		INSERT INTO %s (version, apply_time)
-		VALUES ($1, $2)`,
-		p.TableNameWithSchema(),
-	)
-	//nolint:gosec // overflow ok
-	now := uint32(time.Now().Unix())
-	if _, err := p.conn.ExecContext(ctx, q, version, now); err != nil {
-		return errors.Wrap(p.dbError(err, q), "insert migration")
-	}
-	return nil
+func (p *Postgres) InsertMigration(ctx context.Context, migration *entity.Migration) error {
+	return p.InsertMigrationWithApplyTime(ctx, migration, time.Now().Unix())
 }
 
 // RemoveMigration removes the migration record.
 func (p *Postgres) RemoveMigration(ctx context.Context, version string) error {
 	q := fmt.Sprintf(`DELETE FROM %s WHERE (version) = ($1)`, p.TableNameWithSchema())
-	if _, err := p.conn.ExecContext(ctx, q, version); err != nil {
+	if _, err := p.execWrite(ctx, q, version); err != nil {
 		return errors.Wrap(p.dbError(err, q), "remove migration")
 	}
 	return nil
@@ -150,20 +181,62 @@ func (p *Postgres) RemoveMigration(ctx context.Context, version string) error {
 
 // ExecQuery executes a query without returning any rows.
 // The args are for any placeholder parameters in the query.
+// The statement and lock timeouts of the options are set for the query only, with SET LOCAL within a transaction.
 func (p *Postgres) ExecQuery(ctx context.Context, query string, args ...any) error {
-	if _, err := p.conn.ExecContext(ctx, query, args...); err != nil {
+	settings := p.timeoutSettings()
+	if len(settings) == 0 {
+		if _, err := p.conn.ExecContext(ctx, query, args...); err != nil {
+			return p.dbError(err, query)
+		}
+		return nil
+	}
+
+	var set, reset []string
+	if _, err := connection.TxFromContext(ctx); err == nil {
+		// SET LOCAL lasts until the end of the transaction
+		for _, setting := range settings {
+			set = append(set, fmt.Sprintf("SET LOCAL %s = '%s'", setting.Name, setting.Value))
+		}
+	} else {
+		for _, setting := range settings {
+			set = append(set, fmt.Sprintf("SET %s = '%s'", setting.Name, setting.Value))
+			reset = append(reset, "RESET "+setting.Name)
+		}
+	}
+	if err := execWithSettings(ctx, p.conn, set, reset, query, args...); err != nil {
 		return p.dbError(err, query)
 	}
+
 	return nil
 }
 
+// timeoutSettings returns the settings enforcing the statement and lock timeouts of the options.
+func (p *Postgres) timeoutSettings() []sessionSetting {
+	var settings []sessionSetting
+	if p.options.LockTimeout > 0 {
+		settings = append(settings, sessionSetting{
+			Name:  "lock_timeout",
+			Value: strconv.FormatInt(durationMillis(p.options.LockTimeout), 10) + "ms",
+		})
+	}
+	if p.options.StatementTimeout > 0 {
+		settings = append(settings, sessionSetting{
+			Name:  "statement_timeout",
+			Value: strconv.FormatInt(durationMillis(p.options.StatementTimeout), 10) + "ms",
+		})
+	}
+
+	return settings
+}
+
 // ExecQueryTransaction executes txFn within a transaction.
 func (p *Postgres) ExecQueryTransaction(ctx context.Context, txFn func(ctx context.Context) error) error {
 	return p.conn.Transaction(ctx, txFn)
 }
 
 // SupportsDDLTransactions returns true because PostgreSQL supports transactional DDL.
-func (p *Postgres) SupportsDDLTransactions() bool { return true }
+// CockroachDB and YugabyteDB restrict DDL in transactions, so it returns false for them.
+func (p *Postgres) SupportsDDLTransactions() bool { return p.flavor.ddlTransactions }
 
 // CreateMigrationHistoryTable creates a new migration history table.
 func (p *Postgres) CreateMigrationHistoryTable(ctx context.Context) error {
@@ -171,7 +244,13 @@ func (p *Postgres) CreateMigrationHistoryTable(ctx context.Context) error {
 		`
 				CREATE TABLE %s (
 				  version varchar(180) PRIMARY KEY,
-				  apply_time integer
+				  apply_time integer,
+				  executed_sql text,
+				  down_sql text,
+				  checksum varchar(64),
+				  out_of_order boolean NOT NULL DEFAULT false,
+				  status varchar(16) NOT NULL DEFAULT 'applied',
+				  last_statement integer NOT NULL DEFAULT 0
 				)
 			`,
 		p.TableNameWithSchema(),
@@ -183,6 +262,50 @@ func (p *Postgres) CreateMigrationHistoryTable(ctx context.Context) error {
 	return nil
 }
 
+// UpgradeMigrationHistoryTable adds the columns introduced after the history table
+// was first released. It is a no-op when the table already has all of them.
+func (p *Postgres) UpgradeMigrationHistoryTable(ctx context.Context) error {
+	missing, err := p.historyColumnsToAdd(ctx)
+	if err != nil {
+		return errors.Wrap(err, "upgrade migration history table")
+	}
+
+	for _, column := range missing {
+		q := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, p.TableNameWithSchema(), column.Name, column.Type)
+		if _, err := p.conn.ExecContext(ctx, q); err != nil {
+			return errors.Wrap(p.dbError(err, q), "upgrade migration history table")
+		}
+	}
+
+	return nil
+}
+
+// NeedsMigrationHistoryTableUpgrade reports whether the history table lacks columns
+// added by UpgradeMigrationHistoryTable.
+func (p *Postgres) NeedsMigrationHistoryTableUpgrade(ctx context.Context) (bool, error) {
+	missing, err := p.historyColumnsToAdd(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "check migration history table")
+	}
+
+	return len(missing) > 0, nil
+}
+
+// historyColumnsToAdd returns the history columns the table does not have yet.
+func (p *Postgres) historyColumnsToAdd(ctx context.Context) ([]historyColumn, error) {
+	q := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2
+	`
+	existing, err := queryColumnNames(ctx, p.conn, q, p.options.SchemaName, p.options.TableName)
+	if err != nil {
+		return nil, p.dbError(err, q)
+	}
+
+	return missingHistoryColumns(existing, postgresHistoryColumns), nil
+}
+
 // DropMigrationHistoryTable drops the migration history table.
 func (p *Postgres) DropMigrationHistoryTable(ctx context.Context) error {
 	q := fmt.Sprintf(`DROP TABLE %s`, p.TableNameWithSchema())
@@ -258,23 +381,58 @@ func (p *Postgres) TableNameWithSchema() string {
 }
 
 // InsertMigrationWithApplyTime inserts the new migration record with an explicit apply time.
-func (p *Postgres) InsertMigrationWithApplyTime(ctx context.Context, version string, applyTime int64) error {
+func (p *Postgres) InsertMigrationWithApplyTime(ctx context.Context, migration *entity.Migration, applyTime int64) error {
 	q := fmt.Sprintf(`
-		INSERT INTO %s (version, apply_time)
-		VALUES ($1, $2)`,
+		INSERT INTO %s (version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
 		p.TableNameWithSchema(),
 	)
 	//nolint:gosec // overflow ok
-	if _, err := p.conn.ExecContext(ctx, q, version, uint32(applyTime)); err != nil {
+	if _, err := p.execWrite(ctx, q,
+		migration.Version,
+		uint32(applyTime),
+		migration.ExecutedSQL,
+		migration.DownSQL,
+		migration.Checksum,
+		migration.OutOfOrder,
+		migration.StatusOrApplied(),
+		migration.LastStatement,
+	); err != nil {
 		return errors.Wrap(p.dbError(err, q), "insert migration")
 	}
 	return nil
 }
 
+// UpdateMigration updates the record of migration.Version, setting its apply time to now.
+func (p *Postgres) UpdateMigration(ctx context.Context, migration *entity.Migration) error {
+	q := fmt.Sprintf(`
+		UPDATE %s
+		SET apply_time = $1, executed_sql = $2, down_sql = $3, checksum = $4, out_of_order = $5,
+			status = $6, last_statement = $7
+		WHERE version = $8`,
+		p.TableNameWithSchema(),
+	)
+	//nolint:gosec // overflow ok
+	if _, err := p.execWrite(ctx, q,
+		uint32(time.Now().Unix()),
+		migration.ExecutedSQL,
+		migration.DownSQL,
+		migration.Checksum,
+		migration.OutOfOrder,
+		migration.StatusOrApplied(),
+		migration.LastStatement,
+		migration.Version,
+	); err != nil {
+		return errors.Wrap(p.dbError(err, q), "update migration")
+	}
+
+	return nil
+}
+
 // MigrationsByMaxApplyTime returns migrations that share the maximum apply_time value.
 func (p *Postgres) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations, error) {
 	q := fmt.Sprintf(`
-		SELECT version, apply_time
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
 		FROM %s
 		WHERE apply_time = (SELECT MAX(apply_time) FROM %s)
 		ORDER BY version DESC`,
@@ -291,15 +449,29 @@ func (p *Postgres) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrati
 	var migrations entity.Migrations
 	for rows.Next() {
 		var (
-			version   string
-			applyTime int64
+			version       string
+			applyTime     int64
+			executedSQL   sql.NullString
+			downSQL       sql.NullString
+			checksum      sql.NullString
+			outOfOrder    sql.NullBool
+			status        sql.NullString
+			lastStatement sql.NullInt64
 		)
-		if err := rows.Scan(&version, &applyTime); err != nil {
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
 			return nil, errors.Wrap(p.dbError(err, q), "get migrations by max apply time")
 		}
 		migrations = append(migrations, entity.Migration{
-			Version:   version,
-			ApplyTime: applyTime,
+			Version:       version,
+			ApplyTime:     applyTime,
+			ExecutedSQL:   executedSQL.String,
+			DownSQL:       downSQL.String,
+			Checksum:      checksum.String,
+			OutOfOrder:    outOfOrder.Bool,
+			Status:        status.String,
+			LastStatement: int(lastStatement.Int64),
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -309,6 +481,253 @@ func (p *Postgres) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrati
 	return migrations, nil
 }
 
+// DumpSchema returns the DDL of the tables, indexes and views of the schema, built from pg_catalog,
+// without the history and lock tables. The objects are ordered by name, so dumps of the same schema are equal.
+func (p *Postgres) DumpSchema(ctx context.Context) (string, error) {
+	var dump schemaDump
+	if err := p.dumpTables(ctx, &dump); err != nil {
+		return "", errors.Wrap(err, "dump tables")
+	}
+
+	q := `
+		SELECT pg_catalog.pg_get_indexdef(i.indexrelid)
+		FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_class t ON t.oid = i.indrelid
+		JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = $1 AND t.relname <> $2
+			AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint con WHERE con.conindid = i.indexrelid)
+		ORDER BY t.relname, ic.relname`
+	indexes, err := queryStrings(ctx, p.conn, q, 1, p.options.SchemaName, p.options.TableName)
+	if err != nil {
+		return "", errors.Wrap(p.dbError(err, q), "dump indexes")
+	}
+	for _, index := range indexes {
+		dump.add(index[0])
+	}
+
+	q = `
+		SELECT c.relname, CASE c.relkind WHEN 'm' THEN 'MATERIALIZED VIEW' ELSE 'VIEW' END,
+			pg_catalog.pg_get_viewdef(c.oid, true)
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('v', 'm')
+		ORDER BY c.relname`
+	views, err := queryStrings(ctx, p.conn, q, 3, p.options.SchemaName)
+	if err != nil {
+		return "", errors.Wrap(p.dbError(err, q), "dump views")
+	}
+	for _, view := range views {
+		dump.add(fmt.Sprintf("CREATE %s %s.%s AS\n%s", view[1], p.options.SchemaName, view[0], view[2]))
+	}
+
+	return dump.String(), nil
+}
+
+// LoadSchema is not supported: the Postgres DDL cannot be read into the DDL IR of the schema diff.
+func (p *Postgres) LoadSchema(context.Context) (*ddl.Schema, error) {
+	return nil, errors.WithStack(ErrLoadSchemaUnsupported)
+}
+
+// dumpTables adds the CREATE TABLE statements of the tables of the schema, with their columns
+// and constraints, to dump.
+func (p *Postgres) dumpTables(ctx context.Context, dump *schemaDump) error {
+	q := `
+		SELECT c.relname, a.attname, pg_catalog.format_type(a.atttypid, a.atttypmod),
+			CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END,
+			COALESCE(' DEFAULT ' || pg_catalog.pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = c.oid AND d.adnum = a.attnum
+		WHERE n.nspname = $1 AND c.relname <> $2 AND c.relkind IN ('r', 'p')
+		ORDER BY c.relname, a.attnum`
+	columns, err := queryStrings(ctx, p.conn, q, 5, p.options.SchemaName, p.options.TableName)
+	if err != nil {
+		return p.dbError(err, q)
+	}
+
+	q = `
+		SELECT c.relname, con.conname, pg_catalog.pg_get_constraintdef(con.oid, true)
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class c ON c.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relname <> $2
+		ORDER BY c.relname, con.conname`
+	constraints, err := queryStrings(ctx, p.conn, q, 3, p.options.SchemaName, p.options.TableName)
+	if err != nil {
+		return p.dbError(err, q)
+	}
+
+	var (
+		tables      []string
+		definitions = make(map[string][]string)
+	)
+	for _, column := range columns {
+		if p.isLockTable(column[0]) {
+			continue
+		}
+		if _, ok := definitions[column[0]]; !ok {
+			tables = append(tables, column[0])
+		}
+		definitions[column[0]] = append(definitions[column[0]], column[1]+" "+column[2]+column[3]+column[4])
+	}
+	for _, constraint := range constraints {
+		if p.isLockTable(constraint[0]) {
+			continue
+		}
+		definitions[constraint[0]] = append(definitions[constraint[0]],
+			"CONSTRAINT "+constraint[1]+" "+constraint[2])
+	}
+	for _, table := range tables {
+		dump.add(fmt.Sprintf("CREATE TABLE %s.%s (\n    %s\n)",
+			p.options.SchemaName, table, strings.Join(definitions[table], ",\n    ")))
+	}
+
+	return nil
+}
+
+// TryLock tries to take the advisory lock of the history table without waiting.
+// The lock is held by a dedicated session, whose application_name is set to the lock owner
+// so that other processes can tell who holds it. PostgreSQL releases the lock when the session ends.
+// A flavor without advisory locks inserts the row of the lock table instead.
+func (p *Postgres) TryLock(ctx context.Context, lock *entity.Lock) (bool, error) {
+	if !p.flavor.advisoryLocks {
+		return p.tryLockTable(ctx, lock)
+	}
+	if p.session != nil {
+		return true, nil
+	}
+
+	session, err := openSession(ctx, p.conn)
+	if err != nil {
+		return false, errors.Wrap(err, "try lock")
+	}
+
+	q := "SELECT set_config('application_name', $1, false)"
+	if _, err := session.ExecContext(ctx, q, lock.Owner); err != nil {
+		_ = session.Close()
+		return false, errors.Wrap(p.dbError(err, q), "try lock")
+	}
+
+	var acquired bool
+	classID, objID := p.advisoryLockKeys()
+	q = "SELECT pg_try_advisory_lock($1, $2)"
+	if _, err := queryRow(ctx, session, q, []any{classID, objID}, &acquired); err != nil {
+		_ = session.Close()
+		return false, errors.Wrap(p.dbError(err, q), "try lock")
+	}
+
+	if !acquired {
+		if err := session.Close(); err != nil {
+			return false, errors.Wrap(err, "try lock")
+		}
+
+		return false, nil
+	}
+
+	p.session = session
+
+	return true, nil
+}
+
+// Unlock releases the advisory lock taken by TryLock and returns its session to the pool.
+func (p *Postgres) Unlock(ctx context.Context) error {
+	if !p.flavor.advisoryLocks {
+		return p.unlockTable(ctx)
+	}
+	if p.session == nil {
+		return nil
+	}
+	session := p.session
+	p.session = nil
+	defer session.Close()
+
+	classID, objID := p.advisoryLockKeys()
+	q := "SELECT pg_advisory_unlock($1, $2)"
+	if _, err := session.ExecContext(ctx, q, classID, objID); err != nil {
+		return errors.Wrap(p.dbError(err, q), "unlock")
+	}
+
+	q = "RESET application_name"
+	if _, err := session.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(p.dbError(err, q), "unlock")
+	}
+
+	return nil
+}
+
+// LockHolder returns the session holding the advisory lock of the history table, or nil when the lock is free.
+// The holder is identified by its application_name, and the lock is considered taken when the session
+// finished its last statement, which for a lock session is the statement that took the lock.
+func (p *Postgres) LockHolder(ctx context.Context) (*entity.Lock, error) {
+	if !p.flavor.advisoryLocks {
+		return p.lockTableHolder(ctx)
+	}
+
+	var (
+		q = `
+			SELECT a.pid, COALESCE(a.application_name, ''), COALESCE(EXTRACT(EPOCH FROM a.state_change)::bigint, 0)
+			FROM pg_locks l
+			JOIN pg_stat_activity a ON a.pid = l.pid
+			WHERE l.locktype = 'advisory' AND l.granted AND l.classid = $1 AND l.objid = $2 AND l.objsubid = 2
+			LIMIT 1`
+		pid             int64
+		applicationName string
+		acquiredAt      int64
+	)
+
+	classID, objID := p.advisoryLockKeys()
+	found, err := queryRow(ctx, p.conn, q, []any{classID, objID}, &pid, &applicationName, &acquiredAt)
+	if err != nil {
+		return nil, errors.Wrap(p.dbError(err, q), "get lock holder")
+	}
+	if !found {
+		return nil, nil
+	}
+
+	owner := applicationName
+	if owner == "" {
+		owner = "backend pid " + strconv.FormatInt(pid, 10)
+	}
+
+	return &entity.Lock{Owner: owner, AcquiredAt: acquiredAt}, nil
+}
+
+// ForceUnlock terminates the session holding the advisory lock of the history table,
+// which makes PostgreSQL release the lock.
+func (p *Postgres) ForceUnlock(ctx context.Context) error {
+	if !p.flavor.advisoryLocks {
+		return p.forceUnlockTable(ctx)
+	}
+
+	q := `
+		SELECT pg_terminate_backend(l.pid)
+		FROM pg_locks l
+		WHERE l.locktype = 'advisory' AND l.granted AND l.classid = $1 AND l.objid = $2 AND l.objsubid = 2`
+
+	classID, objID := p.advisoryLockKeys()
+	if _, err := p.conn.ExecContext(ctx, q, classID, objID); err != nil {
+		return errors.Wrap(p.dbError(err, q), "force unlock")
+	}
+
+	return nil
+}
+
+// SessionLock returns true, because PostgreSQL releases the advisory lock when the session ends.
+// The row of the lock table of a flavor without advisory locks outlives a crashed holder.
+func (p *Postgres) SessionLock() bool {
+	return p.flavor.advisoryLocks
+}
+
+// advisoryLockKeys returns the pair of keys of the advisory lock guarding the history table.
+func (p *Postgres) advisoryLockKeys() (classID, objID int64) {
+	return lockKey(lockNamePrefix), lockKey(p.TableNameWithSchema())
+}
+
+// postgresLockTimeoutCode is the SQLSTATE lock_not_available, returned when lock_timeout expires.
+const postgresLockTimeoutCode = "55P03"
+
 // dbError returns DBError is err is db error else returns got error.
 func (p *Postgres) dbError(err error, q string) error {
 	var pgErr *pq.Error
@@ -327,5 +746,6 @@ func (p *Postgres) dbError(err error, q string) error {
 		Details:       pgErr.Detail,
 		InternalQuery: q,
 		Cause:         err,
+		LockTimedOut:  pgErr.SQLState() == postgresLockTimeoutCode,
 	})
 }
@@ -0,0 +1,230 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+)
+
+// serializationFailureCode is the SQLSTATE of a transaction aborted by a conflict with a concurrent one.
+// CockroachDB and YugabyteDB return it under contention and expect the client to run the transaction again.
+const serializationFailureCode = "40001"
+
+// serializationRetryBackoff is the pause before the first retry of a write failed with a serialization failure,
+// doubled before every next one.
+const serializationRetryBackoff = 50 * time.Millisecond
+
+// postgresFlavor describes how a database speaking the PostgreSQL wire protocol differs from PostgreSQL.
+type postgresFlavor struct {
+	// pgCatalog is true when pg_class and pg_namespace list every table, otherwise information_schema is used.
+	pgCatalog bool
+	// ddlTransactions is true when DDL statements are rolled back with their transaction.
+	ddlTransactions bool
+	// advisoryLocks is true when pg_try_advisory_lock works, otherwise the migration lock is a row of the lock table.
+	advisoryLocks bool
+	// serializationRetries is how many times a write to the history or the lock table failed with
+	// a serialization failure is run again.
+	serializationRetries int
+}
+
+var (
+	postgresFlavorPostgreSQL = postgresFlavor{
+		pgCatalog:       true,
+		ddlTransactions: true,
+		advisoryLocks:   true,
+	}
+	// CockroachDB commits schema changes of a transaction asynchronously, after the transaction,
+	// and its pg_try_advisory_lock always returns true without locking anything.
+	postgresFlavorCockroachDB = postgresFlavor{
+		serializationRetries: 5,
+	}
+	// YugabyteDB runs DDL outside the transaction unless transactional DDL is enabled on the cluster,
+	// and has advisory locks only behind a preview flag.
+	postgresFlavorYugabyteDB = postgresFlavor{
+		serializationRetries: 5,
+	}
+)
+
+// NewCockroachDB creates a new Postgres repository instance with the overrides of CockroachDB.
+func NewCockroachDB(conn Connection, options *Options) *Postgres {
+	return &Postgres{
+		conn:    conn,
+		options: options,
+		flavor:  postgresFlavorCockroachDB,
+	}
+}
+
+// NewYugabyteDB creates a new Postgres repository instance with the overrides of YugabyteDB.
+func NewYugabyteDB(conn Connection, options *Options) *Postgres {
+	return &Postgres{
+		conn:    conn,
+		options: options,
+		flavor:  postgresFlavorYugabyteDB,
+	}
+}
+
+// hasTable reports whether the table exists in the schema, looked up in information_schema.
+func (p *Postgres) hasTable(ctx context.Context, table string) (bool, error) {
+	var (
+		q = `
+			SELECT EXISTS(
+				SELECT 1 FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2
+			)`
+		exists bool
+	)
+	if _, err := queryRow(ctx, p.conn, q, []any{p.options.SchemaName, table}, &exists); err != nil {
+		return false, p.dbError(err, q)
+	}
+
+	return exists, nil
+}
+
+// execWrite executes a write to the history or the lock table and executes it again, up to the serialization
+// retries of the flavor, while it fails with a serialization failure. Within a transaction the write is not
+// retried: the failure aborted the whole transaction.
+func (p *Postgres) execWrite(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	retries := p.flavor.serializationRetries
+	if _, err := connection.TxFromContext(ctx); err == nil {
+		retries = 0
+	}
+
+	backoff := serializationRetryBackoff
+	for attempt := 0; ; attempt++ {
+		result, err := p.conn.ExecContext(ctx, query, args...)
+		if err == nil || attempt >= retries || !isSerializationFailure(p.dbError(err, query)) {
+			return result, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// isSerializationFailure reports whether err is a DBError of a transaction aborted by a concurrent one.
+func isSerializationFailure(err error) bool {
+	var dbErr *DBError
+
+	return errors.As(err, &dbErr) && dbErr.Code == serializationFailureCode
+}
+
+// tryLockTable tries to take the migration lock by inserting the only row of the lock table without waiting.
+// The primary key of the row lets a single process insert it, the others insert nothing and get false.
+func (p *Postgres) tryLockTable(ctx context.Context, lock *entity.Lock) (bool, error) {
+	if p.lock != nil {
+		return true, nil
+	}
+
+	q := `
+		CREATE TABLE IF NOT EXISTS ` + p.lockTableNameWithSchema() + ` (
+		  id integer PRIMARY KEY CHECK (id = 1),
+		  owner text NOT NULL,
+		  acquired_at bigint NOT NULL
+		)`
+	if _, err := p.execWrite(ctx, q); err != nil {
+		return false, errors.Wrap(p.dbError(err, q), "try lock")
+	}
+
+	q = "INSERT INTO " + p.lockTableNameWithSchema() + " (id, owner, acquired_at) VALUES (1, $1, $2) " +
+		"ON CONFLICT (id) DO NOTHING"
+	result, err := p.execWrite(ctx, q, lock.Owner, lock.AcquiredAt)
+	if err != nil {
+		return false, errors.Wrap(p.dbError(err, q), "try lock")
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(p.dbError(err, q), "try lock")
+	}
+	if inserted == 0 {
+		return false, nil
+	}
+
+	p.lock = lock
+
+	return true, nil
+}
+
+// unlockTable removes the lock row inserted by tryLockTable.
+func (p *Postgres) unlockTable(ctx context.Context) error {
+	if p.lock == nil {
+		return nil
+	}
+	owner := p.lock.Owner
+	p.lock = nil
+
+	q := "DELETE FROM " + p.lockTableNameWithSchema() + " WHERE owner = $1"
+	if _, err := p.execWrite(ctx, q, owner); err != nil {
+		return errors.Wrap(p.dbError(err, q), "unlock")
+	}
+
+	return nil
+}
+
+// lockTableHolder returns the owner of the row of the lock table, or nil when the lock is free.
+func (p *Postgres) lockTableHolder(ctx context.Context) (*entity.Lock, error) {
+	exists, err := p.hasTable(ctx, p.lockTableName())
+	if err != nil || !exists {
+		return nil, errors.Wrap(err, "get lock holder")
+	}
+
+	var (
+		q    = "SELECT owner, acquired_at FROM " + p.lockTableNameWithSchema()
+		lock entity.Lock
+	)
+	found, err := queryRow(ctx, p.conn, q, nil, &lock.Owner, &lock.AcquiredAt)
+	if err != nil {
+		return nil, errors.Wrap(p.dbError(err, q), "get lock holder")
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return &lock, nil
+}
+
+// forceUnlockTable removes the row of the lock table regardless of its owner.
+func (p *Postgres) forceUnlockTable(ctx context.Context) error {
+	exists, err := p.hasTable(ctx, p.lockTableName())
+	if err != nil || !exists {
+		return errors.Wrap(err, "force unlock")
+	}
+
+	q := "DELETE FROM " + p.lockTableNameWithSchema()
+	if _, err := p.execWrite(ctx, q); err != nil {
+		return errors.Wrap(p.dbError(err, q), "force unlock")
+	}
+
+	return nil
+}
+
+// isLockTable reports whether table is the lock table of a flavor without advisory locks.
+func (p *Postgres) isLockTable(table string) bool {
+	return !p.flavor.advisoryLocks && table == p.lockTableName()
+}
+
+// lockTableName returns the name of the table holding the migration lock.
+func (p *Postgres) lockTableName() string {
+	return p.options.TableName + lockTableSuffix
+}
+
+// lockTableNameWithSchema returns the name of the table holding the migration lock with the schema prefix.
+func (p *Postgres) lockTableNameWithSchema() string {
+	return p.options.SchemaName + "." + p.lockTableName()
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCockroachDB_HasMigrationHistoryTable_InformationSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM information_schema.tables")
+		}), "public", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{true}), nil).
+		Once()
+
+	repo := NewCockroachDB(conn, &Options{TableName: "migration", SchemaName: "public"})
+	exists, err := repo.HasMigrationHistoryTable(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestCockroachDB_InsertMigration_SerializationFailure_Retried(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "INSERT INTO public.migration")
+		}), mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything).
+		Return(nil, &pq.Error{Code: "40001", Message: "restart transaction"}).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything).
+		Return(driver.RowsAffected(1), nil).
+		Once()
+
+	repo := NewCockroachDB(conn, &Options{TableName: "migration", SchemaName: "public"})
+	require.NoError(t, repo.InsertMigration(ctx, &entity.Migration{Version: "200101_000000_init"}))
+}
+
+func TestYugabyteDB_RemoveMigration_SerializationFailure_InTransaction_NotRetried(t *testing.T) {
+	ctx := connection.ContextWithTx(context.Background(), sqlex.NewTx(nil))
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, "DELETE FROM public.migration WHERE (version) = ($1)", "200101_000000_init").
+		Return(nil, &pq.Error{Code: "40001", Message: "restart transaction"}).
+		Once()
+
+	repo := NewYugabyteDB(conn, &Options{TableName: "migration", SchemaName: "public"})
+	err := repo.RemoveMigration(ctx, "200101_000000_init")
+	require.Error(t, err)
+	assert.True(t, isSerializationFailure(err))
+}
+
+func TestPostgres_InsertMigration_SerializationFailure_NotRetried(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, &pq.Error{Code: "40001", Message: "could not serialize access"}).
+		Once()
+
+	repo := NewPostgres(conn, &Options{TableName: "migration", SchemaName: "public"})
+	require.Error(t, repo.InsertMigration(ctx, &entity.Migration{Version: "200101_000000_init"}))
+}
+
+func TestCockroachDB_TryLock_LockTable_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "CREATE TABLE IF NOT EXISTS public.migration_lock")
+		})).
+		Return(driver.ResultNoRows, nil).
+		Twice()
+	conn.EXPECT().
+		ExecContext(ctx, "INSERT INTO public.migration_lock (id, owner, acquired_at) VALUES (1, $1, $2) "+
+			"ON CONFLICT (id) DO NOTHING", "host-1:1", int64(100)).
+		Return(driver.RowsAffected(1), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "INSERT INTO public.migration_lock (id, owner, acquired_at) VALUES (1, $1, $2) "+
+			"ON CONFLICT (id) DO NOTHING", "host-2:2", int64(200)).
+		Return(driver.RowsAffected(0), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "DELETE FROM public.migration_lock WHERE owner = $1", "host-1:1").
+		Return(driver.RowsAffected(1), nil).
+		Once()
+
+	repo := NewCockroachDB(conn, &Options{TableName: "migration", SchemaName: "public"})
+	other := NewCockroachDB(conn, &Options{TableName: "migration", SchemaName: "public"})
+
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "host-1:1", AcquiredAt: 100})
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = other.TryLock(ctx, &entity.Lock{Owner: "host-2:2", AcquiredAt: 200})
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	require.NoError(t, repo.Unlock(ctx))
+	require.NoError(t, repo.Unlock(ctx), "unlocking twice must be a no-op")
+	assert.False(t, repo.SessionLock())
+}
+
+func TestYugabyteDB_LockHolder_LockTable_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM information_schema.tables")
+		}), "public", "migration_lock").
+		Return(sqlex.NewRowsWithSlice([]any{true}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, "SELECT owner, acquired_at FROM public.migration_lock").
+		Return(sqlex.NewRowsWithSlice([]any{[]any{"host-1:1", int64(100)}}), nil).
+		Once()
+
+	repo := NewYugabyteDB(conn, &Options{TableName: "migration", SchemaName: "public"})
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Lock{Owner: "host-1:1", AcquiredAt: 100}, holder)
+}
+
+func TestYugabyteDB_ForceUnlock_NoLockTable_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.Anything, "public", "migration_lock").
+		Return(sqlex.NewRowsWithSlice([]any{false}), nil).
+		Once()
+
+	repo := NewYugabyteDB(conn, &Options{TableName: "migration", SchemaName: "public"})
+	require.NoError(t, repo.ForceUnlock(ctx))
+}
+
+func TestCockroachDB_DumpSchema_WithoutLockTable(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "JOIN pg_catalog.pg_attribute")
+		}), "public", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"migration_lock", "id", "bigint", " NOT NULL", ""},
+			[]any{"users", "id", "bigint", " NOT NULL", ""},
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "pg_get_constraintdef")
+		}), "public", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"migration_lock", "migration_lock_pkey", "PRIMARY KEY (id)"},
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "pg_get_indexdef")
+		}), "public", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "pg_get_viewdef")
+		}), "public").
+		Return(sqlex.NewRowsWithSlice([]any{}), nil).
+		Once()
+
+	repo := NewCockroachDB(conn, &Options{TableName: "migration", SchemaName: "public"})
+	schema, err := repo.DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE public.users (\n    id bigint NOT NULL\n);\n", schema)
+}
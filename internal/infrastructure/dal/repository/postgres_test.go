@@ -2,11 +2,15 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
 	thelp "github.com/raoptimus/db-migrator.go/internal/testutil"
 	"github.com/stretchr/testify/assert"
@@ -50,6 +54,52 @@ func TestPostgres_ExecQuery_Failure(t *testing.T) {
 	assert.Equal(t, "SELECT 1", dbErr.InternalQuery)
 }
 
+func TestPostgres_ExecQuery_Timeouts_Session_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn, _, opener := newSessionConnection(t)
+	session := NewMockSession(t)
+
+	opener.EXPECT().Session(ctx).Return(session, nil).Once()
+	session.EXPECT().ExecContext(ctx, "SET lock_timeout = '5000ms'").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(ctx, "SET statement_timeout = '1ms'").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(ctx, "ALTER TABLE t ADD COLUMN c int").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(mock.Anything, "RESET lock_timeout").Return(nil, nil).Once()
+	session.EXPECT().ExecContext(mock.Anything, "RESET statement_timeout").Return(nil, nil).Once()
+	session.EXPECT().Close().Return(nil).Once()
+
+	repo := NewPostgres(conn, &Options{LockTimeout: 5 * time.Second, StatementTimeout: time.Microsecond})
+	err := repo.ExecQuery(ctx, "ALTER TABLE t ADD COLUMN c int")
+	require.NoError(t, err)
+}
+
+func TestPostgres_ExecQuery_Timeouts_Transaction_Successfully(t *testing.T) {
+	ctx := connection.ContextWithTx(context.Background(), sqlex.NewTx(nil))
+	conn := NewMockConnection(t)
+
+	conn.EXPECT().ExecContext(ctx, "SET LOCAL lock_timeout = '2000ms'").Return(nil, nil).Once()
+	conn.EXPECT().ExecContext(ctx, "ALTER TABLE t ADD COLUMN c int").Return(nil, nil).Once()
+
+	repo := NewPostgres(conn, &Options{LockTimeout: 2 * time.Second})
+	err := repo.ExecQuery(ctx, "ALTER TABLE t ADD COLUMN c int")
+	require.NoError(t, err)
+}
+
+func TestPostgres_ExecQuery_LockTimeout_Failure(t *testing.T) {
+	ctx := connection.ContextWithTx(context.Background(), sqlex.NewTx(nil))
+	conn := NewMockConnection(t)
+
+	conn.EXPECT().ExecContext(ctx, "SET LOCAL lock_timeout = '2000ms'").Return(nil, nil).Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE t ADD COLUMN c int").
+		Return(nil, &pq.Error{Code: "55P03", Message: "canceling statement due to lock timeout"}).
+		Once()
+
+	repo := NewPostgres(conn, &Options{LockTimeout: 2 * time.Second})
+	err := repo.ExecQuery(ctx, "ALTER TABLE t ADD COLUMN c int")
+	require.Error(t, err)
+	assert.True(t, IsLockTimeout(err))
+}
+
 func TestPostgres_ExecQueryTransaction_Successfully(t *testing.T) {
 	ctx := context.Background()
 
@@ -96,15 +146,24 @@ func TestPostgres_Migrations_Successfully(t *testing.T) {
 	ctx := context.Background()
 
 	expectedSQL := `
-		SELECT version, apply_time
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
 		FROM public.migration
 		ORDER BY apply_time DESC, version DESC
 		LIMIT $1
 	`
 
 	rows := sqlex.NewRowsWithSlice([]interface{}{
-		[]any{"210328_221600_create_users", int64(1616968560)},
-		[]any{"210329_121500_add_index", int64(1617020100)},
+		[]any{
+			"210328_221600_create_users",
+			int64(1616968560),
+			sql.NullString{String: "CREATE TABLE users (id int);\n", Valid: true},
+			sql.NullString{String: "DROP TABLE users;", Valid: true},
+			sql.NullString{String: "58e7702b20f3e39e3a58072997bbd6307b96a7ed91068ac2eed59f748f1ad7bf", Valid: true},
+			sql.NullBool{Bool: true, Valid: true},
+			sql.NullString{String: "failed", Valid: true},
+			sql.NullInt64{Int64: 2, Valid: true},
+		},
+		[]any{"210329_121500_add_index", int64(1617020100), nil, nil, nil, nil, nil, nil},
 	})
 
 	conn := NewMockConnection(t)
@@ -123,6 +182,17 @@ func TestPostgres_Migrations_Successfully(t *testing.T) {
 	require.Len(t, migrations, 2)
 	assert.Equal(t, "210328_221600_create_users", migrations[0].Version)
 	assert.Equal(t, int64(1616968560), migrations[0].ApplyTime)
+	assert.Equal(t, "CREATE TABLE users (id int);\n", migrations[0].ExecutedSQL)
+	assert.Equal(t, "DROP TABLE users;", migrations[0].DownSQL)
+	assert.Equal(t, "58e7702b20f3e39e3a58072997bbd6307b96a7ed91068ac2eed59f748f1ad7bf", migrations[0].Checksum)
+	assert.True(t, migrations[0].OutOfOrder)
+	assert.Equal(t, entity.StatusFailed, migrations[0].Status)
+	assert.Equal(t, 2, migrations[0].LastStatement)
+	assert.Empty(t, migrations[1].ExecutedSQL)
+	assert.Empty(t, migrations[1].DownSQL)
+	assert.Empty(t, migrations[1].Checksum)
+	assert.False(t, migrations[1].OutOfOrder)
+	assert.Equal(t, entity.StatusApplied, migrations[1].StatusOrApplied())
 }
 
 func TestPostgres_Migrations_Failure(t *testing.T) {
@@ -229,9 +299,24 @@ func TestPostgres_HasMigrationHistoryTable_Failure(t *testing.T) {
 func TestPostgres_InsertMigration_Successfully(t *testing.T) {
 	ctx := context.Background()
 
+	expectedSQL := `
+		INSERT INTO public.migration (version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
 	conn := NewMockConnection(t)
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32")).
+		ExecContext(ctx,
+			mock.MatchedBy(thelp.CompareSQL(expectedSQL)),
+			"210328_221600_test",
+			mock.AnythingOfType("uint32"),
+			"CREATE TABLE test (id int);\n",
+			"DROP TABLE test;",
+			"0f1e2d3c",
+			true,
+			"failed",
+			2,
+		).
 		Return(nil, nil).
 		Once()
 
@@ -239,7 +324,15 @@ func TestPostgres_InsertMigration_Successfully(t *testing.T) {
 		TableName:  "migration",
 		SchemaName: "public",
 	})
-	err := repo.InsertMigration(ctx, "210328_221600_test")
+	err := repo.InsertMigration(ctx, &entity.Migration{
+		Version:       "210328_221600_test",
+		ExecutedSQL:   "CREATE TABLE test (id int);\n",
+		DownSQL:       "DROP TABLE test;",
+		Checksum:      "0f1e2d3c",
+		OutOfOrder:    true,
+		Status:        entity.StatusFailed,
+		LastStatement: 2,
+	})
 
 	require.NoError(t, err)
 }
@@ -249,7 +342,8 @@ func TestPostgres_InsertMigration_Failure(t *testing.T) {
 
 	conn := NewMockConnection(t)
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32")).
+		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("uint32"), "", "", "", false,
+			"applied", 0).
 		Return(nil, &pq.Error{Code: "23505", Message: "duplicate key"}).
 		Once()
 
@@ -257,12 +351,77 @@ func TestPostgres_InsertMigration_Failure(t *testing.T) {
 		TableName:  "migration",
 		SchemaName: "public",
 	})
-	err := repo.InsertMigration(ctx, "210328_221600_test")
+	err := repo.InsertMigration(ctx, &entity.Migration{Version: "210328_221600_test"})
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "insert migration")
 }
 
+func TestPostgres_UpdateMigration_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	expectedSQL := `
+		UPDATE public.migration
+		SET apply_time = $1, executed_sql = $2, down_sql = $3, checksum = $4, out_of_order = $5,
+			status = $6, last_statement = $7
+		WHERE version = $8
+	`
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx,
+			mock.MatchedBy(thelp.CompareSQL(expectedSQL)),
+			mock.AnythingOfType("uint32"),
+			"CREATE TABLE test (id int);\n",
+			"DROP TABLE test;",
+			"0f1e2d3c",
+			false,
+			"applied",
+			0,
+			"210328_221600_test",
+		).
+		Return(nil, nil).
+		Once()
+
+	repo := NewPostgres(conn, &Options{
+		TableName:  "migration",
+		SchemaName: "public",
+	})
+	err := repo.UpdateMigration(ctx, &entity.Migration{
+		Version:     "210328_221600_test",
+		ExecutedSQL: "CREATE TABLE test (id int);\n",
+		DownSQL:     "DROP TABLE test;",
+		Checksum:    "0f1e2d3c",
+		Status:      entity.StatusApplied,
+	})
+
+	require.NoError(t, err)
+}
+
+func TestPostgres_UpdateMigration_Failure(t *testing.T) {
+	ctx := context.Background()
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, mock.AnythingOfType("string"), mock.AnythingOfType("uint32"), "", "", "", false,
+			"failed", 1, "210328_221600_test").
+		Return(nil, &pq.Error{Severity: pq.Efatal, Message: "connection lost"}).
+		Once()
+
+	repo := NewPostgres(conn, &Options{
+		TableName:  "migration",
+		SchemaName: "public",
+	})
+	err := repo.UpdateMigration(ctx, &entity.Migration{
+		Version:       "210328_221600_test",
+		Status:        entity.StatusFailed,
+		LastStatement: 1,
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "update migration")
+}
+
 func TestPostgres_RemoveMigration_Successfully(t *testing.T) {
 	ctx := context.Background()
 
@@ -308,7 +467,13 @@ func TestPostgres_CreateMigrationHistoryTable_Successfully(t *testing.T) {
 	expectedSQL := `
 		CREATE TABLE public.migration (
 		  version varchar(180) PRIMARY KEY,
-		  apply_time integer
+		  apply_time integer,
+		  executed_sql text,
+		  down_sql text,
+		  checksum varchar(64),
+		  out_of_order boolean NOT NULL DEFAULT false,
+		  status varchar(16) NOT NULL DEFAULT 'applied',
+		  last_statement integer NOT NULL DEFAULT 0
 		)
 	`
 
@@ -346,6 +511,140 @@ func TestPostgres_CreateMigrationHistoryTable_Failure(t *testing.T) {
 	assert.Contains(t, err.Error(), "create migration history table")
 }
 
+func TestPostgres_UpgradeMigrationHistoryTable_AddsMissingColumns_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	expectedSQL := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2
+	`
+	rows := sqlex.NewRowsWithSlice([]interface{}{"version", "apply_time"})
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(thelp.CompareSQL(expectedSQL)), "public", "migration").
+		Return(rows, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE public.migration ADD COLUMN executed_sql text").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE public.migration ADD COLUMN down_sql text").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE public.migration ADD COLUMN checksum varchar(64)").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE public.migration ADD COLUMN out_of_order boolean NOT NULL DEFAULT false").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE public.migration ADD COLUMN status varchar(16) NOT NULL DEFAULT 'applied'").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "ALTER TABLE public.migration ADD COLUMN last_statement integer NOT NULL DEFAULT 0").
+		Return(nil, nil).
+		Once()
+
+	repo := NewPostgres(conn, &Options{
+		TableName:  "migration",
+		SchemaName: "public",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+
+	require.NoError(t, err)
+}
+
+func TestPostgres_UpgradeMigrationHistoryTable_UpToDate_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	rows := sqlex.NewRowsWithSlice([]interface{}{"version", "apply_time", "executed_sql", "down_sql", "checksum", "out_of_order", "status", "last_statement"})
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.AnythingOfType("string"), "public", "migration").
+		Return(rows, nil).
+		Once()
+
+	repo := NewPostgres(conn, &Options{
+		TableName:  "migration",
+		SchemaName: "public",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+
+	require.NoError(t, err)
+}
+
+func TestPostgres_NeedsMigrationHistoryTableUpgrade_Successfully(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []interface{}
+		want    bool
+	}{
+		{
+			name:    "outdated",
+			columns: []interface{}{"version", "apply_time"},
+			want:    true,
+		},
+		{
+			name:    "up to date",
+			columns: []interface{}{"version", "apply_time", "executed_sql", "down_sql", "checksum", "out_of_order", "status", "last_statement"},
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			conn := NewMockConnection(t)
+			conn.EXPECT().
+				QueryContext(ctx, mock.AnythingOfType("string"), "public", "migration").
+				Return(sqlex.NewRowsWithSlice(tt.columns), nil).
+				Once()
+
+			repo := NewPostgres(conn, &Options{
+				TableName:  "migration",
+				SchemaName: "public",
+			})
+			needed, err := repo.NeedsMigrationHistoryTableUpgrade(ctx)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, needed)
+		})
+	}
+}
+
+func TestPostgres_UpgradeMigrationHistoryTable_Failure(t *testing.T) {
+	ctx := context.Background()
+
+	rows := sqlex.NewRowsWithSlice([]interface{}{"version", "apply_time"})
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.AnythingOfType("string"), "public", "migration").
+		Return(rows, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, mock.AnythingOfType("string")).
+		Return(nil, &pq.Error{Code: "42501", Message: "permission denied"}).
+		Once()
+
+	repo := NewPostgres(conn, &Options{
+		TableName:  "migration",
+		SchemaName: "public",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upgrade migration history table")
+}
+
 func TestPostgres_DropMigrationHistoryTable_Successfully(t *testing.T) {
 	ctx := context.Background()
 
@@ -616,3 +915,189 @@ func TestPostgres_dbError_NonPqError_Successfully(t *testing.T) {
 	var dbErr *DBError
 	assert.False(t, errors.As(err, &dbErr))
 }
+
+func TestPostgres_TryLock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn, _, opener := newSessionConnection(t)
+	session := NewMockSession(t)
+	classID, objID := lockKey("db-migrator:"), lockKey("public.migration")
+
+	opener.EXPECT().Session(ctx).Return(session, nil).Once()
+	session.EXPECT().
+		ExecContext(ctx, "SELECT set_config('application_name', $1, false)", "db-migrator@host:1").
+		Return(nil, nil).
+		Once()
+	session.EXPECT().
+		QueryContext(ctx, "SELECT pg_try_advisory_lock($1, $2)", classID, objID).
+		Return(sqlex.NewRowsWithSlice([]any{true}), nil).
+		Once()
+	session.EXPECT().
+		ExecContext(ctx, "SELECT pg_advisory_unlock($1, $2)", classID, objID).
+		Return(nil, nil).
+		Once()
+	session.EXPECT().ExecContext(ctx, "RESET application_name").Return(nil, nil).Once()
+	session.EXPECT().Close().Return(nil).Once()
+
+	repo := NewPostgres(conn, &Options{TableName: "migration", SchemaName: "public"})
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1"})
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	require.NoError(t, repo.Unlock(ctx))
+	require.NoError(t, repo.Unlock(ctx), "unlocking twice must be a no-op")
+}
+
+func TestPostgres_TryLock_HeldByAnotherSession(t *testing.T) {
+	ctx := context.Background()
+	conn, _, opener := newSessionConnection(t)
+	session := NewMockSession(t)
+
+	opener.EXPECT().Session(ctx).Return(session, nil).Once()
+	session.EXPECT().
+		ExecContext(ctx, mock.Anything, "db-migrator@host:1").
+		Return(nil, nil).
+		Once()
+	session.EXPECT().
+		QueryContext(ctx, "SELECT pg_try_advisory_lock($1, $2)", mock.Anything, mock.Anything).
+		Return(sqlex.NewRowsWithSlice([]any{false}), nil).
+		Once()
+	session.EXPECT().Close().Return(nil).Once()
+
+	repo := NewPostgres(conn, &Options{TableName: "migration", SchemaName: "public"})
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1"})
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	require.NoError(t, repo.Unlock(ctx))
+}
+
+func TestPostgres_TryLock_SessionNotSupported_Failure(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().Driver().Return(connection.DriverPostgres).Once()
+
+	repo := NewPostgres(conn, &Options{TableName: "migration", SchemaName: "public"})
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1"})
+	require.ErrorIs(t, err, connection.ErrSessionNotSupported)
+	assert.False(t, acquired)
+}
+
+func TestPostgres_LockHolder_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "FROM pg_locks l")
+		}), lockKey("db-migrator:"), lockKey("public.migration")).
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{int64(42), "db-migrator@host:1", int64(1700000000)},
+		}), nil).
+		Once()
+
+	repo := NewPostgres(conn, &Options{TableName: "migration", SchemaName: "public"})
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}, holder)
+}
+
+func TestPostgres_LockHolder_WithoutApplicationName_UsesPid(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.Anything, mock.Anything, mock.Anything).
+		Return(sqlex.NewRowsWithSlice([]any{[]any{int64(42), "", int64(0)}}), nil).
+		Once()
+
+	repo := NewPostgres(conn, &Options{TableName: "migration", SchemaName: "public"})
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Lock{Owner: "backend pid 42"}, holder)
+}
+
+func TestPostgres_LockHolder_Free(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.Anything, mock.Anything, mock.Anything).
+		Return(sqlex.NewRowsWithSlice([]any{}), nil).
+		Once()
+
+	repo := NewPostgres(conn, &Options{TableName: "migration", SchemaName: "public"})
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+}
+
+func TestPostgres_ForceUnlock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "pg_terminate_backend(l.pid)")
+		}), lockKey("db-migrator:"), lockKey("public.migration")).
+		Return(nil, nil).
+		Once()
+
+	repo := NewPostgres(conn, &Options{TableName: "migration", SchemaName: "public"})
+	require.NoError(t, repo.ForceUnlock(ctx))
+}
+
+func TestPostgres_DumpSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "JOIN pg_catalog.pg_attribute")
+		}), "public", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"users", "id", "integer", " NOT NULL", ""},
+			[]any{"users", "name", "text", "", " DEFAULT ''::text"},
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "pg_get_constraintdef")
+		}), "public", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"users", "users_pkey", "PRIMARY KEY (id)"},
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "pg_get_indexdef")
+		}), "public", "migration").
+		Return(sqlex.NewRowsWithSlice([]any{
+			"CREATE INDEX users_name_idx ON public.users USING btree (name)",
+		}), nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "pg_get_viewdef")
+		}), "public").
+		Return(sqlex.NewRowsWithSlice([]any{
+			[]any{"user_names", "VIEW", " SELECT name\n   FROM users;"},
+		}), nil).
+		Once()
+
+	repo := NewPostgres(conn, &Options{TableName: "migration", SchemaName: "public"})
+	schema, err := repo.DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `CREATE TABLE public.users (
+    id integer NOT NULL,
+    name text DEFAULT ''::text,
+    CONSTRAINT users_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX users_name_idx ON public.users USING btree (name);
+
+CREATE VIEW public.user_names AS
+ SELECT name
+   FROM users;
+`, schema)
+}
+
+func TestPostgres_LoadSchema_Unsupported(t *testing.T) {
+	repo := NewPostgres(NewMockConnection(t), &Options{})
+
+	_, err := repo.LoadSchema(context.Background())
+	require.ErrorIs(t, err, ErrLoadSchemaUnsupported)
+}
@@ -12,6 +12,7 @@ import (
 	"context"
 
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 )
 
 // Repository defines the interface for database-specific migration history operations.
@@ -21,10 +22,13 @@ type Repository interface {
 	Migrations(ctx context.Context, limit int) (entity.Migrations, error)
 	// HasMigrationHistoryTable checks if the migration history table exists in the database.
 	HasMigrationHistoryTable(ctx context.Context) (exists bool, err error)
-	// InsertMigration inserts a new migration version into the migration history table.
-	InsertMigration(ctx context.Context, version string) error
+	// InsertMigration inserts a new migration record, including its executed up SQL and down SQL,
+	// into the migration history table.
+	InsertMigration(ctx context.Context, migration *entity.Migration) error
 	// RemoveMigration removes a migration version from the migration history table.
 	RemoveMigration(ctx context.Context, version string) error
+	// UpdateMigration updates the record of migration.Version, setting its apply time to now.
+	UpdateMigration(ctx context.Context, migration *entity.Migration) error
 	// ExecQuery executes a query that doesn't return rows with the provided arguments.
 	ExecQuery(ctx context.Context, query string, args ...any) error
 	// QueryScalar executes a query that returns a single scalar value into the provided pointer.
@@ -37,16 +41,39 @@ type Repository interface {
 	DropMigrationHistoryTable(ctx context.Context) error
 	// CreateMigrationHistoryTable creates the migration history table in the database.
 	CreateMigrationHistoryTable(ctx context.Context) error
+	// UpgradeMigrationHistoryTable adds the columns missing from a history table created by an older version.
+	UpgradeMigrationHistoryTable(ctx context.Context) error
+	// NeedsMigrationHistoryTableUpgrade reports whether the history table was created by an older version
+	// and lacks columns added by UpgradeMigrationHistoryTable.
+	NeedsMigrationHistoryTableUpgrade(ctx context.Context) (bool, error)
 	// MigrationsCount returns the total number of applied migrations in the database.
 	MigrationsCount(ctx context.Context) (int, error)
 	// ExistsMigration checks if a specific migration version exists in the migration history.
 	ExistsMigration(ctx context.Context, version string) (bool, error)
 	// TableNameWithSchema returns the fully qualified table name including schema.
 	TableNameWithSchema() string
-	// InsertMigrationWithApplyTime inserts a new migration version with an explicit apply time.
-	InsertMigrationWithApplyTime(ctx context.Context, version string, applyTime int64) error
+	// InsertMigrationWithApplyTime inserts a new migration record with an explicit apply time.
+	InsertMigrationWithApplyTime(ctx context.Context, migration *entity.Migration, applyTime int64) error
 	// MigrationsByMaxApplyTime returns migrations that share the maximum apply_time value.
 	MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations, error)
+	// TryLock tries to take the migration lock for lock.Owner without waiting.
+	// It returns false when the lock is held by another owner.
+	TryLock(ctx context.Context, lock *entity.Lock) (bool, error)
+	// Unlock releases the migration lock taken by TryLock.
+	Unlock(ctx context.Context) error
+	// LockHolder returns the current holder of the migration lock, or nil when the lock is free.
+	LockHolder(ctx context.Context) (*entity.Lock, error)
+	// ForceUnlock releases the migration lock regardless of its holder.
+	ForceUnlock(ctx context.Context) error
+	// SessionLock reports whether the migration lock is bound to the session of its holder,
+	// so the database releases it when the holder disconnects and it never goes stale.
+	SessionLock() bool
+	// DumpSchema returns the current schema of the database, without the migration history and lock tables,
+	// as it is introspected by the database.
+	DumpSchema(ctx context.Context) (string, error)
+	// LoadSchema returns the current tables of the database, without the migration history and lock tables,
+	// as the schema diff compares them. Only Iceberg supports it.
+	LoadSchema(ctx context.Context) (*ddl.Schema, error)
 }
 
 // New creates repository by connection
@@ -0,0 +1,511 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
+	"modernc.org/sqlite"
+)
+
+// sqliteHistoryColumns lists the history table columns added after its first release.
+var sqliteHistoryColumns = []historyColumn{
+	{Name: "executed_sql", Type: "text"},
+	{Name: "down_sql", Type: "text"},
+	{Name: "checksum", Type: "varchar(64)"},
+	{Name: "out_of_order", Type: "boolean NOT NULL DEFAULT 0"},
+	{Name: "status", Type: "varchar(16) NOT NULL DEFAULT 'applied'"},
+	{Name: "last_statement", Type: "integer NOT NULL DEFAULT 0"},
+}
+
+// SQLite implements Repository interface for SQLite database.
+// It handles migration history tracking and SQL execution for SQLite.
+type SQLite struct {
+	conn    Connection
+	options *Options
+	// lock is the lock row inserted by TryLock until Unlock.
+	lock *entity.Lock
+}
+
+// NewSQLite creates a new SQLite repository instance.
+// It returns a repository configured with the provided connection and options.
+func NewSQLite(conn Connection, options *Options) *SQLite {
+	return &SQLite{
+		conn:    conn,
+		options: options,
+	}
+}
+
+// Migrations returns applied migrations history.
+func (s *SQLite) Migrations(ctx context.Context, limit int) (entity.Migrations, error) {
+	q := fmt.Sprintf(
+		`
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
+		FROM %s
+		ORDER BY apply_time DESC, version DESC
+		LIMIT ?`,
+		s.TableNameWithSchema(),
+	)
+
+	migrations, err := s.queryMigrations(ctx, q, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "get migrations")
+	}
+
+	return migrations, nil
+}
+
+// queryMigrations runs a query returning the columns of the history table and collects the migrations.
+func (s *SQLite) queryMigrations(ctx context.Context, q string, args ...any) (entity.Migrations, error) {
+	rows, err := s.conn.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, s.dbError(err, q)
+	}
+	defer rows.Close()
+
+	var migrations entity.Migrations
+	for rows.Next() {
+		var (
+			version       string
+			applyTime     int64
+			executedSQL   sql.NullString
+			downSQL       sql.NullString
+			checksum      sql.NullString
+			outOfOrder    sql.NullBool
+			status        sql.NullString
+			lastStatement sql.NullInt64
+		)
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
+			return nil, s.dbError(err, q)
+		}
+
+		migrations = append(migrations, entity.Migration{
+			Version:       version,
+			ApplyTime:     applyTime,
+			ExecutedSQL:   executedSQL.String,
+			DownSQL:       downSQL.String,
+			Checksum:      checksum.String,
+			OutOfOrder:    outOfOrder.Bool,
+			Status:        status.String,
+			LastStatement: int(lastStatement.Int64),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, s.dbError(err, q)
+	}
+
+	return migrations, nil
+}
+
+// HasMigrationHistoryTable returns true if migration history table exists.
+func (s *SQLite) HasMigrationHistoryTable(ctx context.Context) (exists bool, err error) {
+	exists, err = s.hasTable(ctx, s.options.TableName)
+	if err != nil {
+		return false, errors.Wrap(err, "get schema table")
+	}
+
+	return exists, nil
+}
+
+// hasTable returns true if the table exists.
+func (s *SQLite) hasTable(ctx context.Context, table string) (bool, error) {
+	var (
+		q      = `SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`
+		exists bool
+	)
+	if _, err := queryRow(ctx, s.conn, q, []any{table}, &exists); err != nil {
+		return false, s.dbError(err, q)
+	}
+
+	return exists, nil
+}
+
+// InsertMigration inserts the new migration record.
+func (s *SQLite) InsertMigration(ctx context.Context, migration *entity.Migration) error {
+	return s.InsertMigrationWithApplyTime(ctx, migration, time.Now().Unix())
+}
+
+// RemoveMigration removes the migration record.
+func (s *SQLite) RemoveMigration(ctx context.Context, version string) error {
+	q := fmt.Sprintf(`DELETE FROM %s WHERE version = ?`, s.TableNameWithSchema())
+	if _, err := s.conn.ExecContext(ctx, q, version); err != nil {
+		return errors.Wrap(s.dbError(err, q), "remove migration")
+	}
+
+	return nil
+}
+
+// ExecQuery executes a query without returning any rows.
+// The args are for any placeholder parameters in the query.
+// The lock timeout of the options sets busy_timeout, the time a statement waits for the lock of the database file.
+// SQLite has no statement timeout of its own, the statement timeout is enforced on the client only.
+// The busy_timeout is a setting of the connection, so it is not reset: every statement of the run waits the same.
+func (s *SQLite) ExecQuery(ctx context.Context, query string, args ...any) error {
+	if s.options.LockTimeout <= 0 {
+		if _, err := s.conn.ExecContext(ctx, query, args...); err != nil {
+			return s.dbError(err, query)
+		}
+		return nil
+	}
+
+	set := []string{"PRAGMA busy_timeout = " + strconv.FormatInt(durationMillis(s.options.LockTimeout), 10)}
+	if err := execWithSettings(ctx, s.conn, set, nil, query, args...); err != nil {
+		return s.dbError(err, query)
+	}
+
+	return nil
+}
+
+// ExecQueryTransaction executes txFn within a transaction.
+func (s *SQLite) ExecQueryTransaction(ctx context.Context, txFn func(ctx context.Context) error) error {
+	return s.conn.Transaction(ctx, txFn)
+}
+
+// SupportsDDLTransactions returns true because SQLite supports transactional DDL.
+func (s *SQLite) SupportsDDLTransactions() bool { return true }
+
+// CreateMigrationHistoryTable creates a new migration history table.
+func (s *SQLite) CreateMigrationHistoryTable(ctx context.Context) error {
+	q := fmt.Sprintf(
+		`
+		CREATE TABLE %s (
+		  version varchar(180) PRIMARY KEY,
+		  apply_time integer,
+		  executed_sql text,
+		  down_sql text,
+		  checksum varchar(64),
+		  out_of_order boolean NOT NULL DEFAULT 0,
+		  status varchar(16) NOT NULL DEFAULT 'applied',
+		  last_statement integer NOT NULL DEFAULT 0
+		)
+		`,
+		s.TableNameWithSchema(),
+	)
+
+	if _, err := s.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(s.dbError(err, q), "create migration history table")
+	}
+
+	return nil
+}
+
+// UpgradeMigrationHistoryTable adds the columns introduced after the history table
+// was first released. It is a no-op when the table already has all of them.
+func (s *SQLite) UpgradeMigrationHistoryTable(ctx context.Context) error {
+	missing, err := s.historyColumnsToAdd(ctx)
+	if err != nil {
+		return errors.Wrap(err, "upgrade migration history table")
+	}
+
+	for _, column := range missing {
+		q := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, s.TableNameWithSchema(), column.Name, column.Type)
+		if _, err := s.conn.ExecContext(ctx, q); err != nil {
+			return errors.Wrap(s.dbError(err, q), "upgrade migration history table")
+		}
+	}
+
+	return nil
+}
+
+// NeedsMigrationHistoryTableUpgrade reports whether the history table lacks columns
+// added by UpgradeMigrationHistoryTable.
+func (s *SQLite) NeedsMigrationHistoryTableUpgrade(ctx context.Context) (bool, error) {
+	missing, err := s.historyColumnsToAdd(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "check migration history table")
+	}
+
+	return len(missing) > 0, nil
+}
+
+// historyColumnsToAdd returns the history columns the table does not have yet.
+func (s *SQLite) historyColumnsToAdd(ctx context.Context) ([]historyColumn, error) {
+	q := `SELECT name FROM pragma_table_info(?)`
+	existing, err := queryColumnNames(ctx, s.conn, q, s.options.TableName)
+	if err != nil {
+		return nil, s.dbError(err, q)
+	}
+
+	return missingHistoryColumns(existing, sqliteHistoryColumns), nil
+}
+
+// DropMigrationHistoryTable drops the migration history table.
+func (s *SQLite) DropMigrationHistoryTable(ctx context.Context) error {
+	q := fmt.Sprintf(`DROP TABLE %s`, s.TableNameWithSchema())
+	if _, err := s.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(s.dbError(err, q), "drop migration history table")
+	}
+
+	return nil
+}
+
+// MigrationsCount returns the number of migrations
+func (s *SQLite) MigrationsCount(ctx context.Context) (int, error) {
+	q := fmt.Sprintf(`SELECT count(*) FROM %s`, s.TableNameWithSchema())
+	var c int
+	if err := s.QueryScalar(ctx, q, &c); err != nil {
+		return 0, err
+	}
+
+	return c, nil
+}
+
+// QueryScalar executes a query and scans a single scalar value into ptr.
+// The ptr parameter must be a pointer to a scalar type (int, string, bool, etc).
+func (s *SQLite) QueryScalar(ctx context.Context, query string, ptr any) error {
+	if err := checkArgIsPtrAndScalar(ptr); err != nil {
+		return err
+	}
+	if _, err := queryRow(ctx, s.conn, query, nil, ptr); err != nil {
+		return s.dbError(err, query)
+	}
+
+	return nil
+}
+
+// ExistsMigration checks if a migration with the given version exists in the history table.
+// It returns true if the migration record is found, false otherwise.
+func (s *SQLite) ExistsMigration(ctx context.Context, version string) (bool, error) {
+	var (
+		q      = fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE version = ?)`, s.TableNameWithSchema())
+		exists bool
+	)
+	if _, err := queryRow(ctx, s.conn, q, []any{version}, &exists); err != nil {
+		return false, s.dbError(err, q)
+	}
+
+	return exists, nil
+}
+
+// TableNameWithSchema returns the name of the history table.
+// An SQLite database has no schemas, so it is the table name only.
+func (s *SQLite) TableNameWithSchema() string {
+	return s.options.TableName
+}
+
+// InsertMigrationWithApplyTime inserts the new migration record with an explicit apply time.
+func (s *SQLite) InsertMigrationWithApplyTime(ctx context.Context, migration *entity.Migration, applyTime int64) error {
+	q := fmt.Sprintf(`
+		INSERT INTO %s (version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.TableNameWithSchema(),
+	)
+	if _, err := s.conn.ExecContext(ctx, q,
+		migration.Version,
+		applyTime,
+		migration.ExecutedSQL,
+		migration.DownSQL,
+		migration.Checksum,
+		migration.OutOfOrder,
+		migration.StatusOrApplied(),
+		migration.LastStatement,
+	); err != nil {
+		return errors.Wrap(s.dbError(err, q), "insert migration")
+	}
+
+	return nil
+}
+
+// UpdateMigration updates the record of migration.Version, setting its apply time to now.
+func (s *SQLite) UpdateMigration(ctx context.Context, migration *entity.Migration) error {
+	q := fmt.Sprintf(`
+		UPDATE %s
+		SET apply_time = ?, executed_sql = ?, down_sql = ?, checksum = ?, out_of_order = ?,
+			status = ?, last_statement = ?
+		WHERE version = ?`,
+		s.TableNameWithSchema(),
+	)
+	if _, err := s.conn.ExecContext(ctx, q,
+		time.Now().Unix(),
+		migration.ExecutedSQL,
+		migration.DownSQL,
+		migration.Checksum,
+		migration.OutOfOrder,
+		migration.StatusOrApplied(),
+		migration.LastStatement,
+		migration.Version,
+	); err != nil {
+		return errors.Wrap(s.dbError(err, q), "update migration")
+	}
+
+	return nil
+}
+
+// MigrationsByMaxApplyTime returns migrations that share the maximum apply_time value.
+func (s *SQLite) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations, error) {
+	q := fmt.Sprintf(`
+		SELECT version, apply_time, executed_sql, down_sql, checksum, out_of_order, status, last_statement
+		FROM %s
+		WHERE apply_time = (SELECT MAX(apply_time) FROM %s)
+		ORDER BY version DESC`,
+		s.TableNameWithSchema(),
+		s.TableNameWithSchema(),
+	)
+
+	migrations, err := s.queryMigrations(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "get migrations by max apply time")
+	}
+
+	return migrations, nil
+}
+
+// DumpSchema returns the CREATE statements SQLite keeps for the tables, indexes, views and triggers
+// of the database, without the history and lock tables and the internal sqlite_ tables.
+// The tables come first, then the indexes, views and triggers, each ordered by name.
+func (s *SQLite) DumpSchema(ctx context.Context) (string, error) {
+	q := `
+		SELECT sql
+		FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\' AND tbl_name NOT IN (?, ?)
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'view' THEN 2 ELSE 3 END, name`
+	statements, err := queryStrings(ctx, s.conn, q, 1, s.options.TableName, s.lockTableName())
+	if err != nil {
+		return "", errors.Wrap(s.dbError(err, q), "dump schema")
+	}
+
+	var dump schemaDump
+	for _, statement := range statements {
+		dump.add(statement[0])
+	}
+
+	return dump.String(), nil
+}
+
+// LoadSchema is not supported: the SQLite DDL cannot be read into the DDL IR of the schema diff.
+func (s *SQLite) LoadSchema(context.Context) (*ddl.Schema, error) {
+	return nil, errors.WithStack(ErrLoadSchemaUnsupported)
+}
+
+// TryLock tries to take the migration lock by inserting the only row of the lock table without waiting.
+// The primary key of the row lets a single process insert it, the others insert nothing and get false.
+func (s *SQLite) TryLock(ctx context.Context, lock *entity.Lock) (bool, error) {
+	if s.lock != nil {
+		return true, nil
+	}
+
+	q := `
+		CREATE TABLE IF NOT EXISTS ` + s.lockTableName() + ` (
+		  id integer PRIMARY KEY CHECK (id = 1),
+		  owner text NOT NULL,
+		  acquired_at integer NOT NULL
+		)`
+	if _, err := s.conn.ExecContext(ctx, q); err != nil {
+		return false, errors.Wrap(s.dbError(err, q), "try lock")
+	}
+
+	q = "INSERT OR IGNORE INTO " + s.lockTableName() + " (id, owner, acquired_at) VALUES (1, ?, ?)"
+	result, err := s.conn.ExecContext(ctx, q, lock.Owner, lock.AcquiredAt)
+	if err != nil {
+		return false, errors.Wrap(s.dbError(err, q), "try lock")
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(s.dbError(err, q), "try lock")
+	}
+	if inserted == 0 {
+		return false, nil
+	}
+
+	s.lock = lock
+
+	return true, nil
+}
+
+// Unlock removes the lock row inserted by TryLock.
+func (s *SQLite) Unlock(ctx context.Context) error {
+	if s.lock == nil {
+		return nil
+	}
+	owner := s.lock.Owner
+	s.lock = nil
+
+	q := "DELETE FROM " + s.lockTableName() + " WHERE owner = ?"
+	if _, err := s.conn.ExecContext(ctx, q, owner); err != nil {
+		return errors.Wrap(s.dbError(err, q), "unlock")
+	}
+
+	return nil
+}
+
+// LockHolder returns the owner of the row of the lock table, or nil when the lock is free.
+func (s *SQLite) LockHolder(ctx context.Context) (*entity.Lock, error) {
+	exists, err := s.hasTable(ctx, s.lockTableName())
+	if err != nil || !exists {
+		return nil, errors.Wrap(err, "get lock holder")
+	}
+
+	var (
+		q    = "SELECT owner, acquired_at FROM " + s.lockTableName()
+		lock entity.Lock
+	)
+	found, err := queryRow(ctx, s.conn, q, nil, &lock.Owner, &lock.AcquiredAt)
+	if err != nil {
+		return nil, errors.Wrap(s.dbError(err, q), "get lock holder")
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return &lock, nil
+}
+
+// ForceUnlock removes the row of the lock table regardless of its owner.
+func (s *SQLite) ForceUnlock(ctx context.Context) error {
+	exists, err := s.hasTable(ctx, s.lockTableName())
+	if err != nil || !exists {
+		return errors.Wrap(err, "force unlock")
+	}
+
+	q := "DELETE FROM " + s.lockTableName()
+	if _, err := s.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(s.dbError(err, q), "force unlock")
+	}
+
+	return nil
+}
+
+// SessionLock returns false, because the lock row outlives a crashed holder.
+func (s *SQLite) SessionLock() bool {
+	return false
+}
+
+// lockTableName returns the name of the table holding the migration lock.
+func (s *SQLite) lockTableName() string {
+	return s.options.TableName + lockTableSuffix
+}
+
+// sqliteLockTimeoutCode is SQLITE_BUSY, returned when busy_timeout expires.
+const sqliteLockTimeoutCode = 5
+
+// dbError returns DBError is err is db error else returns got error.
+// The code of the DBError is the primary result code of SQLite, so every kind of SQLITE_BUSY is a lock timeout.
+func (s *SQLite) dbError(err error, q string) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	return errors.WithStack(&DBError{
+		Code:          strconv.Itoa(sqliteErr.Code() & 0xff),
+		Message:       sqliteErr.Error(),
+		InternalQuery: q,
+		Cause:         err,
+		LockTimedOut:  sqliteErr.Code()&0xff == sqliteLockTimeoutCode,
+	})
+}
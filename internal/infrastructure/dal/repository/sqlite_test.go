@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/connection"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLite returns an SQLite repository of a new database file of the test.
+func newSQLite(t *testing.T, options *Options) (*SQLite, *connection.Connection) {
+	t.Helper()
+	conn, err := connection.New("sqlite://" + filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	if options.TableName == "" {
+		options.TableName = "migration"
+	}
+
+	return NewSQLite(conn, options), conn
+}
+
+func TestSQLite_MigrationHistoryTable_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newSQLite(t, &Options{})
+
+	exists, err := repo.HasMigrationHistoryTable(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, repo.CreateMigrationHistoryTable(ctx))
+	exists, err = repo.HasMigrationHistoryTable(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	needsUpgrade, err := repo.NeedsMigrationHistoryTableUpgrade(ctx)
+	require.NoError(t, err)
+	assert.False(t, needsUpgrade)
+	require.NoError(t, repo.UpgradeMigrationHistoryTable(ctx))
+
+	require.NoError(t, repo.DropMigrationHistoryTable(ctx))
+	exists, err = repo.HasMigrationHistoryTable(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestSQLite_UpgradeMigrationHistoryTable_AddsMissingColumns_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, conn := newSQLite(t, &Options{})
+	_, err := conn.ExecContext(ctx, "CREATE TABLE migration (version varchar(180) PRIMARY KEY, apply_time integer)")
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, "INSERT INTO migration (version, apply_time) VALUES ('200101_000000_init', 1)")
+	require.NoError(t, err)
+
+	needsUpgrade, err := repo.NeedsMigrationHistoryTableUpgrade(ctx)
+	require.NoError(t, err)
+	assert.True(t, needsUpgrade)
+
+	require.NoError(t, repo.UpgradeMigrationHistoryTable(ctx))
+	needsUpgrade, err = repo.NeedsMigrationHistoryTableUpgrade(ctx)
+	require.NoError(t, err)
+	assert.False(t, needsUpgrade)
+
+	migrations, err := repo.Migrations(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Migrations{
+		{Version: "200101_000000_init", ApplyTime: 1, Status: entity.StatusApplied},
+	}, migrations)
+}
+
+func TestSQLite_Migrations_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newSQLite(t, &Options{})
+	require.NoError(t, repo.CreateMigrationHistoryTable(ctx))
+
+	first := &entity.Migration{
+		Version:     "200101_000000_first",
+		ExecutedSQL: "CREATE TABLE a (id integer)",
+		DownSQL:     "DROP TABLE a",
+		Checksum:    "abc",
+	}
+	second := &entity.Migration{Version: "200102_000000_second", OutOfOrder: true}
+	third := &entity.Migration{Version: "200103_000000_third", Status: entity.StatusFailed, LastStatement: 2}
+	require.NoError(t, repo.InsertMigrationWithApplyTime(ctx, first, 100))
+	require.NoError(t, repo.InsertMigrationWithApplyTime(ctx, second, 200))
+	require.NoError(t, repo.InsertMigrationWithApplyTime(ctx, third, 200))
+
+	migrations, err := repo.Migrations(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Migrations{
+		{Version: "200103_000000_third", ApplyTime: 200, Status: entity.StatusFailed, LastStatement: 2},
+		{Version: "200102_000000_second", ApplyTime: 200, OutOfOrder: true, Status: entity.StatusApplied},
+	}, migrations)
+
+	migrations, err = repo.MigrationsByMaxApplyTime(ctx)
+	require.NoError(t, err)
+	assert.Len(t, migrations, 2)
+
+	count, err := repo.MigrationsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	third.Status = entity.StatusApplied
+	third.LastStatement = 0
+	require.NoError(t, repo.UpdateMigration(ctx, third))
+	migrations, err = repo.MigrationsByMaxApplyTime(ctx)
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, "200103_000000_third", migrations[0].Version)
+	assert.Equal(t, entity.StatusApplied, migrations[0].Status)
+
+	require.NoError(t, repo.RemoveMigration(ctx, first.Version))
+	exists, err := repo.ExistsMigration(ctx, first.Version)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = repo.ExistsMigration(ctx, second.Version)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestSQLite_InsertMigration_Duplicate_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newSQLite(t, &Options{})
+	require.NoError(t, repo.CreateMigrationHistoryTable(ctx))
+	require.NoError(t, repo.InsertMigration(ctx, &entity.Migration{Version: "200101_000000_first"}))
+
+	err := repo.InsertMigration(ctx, &entity.Migration{Version: "200101_000000_first"})
+	require.Error(t, err)
+	var dbErr *DBError
+	require.ErrorAs(t, err, &dbErr)
+	assert.Equal(t, "19", dbErr.Code) // SQLITE_CONSTRAINT
+	assert.Contains(t, dbErr.InternalQuery, "INSERT INTO migration")
+}
+
+func TestSQLite_ExecQueryTransaction_Rollback(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newSQLite(t, &Options{LockTimeout: time.Second})
+
+	err := repo.ExecQueryTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.ExecQuery(ctx, "CREATE TABLE test (id integer PRIMARY KEY)"); err != nil {
+			return err
+		}
+		if err := repo.ExecQuery(ctx, "INSERT INTO test (id) VALUES (?)", 1); err != nil {
+			return err
+		}
+		return repo.ExecQuery(ctx, "INSERT INTO test (id) VALUES (?)", 1)
+	})
+	require.Error(t, err)
+	assert.False(t, IsLockTimeout(err))
+
+	var exists bool
+	require.NoError(t, repo.QueryScalar(ctx,
+		"SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE name = 'test')", &exists))
+	assert.False(t, exists)
+
+	require.NoError(t, repo.ExecQueryTransaction(ctx, func(ctx context.Context) error {
+		return repo.ExecQuery(ctx, "CREATE TABLE test (id integer PRIMARY KEY)")
+	}))
+	require.NoError(t, repo.QueryScalar(ctx,
+		"SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE name = 'test')", &exists))
+	assert.True(t, exists)
+}
+
+func TestSQLite_ExecQuery_LockTimeout_Failure(t *testing.T) {
+	ctx := context.Background()
+	repo, conn := newSQLite(t, &Options{LockTimeout: 50 * time.Millisecond})
+	require.NoError(t, repo.ExecQuery(ctx, "CREATE TABLE test (id integer)"))
+
+	session, err := conn.Session(ctx)
+	require.NoError(t, err)
+	defer session.Close()
+	_, err = session.ExecContext(ctx, "BEGIN EXCLUSIVE")
+	require.NoError(t, err)
+	defer func() { _, _ = session.ExecContext(ctx, "ROLLBACK") }()
+
+	err = repo.ExecQuery(ctx, "INSERT INTO test (id) VALUES (1)")
+	require.Error(t, err)
+	assert.True(t, IsLockTimeout(err))
+}
+
+func TestSQLite_Lock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, conn := newSQLite(t, &Options{})
+	other := NewSQLite(conn, &Options{TableName: "migration"})
+
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+	require.NoError(t, repo.ForceUnlock(ctx))
+
+	lock := &entity.Lock{Owner: "host-1:1", AcquiredAt: 100}
+	ok, err := repo.TryLock(ctx, lock)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = other.TryLock(ctx, &entity.Lock{Owner: "host-2:2", AcquiredAt: 200})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	holder, err = other.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, lock, holder)
+
+	require.NoError(t, repo.Unlock(ctx))
+	holder, err = repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+
+	ok, err = other.TryLock(ctx, &entity.Lock{Owner: "host-2:2", AcquiredAt: 200})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, repo.ForceUnlock(ctx))
+	holder, err = repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+	assert.False(t, repo.SessionLock())
+}
+
+func TestSQLite_DumpSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newSQLite(t, &Options{})
+	require.NoError(t, repo.CreateMigrationHistoryTable(ctx))
+	ok, err := repo.TryLock(ctx, &entity.Lock{Owner: "host-1:1", AcquiredAt: 100})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	for _, q := range []string{
+		"CREATE TABLE users (id integer PRIMARY KEY, name text)",
+		"CREATE INDEX users_name ON users (name)",
+		"CREATE VIEW active_users AS SELECT id FROM users",
+		"CREATE TABLE audit (id integer)",
+	} {
+		require.NoError(t, repo.ExecQuery(ctx, q))
+	}
+
+	dump, err := repo.DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE audit (id integer);\n\n"+
+		"CREATE TABLE users (id integer PRIMARY KEY, name text);\n\n"+
+		"CREATE INDEX users_name ON users (name);\n\n"+
+		"CREATE VIEW active_users AS SELECT id FROM users;\n", dump)
+}
+
+func TestSQLite_LoadSchema_Unsupported(t *testing.T) {
+	repo := NewSQLite(nil, &Options{TableName: "migration"})
+
+	_, err := repo.LoadSchema(context.Background())
+	require.ErrorIs(t, err, ErrLoadSchemaUnsupported)
+}
+
+func TestSQLite_dbError_NonSQLiteError_Successfully(t *testing.T) {
+	repo := NewSQLite(nil, &Options{TableName: "migration"})
+	err := errors.New("some error")
+
+	assert.Equal(t, err, repo.dbError(err, "SELECT 1"))
+}
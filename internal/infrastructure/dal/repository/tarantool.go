@@ -17,6 +17,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/iceberg/ddl"
 	"github.com/tarantool/go-tarantool/v2"
 )
 
@@ -24,11 +25,34 @@ const tarantoolIteratorLT = "LT"
 const tarantoolIteratorEQ = "EQ"
 const tarantoolIteratorREQ = "REQ"
 
+// tarantoolHistoryFieldCount is the number of fields in the current history space format.
+const tarantoolHistoryFieldCount = 8
+
+// tarantoolHistoryFormat is the Lua format definition of the history space.
+const tarantoolHistoryFormat = "{{'version',type = 'string',is_nullable = false}," +
+	"{'apply_time', type = 'unsigned', is_nullable = false}," +
+	"{'executed_sql', type = 'string', is_nullable = true}," +
+	"{'down_sql', type = 'string', is_nullable = true}," +
+	"{'checksum', type = 'string', is_nullable = true}," +
+	"{'out_of_order', type = 'boolean', is_nullable = true}," +
+	"{'status', type = 'string', is_nullable = true}," +
+	"{'last_statement', type = 'unsigned', is_nullable = true}}"
+
+// tarantoolHistoryProjection wraps a Lua expression returning history tuples so that every
+// row has the same number of fields, filling the ones missing in tuples written by older versions.
+func tarantoolHistoryProjection(selectExpr string) string {
+	return "(function(ts) local r = {} for _, t in ipairs(ts) do " +
+		"r[#r+1] = {t[1], t[2], t[3] or '', t[4] or '', t[5] or '', t[6] or false, t[7] or '', t[8] or 0} " +
+		"end return r end)(" + selectExpr + ")"
+}
+
 // Tarantool implements Repository interface for Tarantool database.
 // It handles migration history tracking and Lua script execution for Tarantool.
 type Tarantool struct {
 	conn    Connection
 	options *Options
+	// lock is the lock tuple inserted by TryLock until Unlock.
+	lock *entity.Lock
 }
 
 // NewTarantool creates a new Tarantool repository instance.
@@ -40,14 +64,16 @@ func NewTarantool(conn Connection, options *Options) *Tarantool {
 	}
 }
 
-// Migrations returns applied migrations history.
+// Migrations returns applied migrations history, the most recently applied first.
 func (p *Tarantool) Migrations(ctx context.Context, limit int) (entity.Migrations, error) {
 	var migrations entity.Migrations
 
-	q := fmt.Sprintf("return box.space.%s:select({}, {iterator='%s', limit = %d})",
-		p.TableNameWithSchema(),
-		tarantoolIteratorLT,
-		limit,
+	q := fmt.Sprintf("return %s",
+		tarantoolHistoryProjection(fmt.Sprintf("box.space.%s.index.secondary:select({}, {iterator='%s', limit = %d})",
+			p.TableNameWithSchema(),
+			tarantoolIteratorLT,
+			limit,
+		)),
 	)
 	rows, err := p.conn.QueryContext(ctx, q)
 	if err != nil {
@@ -57,18 +83,32 @@ func (p *Tarantool) Migrations(ctx context.Context, limit int) (entity.Migration
 
 	for rows.Next() {
 		var (
-			version   string
-			applyTime int64
+			version       string
+			applyTime     int64
+			executedSQL   string
+			downSQL       string
+			checksum      string
+			outOfOrder    bool
+			status        string
+			lastStatement int
 		)
 
-		if err := rows.Scan(&version, &applyTime); err != nil {
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
 			return nil, errors.Wrap(p.dbError(err, q), "get migrations")
 		}
 
 		migrations = append(migrations,
 			entity.Migration{
-				Version:   version,
-				ApplyTime: applyTime,
+				Version:       version,
+				ApplyTime:     applyTime,
+				ExecutedSQL:   executedSQL,
+				DownSQL:       downSQL,
+				Checksum:      checksum,
+				OutOfOrder:    outOfOrder,
+				Status:        status,
+				LastStatement: lastStatement,
 			},
 		)
 	}
@@ -100,13 +140,8 @@ func (p *Tarantool) HasMigrationHistoryTable(ctx context.Context) (exists bool,
 }
 
 // InsertMigration inserts the new migration record.
-func (p *Tarantool) InsertMigration(ctx context.Context, version string) error {
-	q := fmt.Sprintf("box.space.%s:insert({...})", p.TableNameWithSchema())
-
-	if _, err := p.conn.ExecContext(ctx, q, version, time.Now().Unix()); err != nil {
-		return errors.Wrap(p.dbError(err, q), "insert migration")
-	}
-	return nil
+func (p *Tarantool) InsertMigration(ctx context.Context, migration *entity.Migration) error {
+	return p.InsertMigrationWithApplyTime(ctx, migration, time.Now().Unix())
 }
 
 // RemoveMigration removes the migration record.
@@ -147,8 +182,7 @@ func (p *Tarantool) CreateMigrationHistoryTable(ctx context.Context) error {
 	}
 
 	// set space format
-	q = fmt.Sprintf("box.space.%s:format", p.TableNameWithSchema())
-	q += "({{'version',type = 'string',is_nullable = false},{'apply_time', type = 'unsigned', is_nullable = false}})"
+	q = fmt.Sprintf("box.space.%s:format(%s)", p.TableNameWithSchema(), tarantoolHistoryFormat)
 	if _, err := p.conn.ExecContext(ctx, q); err != nil {
 		return errors.Wrap(p.dbError(err, q), "create migration history table")
 	}
@@ -170,6 +204,48 @@ func (p *Tarantool) CreateMigrationHistoryTable(ctx context.Context) error {
 	return nil
 }
 
+// UpgradeMigrationHistoryTable extends the space format with the fields introduced
+// after the history space was first released. Tuples written by older versions keep
+// working because the new fields are nullable.
+func (p *Tarantool) UpgradeMigrationHistoryTable(ctx context.Context) error {
+	needed, err := p.formatNeedsUpgrade(ctx)
+	if err != nil {
+		return errors.Wrap(err, "upgrade migration history table")
+	}
+	if !needed {
+		return nil
+	}
+
+	q := fmt.Sprintf("box.space.%s:format(%s)", p.TableNameWithSchema(), tarantoolHistoryFormat)
+	if _, err := p.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(p.dbError(err, q), "upgrade migration history table")
+	}
+
+	return nil
+}
+
+// NeedsMigrationHistoryTableUpgrade reports whether the space format lacks fields
+// added by UpgradeMigrationHistoryTable.
+func (p *Tarantool) NeedsMigrationHistoryTableUpgrade(ctx context.Context) (bool, error) {
+	needed, err := p.formatNeedsUpgrade(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "check migration history table")
+	}
+
+	return needed, nil
+}
+
+// formatNeedsUpgrade reports whether the history space has fewer fields than the current format.
+func (p *Tarantool) formatNeedsUpgrade(ctx context.Context) (bool, error) {
+	q := fmt.Sprintf("return #box.space.%s:format()", p.TableNameWithSchema())
+	var fieldCount int
+	if err := p.QueryScalar(ctx, q, &fieldCount); err != nil {
+		return false, err
+	}
+
+	return fieldCount < tarantoolHistoryFieldCount, nil
+}
+
 // DropMigrationHistoryTable drops the migration history table.
 func (p *Tarantool) DropMigrationHistoryTable(ctx context.Context) error {
 	q := fmt.Sprintf("box.space.%s:drop()", p.TableNameWithSchema())
@@ -256,20 +332,39 @@ func (p *Tarantool) TableNameWithSchema() string {
 }
 
 // InsertMigrationWithApplyTime inserts the new migration record with an explicit apply time.
-func (p *Tarantool) InsertMigrationWithApplyTime(ctx context.Context, version string, applyTime int64) error {
-	q := fmt.Sprintf("box.space.%s:insert({...})", p.TableNameWithSchema())
+func (p *Tarantool) InsertMigrationWithApplyTime(ctx context.Context, migration *entity.Migration, applyTime int64) error {
+	return p.writeMigration(ctx, "insert", migration, applyTime)
+}
+
+// UpdateMigration replaces the record of migration.Version, setting its apply time to now.
+func (p *Tarantool) UpdateMigration(ctx context.Context, migration *entity.Migration) error {
+	return p.writeMigration(ctx, "replace", migration, time.Now().Unix())
+}
 
-	if _, err := p.conn.ExecContext(ctx, q, version, applyTime); err != nil {
-		return errors.Wrap(p.dbError(err, q), "insert migration")
+// writeMigration writes the tuple of migration with the space method op, insert or replace.
+func (p *Tarantool) writeMigration(ctx context.Context, op string, migration *entity.Migration, applyTime int64) error {
+	q := fmt.Sprintf("box.space.%s:%s({...})", p.TableNameWithSchema(), op)
+
+	if _, err := p.conn.ExecContext(ctx, q,
+		migration.Version,
+		applyTime,
+		migration.ExecutedSQL,
+		migration.DownSQL,
+		migration.Checksum,
+		migration.OutOfOrder,
+		migration.StatusOrApplied(),
+		migration.LastStatement,
+	); err != nil {
+		return errors.Wrap(p.dbError(err, q), op+" migration")
 	}
 	return nil
 }
 
 // MigrationsByMaxApplyTime returns migrations that share the maximum apply_time value.
 func (p *Tarantool) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrations, error) {
-	// max() returns a single tuple, not a list — wrap in {m} so QueryContext unwraps it correctly.
+	// max() returns a single tuple, not a list — wrap in {{...}} so QueryContext unwraps it correctly.
 	maxQ := fmt.Sprintf(
-		"local m=box.space.%s.index.secondary:max(); if m~=nil then return {m} else return {} end",
+		"local m=box.space.%s.index.secondary:max(); if m~=nil then return {{m[1], m[2]}} else return {} end",
 		p.TableNameWithSchema(),
 	)
 
@@ -289,10 +384,12 @@ func (p *Tarantool) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrat
 	}
 
 	// Select all records with this apply_time in descending version order (newest first) for correct rollback.
-	q := fmt.Sprintf("return box.space.%s.index.secondary:select({%d}, {iterator='%s'})",
-		p.TableNameWithSchema(),
-		maxApplyTime,
-		tarantoolIteratorREQ,
+	q := fmt.Sprintf("return %s",
+		tarantoolHistoryProjection(fmt.Sprintf("box.space.%s.index.secondary:select({%d}, {iterator='%s'})",
+			p.TableNameWithSchema(),
+			maxApplyTime,
+			tarantoolIteratorREQ,
+		)),
 	)
 
 	rows, err := p.conn.QueryContext(ctx, q)
@@ -304,21 +401,188 @@ func (p *Tarantool) MigrationsByMaxApplyTime(ctx context.Context) (entity.Migrat
 	var migrations entity.Migrations
 	for rows.Next() {
 		var (
-			version   string
-			applyTime int64
+			version       string
+			applyTime     int64
+			executedSQL   string
+			downSQL       string
+			checksum      string
+			outOfOrder    bool
+			status        string
+			lastStatement int
 		)
-		if err := rows.Scan(&version, &applyTime); err != nil {
+		if err := rows.Scan(
+			&version, &applyTime, &executedSQL, &downSQL, &checksum, &outOfOrder, &status, &lastStatement,
+		); err != nil {
 			return nil, errors.Wrap(p.dbError(err, q), "get migrations by max apply time")
 		}
 		migrations = append(migrations, entity.Migration{
-			Version:   version,
-			ApplyTime: applyTime,
+			Version:       version,
+			ApplyTime:     applyTime,
+			ExecutedSQL:   executedSQL,
+			DownSQL:       downSQL,
+			Checksum:      checksum,
+			OutOfOrder:    outOfOrder,
+			Status:        status,
+			LastStatement: lastStatement,
 		})
 	}
 
 	return migrations, nil
 }
 
+// tarantoolDumpSchema is the Lua script returning the statements creating the user spaces but the ones
+// named as its argument, with their formats and indexes, one per row, ordered by the space name.
+const tarantoolDumpSchema = `
+local json = require('json')
+local skip = {['%[1]s'] = true, ['%[1]s%[2]s'] = true}
+local names = {}
+for _, t in box.space._space:pairs() do
+	if t[1] > box.schema.SYSTEM_ID_MAX and not skip[t[3]] then names[#names + 1] = t[3] end
+end
+table.sort(names)
+local r = {}
+for _, name in ipairs(names) do
+	local s = box.space[name]
+	r[#r + 1] = {string.format("box.schema.space.create('%%s', {engine = '%%s', format = %%s})",
+		name, s.engine, json.encode(s:format()))}
+	for _, t in box.space._index:pairs({s.id}) do
+		local index = s.index[t[2]]
+		local parts = {}
+		for _, part in ipairs(index.parts) do
+			parts[#parts + 1] = {field = part.fieldno, type = part.type, is_nullable = part.is_nullable}
+		end
+		r[#r + 1] = {string.format("box.space.%%s:create_index('%%s', {type = '%%s', unique = %%s, parts = %%s})",
+			name, index.name, index.type, tostring(index.unique), json.encode(parts))}
+	end
+end
+return r`
+
+// DumpSchema returns the Lua statements creating the user spaces with their formats and indexes,
+// read from box.space, without the history and lock spaces, ordered by the space name.
+func (p *Tarantool) DumpSchema(ctx context.Context) (string, error) {
+	q := fmt.Sprintf(tarantoolDumpSchema, p.TableNameWithSchema(), lockTableSuffix)
+	statements, err := queryStrings(ctx, p.conn, q, 1)
+	if err != nil {
+		return "", errors.Wrap(p.dbError(err, q), "dump spaces")
+	}
+
+	var dump schemaDump
+	for _, statement := range statements {
+		dump.add(statement[0])
+	}
+
+	return dump.String(), nil
+}
+
+// LoadSchema is not supported: the Tarantool DDL cannot be read into the DDL IR of the schema diff.
+func (p *Tarantool) LoadSchema(context.Context) (*ddl.Schema, error) {
+	return nil, errors.WithStack(ErrLoadSchemaUnsupported)
+}
+
+// TryLock tries to take the migration lock by inserting the single tuple of the lock space without waiting.
+// The insert fails with a duplicate key error when the lock is held, which makes taking the lock atomic.
+func (p *Tarantool) TryLock(ctx context.Context, lock *entity.Lock) (bool, error) {
+	if p.lock != nil {
+		return true, nil
+	}
+
+	if err := p.createLockSpace(ctx); err != nil {
+		return false, errors.Wrap(err, "try lock")
+	}
+
+	var (
+		q        = fmt.Sprintf("return (pcall(box.space.%[1]s.insert, box.space.%[1]s, {1, ...}))", p.lockSpaceName())
+		acquired bool
+	)
+	if _, err := queryRow(ctx, p.conn, q, []any{lock.Owner, lock.AcquiredAt}, &acquired); err != nil {
+		return false, errors.Wrap(p.dbError(err, q), "try lock")
+	}
+	if !acquired {
+		return false, nil
+	}
+
+	p.lock = lock
+
+	return true, nil
+}
+
+// Unlock deletes the lock tuple inserted by TryLock unless it was taken over by another owner.
+func (p *Tarantool) Unlock(ctx context.Context) error {
+	if p.lock == nil {
+		return nil
+	}
+	owner := p.lock.Owner
+	p.lock = nil
+
+	q := fmt.Sprintf(
+		"local t = box.space.%[1]s:get({1}) if t ~= nil and t[2] == ... then box.space.%[1]s:delete({1}) end",
+		p.lockSpaceName(),
+	)
+	if _, err := p.conn.ExecContext(ctx, q, owner); err != nil {
+		return errors.Wrap(p.dbError(err, q), "unlock")
+	}
+
+	return nil
+}
+
+// LockHolder returns the owner stored in the lock tuple, or nil when the lock is free.
+func (p *Tarantool) LockHolder(ctx context.Context) (*entity.Lock, error) {
+	var (
+		q = fmt.Sprintf(
+			"return box.space.%[1]s ~= nil and box.space.%[1]s:select({1}) or {}",
+			p.lockSpaceName(),
+		)
+		id   int64
+		lock entity.Lock
+	)
+
+	found, err := queryRow(ctx, p.conn, q, nil, &id, &lock.Owner, &lock.AcquiredAt)
+	if err != nil {
+		return nil, errors.Wrap(p.dbError(err, q), "get lock holder")
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return &lock, nil
+}
+
+// ForceUnlock deletes the lock tuple regardless of its owner.
+func (p *Tarantool) ForceUnlock(ctx context.Context) error {
+	q := fmt.Sprintf("if box.space.%[1]s ~= nil then box.space.%[1]s:delete({1}) end", p.lockSpaceName())
+	if _, err := p.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(p.dbError(err, q), "force unlock")
+	}
+
+	return nil
+}
+
+// SessionLock returns false, because the lock tuple outlives a crashed holder.
+func (p *Tarantool) SessionLock() bool {
+	return false
+}
+
+// createLockSpace creates the lock space and its primary index if they do not exist.
+func (p *Tarantool) createLockSpace(ctx context.Context) error {
+	q := fmt.Sprintf("box.schema.space.create('%s', {if_not_exists = true})", p.lockSpaceName())
+	if _, err := p.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(p.dbError(err, q), "create lock space")
+	}
+
+	q = fmt.Sprintf("box.space.%s:create_index", p.lockSpaceName())
+	q += "('primary', {parts = {{1, 'unsigned'}}, if_not_exists = true})"
+	if _, err := p.conn.ExecContext(ctx, q); err != nil {
+		return errors.Wrap(p.dbError(err, q), "create lock space")
+	}
+
+	return nil
+}
+
+// lockSpaceName returns the name of the space holding the migration lock.
+func (p *Tarantool) lockSpaceName() string {
+	return p.TableNameWithSchema() + lockTableSuffix
+}
+
 // dbError returns DBError is err is db error else returns got error.
 func (p *Tarantool) dbError(err error, q string) error {
 	var tErr tarantool.Error
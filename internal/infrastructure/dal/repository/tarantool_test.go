@@ -10,9 +10,11 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/dal/entity"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/sqlex"
 	thelp "github.com/raoptimus/db-migrator.go/internal/testutil"
 	"github.com/stretchr/testify/assert"
@@ -94,7 +96,15 @@ func TestTarantool_CreateMigrationHistoryTable_Successfully(t *testing.T) {
 	ctx := context.Background()
 
 	expectedCreateSpace := `box.schema.space.create('migration', {if_not_exists = true})`
-	expectedFormat := `box.space.migration:format({{'version',type = 'string',is_nullable = false},{'apply_time', type = 'unsigned', is_nullable = false}})`
+	expectedFormat := `box.space.migration:format({` +
+		`{'version',type = 'string',is_nullable = false},` +
+		`{'apply_time', type = 'unsigned', is_nullable = false},` +
+		`{'executed_sql', type = 'string', is_nullable = true},` +
+		`{'down_sql', type = 'string', is_nullable = true},` +
+		`{'checksum', type = 'string', is_nullable = true},` +
+		`{'out_of_order', type = 'boolean', is_nullable = true},` +
+		`{'status', type = 'string', is_nullable = true},` +
+		`{'last_statement', type = 'unsigned', is_nullable = true}})`
 	expectedPrimaryIndex := `box.space.migration:create_index('primary', {parts = {'version'}, if_not_exists = true})`
 	expectedSecondaryIndex := `box.space.migration:create_index('secondary', {parts = {{'apply_time'}, {'version'}}, if_not_exists = true})`
 
@@ -143,6 +153,69 @@ func TestTarantool_CreateMigrationHistoryTable_CreateSpaceFails(t *testing.T) {
 	assert.Equal(t, "10", dbErr.Code)
 }
 
+func TestTarantool_UpgradeMigrationHistoryTable_ExtendsFormat_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	expectedFormat := `box.space.migration:format({` +
+		`{'version',type = 'string',is_nullable = false},` +
+		`{'apply_time', type = 'unsigned', is_nullable = false},` +
+		`{'executed_sql', type = 'string', is_nullable = true},` +
+		`{'down_sql', type = 'string', is_nullable = true},` +
+		`{'checksum', type = 'string', is_nullable = true},` +
+		`{'out_of_order', type = 'boolean', is_nullable = true},` +
+		`{'status', type = 'string', is_nullable = true},` +
+		`{'last_statement', type = 'unsigned', is_nullable = true}})`
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, "return #box.space.migration:format()").
+		Return(sqlex.NewRowsWithSlice([]interface{}{2}), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, mock.MatchedBy(thelp.CompareSQL(expectedFormat))).
+		Return(nil, nil).
+		Once()
+
+	repo := NewTarantool(conn, &Options{
+		TableName: "migration",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+	require.NoError(t, err)
+}
+
+func TestTarantool_UpgradeMigrationHistoryTable_UpToDate_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, "return #box.space.migration:format()").
+		Return(sqlex.NewRowsWithSlice([]interface{}{8}), nil).
+		Once()
+
+	repo := NewTarantool(conn, &Options{
+		TableName: "migration",
+	})
+	err := repo.UpgradeMigrationHistoryTable(ctx)
+	require.NoError(t, err)
+}
+
+func TestTarantool_NeedsMigrationHistoryTableUpgrade_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, "return #box.space.migration:format()").
+		Return(sqlex.NewRowsWithSlice([]interface{}{2}), nil).
+		Once()
+
+	repo := NewTarantool(conn, &Options{
+		TableName: "migration",
+	})
+	needed, err := repo.NeedsMigrationHistoryTableUpgrade(ctx)
+	require.NoError(t, err)
+	assert.True(t, needed)
+}
+
 func TestTarantool_DropMigrationHistoryTable_Successfully(t *testing.T) {
 	ctx := context.Background()
 
@@ -186,14 +259,30 @@ func TestTarantool_InsertMigration_Successfully(t *testing.T) {
 
 	conn := NewMockConnection(t)
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("int64")).
+		ExecContext(ctx,
+			"box.space.migration:insert({...})",
+			"210328_221600_test",
+			mock.AnythingOfType("int64"),
+			"box.schema.space.create('test')\n",
+			"box.space.test:drop()",
+			"0f1e2d3c",
+			true,
+			"applied",
+			0,
+		).
 		Return(nil, nil).
 		Once()
 
 	repo := NewTarantool(conn, &Options{
 		TableName: "migration",
 	})
-	err := repo.InsertMigration(ctx, "210328_221600_test")
+	err := repo.InsertMigration(ctx, &entity.Migration{
+		Version:     "210328_221600_test",
+		ExecutedSQL: "box.schema.space.create('test')\n",
+		DownSQL:     "box.space.test:drop()",
+		Checksum:    "0f1e2d3c",
+		OutOfOrder:  true,
+	})
 	require.NoError(t, err)
 }
 
@@ -203,20 +292,52 @@ func TestTarantool_InsertMigration_Failure(t *testing.T) {
 	conn := NewMockConnection(t)
 	tErr := tarantool.Error{Code: 3, Msg: "Duplicate key exists"}
 	conn.EXPECT().
-		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("int64")).
+		ExecContext(ctx, mock.AnythingOfType("string"), "210328_221600_test", mock.AnythingOfType("int64"), "", "", "", false,
+			"applied", 0).
 		Return(nil, tErr).
 		Once()
 
 	repo := NewTarantool(conn, &Options{
 		TableName: "migration",
 	})
-	err := repo.InsertMigration(ctx, "210328_221600_test")
+	err := repo.InsertMigration(ctx, &entity.Migration{Version: "210328_221600_test"})
 	require.Error(t, err)
 	var dbErr *DBError
 	require.ErrorAs(t, err, &dbErr)
 	assert.Equal(t, "3", dbErr.Code)
 }
 
+func TestTarantool_UpdateMigration_Successfully(t *testing.T) {
+	ctx := context.Background()
+
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx,
+			"box.space.migration:replace({...})",
+			"210328_221600_test",
+			mock.AnythingOfType("int64"),
+			"box.schema.space.create('test')\n",
+			"",
+			"",
+			false,
+			"failed",
+			1,
+		).
+		Return(nil, nil).
+		Once()
+
+	repo := NewTarantool(conn, &Options{
+		TableName: "migration",
+	})
+	err := repo.UpdateMigration(ctx, &entity.Migration{
+		Version:       "210328_221600_test",
+		ExecutedSQL:   "box.schema.space.create('test')\n",
+		Status:        entity.StatusFailed,
+		LastStatement: 1,
+	})
+	require.NoError(t, err)
+}
+
 func TestTarantool_RemoveMigration_Successfully(t *testing.T) {
 	ctx := context.Background()
 
@@ -258,7 +379,10 @@ func TestTarantool_RemoveMigration_Failure(t *testing.T) {
 func TestTarantool_Migrations_Failure(t *testing.T) {
 	ctx := context.Background()
 
-	expectedLua := `return box.space.migration:select({}, {iterator='LT', limit = 10})`
+	expectedLua := `return (function(ts) local r = {} for _, t in ipairs(ts) do
+		r[#r+1] = {t[1], t[2], t[3] or '', t[4] or '', t[5] or '', t[6] or false,
+		t[7] or '', t[8] or 0} end return r end)(
+		box.space.migration.index.secondary:select({}, {iterator='LT', limit = 10}))`
 
 	conn := NewMockConnection(t)
 	tErr := tarantool.Error{Code: 36, Msg: "Space not found"}
@@ -394,11 +518,15 @@ func TestTarantool_dbError_NonTarantoolError(t *testing.T) {
 func TestTarantool_Migrations_Successfully(t *testing.T) {
 	ctx := context.Background()
 
-	expectedLua := `return box.space.migration:select({}, {iterator='LT', limit = 10})`
+	expectedLua := `return (function(ts) local r = {} for _, t in ipairs(ts) do
+		r[#r+1] = {t[1], t[2], t[3] or '', t[4] or '', t[5] or '', t[6] or false,
+		t[7] or '', t[8] or 0} end return r end)(
+		box.space.migration.index.secondary:select({}, {iterator='LT', limit = 10}))`
 
 	rows := sqlex.NewRowsWithSlice([]interface{}{
-		[]any{"210328_221600_create_users", int64(1616968560)},
-		[]any{"210329_121500_add_index", int64(1617020100)},
+		[]any{"210328_221600_create_users", int64(1616968560), "box.schema.space.create('users')\n", "", "0f1e2d3c", true,
+			"failed", 2},
+		[]any{"210329_121500_add_index", int64(1617020100), "", "", "", false, "", 0},
 	})
 
 	conn := NewMockConnection(t)
@@ -416,6 +544,13 @@ func TestTarantool_Migrations_Successfully(t *testing.T) {
 	require.Len(t, migrations, 2)
 	assert.Equal(t, "210328_221600_create_users", migrations[0].Version)
 	assert.Equal(t, int64(1616968560), migrations[0].ApplyTime)
+	assert.Equal(t, "box.schema.space.create('users')\n", migrations[0].ExecutedSQL)
+	assert.Equal(t, "0f1e2d3c", migrations[0].Checksum)
+	assert.True(t, migrations[0].OutOfOrder)
+	assert.Equal(t, entity.StatusFailed, migrations[0].Status)
+	assert.Equal(t, 2, migrations[0].LastStatement)
+	assert.False(t, migrations[1].OutOfOrder)
+	assert.Equal(t, entity.StatusApplied, migrations[1].StatusOrApplied())
 }
 
 func TestTarantool_Migrations_EmptyResult_Successfully(t *testing.T) {
@@ -577,3 +712,113 @@ func TestTarantool_ExistsMigration_NotExists_Successfully(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, exists)
 }
+
+func TestTarantool_TryLock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	lock := &entity.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}
+
+	conn.EXPECT().
+		ExecContext(ctx, "box.schema.space.create('migration_lock', {if_not_exists = true})").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx, "box.space.migration_lock:create_index('primary', {parts = {{1, 'unsigned'}}, if_not_exists = true})").
+		Return(nil, nil).
+		Once()
+	conn.EXPECT().
+		QueryContext(ctx,
+			"return (pcall(box.space.migration_lock.insert, box.space.migration_lock, {1, ...}))",
+			lock.Owner,
+			lock.AcquiredAt,
+		).
+		Return(sqlex.NewRowsWithSlice([]any{true}), nil).
+		Once()
+	conn.EXPECT().
+		ExecContext(ctx,
+			"local t = box.space.migration_lock:get({1}) if t ~= nil and t[2] == ... then box.space.migration_lock:delete({1}) end",
+			lock.Owner,
+		).
+		Return(nil, nil).
+		Once()
+
+	repo := NewTarantool(conn, &Options{TableName: "migration"})
+	acquired, err := repo.TryLock(ctx, lock)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	require.NoError(t, repo.Unlock(ctx))
+}
+
+func TestTarantool_TryLock_Held(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().ExecContext(ctx, mock.Anything).Return(nil, nil).Twice()
+	conn.EXPECT().
+		QueryContext(ctx, mock.Anything, mock.Anything, mock.Anything).
+		Return(sqlex.NewRowsWithSlice([]any{false}), nil).
+		Once()
+
+	repo := NewTarantool(conn, &Options{TableName: "migration"})
+	acquired, err := repo.TryLock(ctx, &entity.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000})
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	require.NoError(t, repo.Unlock(ctx))
+}
+
+func TestTarantool_LockHolder_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, "return box.space.migration_lock ~= nil and box.space.migration_lock:select({1}) or {}").
+		Return(sqlex.NewRowsWithSlice([]any{[]any{uint64(1), "db-migrator@host:1", uint64(1700000000)}}), nil).
+		Once()
+
+	repo := NewTarantool(conn, &Options{TableName: "migration"})
+	holder, err := repo.LockHolder(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Lock{Owner: "db-migrator@host:1", AcquiredAt: 1700000000}, holder)
+}
+
+func TestTarantool_ForceUnlock_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		ExecContext(ctx, "if box.space.migration_lock ~= nil then box.space.migration_lock:delete({1}) end").
+		Return(nil, nil).
+		Once()
+
+	repo := NewTarantool(conn, &Options{TableName: "migration"})
+	require.NoError(t, repo.ForceUnlock(ctx))
+}
+
+func TestTarantool_DumpSchema_Successfully(t *testing.T) {
+	ctx := context.Background()
+	conn := NewMockConnection(t)
+	conn.EXPECT().
+		QueryContext(ctx, mock.MatchedBy(func(q string) bool {
+			return strings.Contains(q, "local skip = {['migration'] = true, ['migration_lock'] = true}") &&
+				strings.Contains(q, "string.format(\"box.schema.space.create('%s'")
+		})).
+		Return(sqlex.NewRowsWithSlice([]any{
+			"box.schema.space.create('users', {engine = 'memtx', format = [{\"name\":\"id\",\"type\":\"unsigned\"}]})",
+			"box.space.users:create_index('primary', {type = 'TREE', unique = true, parts = [{\"field\":1,\"type\":\"unsigned\"}]})",
+		}), nil).
+		Once()
+
+	repo := NewTarantool(conn, &Options{TableName: "migration"})
+	schema, err := repo.DumpSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"box.schema.space.create('users', {engine = 'memtx', format = [{\"name\":\"id\",\"type\":\"unsigned\"}]});\n\n"+
+			"box.space.users:create_index('primary', {type = 'TREE', unique = true, "+
+			"parts = [{\"field\":1,\"type\":\"unsigned\"}]});\n",
+		schema)
+}
+
+func TestTarantool_LoadSchema_Unsupported(t *testing.T) {
+	repo := NewTarantool(NewMockConnection(t), &Options{})
+
+	_, err := repo.LoadSchema(context.Background())
+	require.ErrorIs(t, err, ErrLoadSchemaUnsupported)
+}
@@ -171,6 +171,22 @@ func (c *Client) UpdateNamespaceProperties(
 	return nil
 }
 
+// ListNamespaces returns the top-level namespaces of the catalog.
+func (c *Client) ListNamespaces(ctx context.Context) ([][]string, error) {
+	// see TableExists for why the page size is suppressed
+	namespaces, err := c.cat.ListNamespaces(c.cat.SetPageSize(ctx, 0), nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "list namespaces")
+	}
+
+	result := make([][]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		result = append(result, ns)
+	}
+
+	return result, nil
+}
+
 // extractS3Props scans DSN query parameters and returns all entries whose key
 // starts with "s3." as an iceberg.Properties map. This makes the DSN the single
 // source of S3/MinIO storage configuration — callers pass the result directly to
@@ -107,6 +107,97 @@ func (c *Client) TableExists(ctx context.Context, id ddl.Ident) (bool, error) {
 	return false, nil
 }
 
+// ListTables returns the tables of the namespace.
+func (c *Client) ListTables(ctx context.Context, ns []string) ([]ddl.Ident, error) {
+	// see TableExists for why the page size is suppressed
+	ctx = c.cat.SetPageSize(ctx, 0)
+	var tables []ddl.Ident
+	for tbl, err := range c.cat.ListTables(ctx, ns) {
+		if err != nil {
+			return nil, errors.WithMessage(err, "list tables")
+		}
+		if len(tbl) > 0 {
+			tables = append(tables, ddl.Ident{Namespace: ns, Table: tbl[len(tbl)-1]})
+		}
+	}
+
+	return tables, nil
+}
+
+// DescribeTable returns the current schema, partition spec and sort order of the table as text.
+func (c *Client) DescribeTable(ctx context.Context, id ddl.Ident) (string, error) {
+	tbl, err := c.cat.LoadTable(ctx, ident(id))
+	if err != nil {
+		return "", errors.WithMessage(err, "load table")
+	}
+
+	return fmt.Sprintf("%s\npartitioned by %s\nsorted by %s",
+		tbl.Schema(), tbl.Spec(), tbl.SortOrder()), nil
+}
+
+// LoadTable returns the current schema, partition spec, sort order, comment and properties of the table as IR.
+func (c *Client) LoadTable(ctx context.Context, id ddl.Ident) (*ddl.Table, error) {
+	tbl, err := c.cat.LoadTable(ctx, ident(id))
+	if err != nil {
+		return nil, errors.WithMessage(err, "load table")
+	}
+
+	schema := tbl.Schema()
+	columns, err := fromNestedFields(schema.Fields())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "table %s", id.Table)
+	}
+
+	spec := tbl.Spec()
+	partition := make([]ddl.PartitionField, 0, spec.NumFields())
+	for _, pf := range spec.Fields() {
+		transform, param, err := fromIcebergTransform(pf.Transform)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "partition field %s", pf.Name)
+		}
+		col, _ := schema.FindColumnName(pf.SourceID())
+		partition = append(partition, ddl.PartitionField{
+			Transform: transform,
+			Param:     param,
+			SourceCol: col,
+			Name:      pf.Name,
+		})
+	}
+
+	var sort ddl.SortSpec
+	for _, sf := range tbl.SortOrder().Fields() {
+		transform, param, err := fromIcebergTransform(sf.Transform)
+		if err != nil {
+			return nil, errors.WithMessage(err, "sort field")
+		}
+		col, _ := schema.FindColumnName(sf.SourceID())
+		field := ddl.SortField{Transform: transform, Param: param, SourceCol: col}
+		if sf.Direction == table.SortDESC {
+			field.Direction = ddl.SortDesc
+		}
+		if sf.NullOrder == table.NullsLast {
+			field.NullOrder = ddl.NullsLast
+		}
+		sort.Fields = append(sort.Fields, field)
+	}
+
+	props := make(map[string]string, len(tbl.Properties()))
+	for k, v := range tbl.Properties() {
+		props[k] = v
+	}
+	comment := props["comment"]
+	delete(props, "comment")
+
+	return &ddl.Table{
+		Ident:     id,
+		Columns:   columns,
+		Partition: partition,
+		Sort:      sort,
+		Comment:   comment,
+		Props:     props,
+	}, nil
+}
+
 // DropTable drops an Iceberg table.
 func (c *Client) DropTable(ctx context.Context, id ddl.Ident) error {
 	if err := c.cat.DropTable(ctx, ident(id)); err != nil {
@@ -445,6 +536,79 @@ func toIcebergType(t ddl.IcebergType, ctr *idCounter) (iceberg.Type, error) {
 	}
 }
 
+// fromNestedFields converts []iceberg.NestedField back to []ddl.Field.
+func fromNestedFields(fields []iceberg.NestedField) ([]ddl.Field, error) {
+	result := make([]ddl.Field, 0, len(fields))
+	for _, f := range fields {
+		t, err := fromIcebergType(f.Type)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "field %s", f.Name)
+		}
+		result = append(result, ddl.Field{Name: f.Name, Type: t, Doc: f.Doc, Required: f.Required})
+	}
+	return result, nil
+}
+
+// fromIcebergType maps an iceberg.Type back to a ddl.IcebergType recursively.
+func fromIcebergType(t iceberg.Type) (ddl.IcebergType, error) {
+	switch t := t.(type) {
+	case iceberg.BooleanType:
+		return ddl.IcebergType{Kind: ddl.Boolean}, nil
+	case iceberg.Int32Type:
+		return ddl.IcebergType{Kind: ddl.Int}, nil
+	case iceberg.Int64Type:
+		return ddl.IcebergType{Kind: ddl.Long}, nil
+	case iceberg.Float32Type:
+		return ddl.IcebergType{Kind: ddl.Float}, nil
+	case iceberg.Float64Type:
+		return ddl.IcebergType{Kind: ddl.Double}, nil
+	case iceberg.DecimalType:
+		return ddl.IcebergType{Kind: ddl.Decimal, Prec: t.Precision(), Scale: t.Scale()}, nil
+	case iceberg.DateType:
+		return ddl.IcebergType{Kind: ddl.Date}, nil
+	case iceberg.TimeType:
+		return ddl.IcebergType{Kind: ddl.Time}, nil
+	case iceberg.TimestampTzType:
+		return ddl.IcebergType{Kind: ddl.TimestampTz}, nil
+	case iceberg.TimestampType:
+		return ddl.IcebergType{Kind: ddl.Timestamp}, nil
+	case iceberg.StringType:
+		return ddl.IcebergType{Kind: ddl.String}, nil
+	case iceberg.UUIDType:
+		return ddl.IcebergType{Kind: ddl.UUID}, nil
+	case iceberg.BinaryType:
+		return ddl.IcebergType{Kind: ddl.Binary}, nil
+
+	case *iceberg.StructType:
+		fields, err := fromNestedFields(t.FieldList)
+		if err != nil {
+			return ddl.IcebergType{}, err
+		}
+		return ddl.IcebergType{Kind: ddl.Struct, Fields: fields}, nil
+
+	case *iceberg.ListType:
+		elem, err := fromIcebergType(t.Element)
+		if err != nil {
+			return ddl.IcebergType{}, errors.WithMessage(err, "list element")
+		}
+		return ddl.IcebergType{Kind: ddl.List, Elem: &elem}, nil
+
+	case *iceberg.MapType:
+		key, err := fromIcebergType(t.KeyType)
+		if err != nil {
+			return ddl.IcebergType{}, errors.WithMessage(err, "map key")
+		}
+		val, err := fromIcebergType(t.ValueType)
+		if err != nil {
+			return ddl.IcebergType{}, errors.WithMessage(err, "map value")
+		}
+		return ddl.IcebergType{Kind: ddl.Map, Key: &key, Val: &val}, nil
+
+	default:
+		return ddl.IcebergType{}, errors.Errorf("unsupported type: %s", t)
+	}
+}
+
 // ─── partition spec building ─────────────────────────────────────────────────
 
 // buildPartitionSpec converts a []ddl.PartitionField and a name→fieldID map
@@ -549,3 +713,25 @@ func toIcebergTransform(kind ddl.TransformKind, param int) (iceberg.Transform, e
 		return nil, errors.Errorf("unsupported transform kind: %d", kind)
 	}
 }
+
+// fromIcebergTransform maps an iceberg.Transform back to a ddl.TransformKind and its numeric param.
+func fromIcebergTransform(t iceberg.Transform) (ddl.TransformKind, int, error) {
+	switch t := t.(type) {
+	case iceberg.IdentityTransform:
+		return ddl.Identity, 0, nil
+	case iceberg.YearTransform:
+		return ddl.Years, 0, nil
+	case iceberg.MonthTransform:
+		return ddl.Months, 0, nil
+	case iceberg.DayTransform:
+		return ddl.Days, 0, nil
+	case iceberg.HourTransform:
+		return ddl.Hours, 0, nil
+	case iceberg.BucketTransform:
+		return ddl.Bucket, t.NumBuckets, nil
+	case iceberg.TruncateTransform:
+		return ddl.Truncate, t.Width, nil
+	default:
+		return 0, 0, errors.Errorf("unsupported transform: %s", t)
+	}
+}
@@ -557,3 +557,60 @@ func TestFindPartitionFieldName_PartitionFieldNotInSpec(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "not found in current spec")
 }
+
+// ─── iceberg-go → IR mapping ────────────────────────────────────────────────
+
+// TestFromNestedFields_RoundTrip verifies that the fields built by buildSchema map back to the same IR.
+func TestFromNestedFields_RoundTrip(t *testing.T) {
+	fields := []ddl.Field{
+		{Name: "id", Type: ddl.IcebergType{Kind: ddl.Long}, Required: true},
+		{Name: "amount", Type: ddl.IcebergType{Kind: ddl.Decimal, Prec: 10, Scale: 2}, Doc: "in cents"},
+		{Name: "geo", Type: ddl.IcebergType{Kind: ddl.Struct, Fields: []ddl.Field{
+			{Name: "lat", Type: ddl.IcebergType{Kind: ddl.Double}},
+		}}},
+		{Name: "tags", Type: ddl.IcebergType{Kind: ddl.List, Elem: &ddl.IcebergType{Kind: ddl.String}}},
+		{Name: "attrs", Type: ddl.IcebergType{
+			Kind: ddl.Map, Key: &ddl.IcebergType{Kind: ddl.String}, Val: &ddl.IcebergType{Kind: ddl.TimestampTz},
+		}},
+	}
+	schema, _, err := buildSchema(fields)
+	require.NoError(t, err)
+
+	got, err := fromNestedFields(schema.Fields())
+	require.NoError(t, err)
+	assert.Equal(t, fields, got)
+}
+
+// TestFromIcebergTransform verifies the mapping of every supported transform back to the IR.
+func TestFromIcebergTransform(t *testing.T) {
+	tests := []struct {
+		transform iceberg.Transform
+		kind      ddl.TransformKind
+		param     int
+	}{
+		{iceberg.IdentityTransform{}, ddl.Identity, 0},
+		{iceberg.YearTransform{}, ddl.Years, 0},
+		{iceberg.MonthTransform{}, ddl.Months, 0},
+		{iceberg.DayTransform{}, ddl.Days, 0},
+		{iceberg.HourTransform{}, ddl.Hours, 0},
+		{iceberg.BucketTransform{NumBuckets: 16}, ddl.Bucket, 16},
+		{iceberg.TruncateTransform{Width: 4}, ddl.Truncate, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transform.String(), func(t *testing.T) {
+			kind, param, err := fromIcebergTransform(tt.transform)
+			require.NoError(t, err)
+			assert.Equal(t, tt.kind, kind)
+			assert.Equal(t, tt.param, param)
+		})
+	}
+
+	_, _, err := fromIcebergTransform(iceberg.VoidTransform{})
+	require.Error(t, err)
+}
+
+// TestFromIcebergType_Unsupported verifies that a type outside the IR is rejected.
+func TestFromIcebergType_Unsupported(t *testing.T) {
+	_, err := fromIcebergType(iceberg.FixedTypeOf(16))
+	require.Error(t, err)
+}
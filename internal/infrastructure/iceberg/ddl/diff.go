@@ -0,0 +1,162 @@
+package ddl
+
+import (
+	"strings"
+)
+
+// step is a change proposed by Diff: the up operations make it, the down operations undo it.
+type step struct {
+	up   []Operation
+	down []Operation
+}
+
+// Diff returns the operations that change the current schema into the desired one (up)
+// and the operations that change it back (down).
+//
+// Namespaces are created before and dropped after the tables. A table is created with its sort order;
+// an existing table gets its columns added, retyped and dropped and its partition fields and sort order
+// replaced when they differ. Renames are not detected:
+// a renamed table or column is proposed as dropped and created again. Column comments are not compared.
+// The down operations undo the up ones in reverse order.
+func Diff(current, desired *Schema) (up, down []Operation) {
+	var steps []step
+
+	for _, ns := range desired.Namespaces {
+		if current.namespace(ns) < 0 {
+			steps = append(steps, step{
+				up:   []Operation{{Kind: CreateNamespace, Table: Ident{Namespace: ns}}},
+				down: []Operation{{Kind: DropNamespace, Table: Ident{Namespace: ns}}},
+			})
+		}
+	}
+	for _, t := range desired.Tables {
+		cur := current.Table(t.Ident)
+		if cur == nil {
+			steps = append(steps, step{up: createTable(t), down: []Operation{{Kind: DropTable, Table: t.Ident}}})
+			continue
+		}
+		steps = append(steps, diffTable(cur, t)...)
+	}
+	for _, t := range current.Tables {
+		if desired.Table(t.Ident) == nil {
+			steps = append(steps, step{up: []Operation{{Kind: DropTable, Table: t.Ident}}, down: createTable(t)})
+		}
+	}
+	for _, ns := range current.Namespaces {
+		if desired.namespace(ns) < 0 {
+			steps = append(steps, step{
+				up:   []Operation{{Kind: DropNamespace, Table: Ident{Namespace: ns}}},
+				down: []Operation{{Kind: CreateNamespace, Table: Ident{Namespace: ns}}},
+			})
+		}
+	}
+
+	for i := range steps {
+		up = append(up, steps[i].up...)
+	}
+	for i := len(steps) - 1; i >= 0; i-- {
+		down = append(down, steps[i].down...)
+	}
+
+	return up, down
+}
+
+// createTable returns the operations that create t with its sort order.
+func createTable(t *Table) []Operation {
+	ops := []Operation{{
+		Kind:  CreateTable,
+		Table: t.Ident,
+		Create: &CreateTableSpec{
+			Schema:    t.Columns,
+			Partition: t.Partition,
+			Props:     t.Props,
+			Comment:   t.Comment,
+		},
+	}}
+	if len(t.Sort.Fields) > 0 {
+		ops = append(ops, Operation{Kind: SetSortOrder, Table: t.Ident, Sort: &SortSpec{Fields: t.Sort.Fields}})
+	}
+	return ops
+}
+
+// diffTable returns the steps that change the existing table cur into des. The columns are dropped
+// after the partition fields and the sort order that may refer to them are replaced.
+func diffTable(cur, des *Table) []step {
+	id := des.Ident
+	var steps, dropColumns []step
+
+	for i := range des.Columns {
+		col := &des.Columns[i]
+		j := cur.column(col.Name)
+		switch {
+		case j < 0:
+			steps = append(steps, step{
+				up:   []Operation{{Kind: AddColumn, Table: id, Column: col}},
+				down: []Operation{{Kind: DropColumn, Table: id, Column: &Field{Name: col.Name}}},
+			})
+		case !sameType(cur.Columns[j].Type, col.Type):
+			steps = append(steps, step{
+				up:   []Operation{{Kind: AlterColumnType, Table: id, Column: &Field{Name: col.Name, Type: col.Type}}},
+				down: []Operation{{Kind: AlterColumnType, Table: id, Column: &Field{Name: col.Name, Type: cur.Columns[j].Type}}},
+			})
+		}
+	}
+	for i := range cur.Columns {
+		col := &cur.Columns[i]
+		if des.column(col.Name) < 0 {
+			dropColumns = append(dropColumns, step{
+				up:   []Operation{{Kind: DropColumn, Table: id, Column: &Field{Name: col.Name}}},
+				down: []Operation{{Kind: AddColumn, Table: id, Column: col}},
+			})
+		}
+	}
+
+	for i := range cur.Partition {
+		if des.partitionField(cur.Partition[i]) < 0 {
+			steps = append(steps, step{
+				up:   []Operation{{Kind: DropPartitionField, Table: id, Partition: &cur.Partition[i]}},
+				down: []Operation{{Kind: AddPartitionField, Table: id, Partition: &cur.Partition[i]}},
+			})
+		}
+	}
+	for i := range des.Partition {
+		if cur.partitionField(des.Partition[i]) < 0 {
+			steps = append(steps, step{
+				up:   []Operation{{Kind: AddPartitionField, Table: id, Partition: &des.Partition[i]}},
+				down: []Operation{{Kind: DropPartitionField, Table: id, Partition: &des.Partition[i]}},
+			})
+		}
+	}
+
+	if !sameSort(cur.Sort, des.Sort) {
+		steps = append(steps, step{
+			up:   []Operation{{Kind: SetSortOrder, Table: id, Sort: &SortSpec{Fields: des.Sort.Fields}}},
+			down: []Operation{{Kind: SetSortOrder, Table: id, Sort: &SortSpec{Fields: cur.Sort.Fields}}},
+		})
+	}
+
+	return append(steps, dropColumns...)
+}
+
+// sameType reports whether a and b are the same type; the names of struct fields are case-insensitive.
+func sameType(a, b IcebergType) bool {
+	return strings.EqualFold(formatType(a), formatType(b))
+}
+
+// sameSort reports whether a and b are the same write sort order.
+func sameSort(a, b SortSpec) bool {
+	if len(a.Fields) != len(b.Fields) {
+		return false
+	}
+	for i := range a.Fields {
+		fa, fb := a.Fields[i], b.Fields[i]
+		if fa.Direction != fb.Direction || fa.NullOrder != fb.NullOrder ||
+			!samePartitionField(
+				PartitionField{Transform: fa.Transform, Param: fa.Param, SourceCol: fa.SourceCol},
+				PartitionField{Transform: fb.Transform, Param: fb.Param, SourceCol: fb.SourceCol},
+			) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package ddl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// formatAll formats every operation.
+func formatAll(t *testing.T, ops []Operation) []string {
+	t.Helper()
+	out := make([]string, 0, len(ops))
+	for _, op := range ops {
+		s, err := Format(op)
+		require.NoError(t, err)
+		out = append(out, s)
+	}
+	return out
+}
+
+// TestDiff verifies the up and down operations proposed for the differences of an existing table.
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	current := replay(t,
+		"CREATE TABLE app.users (id int, name string, ts timestamp) PARTITIONED BY (days(ts))",
+	)
+	desired := replay(t,
+		"CREATE TABLE app.users (id bigint, ts timestamp, email string) PARTITIONED BY (bucket(8, id))",
+		"ALTER TABLE app.users WRITE ORDERED BY ts DESC",
+	)
+
+	up, down := Diff(current, desired)
+
+	assert.Equal(t, []string{
+		"ALTER TABLE app.users ALTER COLUMN id TYPE bigint",
+		"ALTER TABLE app.users ADD COLUMN email string",
+		"ALTER TABLE app.users DROP PARTITION FIELD days(ts)",
+		"ALTER TABLE app.users ADD PARTITION FIELD bucket(8, id)",
+		"ALTER TABLE app.users WRITE ORDERED BY ts DESC NULLS LAST",
+		"ALTER TABLE app.users DROP COLUMN name",
+	}, formatAll(t, up))
+	assert.Equal(t, []string{
+		"ALTER TABLE app.users ADD COLUMN name string",
+		"ALTER TABLE app.users WRITE UNORDERED",
+		"ALTER TABLE app.users DROP PARTITION FIELD bucket(8, id)",
+		"ALTER TABLE app.users ADD PARTITION FIELD days(ts)",
+		"ALTER TABLE app.users DROP COLUMN email",
+		"ALTER TABLE app.users ALTER COLUMN id TYPE int",
+	}, formatAll(t, down))
+}
+
+// TestDiff_Tables verifies that tables and namespaces are created and dropped, and that
+// the up operations lead to the desired schema and the down operations back to the current one.
+func TestDiff_Tables(t *testing.T) {
+	t.Parallel()
+
+	current := replay(t,
+		"CREATE NAMESPACE legacy",
+		"CREATE TABLE legacy.events (id bigint, ts timestamp) PARTITIONED BY (hours(ts)) COMMENT 'old events'",
+		"ALTER TABLE legacy.events WRITE ORDERED BY id",
+		"CREATE TABLE app.users (id bigint)",
+	)
+	desired := replay(t,
+		"CREATE NAMESPACE analytics",
+		"CREATE TABLE analytics.events (id bigint, payload map<string,string>) TBLPROPERTIES ('format-version' = '2')",
+		"CREATE TABLE app.users (id bigint)",
+	)
+
+	up, down := Diff(current, desired)
+
+	assert.Equal(t, []string{
+		"CREATE NAMESPACE analytics",
+		"CREATE TABLE analytics.events (\n    id bigint,\n    payload map<string,string>\n)\nUSING iceberg\n" +
+			"TBLPROPERTIES ('format-version' = '2')",
+		"DROP TABLE legacy.events",
+		"DROP NAMESPACE legacy",
+	}, formatAll(t, up))
+	assert.Equal(t, []string{
+		"CREATE NAMESPACE legacy",
+		"CREATE TABLE legacy.events (\n    id bigint,\n    ts timestamp\n)\nUSING iceberg\n" +
+			"PARTITIONED BY (hours(ts))\nCOMMENT 'old events'",
+		"ALTER TABLE legacy.events WRITE ORDERED BY id ASC NULLS FIRST",
+		"DROP TABLE analytics.events",
+		"DROP NAMESPACE analytics",
+	}, formatAll(t, down))
+
+	for _, op := range up {
+		require.NoError(t, current.Apply(op))
+	}
+	up, _ = Diff(current, desired)
+	assert.Empty(t, up)
+
+	for _, op := range down {
+		require.NoError(t, current.Apply(op))
+	}
+	up, _ = Diff(current, replay(t,
+		"CREATE NAMESPACE legacy",
+		"CREATE TABLE legacy.events (id bigint, ts timestamp) PARTITIONED BY (hours(ts))",
+		"ALTER TABLE legacy.events WRITE ORDERED BY id",
+		"CREATE TABLE app.users (id bigint)",
+	))
+	assert.Empty(t, up)
+}
+
+// TestDiff_NoDifference verifies that equal schemas, compared case-insensitively, produce no operations.
+func TestDiff_NoDifference(t *testing.T) {
+	t.Parallel()
+
+	current := replay(t, "CREATE TABLE app.users (id bigint, tags array<string>)")
+	desired := replay(t, "CREATE TABLE APP.Users (ID BIGINT, Tags ARRAY<STRING>)")
+
+	up, down := Diff(current, desired)
+
+	assert.Empty(t, up)
+	assert.Empty(t, down)
+}
@@ -15,4 +15,7 @@ var (
 
 	// ErrNamespaceRequired is returned when a table identifier has no namespace component.
 	ErrNamespaceRequired = errors.New("namespace is required in table identifier")
+
+	// ErrSchema is returned when an operation cannot be applied to a Schema, e.g. it alters a table that does not exist.
+	ErrSchema = errors.New("operation does not match the schema")
 )
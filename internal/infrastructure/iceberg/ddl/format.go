@@ -0,0 +1,265 @@
+package ddl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Format renders op as a single Spark-SQL (Iceberg) DDL statement, without the trailing ";",
+// that Parse reads back into the same Operation. It is the inverse of Parse used to write
+// the statements proposed by the schema diff.
+func Format(op Operation) (string, error) {
+	table := formatIdent(op.Table)
+
+	switch op.Kind {
+	case CreateNamespace:
+		return "CREATE NAMESPACE " + ifNotExists(op.IfNotExists) + formatNamespace(op.Table.Namespace), nil
+	case DropNamespace:
+		return "DROP NAMESPACE " + ifExists(op.IfExists) + formatNamespace(op.Table.Namespace), nil
+	case CreateTable:
+		if op.Create == nil {
+			return "", errors.Wrap(ErrUnsupportedDDL, "CreateTable has no table specification")
+		}
+		return formatCreateTable(op.Table, *op.Create, op.IfNotExists), nil
+	case DropTable:
+		return "DROP TABLE " + ifExists(op.IfExists) + table, nil
+	case RenameTable:
+		if op.RenameTo == nil {
+			return "", errors.Wrap(ErrUnsupportedDDL, "RenameTable has no destination")
+		}
+		return "RENAME TABLE " + table + " TO " + formatIdent(*op.RenameTo), nil
+	case AddColumn:
+		if op.Column == nil {
+			return "", errors.Wrap(ErrUnsupportedDDL, "AddColumn has no column")
+		}
+		return "ALTER TABLE " + table + " ADD COLUMN " + formatField(*op.Column), nil
+	case DropColumn:
+		if op.Column == nil {
+			return "", errors.Wrap(ErrUnsupportedDDL, "DropColumn has no column")
+		}
+		return "ALTER TABLE " + table + " DROP COLUMN " + quoteIdent(op.Column.Name), nil
+	case RenameColumn:
+		if op.Column == nil {
+			return "", errors.Wrap(ErrUnsupportedDDL, "RenameColumn has no column")
+		}
+		return "ALTER TABLE " + table + " RENAME COLUMN " + quoteIdent(op.Column.Name) +
+			" TO " + quoteIdent(op.NewName), nil
+	case AlterColumnType:
+		if op.Column == nil {
+			return "", errors.Wrap(ErrUnsupportedDDL, "AlterColumnType has no column")
+		}
+		return "ALTER TABLE " + table + " ALTER COLUMN " + quoteIdent(op.Column.Name) +
+			" TYPE " + formatType(op.Column.Type), nil
+	case AddPartitionField:
+		if op.Partition == nil {
+			return "", errors.Wrap(ErrUnsupportedDDL, "AddPartitionField has no partition field")
+		}
+		return "ALTER TABLE " + table + " ADD PARTITION FIELD " + formatTransform(*op.Partition), nil
+	case DropPartitionField:
+		if op.Partition == nil {
+			return "", errors.Wrap(ErrUnsupportedDDL, "DropPartitionField has no partition field")
+		}
+		return "ALTER TABLE " + table + " DROP PARTITION FIELD " + formatTransform(*op.Partition), nil
+	case SetSortOrder:
+		if op.Sort == nil || op.Sort.Unordered || len(op.Sort.Fields) == 0 {
+			return "ALTER TABLE " + table + " WRITE UNORDERED", nil
+		}
+		return "ALTER TABLE " + table + " WRITE ORDERED BY " + formatSortFields(op.Sort.Fields), nil
+	default:
+		return "", errors.Wrapf(ErrUnsupportedDDL, "operation kind %d", op.Kind)
+	}
+}
+
+// formatCreateTable renders CREATE TABLE with one column per line followed by the optional clauses.
+func formatCreateTable(id Ident, spec CreateTableSpec, ifNotExist bool) string {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE " + ifNotExists(ifNotExist) + formatIdent(id) + " (\n")
+	for i, f := range spec.Schema {
+		b.WriteString("    " + formatField(f))
+		if i < len(spec.Schema)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(")\nUSING iceberg")
+	if len(spec.Partition) > 0 {
+		parts := make([]string, 0, len(spec.Partition))
+		for _, pf := range spec.Partition {
+			parts = append(parts, formatTransform(pf))
+		}
+		b.WriteString("\nPARTITIONED BY (" + strings.Join(parts, ", ") + ")")
+	}
+	if spec.Comment != "" {
+		b.WriteString("\nCOMMENT " + quoteString(spec.Comment))
+	}
+	if len(spec.Props) > 0 {
+		keys := make([]string, 0, len(spec.Props))
+		for k := range spec.Props {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		props := make([]string, 0, len(keys))
+		for _, k := range keys {
+			props = append(props, quoteString(k)+" = "+quoteString(spec.Props[k]))
+		}
+		b.WriteString("\nTBLPROPERTIES (" + strings.Join(props, ", ") + ")")
+	}
+	return b.String()
+}
+
+// formatField renders a column definition: "name type [COMMENT '…']".
+func formatField(f Field) string {
+	s := quoteIdent(f.Name) + " " + formatType(f.Type)
+	if f.Doc != "" {
+		s += " COMMENT " + quoteString(f.Doc)
+	}
+	return s
+}
+
+// formatType renders t as the Spark-SQL type expression that parseType reads back.
+// Composite types are written without spaces, so the tokenizer keeps them as a single token.
+func formatType(t IcebergType) string {
+	switch t.Kind {
+	case Boolean:
+		return "boolean"
+	case Int:
+		return "int"
+	case Long:
+		return "bigint"
+	case Float:
+		return "float"
+	case Double:
+		return "double"
+	case Decimal:
+		return fmt.Sprintf("decimal(%d,%d)", t.Prec, t.Scale)
+	case Date:
+		return "date"
+	case Time:
+		return "time"
+	case TimestampTz:
+		return "timestamp"
+	case Timestamp:
+		return "timestamp_ntz"
+	case String:
+		return "string"
+	case UUID:
+		return "uuid"
+	case Binary:
+		return "binary"
+	case Struct:
+		fields := make([]string, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			fields = append(fields, f.Name+":"+formatType(f.Type))
+		}
+		return "struct<" + strings.Join(fields, ",") + ">"
+	case List:
+		if t.Elem == nil {
+			return "array<>"
+		}
+		return "array<" + formatType(*t.Elem) + ">"
+	case Map:
+		if t.Key == nil || t.Val == nil {
+			return "map<>"
+		}
+		return "map<" + formatType(*t.Key) + "," + formatType(*t.Val) + ">"
+	default:
+		return "unknown"
+	}
+}
+
+// formatTransform renders a partition transform such as "days(ts)" or "bucket(16, id)".
+func formatTransform(pf PartitionField) string {
+	switch pf.Transform {
+	case Identity:
+		return "identity(" + pf.SourceCol + ")"
+	case Years:
+		return "years(" + pf.SourceCol + ")"
+	case Months:
+		return "months(" + pf.SourceCol + ")"
+	case Days:
+		return "days(" + pf.SourceCol + ")"
+	case Hours:
+		return "hours(" + pf.SourceCol + ")"
+	case Bucket:
+		return "bucket(" + strconv.Itoa(pf.Param) + ", " + pf.SourceCol + ")"
+	case Truncate:
+		return "truncate(" + strconv.Itoa(pf.Param) + ", " + pf.SourceCol + ")"
+	default:
+		return pf.SourceCol
+	}
+}
+
+// formatSortFields renders the column list of WRITE ORDERED BY, with the direction and null ordering
+// of every column spelled out.
+func formatSortFields(fields []SortField) string {
+	parts := make([]string, 0, len(fields))
+	for _, sf := range fields {
+		col := sf.SourceCol
+		if sf.Transform != Identity {
+			col = formatTransform(PartitionField{Transform: sf.Transform, Param: sf.Param, SourceCol: sf.SourceCol})
+		}
+		direction := "ASC"
+		if sf.Direction == SortDesc {
+			direction = "DESC"
+		}
+		nulls := "NULLS FIRST"
+		if sf.NullOrder == NullsLast {
+			nulls = "NULLS LAST"
+		}
+		parts = append(parts, col+" "+direction+" "+nulls)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatIdent renders a table identifier as "ns.table".
+func formatIdent(id Ident) string {
+	segments := make([]string, 0, len(id.Namespace)+1)
+	for _, s := range id.Namespace {
+		segments = append(segments, quoteIdent(s))
+	}
+	if id.Table != "" {
+		segments = append(segments, quoteIdent(id.Table))
+	}
+	return strings.Join(segments, ".")
+}
+
+// formatNamespace renders a namespace identifier as "a.b".
+func formatNamespace(ns []string) string {
+	return formatIdent(Ident{Namespace: ns})
+}
+
+// quoteIdent quotes name with backticks unless it is a plain identifier.
+func quoteIdent(name string) string {
+	if name == "" || !isIdentStart(name[0]) {
+		return "`" + name + "`"
+	}
+	for i := 0; i < len(name); i++ {
+		if !isIdentChar(name[i]) {
+			return "`" + name + "`"
+		}
+	}
+	return name
+}
+
+// quoteString renders s as a single-quoted string literal.
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func ifNotExists(ok bool) string {
+	if ok {
+		return "IF NOT EXISTS "
+	}
+	return ""
+}
+
+func ifExists(ok bool) string {
+	if ok {
+		return "IF EXISTS "
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package ddl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFormat_RoundTrip verifies that Parse reads every formatted statement back into the same Operation.
+func TestFormat_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	statements := []string{
+		"CREATE NAMESPACE IF NOT EXISTS analytics",
+		"DROP NAMESPACE a.b",
+		"CREATE TABLE IF NOT EXISTS analytics.events (" +
+			"id bigint COMMENT 'event id', ts timestamp, amount decimal(10,2), " +
+			"tags array<string>, attrs map<string,int>, geo struct<lat:double,lon:double>, " +
+			"`user-id` uuid) USING iceberg PARTITIONED BY (days(ts), bucket(16, id)) " +
+			"COMMENT 'all events' TBLPROPERTIES ('format-version' = '2', 'a' = 'b')",
+		"DROP TABLE IF EXISTS analytics.events",
+		"RENAME TABLE analytics.events TO analytics.events_v2",
+		"ALTER TABLE analytics.events ADD COLUMN note string COMMENT 'free text'",
+		"ALTER TABLE analytics.events DROP COLUMN note",
+		"ALTER TABLE analytics.events RENAME COLUMN note TO remark",
+		"ALTER TABLE analytics.events ALTER COLUMN id TYPE bigint",
+		"ALTER TABLE analytics.events ADD PARTITION FIELD truncate(4, name)",
+		"ALTER TABLE analytics.events DROP PARTITION FIELD identity(region)",
+		"ALTER TABLE analytics.events WRITE ORDERED BY ts DESC, bucket(8, id) ASC NULLS LAST",
+		"ALTER TABLE analytics.events WRITE UNORDERED",
+	}
+
+	for _, stmt := range statements {
+		t.Run(stmt, func(t *testing.T) {
+			t.Parallel()
+			want, err := Parse("", stmt)
+			require.NoError(t, err)
+
+			formatted, err := Format(want)
+			require.NoError(t, err)
+
+			got, err := Parse("", formatted)
+			require.NoError(t, err, formatted)
+			assert.Equal(t, want, got, formatted)
+		})
+	}
+}
+
+// TestFormat_Statements verifies the text of the formatted statements.
+func TestFormat_Statements(t *testing.T) {
+	t.Parallel()
+
+	events := Ident{Namespace: []string{"analytics"}, Table: "events"}
+	tests := []struct {
+		name string
+		op   Operation
+		want string
+	}{
+		{
+			name: "create table",
+			op: Operation{Kind: CreateTable, Table: events, Create: &CreateTableSpec{
+				Schema: []Field{
+					{Name: "id", Type: IcebergType{Kind: Long}},
+					{Name: "ts", Type: IcebergType{Kind: TimestampTz}, Doc: "it's the time"},
+				},
+				Partition: []PartitionField{{Transform: Days, SourceCol: "ts"}},
+			}},
+			want: "CREATE TABLE analytics.events (\n" +
+				"    id bigint,\n" +
+				"    ts timestamp COMMENT 'it''s the time'\n" +
+				")\nUSING iceberg\nPARTITIONED BY (days(ts))",
+		},
+		{
+			name: "sort order",
+			op: Operation{Kind: SetSortOrder, Table: events, Sort: &SortSpec{Fields: []SortField{
+				{Transform: Identity, SourceCol: "ts", Direction: SortDesc, NullOrder: NullsLast},
+			}}},
+			want: "ALTER TABLE analytics.events WRITE ORDERED BY ts DESC NULLS LAST",
+		},
+		{
+			name: "empty sort order",
+			op:   Operation{Kind: SetSortOrder, Table: events, Sort: &SortSpec{}},
+			want: "ALTER TABLE analytics.events WRITE UNORDERED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Format(tt.op)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestFormat_Invalid verifies that an operation without its specification is rejected.
+func TestFormat_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, op := range []Operation{
+		{Kind: CreateTable},
+		{Kind: AddColumn},
+		{Kind: AddPartitionField},
+		{Kind: OpKind(100)},
+	} {
+		_, err := Format(op)
+		require.ErrorIs(t, err, ErrUnsupportedDDL)
+	}
+}
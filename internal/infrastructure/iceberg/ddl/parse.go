@@ -46,6 +46,23 @@ func Parse(catalog, statement string) (Operation, error) {
 	return p.parse()
 }
 
+// IsDDL reports whether the statement changes the schema: its first keyword, after the comments,
+// is CREATE, DROP, ALTER, RENAME, REPLACE or COMMENT. Parse fails on the DDL it does not support;
+// the statements that are not DDL, such as inserts, do not change the schema.
+func IsDDL(statement string) bool {
+	words := strings.Fields(stripComments(statement))
+	if len(words) == 0 {
+		return false
+	}
+
+	switch strings.ToUpper(strings.TrimRight(words[0], ";")) {
+	case "CREATE", kwDROP, kwALTER, kwRENAME, "REPLACE", kwCOMMENT:
+		return true
+	default:
+		return false
+	}
+}
+
 // stripComments removes SQL line comments (-- … <EOL>) and block comments (/* … */) from s,
 // while preserving the content of single-quoted string literals ('…') and backtick-quoted
 // identifiers (`…`).  The function is intentionally conservative: it only skips what it
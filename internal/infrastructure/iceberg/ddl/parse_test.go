@@ -880,3 +880,25 @@ func TestParse_AlterTable_ErrorPaths(t *testing.T) {
 		})
 	}
 }
+
+// TestIsDDL verifies that the statements changing the schema are told from the others,
+// whatever their case and leading comments.
+func TestIsDDL(t *testing.T) {
+	t.Parallel()
+
+	for stmt, want := range map[string]bool{
+		"CREATE TABLE raw.t (id int)":                   true,
+		"create view raw.v as select 1":                 true,
+		"-- note\nALTER TABLE raw.t ADD COLUMN x int":   true,
+		"/* drop */ DROP INDEX idx ON raw.t":            true,
+		"RENAME TABLE raw.t TO raw.t2":                  true,
+		"REPLACE TABLE raw.t (id int)":                  true,
+		"COMMENT ON TABLE raw.t IS 'x'":                 true,
+		"INSERT INTO raw.t VALUES (1)":                  false,
+		"SELECT * FROM raw.t":                           false,
+		"-- CREATE TABLE raw.t (id int)\nDELETE FROM t": false,
+		"   ": false,
+	} {
+		assert.Equal(t, want, IsDDL(stmt), stmt)
+	}
+}
@@ -0,0 +1,243 @@
+package ddl
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Table is the state of a table compared by the schema diff.
+type Table struct {
+	Ident     Ident
+	Columns   []Field
+	Partition []PartitionField
+	// Sort is the write sort order; no fields mean the table is unordered.
+	Sort    SortSpec
+	Comment string
+	Props   map[string]string
+}
+
+// column returns the index of the column name, or -1.
+func (t *Table) column(name string) int {
+	for i := range t.Columns {
+		if strings.EqualFold(t.Columns[i].Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// partitionField returns the index of the partition field with the transform of pf, or -1.
+func (t *Table) partitionField(pf PartitionField) int {
+	for i := range t.Partition {
+		if samePartitionField(t.Partition[i], pf) {
+			return i
+		}
+	}
+	return -1
+}
+
+// renameColumn renames the column and every reference to it.
+func (t *Table) renameColumn(from, to string) {
+	t.Columns[t.column(from)].Name = to
+	for i := range t.Partition {
+		if strings.EqualFold(t.Partition[i].SourceCol, from) {
+			t.Partition[i].SourceCol = to
+		}
+	}
+	for i := range t.Sort.Fields {
+		if strings.EqualFold(t.Sort.Fields[i].SourceCol, from) {
+			t.Sort.Fields[i].SourceCol = to
+		}
+	}
+}
+
+// Schema is the state of the namespaces and tables of a database. The namespaces are only those
+// created explicitly; the tables keep the order they were created in.
+type Schema struct {
+	Namespaces [][]string
+	Tables     []*Table
+}
+
+// NewSchema creates an empty Schema.
+func NewSchema() *Schema {
+	return &Schema{}
+}
+
+// Table returns the table id, or nil when the schema has no such table.
+func (s *Schema) Table(id Ident) *Table {
+	if i := s.table(id); i >= 0 {
+		return s.Tables[i]
+	}
+	return nil
+}
+
+func (s *Schema) table(id Ident) int {
+	for i, t := range s.Tables {
+		if sameIdent(t.Ident, id) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Schema) namespace(ns []string) int {
+	for i := range s.Namespaces {
+		if sameIdent(Ident{Namespace: s.Namespaces[i]}, Ident{Namespace: ns}) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Apply changes the schema the way the database changes when it executes op.
+// It is used to replay the migrations into the schema they build without a database.
+func (s *Schema) Apply(op Operation) error {
+	switch op.Kind {
+	case CreateNamespace:
+		if s.namespace(op.Table.Namespace) < 0 {
+			s.Namespaces = append(s.Namespaces, op.Table.Namespace)
+		} else if !op.IfNotExists {
+			return errors.Wrapf(ErrSchema, "namespace %s already exists", formatNamespace(op.Table.Namespace))
+		}
+		return nil
+	case DropNamespace:
+		i := s.namespace(op.Table.Namespace)
+		if i < 0 {
+			if op.IfExists {
+				return nil
+			}
+			return errors.Wrapf(ErrSchema, "namespace %s does not exist", formatNamespace(op.Table.Namespace))
+		}
+		s.Namespaces = append(s.Namespaces[:i], s.Namespaces[i+1:]...)
+		return nil
+	case CreateTable:
+		if op.Create == nil {
+			return errors.Wrap(ErrUnsupportedDDL, "CreateTable has no table specification")
+		}
+		if s.table(op.Table) >= 0 {
+			if op.IfNotExists {
+				return nil
+			}
+			return errors.Wrapf(ErrSchema, "table %s already exists", formatIdent(op.Table))
+		}
+		s.Tables = append(s.Tables, &Table{
+			Ident:     op.Table,
+			Columns:   append([]Field(nil), op.Create.Schema...),
+			Partition: append([]PartitionField(nil), op.Create.Partition...),
+			Comment:   op.Create.Comment,
+			Props:     op.Create.Props,
+		})
+		return nil
+	case DropTable:
+		i := s.table(op.Table)
+		if i < 0 {
+			if op.IfExists {
+				return nil
+			}
+			return errors.Wrapf(ErrSchema, "table %s does not exist", formatIdent(op.Table))
+		}
+		s.Tables = append(s.Tables[:i], s.Tables[i+1:]...)
+		return nil
+	default:
+		return s.alterTable(op)
+	}
+}
+
+// alterTable applies the operations that change an existing table.
+func (s *Schema) alterTable(op Operation) error {
+	t, err := s.mustTable(op.Table)
+	if err != nil {
+		return err
+	}
+
+	switch op.Kind {
+	case RenameTable:
+		if op.RenameTo == nil {
+			return errors.Wrap(ErrUnsupportedDDL, "RenameTable has no destination")
+		}
+		if s.table(*op.RenameTo) >= 0 {
+			return errors.Wrapf(ErrSchema, "table %s already exists", formatIdent(*op.RenameTo))
+		}
+		t.Ident = *op.RenameTo
+	case AddColumn, DropColumn, RenameColumn, AlterColumnType:
+		if op.Column == nil {
+			return errors.Wrap(ErrUnsupportedDDL, "column operation has no column")
+		}
+		i := t.column(op.Column.Name)
+		if op.Kind == AddColumn {
+			if i >= 0 {
+				return errors.Wrapf(ErrSchema, "column %s already exists in %s", op.Column.Name, formatIdent(op.Table))
+			}
+			t.Columns = append(t.Columns, *op.Column)
+			return nil
+		}
+		if i < 0 {
+			return errors.Wrapf(ErrSchema, "column %s does not exist in %s", op.Column.Name, formatIdent(op.Table))
+		}
+		switch op.Kind {
+		case DropColumn:
+			t.Columns = append(t.Columns[:i], t.Columns[i+1:]...)
+		case RenameColumn:
+			if t.column(op.NewName) >= 0 {
+				return errors.Wrapf(ErrSchema, "column %s already exists in %s", op.NewName, formatIdent(op.Table))
+			}
+			t.renameColumn(op.Column.Name, op.NewName)
+		default:
+			t.Columns[i].Type = op.Column.Type
+		}
+	case AddPartitionField, DropPartitionField:
+		if op.Partition == nil {
+			return errors.Wrap(ErrUnsupportedDDL, "partition operation has no partition field")
+		}
+		i := t.partitionField(*op.Partition)
+		if op.Kind == AddPartitionField {
+			if i >= 0 {
+				return errors.Wrapf(ErrSchema, "partition field %s already exists in %s",
+					formatTransform(*op.Partition), formatIdent(op.Table))
+			}
+			t.Partition = append(t.Partition, *op.Partition)
+			return nil
+		}
+		if i < 0 {
+			return errors.Wrapf(ErrSchema, "partition field %s does not exist in %s",
+				formatTransform(*op.Partition), formatIdent(op.Table))
+		}
+		t.Partition = append(t.Partition[:i], t.Partition[i+1:]...)
+	case SetSortOrder:
+		t.Sort = SortSpec{}
+		if op.Sort != nil && !op.Sort.Unordered {
+			t.Sort.Fields = append([]SortField(nil), op.Sort.Fields...)
+		}
+	default:
+		return errors.Wrapf(ErrUnsupportedDDL, "operation kind %d", op.Kind)
+	}
+
+	return nil
+}
+
+func (s *Schema) mustTable(id Ident) (*Table, error) {
+	t := s.Table(id)
+	if t == nil {
+		return nil, errors.Wrapf(ErrSchema, "table %s does not exist", formatIdent(id))
+	}
+	return t, nil
+}
+
+// sameIdent reports whether a and b name the same object; identifiers are case-insensitive.
+func sameIdent(a, b Ident) bool {
+	if len(a.Namespace) != len(b.Namespace) || !strings.EqualFold(a.Table, b.Table) {
+		return false
+	}
+	for i := range a.Namespace {
+		if !strings.EqualFold(a.Namespace[i], b.Namespace[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// samePartitionField reports whether a and b are the same transform of the same column.
+func samePartitionField(a, b PartitionField) bool {
+	return a.Transform == b.Transform && a.Param == b.Param && strings.EqualFold(a.SourceCol, b.SourceCol)
+}
@@ -0,0 +1,111 @@
+package ddl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replay parses the statements and applies them to a new Schema.
+func replay(t *testing.T, statements ...string) *Schema {
+	t.Helper()
+	s := NewSchema()
+	for _, stmt := range statements {
+		op, err := Parse("", stmt)
+		require.NoError(t, err, stmt)
+		require.NoError(t, s.Apply(op), stmt)
+	}
+	return s
+}
+
+// TestSchema_Apply verifies that replaying statements builds the schema they describe.
+func TestSchema_Apply(t *testing.T) {
+	t.Parallel()
+
+	s := replay(t,
+		"CREATE NAMESPACE app",
+		"CREATE NAMESPACE tmp",
+		"CREATE TABLE app.users (id bigint, name string, ts timestamp) PARTITIONED BY (days(ts))",
+		"CREATE TABLE app.old (id int)",
+		"ALTER TABLE app.users ADD COLUMN email string",
+		"ALTER TABLE app.users ALTER COLUMN id TYPE bigint",
+		"ALTER TABLE app.users DROP COLUMN name",
+		"ALTER TABLE app.users ADD PARTITION FIELD bucket(8, id)",
+		"ALTER TABLE app.users DROP PARTITION FIELD days(ts)",
+		"ALTER TABLE app.users WRITE ORDERED BY ts DESC",
+		"ALTER TABLE app.users RENAME COLUMN email TO mail",
+		"RENAME TABLE app.old TO app.legacy",
+		"DROP TABLE app.legacy",
+		"DROP NAMESPACE tmp",
+	)
+
+	assert.Equal(t, [][]string{{"app"}}, s.Namespaces)
+	require.Len(t, s.Tables, 1)
+	assert.Equal(t, &Table{
+		Ident: Ident{Namespace: []string{"app"}, Table: "users"},
+		Columns: []Field{
+			{Name: "id", Type: IcebergType{Kind: Long}},
+			{Name: "ts", Type: IcebergType{Kind: TimestampTz}},
+			{Name: "mail", Type: IcebergType{Kind: String}},
+		},
+		Partition: []PartitionField{{Transform: Bucket, Param: 8, SourceCol: "id"}},
+		Sort: SortSpec{Fields: []SortField{
+			{Transform: Identity, SourceCol: "ts", Direction: SortDesc, NullOrder: NullsLast},
+		}},
+		Props: map[string]string{},
+	}, s.Tables[0])
+}
+
+// TestSchema_Apply_IfExists verifies that IF [NOT] EXISTS skips the operation instead of failing.
+func TestSchema_Apply_IfExists(t *testing.T) {
+	t.Parallel()
+
+	s := replay(t,
+		"CREATE NAMESPACE app",
+		"CREATE NAMESPACE IF NOT EXISTS app",
+		"CREATE TABLE app.users (id bigint)",
+		"CREATE TABLE IF NOT EXISTS app.users (id int, name string)",
+		"DROP TABLE IF EXISTS app.old",
+		"DROP NAMESPACE IF EXISTS tmp",
+	)
+
+	require.Len(t, s.Tables, 1)
+	assert.Equal(t, []Field{{Name: "id", Type: IcebergType{Kind: Long}}}, s.Tables[0].Columns)
+}
+
+// TestSchema_Apply_Failure verifies that an operation that does not match the schema returns ErrSchema.
+func TestSchema_Apply_Failure(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		stmt string
+	}{
+		{name: "namespace exists", stmt: "CREATE NAMESPACE app"},
+		{name: "namespace does not exist", stmt: "DROP NAMESPACE tmp"},
+		{name: "table exists", stmt: "CREATE TABLE app.users (id int)"},
+		{name: "table does not exist", stmt: "DROP TABLE app.old"},
+		{name: "alter missing table", stmt: "ALTER TABLE app.old ADD COLUMN id int"},
+		{name: "rename to existing table", stmt: "RENAME TABLE app.users TO app.users"},
+		{name: "column exists", stmt: "ALTER TABLE app.users ADD COLUMN ID int"},
+		{name: "column does not exist", stmt: "ALTER TABLE app.users DROP COLUMN name"},
+		{name: "rename to existing column", stmt: "ALTER TABLE app.users RENAME COLUMN id TO ts"},
+		{name: "partition field exists", stmt: "ALTER TABLE app.users ADD PARTITION FIELD days(ts)"},
+		{name: "partition field does not exist", stmt: "ALTER TABLE app.users DROP PARTITION FIELD hours(ts)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := replay(t,
+				"CREATE NAMESPACE app",
+				"CREATE TABLE app.users (id bigint, ts timestamp) PARTITIONED BY (days(ts))",
+			)
+			op, err := Parse("", tt.stmt)
+			require.NoError(t, err)
+
+			require.ErrorIs(t, s.Apply(op), ErrSchema)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package source
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob" // registers the s3:// bucket scheme
+)
+
+// Schemes of the migration sources.
+const (
+	SchemeFile  = "file"
+	SchemeEmbed = "embed"
+	SchemeS3    = "s3"
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+)
+
+var (
+	// ErrUnsupportedScheme indicates that the migration path is a URL of an unknown scheme.
+	ErrUnsupportedScheme = errors.New("unsupported migration source scheme")
+
+	// ErrEmbeddedFSMissing indicates an embed:// migration path without an embedded file system to read it from.
+	ErrEmbeddedFSMissing = errors.New("embed:// migration source requires an embedded file system")
+
+	// ErrArchiveTooLarge indicates an HTTP migration archive whose files exceed maxArchiveSize.
+	ErrArchiveTooLarge = errors.New("migration archive is too large")
+)
+
+// maxArchiveSize limits the unpacked size of an HTTP migration archive.
+const maxArchiveSize = 256 << 20
+
+// archiveDownloadTimeout limits the download of an HTTP migration archive, so a stalled server fails the run.
+const archiveDownloadTimeout = 5 * time.Minute
+
+// httpClient downloads the HTTP migration archives.
+var httpClient = &http.Client{Timeout: archiveDownloadTimeout}
+
+// Source is the location the migration files are read from.
+type Source struct {
+	// FS is the file system of the migration files, nil for the OS file system.
+	FS fs.FS
+	// Directory is the directory of the migration files, a path within FS when it is set.
+	Directory string
+
+	closer io.Closer
+}
+
+// Close releases the resources held by the source, such as a bucket connection.
+func (s *Source) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	return s.closer.Close()
+}
+
+// Open opens the migration source at location, picking the source by its URL scheme:
+//   - a plain path is a directory on the OS file system, or within embedded when it is set;
+//   - file://path is a directory on the OS file system;
+//   - embed://path is a directory within embedded;
+//   - s3://bucket/path?endpoint=...&use_path_style=true is a directory in an S3 compatible bucket;
+//   - http(s)://host/archive.tar.gz#path is a directory in a gzipped tar archive, downloaded once.
+func Open(ctx context.Context, location string, embedded fs.FS) (*Source, error) {
+	if !IsURL(location) {
+		return newFSSource(embedded, location), nil
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	switch u.Scheme {
+	case SchemeFile:
+		return &Source{Directory: filepath.FromSlash(u.Host + u.Path)}, nil
+	case SchemeEmbed:
+		if embedded == nil {
+			return nil, errors.WithStack(ErrEmbeddedFSMissing)
+		}
+
+		return newFSSource(embedded, fsDirectory(u.Host+u.Path)), nil
+	case SchemeS3:
+		return openBucket(ctx, u)
+	case SchemeHTTP, SchemeHTTPS:
+		return openArchive(ctx, u)
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedScheme, "%q", u.Scheme)
+	}
+}
+
+// OpenLazy returns the migration source at location as Open does, except that an S3 bucket or an HTTP archive
+// is opened on the first read of its files rather than at once, so the commands that do not read
+// the migrations do not connect to the bucket or download the archive.
+func OpenLazy(ctx context.Context, location string, embedded fs.FS) (*Source, error) {
+	if !IsURL(location) {
+		return Open(ctx, location, embedded)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var directory string
+	switch u.Scheme {
+	case SchemeS3:
+		directory = fsDirectory(u.Path)
+	case SchemeHTTP, SchemeHTTPS:
+		directory = fsDirectory(u.Fragment)
+	default:
+		return Open(ctx, location, embedded)
+	}
+
+	lazy := &lazyFS{open: func() (*Source, error) {
+		return Open(ctx, location, embedded)
+	}}
+
+	return &Source{FS: lazy, Directory: directory, closer: lazy}, nil
+}
+
+// IsURL reports whether the location is a URL rather than an OS path, such as C:\migrations.
+func IsURL(location string) bool {
+	u, err := url.Parse(location)
+
+	return err == nil && len(u.Scheme) > 1
+}
+
+func newFSSource(fsys fs.FS, directory string) *Source {
+	if fsys != nil {
+		directory = fsDirectory(directory)
+	}
+
+	return &Source{FS: fsys, Directory: directory}
+}
+
+// fsDirectory converts a directory into a path within a file system, "." for its root.
+func fsDirectory(directory string) string {
+	directory = strings.Trim(path.Clean("/"+filepath.ToSlash(directory)), "/")
+	if directory == "" {
+		return "."
+	}
+
+	return directory
+}
+
+// openBucket opens the bucket named by the URL host; the URL path is the directory within the bucket.
+func openBucket(ctx context.Context, u *url.URL) (*Source, error) {
+	bucketURL := url.URL{Scheme: u.Scheme, Host: u.Host, RawQuery: u.RawQuery}
+	bucket, err := blob.OpenBucket(ctx, bucketURL.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening bucket %s", u.Host)
+	}
+	bucket.SetIOFSCallback(func() (context.Context, *blob.ReaderOptions) {
+		return ctx, nil
+	})
+
+	return &Source{FS: bucket, Directory: fsDirectory(u.Path), closer: bucket}, nil
+}
+
+// openArchive downloads a gzipped tar archive and unpacks it into memory.
+// The URL fragment is the directory within the archive, e.g. the top directory of a git tag archive.
+func openArchive(ctx context.Context, u *url.URL) (*Source, error) {
+	archiveURL := *u
+	archiveURL.Fragment = ""
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL.String(), http.NoBody)
+	if err != nil {
+		return nil, errors.Wrapf(err, "downloading %s", archiveURL.Redacted())
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "downloading %s", archiveURL.Redacted())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("downloading %s: unexpected status %s", archiveURL.Redacted(), resp.Status)
+	}
+
+	bucket := memblob.OpenBucket(nil)
+	if err := unpack(ctx, bucket, resp.Body); err != nil {
+		_ = bucket.Close()
+		return nil, errors.WithMessagef(err, "unpacking %s", archiveURL.Redacted())
+	}
+
+	return &Source{FS: bucket, Directory: fsDirectory(u.Fragment), closer: bucket}, nil
+}
+
+// unpack writes the regular files of a gzipped tar archive into the bucket.
+func unpack(ctx context.Context, bucket *blob.Bucket, r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(io.LimitReader(gz, maxArchiveSize+1))
+	var size int64
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		size += header.Size
+		if size > maxArchiveSize {
+			return errors.WithStack(ErrArchiveTooLarge)
+		}
+
+		key := fsDirectory(header.Name)
+		if !fs.ValidPath(key) || key == "." {
+			continue
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := bucket.WriteAll(ctx, key, data, nil); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+}
+
+// lazyFS is the file system of a source opened on the first call of its methods.
+type lazyFS struct {
+	open func() (*Source, error)
+
+	once   sync.Once
+	source *Source
+	err    error
+}
+
+// Open opens the named file of the source.
+func (l *lazyFS) Open(name string) (fs.File, error) {
+	fsys, err := l.openFS()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return fsys.Open(name)
+}
+
+// ReadDir reads the named directory of the source.
+func (l *lazyFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fsys, err := l.openFS()
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	return fs.ReadDir(fsys, name)
+}
+
+// Close releases the source if it was opened.
+func (l *lazyFS) Close() error {
+	if l.source == nil {
+		return nil
+	}
+
+	return l.source.Close()
+}
+
+// openFS opens the source once and returns its file system.
+func (l *lazyFS) openFS() (fs.FS, error) {
+	l.once.Do(func() {
+		l.source, l.err = l.open()
+	})
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return l.source.FS, nil
+}
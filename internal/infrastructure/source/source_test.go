@@ -0,0 +1,225 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gocloud.dev/blob"
+)
+
+func TestIsURL(t *testing.T) {
+	assert.True(t, IsURL("s3://bucket/migrations"))
+	assert.True(t, IsURL("file:///var/migrations"))
+	assert.False(t, IsURL("./migrations"))
+	assert.False(t, IsURL("/var/migrations"))
+	assert.False(t, IsURL(`C:\migrations`))
+}
+
+func TestOpen_Path(t *testing.T) {
+	src, err := Open(t.Context(), "./migrations", nil)
+	require.NoError(t, err)
+	assert.Nil(t, src.FS)
+	assert.Equal(t, "./migrations", src.Directory)
+	assert.NoError(t, src.Close())
+}
+
+func TestOpen_File(t *testing.T) {
+	src, err := Open(t.Context(), "file:///var/migrations", nil)
+	require.NoError(t, err)
+	assert.Nil(t, src.FS)
+	assert.Equal(t, "/var/migrations", src.Directory)
+
+	src, err = Open(t.Context(), "file://./migrations", fstest.MapFS{})
+	require.NoError(t, err)
+	assert.Nil(t, src.FS)
+	assert.Equal(t, "./migrations", src.Directory)
+}
+
+func TestOpen_PathWithinEmbedded(t *testing.T) {
+	embedded := fstest.MapFS{}
+
+	src, err := Open(t.Context(), "./migrations/", embedded)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FS(embedded), src.FS)
+	assert.Equal(t, "migrations", src.Directory)
+}
+
+func TestOpen_Embed(t *testing.T) {
+	embedded := fstest.MapFS{}
+
+	src, err := Open(t.Context(), "embed://migrations/postgres", embedded)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FS(embedded), src.FS)
+	assert.Equal(t, "migrations/postgres", src.Directory)
+
+	src, err = Open(t.Context(), "embed://", embedded)
+	require.NoError(t, err)
+	assert.Equal(t, ".", src.Directory)
+}
+
+func TestOpen_Embed_WithoutFS_Failure(t *testing.T) {
+	_, err := Open(t.Context(), "embed://migrations", nil)
+	assert.ErrorIs(t, err, ErrEmbeddedFSMissing)
+}
+
+func TestOpen_UnsupportedScheme_Failure(t *testing.T) {
+	_, err := Open(t.Context(), "ftp://host/migrations", nil)
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+}
+
+func TestOpen_Archive(t *testing.T) {
+	archive := newArchive(t, map[string]string{
+		"repo-v1.2.0/migrations/200101_120000_init.up.sql":   "CREATE TABLE t (id int);",
+		"repo-v1.2.0/migrations/200101_120000_init.down.sql": "DROP TABLE t;",
+		"repo-v1.2.0/README.md":                              "readme",
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/archive/v1.2.0.tar.gz", r.URL.Path)
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	src, err := Open(t.Context(), server.URL+"/archive/v1.2.0.tar.gz#repo-v1.2.0/migrations", nil)
+	require.NoError(t, err)
+	defer src.Close()
+
+	assert.Equal(t, "repo-v1.2.0/migrations", src.Directory)
+	files, err := fs.Glob(src.FS, src.Directory+"/*.up.sql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"repo-v1.2.0/migrations/200101_120000_init.up.sql"}, files)
+
+	data, err := fs.ReadFile(src.FS, "repo-v1.2.0/migrations/200101_120000_init.down.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "DROP TABLE t;", string(data))
+}
+
+func TestOpenLazy_Archive_DownloadedOnFirstRead(t *testing.T) {
+	archive := newArchive(t, map[string]string{
+		"repo-v1.2.0/migrations/200101_120000_init.up.sql": "CREATE TABLE t (id int);",
+	})
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		downloads++
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	src, err := OpenLazy(t.Context(), server.URL+"/archive/v1.2.0.tar.gz#repo-v1.2.0/migrations", nil)
+	require.NoError(t, err)
+	defer src.Close()
+
+	assert.Equal(t, "repo-v1.2.0/migrations", src.Directory)
+	assert.NotNil(t, src.FS)
+	assert.Zero(t, downloads)
+
+	files, err := fs.Glob(src.FS, src.Directory+"/*.up.sql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"repo-v1.2.0/migrations/200101_120000_init.up.sql"}, files)
+
+	data, err := fs.ReadFile(src.FS, "repo-v1.2.0/migrations/200101_120000_init.up.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE t (id int);", string(data))
+	assert.Equal(t, 1, downloads)
+}
+
+func TestOpenLazy_Archive_NotFound_FailsOnRead(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	src, err := OpenLazy(t.Context(), server.URL+"/archive/v1.2.0.tar.gz", nil)
+	require.NoError(t, err)
+
+	_, err = fs.ReadFile(src.FS, "200101_120000_init.up.sql")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+	assert.NoError(t, src.Close())
+}
+
+func TestOpenLazy_UnsupportedScheme_Failure(t *testing.T) {
+	_, err := OpenLazy(t.Context(), "ftp://host/migrations", nil)
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+}
+
+func TestOpen_Archive_NotFound_Failure(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := Open(t.Context(), server.URL+"/archive/v1.2.0.tar.gz", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestOpen_Archive_NotGzip_Failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("not an archive"))
+	}))
+	defer server.Close()
+
+	_, err := Open(t.Context(), server.URL+"/archive.tar.gz", nil)
+	assert.Error(t, err)
+}
+
+func TestOpenBucket(t *testing.T) {
+	u, err := url.Parse("mem://bucket/migrations/")
+	require.NoError(t, err)
+
+	src, err := openBucket(t.Context(), u)
+	require.NoError(t, err)
+	defer src.Close()
+
+	bucket, ok := src.FS.(*blob.Bucket)
+	require.True(t, ok)
+	require.NoError(t, bucket.WriteAll(t.Context(), "migrations/200101_120000_init.up.sql", []byte("SELECT 1;"), nil))
+
+	assert.Equal(t, "migrations", src.Directory)
+	files, err := fs.Glob(src.FS, src.Directory+"/*.up.sql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"migrations/200101_120000_init.up.sql"}, files)
+}
+
+func TestOpenBucket_UnknownBucketScheme_Failure(t *testing.T) {
+	u, err := url.Parse("unknown://bucket")
+	require.NoError(t, err)
+
+	_, err = openBucket(t.Context(), u)
+	assert.Error(t, err)
+}
+
+func newArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for name, data := range files {
+		require.NoError(t, archive.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := archive.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	require.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
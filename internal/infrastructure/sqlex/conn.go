@@ -0,0 +1,58 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package sqlex
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Conn wraps a single database/sql connection taken out of the pool.
+// Statements executed through it always run in the same database session,
+// which is required for session-level state such as advisory locks.
+type Conn struct {
+	*sql.Conn
+}
+
+// Conn returns a single connection from the pool.
+// The connection must be closed to return it to the pool.
+func (db *DB) Conn(ctx context.Context) (*Conn, error) {
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{Conn: conn}, nil
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT.
+// The args are for any placeholder parameters in the query.
+//
+//nolint:ireturn,nolintlint // its ok
+func (c *Conn) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	rows, err := c.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRowsWithSQLRows(rows), nil
+}
+
+// ExecContext executes a query without returning any rows.
+// The args are for any placeholder parameters in the query.
+//
+//nolint:ireturn,nolintlint // its ok
+func (c *Conn) ExecContext(ctx context.Context, query string, args ...any) (Result, error) {
+	return c.Conn.ExecContext(ctx, query, args...)
+}
+
+// Close returns the connection to the pool.
+func (c *Conn) Close() error {
+	return c.Conn.Close()
+}
@@ -123,6 +123,37 @@ func (tx *tx) ExecContext(ctx context.Context, query string, args ...any) (sqlex
 	return Done(true), nil
 }
 
+// QueryContext evaluates a Lua script within the transaction and returns its result as rows.
+// It returns ErrTransactionAlreadyClosed if the transaction has already been closed.
+//
+//nolint:ireturn,nolintlint // its ok
+func (tx *tx) QueryContext(ctx context.Context, query string, args ...any) (sqlex.Rows, error) {
+	tx.mu.RLock()
+	defer tx.mu.RUnlock()
+
+	if tx.closed {
+		return nil, errors.WithStack(ErrTransactionAlreadyClosed)
+	}
+
+	req := tarantool.NewEvalRequest(query).Context(ctx)
+	if len(args) > 0 {
+		req = req.Args(args)
+	}
+
+	data, err := tx.stream.Do(req).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 1 {
+		if indata, ok := data[0].([]any); ok {
+			data = indata
+		}
+	}
+
+	return sqlex.NewRowsWithSlice(data), nil
+}
+
 // PrepareContext creates a prepared statement for use within the transaction.
 // It returns ErrTransactionAlreadyClosed if the transaction has already been closed.
 //
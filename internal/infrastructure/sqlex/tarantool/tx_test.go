@@ -214,6 +214,39 @@ func TestTx_ExecContext_AlreadyClosed_Failure(t *testing.T) {
 	require.ErrorIs(t, err, ErrTransactionAlreadyClosed)
 }
 
+func TestTx_QueryContext_StreamError_Failure(t *testing.T) {
+	ctx := context.Background()
+	stream := NewMockStreamDoer(t)
+
+	query := "return box.space.test:select()"
+	req := tarantool.NewEvalRequest(query).Context(ctx)
+	fut := tarantool.NewFuture(req)
+	fut.SetError(errExecFailed)
+
+	stream.EXPECT().
+		Do(req).
+		Return(fut)
+
+	tx := newTxWithStreamDoer(stream)
+
+	rows, err := tx.QueryContext(ctx, query)
+
+	require.Nil(t, rows)
+	require.ErrorIs(t, err, errExecFailed)
+}
+
+func TestTx_QueryContext_AlreadyClosed_Failure(t *testing.T) {
+	tx := &tx{
+		stream: nil,
+		closed: true,
+	}
+
+	rows, err := tx.QueryContext(context.Background(), "return true")
+
+	require.Nil(t, rows)
+	require.ErrorIs(t, err, ErrTransactionAlreadyClosed)
+}
+
 func TestTx_PrepareContext_AlreadyClosed_Failure(t *testing.T) {
 	tx := &tx{
 		stream: nil,
@@ -19,6 +19,7 @@ type Tx interface {
 	Rollback() error
 	Commit() error
 	ExecContext(ctx context.Context, query string, args ...any) (Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (Rows, error)
 	PrepareContext(ctx context.Context, query string) (Stmt, error)
 }
 
@@ -52,3 +53,15 @@ func (s *sqlTx) PrepareContext(ctx context.Context, query string) (Stmt, error)
 func (s *sqlTx) ExecContext(ctx context.Context, query string, args ...any) (Result, error) {
 	return s.Tx.ExecContext(ctx, query, args...)
 }
+
+// QueryContext executes a query within the transaction that returns rows.
+//
+//nolint:ireturn,nolintlint // its ok
+func (s *sqlTx) QueryContext(ctx context.Context, query string, args ...any) (Rows, error) {
+	rows, err := s.Tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRowsWithSQLRows(rows), nil
+}
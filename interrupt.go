@@ -0,0 +1,47 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package dbmigrator
+
+import (
+	"context"
+
+	"github.com/raoptimus/db-migrator.go/internal/application/handler"
+	"github.com/raoptimus/db-migrator.go/internal/domain/service"
+)
+
+// ErrInterrupted is returned for a migration that was not started because the run was interrupted.
+var ErrInterrupted = service.ErrInterrupted
+
+// IncompleteError is returned for a migration that failed after some of its statements were committed
+// outside a transaction, leaving the database partially migrated. An applied migration is recorded as failed
+// in the history table, so the next runs refuse to start until it is repaired and recorded with DBService.Force.
+type IncompleteError = service.IncompleteError
+
+// States a migration repaired by hand is recorded in by DBService.Force.
+const (
+	// ForceApplied records the migration as applied without running it.
+	ForceApplied = service.ForceApplied
+	// ForceReverted removes the migration from the history table without reverting it.
+	ForceReverted = service.ForceReverted
+)
+
+// ErrDirtyDatabase is returned when a migration is left pending or failed by an earlier run,
+// see Options.AllowDirty and DBService.Force.
+var ErrDirtyDatabase = handler.ErrDirtyDatabase
+
+// ErrResumeChanged is returned when resuming a migration whose statements committed by the earlier run differ
+// from the ones in its file, see Options.Resume.
+var ErrResumeChanged = service.ErrResumeChanged
+
+// WithInterrupt returns a context that asks the DBService methods run with it to stop once interrupt is closed,
+// e.g. on SIGTERM: the migration in progress runs to its end and no next migration is started.
+// Canceling the context itself stops the migration in progress at once.
+func WithInterrupt(ctx context.Context, interrupt <-chan struct{}) context.Context {
+	return service.WithInterrupt(ctx, interrupt)
+}
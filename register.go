@@ -0,0 +1,60 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package dbmigrator
+
+import (
+	"context"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/service"
+)
+
+// MigrationFunc is the body of a migration written in Go.
+// When the migration runs inside a transaction, ctx carries it and the statements
+// executed through conn with that ctx join the transaction.
+type MigrationFunc func(ctx context.Context, conn Connection) error
+
+// registry holds the migrations registered with Register and RegisterSafe.
+var registry = service.NewGoMigrations()
+
+// Register registers a migration written in Go. It is ordered and tracked in the history table
+// together with the migration files and is applied and reverted like them.
+// The version must follow the YYMMDD_hhmmss_name format of migration files, down may be nil
+// for an irreversible migration.
+// Register is meant to be called from init functions and panics when the version is invalid,
+// already registered or up is nil.
+func Register(version string, up, down MigrationFunc) {
+	register(version, up, down, false)
+}
+
+// RegisterSafe registers a migration written in Go like Register, but the migration runs
+// inside a transaction like a .safe.up.sql migration file.
+func RegisterSafe(version string, up, down MigrationFunc) {
+	register(version, up, down, true)
+}
+
+func register(version string, up, down MigrationFunc, safe bool) {
+	if err := registry.Add(&service.GoMigration{
+		Version: version,
+		Up:      goMigrationFunc(up),
+		Down:    goMigrationFunc(down),
+		Safe:    safe,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func goMigrationFunc(fn MigrationFunc) service.GoMigrationFunc {
+	if fn == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, conn service.Connection) error {
+		return fn(ctx, conn)
+	}
+}
@@ -0,0 +1,86 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package dbmigrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/raoptimus/db-migrator.go/internal/domain/service"
+	"github.com/stretchr/testify/require"
+)
+
+func resetRegistry(t *testing.T) {
+	t.Helper()
+	registry = service.NewGoMigrations()
+	t.Cleanup(func() {
+		registry = service.NewGoMigrations()
+	})
+}
+
+func TestRegister_Successfully(t *testing.T) {
+	resetRegistry(t)
+	conn := NewMockConnection(t)
+	var upConn Connection
+
+	Register("200101_120000_backfill", func(_ context.Context, c Connection) error {
+		upConn = c
+		return nil
+	}, nil)
+	RegisterSafe("200102_120000_seed", func(context.Context, Connection) error {
+		return nil
+	}, func(context.Context, Connection) error {
+		return nil
+	})
+
+	backfill, ok := registry.Get("200101_120000_backfill")
+	require.True(t, ok)
+	require.False(t, backfill.Safe)
+	require.Nil(t, backfill.Down)
+	require.NoError(t, backfill.Up(context.Background(), conn))
+	require.Same(t, conn, upConn)
+
+	seed, ok := registry.Get("200102_120000_seed")
+	require.True(t, ok)
+	require.True(t, seed.Safe)
+	require.NotNil(t, seed.Down)
+}
+
+func TestRegister_Panics(t *testing.T) {
+	up := func(context.Context, Connection) error { return nil }
+
+	tests := []struct {
+		name     string
+		register func()
+	}{
+		{
+			name:     "invalid version",
+			register: func() { Register("backfill", up, nil) },
+		},
+		{
+			name:     "up is nil",
+			register: func() { Register("200101_120000_backfill", nil, nil) },
+		},
+		{
+			name: "already registered",
+			register: func() {
+				Register("200101_120000_backfill", up, nil)
+				RegisterSafe("200101_120000_backfill", up, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+
+			require.Panics(t, tt.register)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package dbmigrator
+
+import (
+	"time"
+
+	"github.com/raoptimus/db-migrator.go/internal/application/presenter"
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+)
+
+// MigrationState describes a migration version as seen from the history table and the migration files.
+type MigrationState = model.MigrationState
+
+const (
+	// StateApplied means the migration is applied and its file exists.
+	StateApplied = model.StateApplied
+	// StatePending means the migration file exists but the migration is not applied yet.
+	StatePending = model.StatePending
+	// StateOutOfOrder means the migration is pending although a newer migration is already applied.
+	StateOutOfOrder = model.StateOutOfOrder
+	// StateMissingFile means the migration is applied but its file no longer exists.
+	StateMissingFile = model.StateMissingFile
+	// StateDirty means the migration is left pending or failed by an earlier run, see DBService.Force.
+	StateDirty = model.StateDirty
+)
+
+type (
+	// MigrationResult is a migration applied or reverted by a command.
+	MigrationResult struct {
+		Version  string
+		Duration time.Duration
+	}
+
+	// Result is the result of a command that applies or reverts migrations.
+	// When the command fails, it holds the migrations run before the failure.
+	Result struct {
+		// Applied are the applied migrations in the order they were applied.
+		Applied []MigrationResult
+		// Reverted are the reverted migrations in the order they were reverted.
+		Reverted []MigrationResult
+	}
+
+	// Migration is a migration listed by History and Pending.
+	Migration struct {
+		Version string
+		// ApplyTime is the time the migration was applied, zero for a pending migration.
+		ApplyTime time.Time
+	}
+
+	// MigrationStatus is the state of a single migration version.
+	MigrationStatus struct {
+		Migration
+		State MigrationState
+	}
+
+	// StatusResult is the state of every migration version in version order.
+	StatusResult struct {
+		Migrations []MigrationStatus
+	}
+)
+
+// Count returns the number of migrations in the given state.
+func (s *StatusResult) Count(state MigrationState) int {
+	count := 0
+	for i := range s.Migrations {
+		if s.Migrations[i].State == state {
+			count++
+		}
+	}
+
+	return count
+}
+
+// UpToDate reports whether all migrations are applied and their files exist.
+func (s *StatusResult) UpToDate() bool {
+	return s.Count(StateApplied) == len(s.Migrations)
+}
+
+// newResult returns the migrations applied and reverted as recorded by the recorder.
+func newResult(recorder *presenter.Recorder) *Result {
+	return &Result{
+		Applied:  toMigrationResults(recorder.Applied),
+		Reverted: toMigrationResults(recorder.Reverted),
+	}
+}
+
+// newMigrations returns the migrations listed as recorded by the recorder.
+func newMigrations(recorder *presenter.Recorder) []Migration {
+	migrations := make([]Migration, 0, recorder.Migrations.Len())
+	for i := range recorder.Migrations {
+		migrations = append(migrations, toMigration(&recorder.Migrations[i]))
+	}
+
+	return migrations
+}
+
+// newStatusResult returns the migration statuses as recorded by the recorder.
+func newStatusResult(recorder *presenter.Recorder) *StatusResult {
+	statuses := make([]MigrationStatus, 0, recorder.Statuses.Len())
+	for i := range recorder.Statuses {
+		statuses = append(statuses, MigrationStatus{
+			Migration: toMigration(&recorder.Statuses[i].Migration),
+			State:     recorder.Statuses[i].State,
+		})
+	}
+
+	return &StatusResult{Migrations: statuses}
+}
+
+func toMigrationResults(recorded []presenter.RecordedMigration) []MigrationResult {
+	results := make([]MigrationResult, 0, len(recorded))
+	for _, migration := range recorded {
+		results = append(results, MigrationResult(migration))
+	}
+
+	return results
+}
+
+func toMigration(migration *model.Migration) Migration {
+	result := Migration{Version: migration.Version}
+	if migration.ApplyTime > 0 {
+		result.ApplyTime = time.Unix(migration.ApplyTime, 0)
+	}
+
+	return result
+}
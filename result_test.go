@@ -0,0 +1,59 @@
+/**
+ * This file is part of the raoptimus/db-migrator.go library
+ *
+ * @copyright Copyright (c) Evgeniy Urvantsev
+ * @license https://github.com/raoptimus/db-migrator.go/blob/master/LICENSE.md
+ * @link https://github.com/raoptimus/db-migrator.go
+ */
+
+package dbmigrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raoptimus/db-migrator.go/internal/application/presenter"
+	"github.com/raoptimus/db-migrator.go/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResult(t *testing.T) {
+	recorder := presenter.NewRecorder(nil)
+	recorder.Applied = []presenter.RecordedMigration{{Version: "200101_120000_init", Duration: time.Second}}
+
+	assert.Equal(t, &Result{
+		Applied:  []MigrationResult{{Version: "200101_120000_init", Duration: time.Second}},
+		Reverted: []MigrationResult{},
+	}, newResult(recorder))
+}
+
+func TestNewMigrations(t *testing.T) {
+	recorder := presenter.NewRecorder(nil)
+	recorder.Migrations = model.Migrations{
+		{Version: "200101_120000_init", ApplyTime: 1700000000},
+		{Version: "200102_120000_users"},
+	}
+
+	assert.Equal(t, []Migration{
+		{Version: "200101_120000_init", ApplyTime: time.Unix(1700000000, 0)},
+		{Version: "200102_120000_users"},
+	}, newMigrations(recorder))
+}
+
+func TestNewStatusResult(t *testing.T) {
+	recorder := presenter.NewRecorder(nil)
+	recorder.Statuses = model.MigrationStatuses{
+		{Migration: model.Migration{Version: "200101_120000_init", ApplyTime: 1700000000}, State: model.StateApplied},
+		{Migration: model.Migration{Version: "200102_120000_users"}, State: model.StatePending},
+	}
+
+	status := newStatusResult(recorder)
+
+	assert.Equal(t, []MigrationStatus{
+		{Migration: Migration{Version: "200101_120000_init", ApplyTime: time.Unix(1700000000, 0)}, State: StateApplied},
+		{Migration: Migration{Version: "200102_120000_users"}, State: StatePending},
+	}, status.Migrations)
+	assert.Equal(t, 1, status.Count(StatePending))
+	assert.False(t, status.UpToDate())
+	assert.True(t, (&StatusResult{}).UpToDate())
+}
@@ -10,11 +10,17 @@ package dbmigrator
 
 import (
 	"context"
+	"io/fs"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/raoptimus/db-migrator.go/internal/application/handler"
+	"github.com/raoptimus/db-migrator.go/internal/application/presenter"
+	"github.com/raoptimus/db-migrator.go/internal/domain/builder"
 	"github.com/raoptimus/db-migrator.go/internal/domain/validator"
 	"github.com/raoptimus/db-migrator.go/internal/infrastructure/log"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/source"
 )
 
 type (
@@ -28,6 +34,32 @@ type (
 		ClusterName string
 		// is replicated used to clickhouse?
 		Replicated bool
+		// directory of the migration files applied by Up and reverted by Down,
+		// or a URL of the migration source: file://, embed://, s3://, http(s):// tar.gz
+		Directory string
+		// file system of the migration files, e.g. an embed.FS; Directory is a path within it, "." by default.
+		// The OS file system is used when it is nil.
+		FS fs.FS
+		// variables substituted into the {name} placeholders of migration SQL
+		Vars map[string]string
+		// fail migrations with placeholders that have neither a value nor a default
+		StrictPlaceholders bool
+		// apply pending migrations older than the latest applied migration instead of failing
+		AllowOutOfOrder bool
+		// run even though a migration is left pending or failed by an earlier run instead of failing
+		AllowDirty bool
+		// continue migrations left pending or failed by an earlier run after their committed statements
+		Resume bool
+		// limit of the execution time of every migration statement, zero means no limit
+		StatementTimeout time.Duration
+		// limit of the execution time of every migration, zero means no limit
+		MigrationTimeout time.Duration
+		// limit of the time a statement waits for a table lock on the database side, zero means the database default
+		StatementLockTimeout time.Duration
+		// how many times a statement that gave up waiting for a lock is run again
+		RetryAttempts int
+		// pause before the first retry, doubled before every next one
+		RetryBackoff time.Duration
 	}
 
 	// DBService provides high-level operations for database migrations.
@@ -36,6 +68,7 @@ type (
 		opts   *handler.Options
 		logger Logger
 		conn   Connection
+		source *source.Source
 	}
 )
 
@@ -45,18 +78,35 @@ func NewDBService(opts *Options, conn Connection, logger Logger) (*DBService, er
 	if logger == nil {
 		logger = &log.NopLogger{}
 	}
+	src, err := source.Open(context.Background(), opts.Directory, opts.FS)
+	if err != nil {
+		return nil, err
+	}
 	options := &handler.Options{
-		DSN:                opts.DSN,
-		MaxConnAttempts:    1,
-		Directory:          "",
-		TableName:          opts.TableName,
-		ClusterName:        opts.ClusterName,
-		Replicated:         opts.Replicated,
-		Compact:            true,
-		Interactive:        true,
-		MaxSQLOutputLength: 0,
+		DSN:                  opts.DSN,
+		MaxConnAttempts:      1,
+		Directory:            src.Directory,
+		TableName:            opts.TableName,
+		ClusterName:          opts.ClusterName,
+		Replicated:           opts.Replicated,
+		Compact:              true,
+		Interactive:          false,
+		MaxSQLOutputLength:   0,
+		GoMigrations:         registry,
+		FS:                   src.FS,
+		Vars:                 opts.Vars,
+		StrictPlaceholders:   opts.StrictPlaceholders,
+		AllowOutOfOrder:      opts.AllowOutOfOrder,
+		AllowDirty:           opts.AllowDirty,
+		Resume:               opts.Resume,
+		StatementTimeout:     opts.StatementTimeout,
+		MigrationTimeout:     opts.MigrationTimeout,
+		StatementLockTimeout: opts.StatementLockTimeout,
+		RetryAttempts:        opts.RetryAttempts,
+		RetryBackoff:         opts.RetryBackoff,
 	}
 	if err := options.Validate(); err != nil {
+		_ = src.Close()
 		return nil, err
 	}
 
@@ -64,9 +114,16 @@ func NewDBService(opts *Options, conn Connection, logger Logger) (*DBService, er
 		opts:   options,
 		conn:   conn,
 		logger: logger,
+		source: src,
 	}, nil
 }
 
+// Close releases the migration source, such as a bucket connection or a downloaded archive.
+// The connection is not closed.
+func (d *DBService) Close() error {
+	return d.source.Close()
+}
+
 // Upgrade apply changes to db. apply specific version of migration.
 func (d *DBService) Upgrade(ctx context.Context, version, sql string, safety bool) error {
 	if err := validator.ValidateVersion(version); err != nil {
@@ -112,3 +169,211 @@ func (d *DBService) Downgrade(ctx context.Context, version, sql string, safety b
 
 	return serviceMigration.RevertSQL(ctx, safety, version, sql)
 }
+
+// UpAll applies all pending migrations in version order: the migration files in Options.Directory
+// together with the migrations registered with Register and RegisterSafe.
+// The migrations are applied under the migration lock.
+func (d *DBService) UpAll(ctx context.Context) (*Result, error) {
+	recorder := presenter.NewRecorder(d.logger)
+	err := d.handleWithLock(ctx, handler.NewUpgrade(d.opts, recorder, d.fileNameBuilder()), commandArgs{allSteps})
+
+	return newResult(recorder), err
+}
+
+// UpN applies the first n pending migrations in version order, like UpAll.
+func (d *DBService) UpN(ctx context.Context, n int) (*Result, error) {
+	recorder := presenter.NewRecorder(d.logger)
+	err := d.handleWithLock(ctx, handler.NewUpgrade(d.opts, recorder, d.fileNameBuilder()), steps(n))
+
+	return newResult(recorder), err
+}
+
+// DownN reverts the last n applied migrations, both migration files and the migrations
+// registered with Register and RegisterSafe.
+// The migrations are reverted under the migration lock.
+func (d *DBService) DownN(ctx context.Context, n int) (*Result, error) {
+	recorder := presenter.NewRecorder(d.logger)
+	err := d.handleWithLock(ctx, handler.NewDowngrade(d.opts, recorder, d.fileNameBuilder()), steps(n))
+
+	return newResult(recorder), err
+}
+
+// Redo reverts the last n applied migrations and applies them again, as the redo command does.
+// The migrations are redone under the migration lock.
+func (d *DBService) Redo(ctx context.Context, n int) (*Result, error) {
+	recorder := presenter.NewRecorder(d.logger)
+	err := d.handleWithLock(ctx, handler.NewRedo(d.opts, recorder, d.fileNameBuilder()), steps(n))
+
+	return newResult(recorder), err
+}
+
+// To migrates the database up or down to the given version, as the to command does.
+// The version is a full migration version or its timestamp, e.g. 200101_120000.
+// The migrations are applied or reverted under the migration lock.
+func (d *DBService) To(ctx context.Context, version string) (*Result, error) {
+	recorder := presenter.NewRecorder(d.logger)
+	err := d.handleWithLock(ctx, handler.NewTo(d.opts, recorder, d.fileNameBuilder()), commandArgs{version})
+
+	return newResult(recorder), err
+}
+
+// Release applies all pending migrations as one release, as the release command does.
+// The release is applied in a single transaction on drivers supporting transactional DDL
+// and can be reverted as a whole with Rollback.
+// The migrations are applied under the migration lock.
+func (d *DBService) Release(ctx context.Context) (*Result, error) {
+	recorder := presenter.NewRecorder(d.logger)
+	err := d.handleWithLock(ctx, handler.NewRelease(d.opts, recorder, d.fileNameBuilder()), commandArgs{})
+
+	return newResult(recorder), err
+}
+
+// Rollback reverts the migrations of the latest release, as the rollback command does.
+// The migrations are reverted under the migration lock.
+func (d *DBService) Rollback(ctx context.Context) (*Result, error) {
+	recorder := presenter.NewRecorder(d.logger)
+	err := d.handleWithLock(ctx, handler.NewRollback(d.opts, recorder, d.fileNameBuilder()), commandArgs{})
+
+	return newResult(recorder), err
+}
+
+// History returns the last limit applied migrations, the latest first,
+// all of them when limit is less than 1.
+func (d *DBService) History(ctx context.Context, limit int) ([]Migration, error) {
+	args := steps(limit)
+	if limit < 1 {
+		args = commandArgs{allSteps}
+	}
+
+	recorder := presenter.NewRecorder(d.logger)
+	if err := d.handle(ctx, handler.NewHistory(d.opts, recorder), args); err != nil {
+		return nil, err
+	}
+
+	return newMigrations(recorder), nil
+}
+
+// Pending returns all pending migrations in version order.
+func (d *DBService) Pending(ctx context.Context) ([]Migration, error) {
+	recorder := presenter.NewRecorder(d.logger)
+	if err := d.handle(ctx, handler.NewHistoryNew(d.opts, recorder), commandArgs{allSteps}); err != nil {
+		return nil, err
+	}
+
+	return newMigrations(recorder), nil
+}
+
+// Status returns the state of every migration version, as the status command shows it.
+// Unlike the command, it does not fail when the database is not up to date, see StatusResult.UpToDate.
+func (d *DBService) Status(ctx context.Context) (*StatusResult, error) {
+	recorder := presenter.NewRecorder(d.logger)
+	if err := d.handle(ctx, handler.NewStatus(d.opts, recorder, d.fileNameBuilder()), commandArgs{}); err != nil {
+		var statusErr *handler.StatusError
+		if !errors.As(err, &statusErr) {
+			return nil, err
+		}
+	}
+
+	return newStatusResult(recorder), nil
+}
+
+// Force records the migration of version, repaired by hand, as applied or reverted without running it,
+// as the force command does. The state is ForceApplied or ForceReverted.
+func (d *DBService) Force(ctx context.Context, version, state string) error {
+	return d.withLock(ctx, func(svc handler.MigrationService) error {
+		return svc.Force(ctx, version, state)
+	})
+}
+
+// DumpSchema returns the current schema of the database as the database introspects it,
+// without the migration history and lock tables, as the dump command writes it into the dump file.
+func (d *DBService) DumpSchema(ctx context.Context) (string, error) {
+	serviceMigration, err := handler.NewMigrationService(d.opts, d.logger, d.conn)
+	if err != nil {
+		return "", err
+	}
+
+	return serviceMigration.DumpSchema(ctx)
+}
+
+// fileNameBuilder returns the builder of the migration file names in Options.Directory, within Options.FS when it is set.
+func (d *DBService) fileNameBuilder() *builder.FileName {
+	return builder.NewFileName(handler.NewMigrationFile(d.opts), d.opts.Directory)
+}
+
+// handleWithLock runs the command handler with args under the migration lock.
+// It fails with ErrDirtyDatabase when a migration is left pending or failed by an earlier run,
+// unless Options.AllowDirty is set.
+func (d *DBService) handleWithLock(ctx context.Context, h handler.ServiceHandler, args handler.Args) error {
+	return d.withLock(ctx, func(svc handler.MigrationService) error {
+		if err := handler.RefuseDirty(ctx, d.opts, svc); err != nil {
+			return err
+		}
+
+		cmd := &handler.Command{Args: args}
+
+		return h.Handle(cmd.WithContext(ctx), svc)
+	})
+}
+
+// withLock runs fn with the migration service under the migration lock.
+func (d *DBService) withLock(ctx context.Context, fn func(svc handler.MigrationService) error) (err error) {
+	serviceMigration, err := handler.NewMigrationService(d.opts, d.logger, d.conn)
+	if err != nil {
+		return err
+	}
+
+	if err := serviceMigration.Lock(ctx); err != nil {
+		return errors.WithMessage(err, "failed to take migration lock")
+	}
+	defer func() {
+		// The lock must be released even when the context was canceled.
+		if unlockErr := serviceMigration.Unlock(context.WithoutCancel(ctx)); unlockErr != nil && err == nil {
+			err = errors.WithMessage(unlockErr, "failed to release migration lock")
+		}
+	}()
+
+	return fn(serviceMigration)
+}
+
+// handle runs the command handler with args.
+func (d *DBService) handle(ctx context.Context, h handler.ServiceHandler, args handler.Args) error {
+	serviceMigration, err := handler.NewMigrationService(d.opts, d.logger, d.conn)
+	if err != nil {
+		return err
+	}
+
+	cmd := &handler.Command{Args: args}
+
+	return h.Handle(cmd.WithContext(ctx), serviceMigration)
+}
+
+// allSteps is the step argument of the commands that run on all migrations.
+const allSteps = "all"
+
+// steps returns the step argument n of the commands.
+func steps(n int) commandArgs {
+	return commandArgs{strconv.Itoa(n)}
+}
+
+// commandArgs is the list of command arguments.
+type commandArgs []string
+
+// First returns the first argument, or else a blank string.
+func (a commandArgs) First() string {
+	if len(a) == 0 {
+		return ""
+	}
+
+	return a[0]
+}
+
+// Present checks if there are any arguments present.
+func (a commandArgs) Present() bool {
+	return len(a) > 0
+}
+
+// Slice returns a copy of the arguments.
+func (a commandArgs) Slice() []string {
+	return append([]string(nil), a...)
+}
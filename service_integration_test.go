@@ -0,0 +1,270 @@
+//#go:build integration
+
+package dbmigrator
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"github.com/raoptimus/db-migrator.go/internal/infrastructure/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gocloud.dev/blob"
+)
+
+func TestIntegration_DBService_UpDownWithGoMigrations_Successfully(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if err := godotenv.Load(".env"); err != nil {
+		require.NoError(t, err, "Load environments")
+	}
+
+	resetRegistry(t)
+	// runs between the table and the trigger migration files
+	RegisterSafe("200905_200000_seed_test_table",
+		func(ctx context.Context, conn Connection) error {
+			_, err := conn.ExecContext(ctx, "insert into test (id, rank, title) values (10, 0, 'seeded from go')")
+			return err
+		},
+		func(ctx context.Context, conn Connection) error {
+			_, err := conn.ExecContext(ctx, "delete from test where id = 10")
+			return err
+		},
+	)
+
+	directory, err := filepath.Abs(os.Getenv("POSTGRES_MIGRATIONS_PATH"))
+	require.NoError(t, err)
+
+	conn, err := TryConnection(os.Getenv("POSTGRES_DSN"), 1)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	svc, err := NewDBService(&Options{
+		DSN:       os.Getenv("POSTGRES_DSN"),
+		TableName: "migration",
+		Directory: directory,
+	}, conn, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, _ = svc.DownN(ctx, 10)
+	})
+
+	// 2 migration files and the migration written in Go
+	result, err := svc.UpN(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"200905_192800_create_test_table",
+		"200905_200000_seed_test_table",
+		"200905_202800_create_test_table_trigger",
+	}, resultVersions(result.Applied))
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from migration", 4) // base + 3 migrations
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from test", 2)
+
+	result, err = svc.DownN(ctx, 2) // the trigger migration file and the migration written in Go
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"200905_202800_create_test_table_trigger",
+		"200905_200000_seed_test_table",
+	}, resultVersions(result.Reverted))
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from migration", 2)
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from test", 1) // the row of the trigger migration file
+
+	_, err = svc.DownN(ctx, 1)
+	require.NoError(t, err)
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from migration", 1) // base
+}
+
+func TestIntegration_DBService_UpToFromFS_Successfully(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if err := godotenv.Load(".env"); err != nil {
+		require.NoError(t, err, "Load environments")
+	}
+
+	resetRegistry(t)
+
+	conn, err := TryConnection(os.Getenv("POSTGRES_DSN"), 1)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	svc, err := NewDBService(&Options{
+		DSN:       os.Getenv("POSTGRES_DSN"),
+		TableName: "migration",
+		FS:        os.DirFS(os.Getenv("POSTGRES_MIGRATIONS_PATH")),
+	}, conn, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, _ = svc.DownN(ctx, 10)
+	})
+
+	_, err = svc.UpN(ctx, 1)
+	require.NoError(t, err)
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from migration", 2) // base + 1 migration
+
+	result, err := svc.To(ctx, "200905_202800")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"200905_202800_create_test_table_trigger"}, resultVersions(result.Applied))
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from migration", 3)
+
+	_, err = svc.DownN(ctx, 2)
+	require.NoError(t, err)
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from migration", 1) // base
+}
+
+func TestIntegration_DBService_UpFromS3_Successfully(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if err := godotenv.Load(".env"); err != nil {
+		require.NoError(t, err, "Load environments")
+	}
+
+	resetRegistry(t)
+
+	ctx := context.Background()
+	uploadMigrations(t, ctx, os.Getenv("POSTGRES_MIGRATIONS_PATH"), os.Getenv("S3_MIGRATIONS_PATH"))
+
+	conn, err := TryConnection(os.Getenv("POSTGRES_DSN"), 1)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	svc, err := NewDBService(&Options{
+		DSN:       os.Getenv("POSTGRES_DSN"),
+		TableName: "migration",
+		Directory: os.Getenv("S3_MIGRATIONS_PATH"),
+	}, conn, nil)
+	require.NoError(t, err)
+	defer svc.Close()
+
+	t.Cleanup(func() {
+		_, _ = svc.DownN(ctx, 10)
+	})
+
+	_, err = svc.To(ctx, "200905_202800")
+	require.NoError(t, err)
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from migration", 3) // base + 2 migrations
+
+	_, err = svc.DownN(ctx, 2)
+	require.NoError(t, err)
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from migration", 1) // base
+}
+
+// uploadMigrations copies the migration files of directory into the bucket directory of the S3 location.
+func uploadMigrations(t *testing.T, ctx context.Context, directory, location string) {
+	t.Helper()
+
+	src, err := source.Open(ctx, location, nil)
+	require.NoError(t, err)
+	defer src.Close()
+
+	bucket, ok := src.FS.(*blob.Bucket)
+	require.True(t, ok)
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.sql"))
+	require.NoError(t, err)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.NoError(t, bucket.WriteAll(ctx, path.Join(src.Directory, filepath.Base(file)), data, nil))
+	}
+}
+
+func TestIntegration_DBService_API_Successfully(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if err := godotenv.Load(".env"); err != nil {
+		require.NoError(t, err, "Load environments")
+	}
+
+	resetRegistry(t)
+
+	directory, err := filepath.Abs(os.Getenv("POSTGRES_MIGRATIONS_PATH"))
+	require.NoError(t, err)
+
+	conn, err := TryConnection(os.Getenv("POSTGRES_DSN"), 1)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	svc, err := NewDBService(&Options{
+		DSN:       os.Getenv("POSTGRES_DSN"),
+		TableName: "migration",
+		Directory: directory,
+	}, conn, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, _ = svc.DownN(ctx, 10)
+	})
+
+	pending, err := svc.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+	assert.Equal(t, "200905_192800_create_test_table", pending[0].Version)
+	assert.True(t, pending[0].ApplyTime.IsZero())
+
+	_, err = svc.UpN(ctx, 2)
+	require.NoError(t, err)
+
+	history, err := svc.History(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "200905_202800_create_test_table_trigger", history[0].Version)
+	assert.False(t, history[0].ApplyTime.IsZero())
+
+	status, err := svc.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.UpToDate())
+	assert.Equal(t, 2, status.Count(StateApplied))
+	assert.Equal(t, 1, status.Count(StatePending))
+
+	result, err := svc.Redo(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"200905_202800_create_test_table_trigger"}, resultVersions(result.Reverted))
+	assert.Equal(t, []string{"200905_202800_create_test_table_trigger"}, resultVersions(result.Applied))
+
+	_, err = svc.DownN(ctx, 2)
+	require.NoError(t, err)
+
+	// the broken migration fails the release, which is rolled back as a whole
+	_, err = svc.Release(ctx)
+	require.Error(t, err)
+	assertEqualQueryCount(t, ctx, conn, "select count(*) from migration", 1) // base
+}
+
+func resultVersions(results []MigrationResult) []string {
+	versions := make([]string, 0, len(results))
+	for _, result := range results {
+		versions = append(versions, result.Version)
+	}
+
+	return versions
+}
+
+func assertEqualQueryCount(t *testing.T, ctx context.Context, conn Connection, query string, expected int) {
+	t.Helper()
+
+	rows, err := conn.QueryContext(ctx, query)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var count int
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&count))
+	assert.Equal(t, expected, count)
+}